@@ -0,0 +1,62 @@
+package agnostic
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bluesky-social/indigo/xrpc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreferencesRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	stored := []byte(`{"preferences":[{"$type":"app.bsky.actor.defs#adultContentPref","enabled":false},{"$type":"com.example.unknownPref","nested":{"a":[1,2,3],"b":"c"},"flag":true}]}`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/app.bsky.actor.getPreferences":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(stored)
+		case "/xrpc/app.bsky.actor.putPreferences":
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(500)
+				return
+			}
+			stored = b
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer srv.Close()
+	c := &xrpc.Client{Host: srv.URL, Client: srv.Client()}
+
+	resp, err := ActorGetPreferences(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(2, len(resp.Preferences))
+
+	// mutate a known preference, and write everything back
+	resp.Preferences[0]["enabled"] = true
+	assert.NoError(ActorPutPreferences(ctx, c, &ActorPutPreferences_Input{Preferences: resp.Preferences}))
+
+	var out map[string]any
+	assert.NoError(json.Unmarshal(stored, &out))
+	prefs := out["preferences"].([]any)
+	assert.Equal(2, len(prefs))
+	assert.Equal(true, prefs[0].(map[string]any)["enabled"])
+	unknown := prefs[1].(map[string]any)
+	assert.Equal("com.example.unknownPref", unknown["$type"])
+	assert.Equal(true, unknown["flag"])
+	assert.Equal(map[string]any{"a": []any{1.0, 2.0, 3.0}, "b": "c"}, unknown["nested"])
+}
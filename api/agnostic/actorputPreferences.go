@@ -16,6 +16,8 @@ type ActorPutPreferences_Input struct {
 }
 
 // ActorPutPreferences calls the XRPC method "app.bsky.actor.putPreferences".
+//
+// If the server responds with an error, the returned error is an [*xrpc.Error] (with the HTTP status code). When the response body was a well-formed XRPC error, that wraps an [*xrpc.XRPCError] with the error name (eg, "InvalidRequest") and message, which can be extracted with [errors.As]. Network and transport failures are not [*xrpc.Error].
 func ActorPutPreferences(ctx context.Context, c *xrpc.Client, input *ActorPutPreferences_Input) error {
 	if err := c.Do(ctx, xrpc.Procedure, "application/json", "app.bsky.actor.putPreferences", nil, input, nil); err != nil {
 		return err
@@ -0,0 +1,64 @@
+package agnostic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bluesky-social/indigo/xrpc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActorPutPreferencesErrors(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	testCases := []struct {
+		status  int
+		body    string
+		errName string
+		message string
+	}{
+		{status: 400, body: `{"error":"InvalidRequest","message":"bad preference"}`, errName: "InvalidRequest", message: "bad preference"},
+		{status: 401, body: `{"error":"AuthRequired","message":"missing auth"}`, errName: "AuthRequired", message: "missing auth"},
+		{status: 502, body: `bad gateway`},
+	}
+
+	for _, tc := range testCases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(tc.status)
+			w.Write([]byte(tc.body))
+		}))
+		// plain client, so that 5xx responses are not retried
+		c := &xrpc.Client{Host: srv.URL, Client: srv.Client()}
+
+		err := ActorPutPreferences(ctx, c, &ActorPutPreferences_Input{Preferences: []map[string]any{}})
+		srv.Close()
+
+		var xerr *xrpc.Error
+		assert.True(errors.As(err, &xerr))
+		assert.Equal(tc.status, xerr.StatusCode)
+
+		var xrpcErr *xrpc.XRPCError
+		if tc.errName == "" {
+			assert.False(errors.As(err, &xrpcErr))
+			continue
+		}
+		if assert.True(errors.As(err, &xrpcErr)) {
+			assert.Equal(tc.errName, xrpcErr.ErrStr)
+			assert.Equal(tc.message, xrpcErr.Message)
+		}
+	}
+
+	// transport failure is not an XRPC error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+	err := ActorPutPreferences(ctx, &xrpc.Client{Host: srv.URL, Client: http.DefaultClient}, &ActorPutPreferences_Input{})
+	assert.Error(err)
+	var xerr *xrpc.Error
+	assert.False(errors.As(err, &xerr))
+}
@@ -0,0 +1,80 @@
+package crypto
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Returned by [VerifyBatch] for items which were skipped because an earlier item failed verification, when [BatchOpts.FailFast] is set.
+var ErrBatchAborted = errors.New("crypto: batch verification aborted after an earlier failure")
+
+// A single signature to be verified by [VerifyBatch].
+type VerifyItem struct {
+	PublicKey PublicKey
+	Content   []byte
+	Sig       []byte
+}
+
+// Options for [VerifyBatch]. The zero value is valid, and verifies every item using GOMAXPROCS workers.
+type BatchOpts struct {
+	// Maximum number of concurrent verifications. Zero or negative means runtime.GOMAXPROCS(0).
+	Workers int
+
+	// If true, items which have not started verification when any item fails are skipped, with the error [ErrBatchAborted]. Otherwise every item is verified.
+	FailFast bool
+}
+
+// Verifies a batch of independent signatures concurrently, returning one error per item, in the same order as the input. A nil error means the signature is valid.
+//
+// Each item is verified with [PublicKey.HashAndVerify], so the same rules apply (content is hashed with SHA-256, and "low-S" signatures are required for the elliptic curve types). Results are exactly the same as verifying each item individually, aside from items skipped with [ErrBatchAborted].
+//
+// This is currently a worker pool over individual verifications. Curve-specific batch verification optimizations may be added in the future, behind the same API.
+func VerifyBatch(items []VerifyItem, opts BatchOpts) []error {
+	errs := make([]error, len(items))
+	if len(items) == 0 {
+		return errs
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	var failed atomic.Bool
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(items) {
+					return
+				}
+				if opts.FailFast && failed.Load() {
+					errs[i] = ErrBatchAborted
+					continue
+				}
+				errs[i] = verifyItem(items[i])
+				if errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func verifyItem(item VerifyItem) error {
+	if item.PublicKey == nil {
+		return errors.New("crypto: batch verification item has no public key")
+	}
+	return item.PublicKey.HashAndVerify(item.Content, item.Sig)
+}
@@ -0,0 +1,189 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Indicates that a derivation path string (for [DeriveP256FromSeed] or [DeriveK256FromSeed]) could not be parsed.
+var ErrInvalidDerivationPath = errors.New("crypto: invalid key derivation path")
+
+// Index offset for hardened child keys in a derivation path (BIP-32)
+const hardenedKeyStart uint32 = 0x80000000
+
+// Parameters for SLIP-0010 derivation on a specific curve
+type slip10Curve struct {
+	// HMAC key used to derive the master key from the seed
+	seedKey string
+	// order of the curve group
+	n *big.Int
+	// compressed public key encoding for a private scalar, used for non-hardened derivation
+	publicBytes func(priv []byte) ([]byte, error)
+}
+
+var slip10P256 = slip10Curve{
+	seedKey: "Nist256p1 seed",
+	n:       curveN_P256,
+	publicBytes: func(priv []byte) ([]byte, error) {
+		k, err := ParsePrivateBytesP256(priv)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		return pub.Bytes(), nil
+	},
+}
+
+var slip10K256 = slip10Curve{
+	seedKey: "Bitcoin seed",
+	n:       curveN_K256,
+	publicBytes: func(priv []byte) ([]byte, error) {
+		k, err := ParsePrivateBytesK256(priv)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		return pub.Bytes(), nil
+	},
+}
+
+// Deterministically derives a P-256 private key from a seed and a derivation path, so the key can be re-created from the seed instead of being stored.
+//
+// Derivation follows SLIP-0010 (https://github.com/satoshilabs/slips/blob/master/slip-0010.md) for the "nist256p1" curve: the master key is HMAC-SHA512 of the seed with the key "Nist256p1 seed", and child keys are derived as in BIP-32, including the SLIP-0010 retry rule for out-of-range intermediate values. The result is the same as other SLIP-0010 implementations, and matches the published test vectors.
+//
+// The seed must be 16 to 64 bytes of high-entropy data, such as the 64-byte output of BIP-39 mnemonic-to-seed conversion (this package does not implement BIP-39). The path has the form "m/44'/0'/0", where a trailing ' (or "h", or "H") marks a hardened index. A path of just "m" returns the master key. Hardened indices are recommended, as a leaked non-hardened child key and the parent chain code reveal the parent key.
+//
+// The seed is equivalent to every key derived from it, and must be protected accordingly.
+func DeriveP256FromSeed(seed []byte, path string) (*PrivateKeyP256, error) {
+	priv, err := deriveSLIP10(slip10P256, seed, path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateBytesP256(priv)
+}
+
+// Deterministically derives a K-256 (secp256k1) private key from a seed and a derivation path, so the key can be re-created from the seed instead of being stored.
+//
+// Derivation follows BIP-32 (https://github.com/bitcoin/bips/blob/master/bip-0032.mediawiki), as generalized by SLIP-0010 for the "secp256k1" curve: the master key is HMAC-SHA512 of the seed with the key "Bitcoin seed". The result is the same as the private key of a BIP-32 wallet with the same seed and path (the SLIP-0010 and BIP-32 rules only differ for out-of-range intermediate values, which have negligible probability), and matches the published test vectors.
+//
+// Seed and path requirements are the same as for [DeriveP256FromSeed].
+func DeriveK256FromSeed(seed []byte, path string) (*PrivateKeyK256, error) {
+	priv, err := deriveSLIP10(slip10K256, seed, path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateBytesK256(priv)
+}
+
+// Parses a derivation path like "m/44'/0'/1" into child indices, with hardened indices offset by hardenedKeyStart
+func parseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("%w: must start with 'm': %q", ErrInvalidDerivationPath, path)
+	}
+	indices := make([]uint32, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		hardened := false
+		if s, ok := strings.CutSuffix(p, "'"); ok {
+			p, hardened = s, true
+		} else if s, ok := strings.CutSuffix(p, "h"); ok {
+			p, hardened = s, true
+		} else if s, ok := strings.CutSuffix(p, "H"); ok {
+			p, hardened = s, true
+		}
+		// ParseUint allows a leading '+', which is not valid here
+		if p == "" || p[0] < '0' || p[0] > '9' {
+			return nil, fmt.Errorf("%w: bad index %q", ErrInvalidDerivationPath, p)
+		}
+		idx, err := strconv.ParseUint(p, 10, 32)
+		if err != nil || uint32(idx) >= hardenedKeyStart {
+			return nil, fmt.Errorf("%w: bad index %q", ErrInvalidDerivationPath, p)
+		}
+		if hardened {
+			idx += uint64(hardenedKeyStart)
+		}
+		indices = append(indices, uint32(idx))
+	}
+	return indices, nil
+}
+
+// Returns the private key scalar (32 bytes) for the seed and path
+func deriveSLIP10(curve slip10Curve, seed []byte, path string) ([]byte, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("crypto: derivation seed must be 16 to 64 bytes, got %d", len(seed))
+	}
+	indices, err := parseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// master key: re-hash until the left half is a valid scalar
+	mac := hmac.New(sha512.New, []byte(curve.seedKey))
+	mac.Write(seed)
+	I := mac.Sum(nil)
+	for {
+		il := new(big.Int).SetBytes(I[:32])
+		if il.Sign() != 0 && il.Cmp(curve.n) < 0 {
+			break
+		}
+		mac = hmac.New(sha512.New, []byte(curve.seedKey))
+		mac.Write(I)
+		I = mac.Sum(nil)
+	}
+	key := new(big.Int).SetBytes(I[:32])
+	chainCode := I[32:]
+
+	for _, idx := range indices {
+		var ser32 [4]byte
+		binary.BigEndian.PutUint32(ser32[:], idx)
+		data := make([]byte, 0, 37)
+		if idx >= hardenedKeyStart {
+			data = append(data, 0x00)
+			data = append(data, key.FillBytes(make([]byte, 32))...)
+		} else {
+			pub, err := curve.publicBytes(key.FillBytes(make([]byte, 32)))
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, pub...)
+		}
+		data = append(data, ser32[:]...)
+
+		mac = hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		I = mac.Sum(nil)
+		for {
+			il := new(big.Int).SetBytes(I[:32])
+			if il.Cmp(curve.n) < 0 {
+				il.Add(il, key)
+				il.Mod(il, curve.n)
+				if il.Sign() != 0 {
+					key = il
+					break
+				}
+			}
+			// out of range: retry with the right half (SLIP-0010)
+			retry := make([]byte, 0, 37)
+			retry = append(retry, 0x01)
+			retry = append(retry, I[32:]...)
+			retry = append(retry, ser32[:]...)
+			mac = hmac.New(sha512.New, chainCode)
+			mac.Write(retry)
+			I = mac.Sum(nil)
+		}
+		chainCode = I[32:]
+	}
+	return key.FillBytes(make([]byte, 32)), nil
+}
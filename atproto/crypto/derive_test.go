@@ -0,0 +1,60 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeriveFromSeed(t *testing.T) {
+	assert := assert.New(t)
+
+	// test vector 1 from BIP-32 (secp256k1) and SLIP-0010 (secp256k1 and nist256p1)
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	vectors := []struct {
+		path string
+		k256 string
+		p256 string
+	}{
+		{"m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35", "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2"},
+		{"m/0H", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea", "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c"},
+		{"m/0H/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368", "284e9d38d07d21e4e281b645089a94f4cf5a5a81369acf151a1c3a57f18b2129"},
+		{"m/0H/1/2H", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca", "694596e8a54f252c960eb771a3c41e7e32496d03b954aeb90f61635b8e092aa7"},
+		{"m/0H/1/2H/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4", "5996c37fd3dd2679039b23ed6f70b506c6b56b3cb5e424681fb0fa64caf82aaa"},
+		{"m/0H/1/2H/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8", "21c4f269ef0a5fd1badf47eeacebeeaa3de22eb8e5b0adcd0f27dd99d34d0119"},
+	}
+	for _, v := range vectors {
+		k, err := DeriveK256FromSeed(seed, v.path)
+		assert.NoError(err)
+		assert.Equal(v.k256, hex.EncodeToString(k.Bytes()), v.path)
+		p, err := DeriveP256FromSeed(seed, v.path)
+		assert.NoError(err)
+		assert.Equal(v.p256, hex.EncodeToString(p.Bytes()), v.path)
+	}
+
+	// hardened index notations are equivalent
+	a, err := DeriveP256FromSeed(seed, "m/0'/1")
+	assert.NoError(err)
+	b, err := DeriveP256FromSeed(seed, "m/0h/1")
+	assert.NoError(err)
+	assert.True(a.Equal(b))
+
+	// derived keys are regular keys, which sign with low-S signatures
+	pub, err := a.PublicKey()
+	assert.NoError(err)
+	msg := []byte("hello world")
+	sig, err := a.HashAndSign(msg)
+	assert.NoError(err)
+	assert.NoError(pub.HashAndVerify(msg, sig))
+
+	// invalid inputs
+	for _, path := range []string{"", "M", "m/", "/0", "m/0/", "m//1", "m/x", "m/-1", "m/+1", "m/1''", "m/2147483648", "m/2147483648H"} {
+		_, err := DeriveP256FromSeed(seed, path)
+		assert.ErrorIs(err, ErrInvalidDerivationPath, path)
+	}
+	_, err = DeriveK256FromSeed(seed[:15], "m")
+	assert.Error(err)
+	_, err = DeriveK256FromSeed(make([]byte, 65), "m")
+	assert.Error(err)
+}
@@ -7,5 +7,17 @@
 //   - P-256/secp256r1, internally implemented using golang's stdlib cryptographic library
 //   - K-256/secp256r1, internally implemented using https://gitlab.com/yawning/secp256k1-voi
 //
-// "Low-S" signatures are enforced for both key types, both when creating signatures and during verification, as required by the atproto specification.
+// Ed25519 keys ([PrivateKeyEd25519], [PublicKeyEd25519]) are also supported, for interoperability with other did:key systems. These are not valid atproto signing keys. Note that Ed25519 signs content directly, without the SHA-256 pre-hashing done for the elliptic curve types.
+//
+// The P-256 and K-256 key types also have SignDigest and VerifyDigest methods, which take a pre-computed 32-byte digest instead of hashing content. These are for callers which manage hashing themselves; the HashAndSign and HashAndVerify methods are the atproto-specified path.
+//
+// [DeriveP256FromSeed] and [DeriveK256FromSeed] deterministically derive keys from a seed and path, following SLIP-0010 (compatible with BIP-32 for K-256), so that recovery keys can be re-created instead of stored.
+//
+// [RemoteSigner] implements [PrivateKey] by delegating signing of digests to an external service (such as a KMS or HSM), for deployments which should not hold secret key material in memory.
+//
+// The concrete key types implement [encoding.BinaryMarshaler] and [encoding.BinaryUnmarshaler] (a one-byte key type tag followed by the Bytes() encoding; also used by encoding/gob), and public keys implement JSON marshaling as did:key strings, so keys can be fields in serialized structs. [ParsePrivateBinary] and [ParsePublicBinary] load the binary encoding when the key type is not known ahead of time.
+//
+// [VerifyBatch] verifies many independent signatures concurrently, with the same semantics as HashAndVerify.
+//
+// "Low-S" signatures are enforced for both key types, both when creating signatures and during verification, as required by the atproto specification. The one explicit, opt-in exception is [PublicKeyP256.HashAndVerifyNonCanonical], for bridging browser-originated (WebCrypto) signatures.
 package crypto
@@ -0,0 +1,176 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/mr-tron/base58"
+)
+
+// Implements the [PrivateKeyExportable] and [PrivateKey] interfaces for the Ed25519 signature system (EdDSA over Curve25519).
+// Secret key material is naively stored in memory.
+//
+// Ed25519 is not currently an atproto signing key type: it is not valid in DID documents or for repository commit signatures. It is supported for adjacent uses (eg, interop with other did:key systems).
+type PrivateKeyEd25519 struct {
+	privEd25519 ed25519.PrivateKey
+}
+
+// Implements the [PublicKey] interface for the Ed25519 signature system (EdDSA over Curve25519).
+type PublicKeyEd25519 struct {
+	pubEd25519 ed25519.PublicKey
+}
+
+var _ PrivateKey = (*PrivateKeyEd25519)(nil)
+var _ PrivateKeyExportable = (*PrivateKeyEd25519)(nil)
+var _ PublicKey = (*PublicKeyEd25519)(nil)
+
+// Creates a secure new cryptographic key from scratch.
+func GeneratePrivateKeyEd25519() (*PrivateKeyEd25519, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("Ed25519 key generation failed: %w", err)
+	}
+	return &PrivateKeyEd25519{privEd25519: key}, nil
+}
+
+// Loads a [PrivateKeyEd25519] from raw bytes, as exported by the PrivateKey.Bytes method. This is the 32-byte "seed" (RFC 8032) format.
+//
+// Calling code needs to know the key type ahead of time, and must remove any string encoding (hex encoding, base64, etc) before calling this function.
+func ParsePrivateBytesEd25519(data []byte) (*PrivateKeyEd25519, error) {
+	if len(data) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid Ed25519 private key: wrong length: %d", len(data))
+	}
+	return &PrivateKeyEd25519{privEd25519: ed25519.NewKeyFromSeed(data)}, nil
+}
+
+// Returns [KeyTypeEd25519]
+func (k *PrivateKeyEd25519) Type() string {
+	return KeyTypeEd25519
+}
+
+// Checks if the two private keys are the same. Note that the naive == operator does not work for most equality checks.
+//
+// The comparison of secret key material is constant-time (when both keys are Ed25519).
+func (k *PrivateKeyEd25519) Equal(other PrivateKey) bool {
+	otherEd25519, ok := other.(*PrivateKeyEd25519)
+	if ok {
+		return subtle.ConstantTimeCompare(k.privEd25519, otherEd25519.privEd25519) == 1
+	}
+	return false
+}
+
+// Serializes the secret key material in to a raw binary format, which can be parsed by [ParsePrivateBytesEd25519].
+//
+// For Ed25519, this is the 32-byte "seed" from RFC 8032 (not the 64-byte seed-plus-public-key format used by some libraries). There is no ASN.1 or other enclosing structure.
+func (k *PrivateKeyEd25519) Bytes() []byte {
+	return k.privEd25519.Seed()
+}
+
+// Multibase string encoding of the private key, including a multicodec indicator
+func (k *PrivateKeyEd25519) Multibase() string {
+	kbytes := k.Bytes()
+	// multicodec ed25519-priv, code 0x1300, varint-encoded bytes: [0x80, 0x26]
+	kbytes = append([]byte{0x80, 0x26}, kbytes...)
+	return "z" + base58.Encode(kbytes)
+}
+
+// Outputs the [PublicKey] corresponding to this [PrivateKeyEd25519]; it will be a [PublicKeyEd25519].
+func (k *PrivateKeyEd25519) PublicKey() (PublicKey, error) {
+	pub, ok := k.privEd25519.Public().(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unexpected Ed25519 public key type (internal)")
+	}
+	return &PublicKeyEd25519{pubEd25519: pub}, nil
+}
+
+// Signs the raw bytes, returning a binary signature.
+//
+// Unlike the ECDSA key types, this method does not apply SHA-256 before signing. Ed25519 ("pure" EdDSA, RFC 8032) signs the message directly, and hashes internally with SHA-512 as part of the algorithm. The method name is retained for compatibility with the [PrivateKey] interface. Signatures are not interoperable with any "pre-hashed" (Ed25519ph) variant, or with code which passes a SHA-256 digest as the message.
+//
+// Calling code is responsible for any string encoding of signatures (eg, hex or base64). For Ed25519, the signature is 64 bytes long. Signatures are deterministic, and there is no "low-S" malleability concern.
+func (k *PrivateKeyEd25519) HashAndSign(content []byte) ([]byte, error) {
+	return ed25519.Sign(k.privEd25519, content), nil
+}
+
+// Loads a [PublicKeyEd25519] from raw bytes, as exported by the PublicKey.Bytes method. This is the 32-byte RFC 8032 encoding.
+//
+// Calling code needs to know the key type ahead of time, and must remove any string encoding (hex encoding, base64, etc) before calling this function.
+func ParsePublicBytesEd25519(data []byte) (*PublicKeyEd25519, error) {
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Ed25519 public key: wrong length: %d", len(data))
+	}
+	pub := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	copy(pub, data)
+	return &PublicKeyEd25519{pubEd25519: pub}, nil
+}
+
+// Returns [KeyTypeEd25519]
+func (k *PublicKeyEd25519) Type() string {
+	return KeyTypeEd25519
+}
+
+// Checks if the two public keys are the same. Note that the naive == operator does not work for most equality checks.
+func (k *PublicKeyEd25519) Equal(other PublicKey) bool {
+	otherEd25519, ok := other.(*PublicKeyEd25519)
+	if ok {
+		return k.pubEd25519.Equal(otherEd25519.pubEd25519)
+	}
+	return false
+}
+
+// Serializes the key in to binary format. Ed25519 has no compressed/uncompressed distinction, so this is the same as Bytes().
+func (k *PublicKeyEd25519) UncompressedBytes() []byte {
+	return k.Bytes()
+}
+
+// Serializes the key in to the 32-byte RFC 8032 binary format.
+func (k *PublicKeyEd25519) Bytes() []byte {
+	out := make([]byte, len(k.pubEd25519))
+	copy(out, k.pubEd25519)
+	return out
+}
+
+// Verifies a signature of the raw bytes, returning `nil` for valid signatures, or an error for any failure.
+//
+// As with [PrivateKeyEd25519.HashAndSign], the content is not pre-hashed with SHA-256: Ed25519 verifies the message directly.
+//
+// Calling code is responsible for any string decoding of signatures (eg, hex or base64) before calling this function.
+func (k *PublicKeyEd25519) HashAndVerify(content, sig []byte) error {
+	if !ed25519.Verify(k.pubEd25519, content, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Same as HashAndVerify(). Ed25519 signatures do not have the "low-S" ambiguity of ECDSA, so there is no lenient mode.
+func (k *PublicKeyEd25519) HashAndVerifyLenient(content, sig []byte) error {
+	return k.HashAndVerify(content, sig)
+}
+
+// Returns a multibased string encoding of the public key, including a multicodec indicator
+func (k *PublicKeyEd25519) Multibase() string {
+	kbytes := k.Bytes()
+	// multicodec ed25519-pub, code 0xED, varint bytes: [0xED, 0x01]
+	kbytes = append([]byte{0xED, 0x01}, kbytes...)
+	return "z" + base58.Encode(kbytes)
+}
+
+// Returns a did:key string encoding of the public key:
+//
+//   - RFC 8032 binary representation
+//   - prefix with ed25519-pub multicodec bytes
+//   - encode bytes with base58btc
+//   - add "z" prefix to indicate encoding
+//   - add "did:key:" prefix
+func (k *PublicKeyEd25519) DIDKey() string {
+	return "did:key:" + k.Multibase()
+}
+
+// DID document verificationMethod entry for this key, using the "Multikey" type, with the given "id" and "controller", and the key in "publicKeyMultibase" format.
+//
+// The output can be parsed with [ParsePublicVerificationMethod].
+func (k *PublicKeyEd25519) VerificationMethod(id, controller string) map[string]any {
+	return verificationMethod(k, id, controller)
+}
@@ -45,3 +45,38 @@ func ExamplePrivateKey() {
 	}
 	// Output: Success!
 }
+
+func ExampleRemoteSigner() {
+	// stand-in for a KMS or HSM; a real service would be called over the network, and would only expose the public key
+	kmsKey, err := GeneratePrivateKeyP256()
+	if err != nil {
+		panic("failed to generate key")
+	}
+	kmsSign := func(digest []byte) ([]byte, error) {
+		sig, err := kmsKey.SignDigest(digest)
+		if err != nil {
+			return nil, err
+		}
+		// many services return DER-encoded signatures
+		return CompactToDER(sig)
+	}
+	pub, err := kmsKey.PublicKey()
+	if err != nil {
+		panic("failed to get public key")
+	}
+
+	var priv PrivateKey
+	priv, err = NewRemoteSigner(pub, kmsSign)
+	if err != nil {
+		panic("failed to create remote signer")
+	}
+
+	msg := []byte("hello world")
+	sig, _ := priv.HashAndSign(msg)
+	if err = pub.HashAndVerify(msg, sig); err != nil {
+		fmt.Println("Verification Failed")
+	} else {
+		fmt.Println("Success!")
+	}
+	// Output: Success!
+}
@@ -0,0 +1,152 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON Web Key (JWK) representation of an elliptic curve key, as specified in RFC 7517 and RFC 7518.
+//
+// Only the "EC" key type is supported, with the "P-256" and "secp256k1" curves. The secret scalar ('D') is only included for private keys. Coordinates and secret are base64url-encoded (no padding), as fixed-length big-endian byte strings.
+type JWK struct {
+	KeyType string  `json:"kty"`
+	Curve   string  `json:"crv"`
+	X       string  `json:"x"`
+	Y       string  `json:"y"`
+	D       string  `json:"d,omitempty"`
+	Use     *string `json:"use,omitempty"`
+	KeyID   *string `json:"kid,omitempty"`
+}
+
+// JWK "crv" names for supported key types
+const (
+	jwkCurveP256 = "P-256"
+	jwkCurveK256 = "secp256k1"
+)
+
+// Length in bytes of curve coordinates and secret scalars, for all currently supported curves.
+const jwkFieldLength = 32
+
+// shared implementation of JWK() for the public key types which support it (P-256 and K-256)
+func publicJWK(pub PublicKey) (*JWK, error) {
+	var crv string
+	switch pub.Type() {
+	case KeyTypeP256:
+		crv = jwkCurveP256
+	case KeyTypeK256:
+		crv = jwkCurveK256
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKeyType, pub.Type())
+	}
+	// uncompressed point encoding is: 0x04 | X | Y
+	raw := pub.UncompressedBytes()
+	if len(raw) != 1+2*jwkFieldLength || raw[0] != 0x04 {
+		return nil, fmt.Errorf("crypto: unexpected uncompressed public key encoding (internal)")
+	}
+	return &JWK{
+		KeyType: "EC",
+		Curve:   crv,
+		X:       base64.RawURLEncoding.EncodeToString(raw[1 : 1+jwkFieldLength]),
+		Y:       base64.RawURLEncoding.EncodeToString(raw[1+jwkFieldLength:]),
+	}, nil
+}
+
+// shared implementation of JWK() for all exportable private key types
+func privateJWK(priv PrivateKeyExportable) (*JWK, error) {
+	pub, err := priv.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	jwk, err := publicJWK(pub)
+	if err != nil {
+		return nil, err
+	}
+	jwk.D = base64.RawURLEncoding.EncodeToString(priv.Bytes())
+	return jwk, nil
+}
+
+func decodeJWKField(name, val string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(val)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: JWK field %s not base64url: %w", name, err)
+	}
+	if len(b) != jwkFieldLength {
+		return nil, fmt.Errorf("crypto: JWK field %s has wrong length: %d", name, len(b))
+	}
+	return b, nil
+}
+
+// parses the public part of a JWK, checking key type and curve
+func (jwk *JWK) publicKey() (PublicKey, error) {
+	if jwk.KeyType != "EC" {
+		return nil, fmt.Errorf("%w: JWK kty %q", ErrUnsupportedKeyType, jwk.KeyType)
+	}
+	x, err := decodeJWKField("x", jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeJWKField("y", jwk.Y)
+	if err != nil {
+		return nil, err
+	}
+	raw := append(append([]byte{0x04}, x...), y...)
+	switch jwk.Curve {
+	case jwkCurveP256:
+		return ParsePublicUncompressedBytesP256(raw)
+	case jwkCurveK256:
+		return ParsePublicUncompressedBytesK256(raw)
+	default:
+		return nil, fmt.Errorf("%w: JWK crv %q", ErrUnsupportedKeyType, jwk.Curve)
+	}
+}
+
+// Loads a [PublicKey] from JWK JSON serialization. The curve ("crv") determines which implementation is returned.
+//
+// Any private key material ("d") is ignored.
+func ParsePublicJWK(data []byte) (PublicKey, error) {
+	var jwk JWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("crypto: parsing JWK JSON: %w", err)
+	}
+	return jwk.publicKey()
+}
+
+// Loads a private key from JWK JSON serialization. The curve ("crv") determines which implementation is returned.
+//
+// The public coordinates ("x" and "y") are required, and must match the private key.
+func ParsePrivateJWK(data []byte) (PrivateKeyExportable, error) {
+	var jwk JWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("crypto: parsing JWK JSON: %w", err)
+	}
+	pub, err := jwk.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	if jwk.D == "" {
+		return nil, fmt.Errorf("crypto: JWK is missing private key material")
+	}
+	d, err := decodeJWKField("d", jwk.D)
+	if err != nil {
+		return nil, err
+	}
+	var priv PrivateKeyExportable
+	switch jwk.Curve {
+	case jwkCurveP256:
+		priv, err = ParsePrivateBytesP256(d)
+	case jwkCurveK256:
+		priv, err = ParsePrivateBytesK256(d)
+	}
+	if err != nil {
+		return nil, err
+	}
+	derived, err := priv.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if !derived.Equal(pub) {
+		return nil, fmt.Errorf("crypto: JWK private key does not match public key")
+	}
+	return priv, nil
+}
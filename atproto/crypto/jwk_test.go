@@ -0,0 +1,132 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// implemented by the concrete key types which support JWK export
+type jwkExporter interface {
+	JWK() (*JWK, error)
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	privP256, err := GeneratePrivateKeyP256()
+	assert.NoError(err)
+	privK256, err := GeneratePrivateKeyK256()
+	assert.NoError(err)
+
+	for _, priv := range []interface {
+		PrivateKeyExportable
+		jwkExporter
+	}{privP256, privK256} {
+		pub, err := priv.PublicKey()
+		assert.NoError(err)
+
+		pubJWK, err := pub.(jwkExporter).JWK()
+		assert.NoError(err)
+		assert.Equal("EC", pubJWK.KeyType)
+		assert.Empty(pubJWK.D)
+		pubJSON, err := json.Marshal(pubJWK)
+		assert.NoError(err)
+		pubParsed, err := ParsePublicJWK(pubJSON)
+		assert.NoError(err)
+		assert.True(pub.Equal(pubParsed))
+
+		// public-only JWK can't be parsed as a private key
+		_, err = ParsePrivateJWK(pubJSON)
+		assert.Error(err)
+
+		privJWK, err := priv.JWK()
+		assert.NoError(err)
+		assert.NotEmpty(privJWK.D)
+		privJSON, err := json.Marshal(privJWK)
+		assert.NoError(err)
+		privParsed, err := ParsePrivateJWK(privJSON)
+		assert.NoError(err)
+		assert.True(priv.Equal(privParsed))
+
+		// private JWK also parses as a public key
+		pubFromPriv, err := ParsePublicJWK(privJSON)
+		assert.NoError(err)
+		assert.True(pub.Equal(pubFromPriv))
+	}
+
+	// curves map to the correct key types
+	pubJWK, err := privP256.JWK()
+	assert.NoError(err)
+	assert.Equal("P-256", pubJWK.Curve)
+	pubJWK, err = privK256.JWK()
+	assert.NoError(err)
+	assert.Equal("secp256k1", pubJWK.Curve)
+}
+
+func TestJWKVectors(t *testing.T) {
+	assert := assert.New(t)
+
+	// RFC 7517, Appendix A.2
+	rfcJWK := []byte(`{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM","d":"870MB6gfuTJ4HtUnUvYMyJpr5eUZNP4Bk43bVdj3eAE","use":"enc","kid":"1"}`)
+	priv, err := ParsePrivateJWK(rfcJWK)
+	assert.NoError(err)
+	assert.Equal(KeyTypeP256, priv.Type())
+	pub, err := ParsePublicJWK(rfcJWK)
+	assert.NoError(err)
+	assert.Equal(KeyTypeP256, pub.Type())
+	out, err := pub.(jwkExporter).JWK()
+	assert.NoError(err)
+	assert.Equal("MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4", out.X)
+	assert.Equal("4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM", out.Y)
+
+	// secp256k1 secret scalar of 1 has the curve generator point as public key
+	b64 := func(h string) string {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	k256JWK := JWK{
+		KeyType: "EC",
+		Curve:   "secp256k1",
+		X:       b64("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"),
+		Y:       b64("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"),
+		D:       b64("0000000000000000000000000000000000000000000000000000000000000001"),
+	}
+	k256JSON, err := json.Marshal(k256JWK)
+	assert.NoError(err)
+	priv, err = ParsePrivateJWK(k256JSON)
+	assert.NoError(err)
+	assert.Equal(KeyTypeK256, priv.Type())
+
+	// mismatched or unsupported types and curves
+	for _, mod := range []func(j *JWK){
+		func(j *JWK) { j.KeyType = "RSA" },
+		func(j *JWK) { j.KeyType = "OKP" },
+		func(j *JWK) { j.Curve = "P-384" },
+		func(j *JWK) { j.Curve = "P-256" },
+		func(j *JWK) { j.X = j.X[1:] },
+	} {
+		bad := k256JWK
+		mod(&bad)
+		badJSON, err := json.Marshal(bad)
+		assert.NoError(err)
+		_, err = ParsePublicJWK(badJSON)
+		assert.Error(err)
+		_, err = ParsePrivateJWK(badJSON)
+		assert.Error(err)
+	}
+
+	// private key which doesn't match public coordinates
+	bad := k256JWK
+	bad.D = b64("0000000000000000000000000000000000000000000000000000000000000002")
+	badJSON, err := json.Marshal(bad)
+	assert.NoError(err)
+	_, err = ParsePrivateJWK(badJSON)
+	assert.Error(err)
+}
@@ -65,13 +65,31 @@ func ParsePrivateBytesK256(data []byte) (*PrivateKeyK256, error) {
 	return &PrivateKeyK256{privK256: sk}, nil
 }
 
+// Returns [KeyTypeK256]
+func (k *PrivateKeyK256) Type() string {
+	return KeyTypeK256
+}
+
 // Checks if the two private keys are the same. Note that the naive == operator does not work for most equality checks.
+//
+// The comparison of secret key material is constant-time (when both keys are K-256).
 func (k *PrivateKeyK256) Equal(other PrivateKey) bool {
 	otherK256, ok := other.(*PrivateKeyK256)
-	if ok {
-		return k.privK256.Equal(otherK256.privK256)
+	if !ok || otherK256 == nil {
+		return false
 	}
-	return false
+	// a wiped key is not equal to anything, including itself
+	if k.privK256 == nil || otherK256.privK256 == nil {
+		return false
+	}
+	return k.privK256.Equal(otherK256.privK256)
+}
+
+// Best-effort clearing of secret key material from memory. The key must not be used after calling this method.
+//
+// The underlying secp256k1 library does not expose its secret scalar for zeroing, so this only drops the reference and leaves the memory to the garbage collector. It is provided for parity with [PrivateKeyP256.Wipe], and so that key management code can treat key types uniformly.
+func (k *PrivateKeyK256) Wipe() {
+	k.privK256 = nil
 }
 
 // Serializes the secret key material in to a raw binary format, which can be parsed by [ParsePrivateBytesK256].
@@ -89,6 +107,11 @@ func (k *PrivateKeyK256) Multibase() string {
 	return "z" + base58.Encode(kbytes)
 }
 
+// JSON Web Key (JWK) representation of this private key, with curve "secp256k1", including the secret key material ("d"). The output can be parsed with [ParsePrivateJWK].
+func (k *PrivateKeyK256) JWK() (*JWK, error) {
+	return privateJWK(k)
+}
+
 // Outputs the [PublicKey] corresponding to this [PrivateKeyK256]; it will be a [PublicKeyK256].
 func (k PrivateKeyK256) PublicKey() (PublicKey, error) {
 	pub := PublicKeyK256{pubK256: k.privK256.PublicKey()}
@@ -108,7 +131,29 @@ func (k PrivateKeyK256) PublicKey() (PublicKey, error) {
 // NIST ECDSA signatures can have a "malleability" issue, meaning that there are multiple valid signatures for the same content with the same signing key. This method always returns a "low-S" signature, as required by atproto.
 func (k PrivateKeyK256) HashAndSign(content []byte) ([]byte, error) {
 	hash := sha256.Sum256(content)
-	return k.privK256.Sign(rand.Reader, hash[:], k256Options)
+	return k.SignDigest(hash[:])
+}
+
+// Signs a pre-computed 32-byte digest, returning a binary signature. Unlike HashAndSign(), no hashing is done.
+//
+// This is for callers who manage hashing themselves (eg, with a different hash function in non-atproto contexts). For atproto data, use HashAndSign(). Returns an error if the digest is not 32 bytes. The signature is "low-S", the same as HashAndSign().
+func (k PrivateKeyK256) SignDigest(digest []byte) ([]byte, error) {
+	if len(digest) != sha256.Size {
+		return nil, fmt.Errorf("crypto: digest must be %d bytes, got len=%d", sha256.Size, len(digest))
+	}
+	return k.privK256.Sign(rand.Reader, digest, k256Options)
+}
+
+// Performs an Elliptic Curve Diffie-Hellman (ECDH) exchange with another K-256 public key, returning the shared secret.
+//
+// The secret is the x-coordinate of the shared point, 32 bytes long, as specified in SEC 1, Version 2.0, Section 3.3.1. It should not be used directly as a symmetric key; pass it through a key derivation function (eg, HKDF) first.
+//
+// This is not part of the atproto signing path. Reusing a signing key for encryption is possible, but calling code should consider whether separate keys would be more appropriate.
+func (k *PrivateKeyK256) SharedSecret(pub *PublicKeyK256) ([]byte, error) {
+	if k.privK256 == nil {
+		return nil, fmt.Errorf("K-256/secp256k1 private key has been wiped")
+	}
+	return k.privK256.ECDH(pub.pubK256)
 }
 
 // Loads a [PublicKeyK256] raw bytes, as exported by the PublicKey.Bytes method. This is the "compressed" curve format.
@@ -151,6 +196,11 @@ func ParsePublicUncompressedBytesK256(data []byte) (*PublicKeyK256, error) {
 	return &pub, nil
 }
 
+// Returns [KeyTypeK256]
+func (k *PublicKeyK256) Type() string {
+	return KeyTypeK256
+}
+
 // Checks if the two public keys are the same. Note that the naive == operator does not work for most equality checks.
 func (k *PublicKeyK256) Equal(other PublicKey) bool {
 	otherK256, ok := other.(*PublicKeyK256)
@@ -190,7 +240,17 @@ func (k *PublicKeyK256) Bytes() []byte {
 // This method requires a "low-S" signature, as specified by atproto.
 func (k *PublicKeyK256) HashAndVerify(content, sig []byte) error {
 	hash := sha256.Sum256(content)
-	if !k.pubK256.Verify(hash[:], sig, k256Options) {
+	return k.VerifyDigest(hash[:], sig)
+}
+
+// Verifies a signature against a pre-computed 32-byte digest. Unlike HashAndVerify(), no hashing is done.
+//
+// This is for callers who manage hashing themselves. For atproto data, use HashAndVerify(). Returns an error if the digest is not 32 bytes. Like HashAndVerify(), this method requires a "low-S" signature.
+func (k *PublicKeyK256) VerifyDigest(digest, sig []byte) error {
+	if len(digest) != sha256.Size {
+		return fmt.Errorf("crypto: digest must be %d bytes, got len=%d", sha256.Size, len(digest))
+	}
+	if !k.pubK256.Verify(digest, sig, k256Options) {
 		return ErrInvalidSignature
 	}
 	return nil
@@ -225,3 +285,15 @@ func (k *PublicKeyK256) Multibase() string {
 func (k *PublicKeyK256) DIDKey() string {
 	return "did:key:" + k.Multibase()
 }
+
+// DID document verificationMethod entry for this key, as atproto expects: "Multikey" type, with the given "id" (eg, "did:plc:abc123#atproto") and "controller" (the DID), and the key in "publicKeyMultibase" format.
+//
+// The output can be parsed with [ParsePublicVerificationMethod].
+func (k *PublicKeyK256) VerificationMethod(id, controller string) map[string]any {
+	return verificationMethod(k, id, controller)
+}
+
+// JSON Web Key (JWK) representation of this public key, with curve "secp256k1". The output can be parsed with [ParsePublicJWK].
+func (k *PublicKeyK256) JWK() (*JWK, error) {
+	return publicJWK(k)
+}
@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"strings"
@@ -10,9 +11,15 @@ import (
 
 // Common interface for all the supported atproto cryptographic systems, when
 // secret key material may not be directly available to be exported as bytes.
+//
+// This interface does not require access to secret key material, so it can be
+// implemented by keys held in an external signing service (see [RemoteSigner]).
 type PrivateKey interface {
 	Equal(other PrivateKey) bool
 
+	// Short name of the key type (curve): [KeyTypeP256], [KeyTypeK256], or [KeyTypeEd25519].
+	Type() string
+
 	PublicKey() (PublicKey, error)
 
 	// Hashes the raw bytes using SHA-256, then signs the digest bytes.
@@ -37,6 +44,9 @@ type PrivateKeyExportable interface {
 type PublicKey interface {
 	Equal(other PublicKey) bool
 
+	// Short name of the key type (curve): [KeyTypeP256], [KeyTypeK256], or [KeyTypeEd25519].
+	Type() string
+
 	// Compact byte serialization (for elliptic curve systems where encoding is ambiguous).
 	Bytes() []byte
 
@@ -54,6 +64,9 @@ type PublicKey interface {
 	// String serialization of the key bytes as a did:key.
 	DIDKey() string
 
+	// DID document verificationMethod entry for this key, using the "Multikey" type.
+	VerificationMethod(id, controller string) map[string]any
+
 	// Non-compact byte serialization (for elliptic curve systems where
 	// encoding is ambiguous)
 	//
@@ -65,8 +78,26 @@ type PublicKey interface {
 	UncompressedBytes() []byte
 }
 
+// Key type names, as returned by the Type() method on keys
+const (
+	KeyTypeP256 = "p256"
+	KeyTypeK256 = "secp256k1"
+	// Ed25519 keys are supported for interoperability, but are not valid atproto signing keys (eg, in DID documents)
+	KeyTypeEd25519 = "ed25519"
+)
+
 var ErrInvalidSignature = errors.New("crytographic signature invalid")
 
+// Indicates that a multicodec-prefixed key encoding was for a key type (curve) not supported by atproto.
+var ErrUnsupportedKeyType = errors.New("unsupported atproto key type")
+
+// Multicodec codes for public key types, as returned by [ParseMultibaseKey]
+const (
+	MulticodecP256Pub    uint64 = 0x1200
+	MulticodecK256Pub    uint64 = 0xE7
+	MulticodecEd25519Pub uint64 = 0xED
+)
+
 /*
 // quick code to verify varint byte conversion (https://play.golang.com/):
 import  (
@@ -74,7 +105,7 @@ import  (
 	"fmt"
 )
 buf := make([]byte, binary.MaxVarintLen64)
-for _, x := range []uint64{0xE7, 0x1200, 0x1306, 0x1301} {
+for _, x := range []uint64{0xE7, 0x1200, 0x1306, 0x1301, 0xED, 0x1300} {
 	n := binary.PutUvarint(buf, x)
 	fmt.Printf("%x -> %x\n", x, buf[:n])
 }
@@ -98,12 +129,80 @@ func ParsePrivateMultibase(encoded string) (PrivateKeyExportable, error) {
 	} else if data[0] == 0x81 && data[1] == 0x26 {
 		// multicodec secp256k1-priv, code 0x1301, varint-encoded bytes: [0x81, 0x26]
 		return ParsePrivateBytesK256(data[2:])
+	} else if data[0] == 0x80 && data[1] == 0x26 {
+		// multicodec ed25519-priv, code 0x1300, varint-encoded bytes: [0x80, 0x26]
+		return ParsePrivateBytesEd25519(data[2:])
 	} else {
-		return nil, fmt.Errorf("unsupported atproto key type (unknown multicodec prefix)")
+		return nil, fmt.Errorf("%w (unknown multicodec prefix: 0x%x)", ErrUnsupportedKeyType, data[:2])
+	}
+}
+
+// Expected lengths of raw (untyped) key byte encodings, as returned by Bytes() methods, indexed by key type
+var (
+	privateKeyLengths = map[string]int{
+		KeyTypeP256:    32,
+		KeyTypeK256:    32,
+		KeyTypeEd25519: 32,
+	}
+	publicKeyLengths = map[string]int{
+		KeyTypeP256:    33,
+		KeyTypeK256:    33,
+		KeyTypeEd25519: 32,
+	}
+)
+
+func checkKeyLength(lengths map[string]int, keyType string, data []byte) error {
+	expected, ok := lengths[keyType]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnsupportedKeyType, keyType)
+	}
+	if len(data) != expected {
+		return fmt.Errorf("crypto: wrong length for %s key: expected %d bytes, got %d", keyType, expected, len(data))
+	}
+	return nil
+}
+
+// Loads a private key from raw bytes (as returned by PrivateKeyExportable.Bytes), with the key type declared separately.
+//
+// The key type must be one of the type names returned by the Type() method ([KeyTypeP256], [KeyTypeK256], or [KeyTypeEd25519]); unknown types return [ErrUnsupportedKeyType]. This is useful when keys are stored with a separate type tag, instead of a multicodec prefix.
+func ParsePrivateBytes(keyType string, data []byte) (PrivateKeyExportable, error) {
+	if err := checkKeyLength(privateKeyLengths, keyType, data); err != nil {
+		return nil, err
+	}
+	switch keyType {
+	case KeyTypeP256:
+		return ParsePrivateBytesP256(data)
+	case KeyTypeK256:
+		return ParsePrivateBytesK256(data)
+	case KeyTypeEd25519:
+		return ParsePrivateBytesEd25519(data)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedKeyType, keyType)
+	}
+}
+
+// Loads a public key from raw bytes (as returned by PublicKey.Bytes; "compressed" for elliptic curve types), with the key type declared separately.
+//
+// The key type must be one of the type names returned by the Type() method ([KeyTypeP256], [KeyTypeK256], or [KeyTypeEd25519]); unknown types return [ErrUnsupportedKeyType].
+func ParsePublicBytes(keyType string, data []byte) (PublicKey, error) {
+	if err := checkKeyLength(publicKeyLengths, keyType, data); err != nil {
+		return nil, err
+	}
+	switch keyType {
+	case KeyTypeP256:
+		return ParsePublicBytesP256(data)
+	case KeyTypeK256:
+		return ParsePublicBytesK256(data)
+	case KeyTypeEd25519:
+		return ParsePublicBytesEd25519(data)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedKeyType, keyType)
 	}
 }
 
 // Loads a public key from multibase string encoding, with multicodec indicating the key type.
+//
+// This is the inverse of PublicKey.Multibase(). The multicodec prefix (p256-pub, secp256k1-pub, or ed25519-pub) determines which [PublicKey] implementation is returned; other multicodecs return [ErrUnsupportedKeyType].
 func ParsePublicMultibase(encoded string) (PublicKey, error) {
 	if len(encoded) < 2 || encoded[0] != 'z' {
 		return nil, fmt.Errorf("crypto: not a multibase base58btc string")
@@ -121,8 +220,11 @@ func ParsePublicMultibase(encoded string) (PublicKey, error) {
 	} else if data[0] == 0xE7 && data[1] == 0x01 {
 		// multicodec secp256k1-pub, code 0xE7, varint bytes: [0xE7, 0x01]
 		return ParsePublicBytesK256(data[2:])
+	} else if data[0] == 0xED && data[1] == 0x01 {
+		// multicodec ed25519-pub, code 0xED, varint bytes: [0xED, 0x01]
+		return ParsePublicBytesEd25519(data[2:])
 	} else {
-		return nil, fmt.Errorf("unsupported atproto key type (unknown multicodec prefix)")
+		return nil, fmt.Errorf("%w (unknown multicodec prefix: 0x%x)", ErrUnsupportedKeyType, data[:2])
 	}
 }
 
@@ -136,3 +238,48 @@ func ParsePublicDIDKey(didKey string) (PublicKey, error) {
 	mb := strings.TrimPrefix(didKey, "did:key:")
 	return ParsePublicMultibase(mb)
 }
+
+// Loads a public key from multibase string encoding, like [ParsePublicMultibase], and also returns the multicodec code which was present (eg, [MulticodecP256Pub]).
+//
+// Only base58btc ('z' prefix) multibase is supported; other multibase encodings are rejected, as are multicodecs other than the supported public key types ([ErrUnsupportedKeyType]), and non-minimal varint encodings of the multicodec.
+func ParseMultibaseKey(encoded string) (PublicKey, uint64, error) {
+	if encoded == "" {
+		return nil, 0, fmt.Errorf("crypto: empty multibase string")
+	}
+	if encoded[0] != 'z' {
+		return nil, 0, fmt.Errorf("crypto: unsupported multibase encoding %q (only base58btc 'z' is supported)", encoded[0])
+	}
+	data, err := base58.Decode(encoded[1:])
+	if err != nil || len(data) == 0 {
+		return nil, 0, fmt.Errorf("crypto: not a multibase base58btc string")
+	}
+	code, n := binary.Uvarint(data)
+	if n <= 0 || n != len(binary.AppendUvarint(nil, code)) {
+		return nil, 0, fmt.Errorf("crypto: invalid multicodec varint in multibase key")
+	}
+	raw := data[n:]
+	var pub PublicKey
+	switch code {
+	case MulticodecP256Pub:
+		pub, err = ParsePublicBytes(KeyTypeP256, raw)
+	case MulticodecK256Pub:
+		pub, err = ParsePublicBytes(KeyTypeK256, raw)
+	case MulticodecEd25519Pub:
+		pub, err = ParsePublicBytes(KeyTypeEd25519, raw)
+	default:
+		return nil, 0, fmt.Errorf("%w (unknown multicodec: 0x%x)", ErrUnsupportedKeyType, code)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return pub, code, nil
+}
+
+// Loads a [PublicKey] from did:key string serialization, like [ParsePublicDIDKey], and also returns the multicodec code which was present. See [ParseMultibaseKey].
+func ParseDIDKeyMulticodec(didKey string) (PublicKey, uint64, error) {
+	mb, ok := strings.CutPrefix(didKey, "did:key:")
+	if !ok {
+		return nil, 0, fmt.Errorf("string is not a DID key: %s", didKey)
+	}
+	return ParseMultibaseKey(mb)
+}
@@ -2,8 +2,15 @@ package crypto
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
 	"testing"
 
+	"github.com/mr-tron/base58"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -56,7 +63,10 @@ func TestKeyBasics(t *testing.T) {
 	assert.NoError(err)
 	assert.True(pubP256.Equal(pubP256FromUncompBytes))
 
-	both := []PrivateKey{privP256, privK256}
+	privEd25519, err := GeneratePrivateKeyEd25519()
+	assert.NoError(err)
+
+	both := []PrivateKey{privP256, privK256, privEd25519}
 	for _, priv := range both {
 		pub, err := priv.PublicKey()
 		assert.NoError(err)
@@ -99,7 +109,10 @@ func TestLowSMany(t *testing.T) {
 		privK256, err := GeneratePrivateKeyK256()
 		assert.NoError(err)
 
-		both := []PrivateKey{privP256, privK256}
+		privEd25519, err := GeneratePrivateKeyEd25519()
+		assert.NoError(err)
+
+		both := []PrivateKey{privP256, privK256, privEd25519}
 		for _, priv := range both {
 			pub, err := priv.PublicKey()
 			assert.NoError(err)
@@ -172,3 +185,773 @@ func TestParsePrivateMultibase(t *testing.T) {
 	_, ok = privK256FromMB.(*PrivateKeyK256)
 	assert.True(ok)
 }
+
+func TestHashAndVerifyEncoded(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := []byte("test-message")
+	privP256, err := GeneratePrivateKeyP256()
+	assert.NoError(err)
+	privK256, err := GeneratePrivateKeyK256()
+	assert.NoError(err)
+
+	privEd25519, err := GeneratePrivateKeyEd25519()
+	assert.NoError(err)
+
+	both := []PrivateKey{privP256, privK256, privEd25519}
+	for _, priv := range both {
+		pub, err := priv.PublicKey()
+		assert.NoError(err)
+		sig, err := priv.HashAndSign(msg)
+		assert.NoError(err)
+
+		encodings := []string{
+			"z" + base58.Encode(sig),
+			"u" + base64.RawURLEncoding.EncodeToString(sig),
+			"M" + base64.StdEncoding.EncodeToString(sig),
+			base64.RawURLEncoding.EncodeToString(sig),
+			base64.URLEncoding.EncodeToString(sig),
+			base64.RawStdEncoding.EncodeToString(sig),
+			base64.StdEncoding.EncodeToString(sig),
+			string(sig),
+		}
+		for _, enc := range encodings {
+			assert.NoError(HashAndVerifyEncoded(pub, msg, enc), enc)
+			assert.ErrorIs(HashAndVerifyEncoded(pub, []byte("other-message"), enc), ErrInvalidSignature)
+		}
+
+		assert.ErrorIs(HashAndVerifyEncoded(pub, msg, "dummy"), ErrUnknownSignatureEncoding)
+		assert.ErrorIs(HashAndVerifyEncoded(pub, msg, ""), ErrUnknownSignatureEncoding)
+	}
+}
+
+func TestHashAndVerifyNonCanonicalP256(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := []byte("test-message")
+	priv, err := GeneratePrivateKeyP256()
+	assert.NoError(err)
+	pubGeneric, err := priv.PublicKey()
+	assert.NoError(err)
+	pub, ok := pubGeneric.(*PublicKeyP256)
+	assert.True(ok)
+
+	sig, err := priv.HashAndSign(msg)
+	assert.NoError(err)
+	assert.NoError(pub.HashAndVerifyLenient(msg, sig))
+
+	// flip to the equivalent high-S signature, as produced by WebCrypto (which does not normalize)
+	s := new(big.Int).SetBytes(sig[32:])
+	s.Sub(curveN_P256, s)
+	highS := make([]byte, 64)
+	copy(highS[:32], sig[:32])
+	s.FillBytes(highS[32:])
+
+	assert.ErrorIs(pub.HashAndVerify(msg, highS), ErrInvalidSignature)
+	assert.NoError(pub.HashAndVerifyLenient(msg, highS))
+	assert.ErrorIs(pub.HashAndVerifyLenient([]byte("other-message"), highS), ErrInvalidSignature)
+
+	// the opt-in path accepts both, and only counts the high-S signature
+	before := NonCanonicalSignaturesAccepted()
+	assert.NoError(pub.HashAndVerifyNonCanonical(msg, sig))
+	assert.Equal(before, NonCanonicalSignaturesAccepted())
+	assert.NoError(pub.HashAndVerifyNonCanonical(msg, highS))
+	assert.Equal(before+1, NonCanonicalSignaturesAccepted())
+	assert.ErrorIs(pub.HashAndVerifyNonCanonical([]byte("other-message"), highS), ErrInvalidSignature)
+	assert.Equal(before+1, NonCanonicalSignaturesAccepted())
+}
+
+func TestVerificationMethod(t *testing.T) {
+	assert := assert.New(t)
+
+	privP256, err := GeneratePrivateKeyP256()
+	assert.NoError(err)
+	privK256, err := GeneratePrivateKeyK256()
+	assert.NoError(err)
+
+	privEd25519, err := GeneratePrivateKeyEd25519()
+	assert.NoError(err)
+
+	both := []PrivateKey{privP256, privK256, privEd25519}
+	for _, priv := range both {
+		pub, err := priv.PublicKey()
+		assert.NoError(err)
+
+		vm := pub.VerificationMethod("did:plc:abc123#atproto", "did:plc:abc123")
+		assert.Equal("Multikey", vm["type"])
+		assert.Equal("did:plc:abc123#atproto", vm["id"])
+		assert.Equal("did:plc:abc123", vm["controller"])
+		assert.Equal(pub.Multibase(), vm["publicKeyMultibase"])
+
+		parsed, err := ParsePublicVerificationMethod(vm)
+		assert.NoError(err)
+		assert.True(pub.Equal(parsed))
+	}
+
+	// legacy key type
+	pub, err := privP256.PublicKey()
+	assert.NoError(err)
+	parsed, err := ParsePublicVerificationMethod(map[string]any{
+		"type":               "EcdsaSecp256r1VerificationKey2019",
+		"publicKeyMultibase": "z" + base58.Encode(pub.UncompressedBytes()),
+	})
+	assert.NoError(err)
+	assert.True(pub.Equal(parsed))
+
+	_, err = ParsePublicVerificationMethod(map[string]any{"type": "Ed25519VerificationKey2020", "publicKeyMultibase": pub.Multibase()})
+	assert.Error(err)
+}
+
+func TestHashAndSignDeterministicP256(t *testing.T) {
+	assert := assert.New(t)
+
+	// test vectors from RFC 6979, appendix A.2.5 (P-256 with SHA-256)
+	privBytes, err := hex.DecodeString("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")
+	assert.NoError(err)
+	priv, err := ParsePrivateBytesP256(privBytes)
+	assert.NoError(err)
+	pub, err := priv.PublicKey()
+	assert.NoError(err)
+
+	vectors := []struct {
+		msg string
+		r   string
+		s   string
+	}{
+		{
+			msg: "sample",
+			r:   "EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716",
+			s:   "F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8",
+		},
+		{
+			msg: "test",
+			r:   "F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367",
+			s:   "019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083",
+		},
+	}
+
+	for _, v := range vectors {
+		sig, err := priv.HashAndSignDeterministic([]byte(v.msg))
+		assert.NoError(err)
+		assert.Equal(64, len(sig))
+
+		// RFC vectors are not normalized, so compare against the low-S variant
+		r, _ := new(big.Int).SetString(v.r, 16)
+		s, _ := new(big.Int).SetString(v.s, 16)
+		s = sigSToLowS_P256(s)
+		assert.Equal(0, r.Cmp(new(big.Int).SetBytes(sig[:32])), v.msg)
+		assert.Equal(0, s.Cmp(new(big.Int).SetBytes(sig[32:])), v.msg)
+
+		assert.NoError(pub.HashAndVerify([]byte(v.msg), sig))
+
+		again, err := priv.HashAndSignDeterministic([]byte(v.msg))
+		assert.NoError(err)
+		assert.Equal(sig, again)
+	}
+}
+
+func TestParsePublicMultibase(t *testing.T) {
+	assert := assert.New(t)
+
+	privP256, err := GeneratePrivateKeyP256()
+	assert.NoError(err)
+	privK256, err := GeneratePrivateKeyK256()
+	assert.NoError(err)
+	pubP256, err := privP256.PublicKey()
+	assert.NoError(err)
+	pubK256, err := privK256.PublicKey()
+	assert.NoError(err)
+
+	pub, err := ParsePublicMultibase(pubP256.Multibase())
+	assert.NoError(err)
+	_, ok := pub.(*PublicKeyP256)
+	assert.True(ok)
+
+	pub, err = ParsePublicDIDKey(pubK256.DIDKey())
+	assert.NoError(err)
+	_, ok = pub.(*PublicKeyK256)
+	assert.True(ok)
+
+	// ed25519-pub multicodec (0xED)
+	ed25519MB := "z" + base58.Encode(append([]byte{0xED, 0x01}, make([]byte, 32)...))
+	pub, err = ParsePublicMultibase(ed25519MB)
+	assert.NoError(err)
+	_, ok = pub.(*PublicKeyEd25519)
+	assert.True(ok)
+
+	// x25519-pub multicodec (0xEC), which is not supported
+	x25519MB := "z" + base58.Encode(append([]byte{0xEC, 0x01}, make([]byte, 32)...))
+	_, err = ParsePublicMultibase(x25519MB)
+	assert.ErrorIs(err, ErrUnsupportedKeyType)
+	_, err = ParsePublicDIDKey("did:key:" + x25519MB)
+	assert.ErrorIs(err, ErrUnsupportedKeyType)
+
+	// private key multicodec is rejected as a public key
+	_, err = ParsePublicMultibase(privP256.Multibase())
+	assert.ErrorIs(err, ErrUnsupportedKeyType)
+
+	_, err = ParsePublicMultibase("u" + pubP256.Multibase()[1:])
+	assert.Error(err)
+}
+
+func TestMemKeyStore(t *testing.T) {
+	assert := assert.New(t)
+
+	ks, err := NewMemKeyStore()
+	assert.NoError(err)
+	priv, err := GeneratePrivateKeyK256()
+	assert.NoError(err)
+	pub, err := priv.PublicKey()
+	assert.NoError(err)
+
+	msg := []byte("test-message")
+	ks.Add("did:plc:abc123", priv)
+
+	sig, err := ks.Sign("did:plc:abc123", msg)
+	assert.NoError(err)
+	assert.NoError(pub.HashAndVerify(msg, sig))
+
+	_, err = ks.Sign("did:plc:other", msg)
+	assert.ErrorIs(err, ErrKeyNotFound)
+
+	ks.Remove("did:plc:abc123")
+	_, err = ks.Sign("did:plc:abc123", msg)
+	assert.ErrorIs(err, ErrKeyNotFound)
+}
+
+func TestWipe(t *testing.T) {
+	assert := assert.New(t)
+
+	privP256, err := GeneratePrivateKeyP256()
+	assert.NoError(err)
+	otherP256, err := GeneratePrivateKeyP256()
+	assert.NoError(err)
+	d := privP256.privP256.D
+	privP256.Wipe()
+	assert.Equal(0, d.Sign())
+	assert.Nil(privP256.privP256.D)
+	assert.Nil(privP256.privP256ecdh)
+	// wiped keys compare unequal, as receiver or argument, without panicking
+	assert.False(privP256.Equal(privP256))
+	assert.False(privP256.Equal(otherP256))
+	assert.False(otherP256.Equal(privP256))
+
+	privK256, err := GeneratePrivateKeyK256()
+	assert.NoError(err)
+	otherK256, err := GeneratePrivateKeyK256()
+	assert.NoError(err)
+	privK256.Wipe()
+	assert.Nil(privK256.privK256)
+	assert.False(privK256.Equal(privK256))
+	assert.False(privK256.Equal(otherK256))
+	assert.False(otherK256.Equal(privK256))
+}
+
+func TestKeyType(t *testing.T) {
+	assert := assert.New(t)
+
+	privP256, err := GeneratePrivateKeyP256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privK256, err := GeneratePrivateKeyK256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privEd25519, err := GeneratePrivateKeyEd25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		priv PrivateKey
+		typ  string
+	}{
+		{privP256, KeyTypeP256},
+		{privK256, KeyTypeK256},
+		{privEd25519, KeyTypeEd25519},
+	} {
+		assert.Equal(tc.typ, tc.priv.Type())
+		pub, err := tc.priv.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(tc.typ, pub.Type())
+
+		// round-trips through multibase parsing
+		parsed, err := ParsePublicMultibase(pub.Multibase())
+		assert.NoError(err)
+		assert.Equal(tc.typ, parsed.Type())
+	}
+}
+
+func TestSharedSecret(t *testing.T) {
+	assert := assert.New(t)
+
+	// P-256
+	aliceP256, err := GeneratePrivateKeyP256()
+	assert.NoError(err)
+	bobP256, err := GeneratePrivateKeyP256()
+	assert.NoError(err)
+	alicePubP256, err := aliceP256.PublicKey()
+	assert.NoError(err)
+	bobPubP256, err := bobP256.PublicKey()
+	assert.NoError(err)
+
+	secretA, err := aliceP256.SharedSecret(bobPubP256.(*PublicKeyP256))
+	assert.NoError(err)
+	secretB, err := bobP256.SharedSecret(alicePubP256.(*PublicKeyP256))
+	assert.NoError(err)
+	assert.Equal(32, len(secretA))
+	assert.Equal(secretA, secretB)
+
+	// K-256
+	aliceK256, err := GeneratePrivateKeyK256()
+	assert.NoError(err)
+	bobK256, err := GeneratePrivateKeyK256()
+	assert.NoError(err)
+	alicePubK256, err := aliceK256.PublicKey()
+	assert.NoError(err)
+	bobPubK256, err := bobK256.PublicKey()
+	assert.NoError(err)
+
+	secretA, err = aliceK256.SharedSecret(bobPubK256.(*PublicKeyK256))
+	assert.NoError(err)
+	secretB, err = bobK256.SharedSecret(alicePubK256.(*PublicKeyK256))
+	assert.NoError(err)
+	assert.Equal(32, len(secretA))
+	assert.Equal(secretA, secretB)
+
+	// different peers give different secrets
+	secretC, err := aliceK256.SharedSecret(alicePubK256.(*PublicKeyK256))
+	assert.NoError(err)
+	assert.NotEqual(secretA, secretC)
+
+	// wiped keys can't be used
+	aliceP256.Wipe()
+	_, err = aliceP256.SharedSecret(bobPubP256.(*PublicKeyP256))
+	assert.Error(err)
+	aliceK256.Wipe()
+	_, err = aliceK256.SharedSecret(bobPubK256.(*PublicKeyK256))
+	assert.Error(err)
+}
+
+func TestEd25519(t *testing.T) {
+	assert := assert.New(t)
+
+	// RFC 8032, section 7.1, "TEST 1" (empty message)
+	seed, _ := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	pubHex := "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+	sigHex := "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b"
+
+	priv, err := ParsePrivateBytesEd25519(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(seed, priv.Bytes())
+	pub, err := priv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(pubHex, hex.EncodeToString(pub.Bytes()))
+	assert.Equal(pub.Bytes(), pub.UncompressedBytes())
+
+	// content is signed directly, not pre-hashed
+	sig, err := priv.HashAndSign([]byte{})
+	assert.NoError(err)
+	assert.Equal(sigHex, hex.EncodeToString(sig))
+	assert.NoError(pub.HashAndVerify([]byte{}, sig))
+	assert.NoError(pub.HashAndVerifyLenient([]byte{}, sig))
+	assert.NoError(HashAndVerifyEncoded(pub, []byte{}, "z"+base58.Encode(sig)))
+	assert.ErrorIs(pub.HashAndVerify([]byte("other"), sig), ErrInvalidSignature)
+
+	// private key multibase round-trip
+	privFromMB, err := ParsePrivateMultibase(priv.Multibase())
+	assert.NoError(err)
+	assert.True(priv.Equal(privFromMB))
+
+	// public key byte round-trip
+	pubFromBytes, err := ParsePublicBytesEd25519(pub.Bytes())
+	assert.NoError(err)
+	assert.True(pub.Equal(pubFromBytes))
+
+	// invalid lengths
+	_, err = ParsePrivateBytesEd25519(seed[:31])
+	assert.Error(err)
+	_, err = ParsePublicBytesEd25519(pub.Bytes()[:31])
+	assert.Error(err)
+
+	// not equal to keys of other types
+	privP256, err := GeneratePrivateKeyP256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(priv.Equal(privP256))
+}
+
+func TestParseBytesByType(t *testing.T) {
+	assert := assert.New(t)
+
+	privP256, err := GeneratePrivateKeyP256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privK256, err := GeneratePrivateKeyK256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privEd25519, err := GeneratePrivateKeyEd25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, priv := range []PrivateKeyExportable{privP256, privK256, privEd25519} {
+		parsed, err := ParsePrivateBytes(priv.Type(), priv.Bytes())
+		assert.NoError(err)
+		assert.True(priv.Equal(parsed))
+
+		pub, err := priv.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsedPub, err := ParsePublicBytes(pub.Type(), pub.Bytes())
+		assert.NoError(err)
+		assert.True(pub.Equal(parsedPub))
+
+		// wrong lengths
+		_, err = ParsePrivateBytes(priv.Type(), priv.Bytes()[1:])
+		assert.ErrorContains(err, "wrong length")
+		_, err = ParsePublicBytes(pub.Type(), pub.UncompressedBytes()[:10])
+		assert.ErrorContains(err, "wrong length")
+	}
+
+	// mismatched type: a compressed P-256 point is not an Ed25519 key
+	pubP256, err := privP256.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParsePublicBytes(KeyTypeEd25519, pubP256.Bytes())
+	assert.Error(err)
+
+	// unknown types
+	_, err = ParsePrivateBytes("rsa", privP256.Bytes())
+	assert.ErrorIs(err, ErrUnsupportedKeyType)
+	_, err = ParsePublicBytes("P-256", pubP256.Bytes())
+	assert.ErrorIs(err, ErrUnsupportedKeyType)
+}
+
+func TestSignatureDER(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := []byte("hello atproto")
+
+	// round-trip signatures from this package
+	privP256, err := GeneratePrivateKeyP256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privK256, err := GeneratePrivateKeyK256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, priv := range []PrivateKey{privP256, privK256} {
+		sig, err := priv.HashAndSign(msg)
+		assert.NoError(err)
+		der, err := CompactToDER(sig)
+		assert.NoError(err)
+		assert.Equal(byte(0x30), der[0])
+		compact, err := DERToCompact(der)
+		assert.NoError(err)
+		assert.Equal(sig, compact)
+
+		// already low-S
+		norm, err := NormalizeLowS(priv.Type(), sig)
+		assert.NoError(err)
+		assert.Equal(sig, norm)
+	}
+
+	// signatures generated with OpenSSL ('openssl dgst -sha256 -sign'), which are both "high-S"
+	for _, tc := range []struct {
+		keyType string
+		privHex string
+		derHex  string
+	}{
+		{
+			keyType: KeyTypeP256,
+			privHex: "5c611ee4a225ce314ac581d57069400747e82446a017a5a8d5a173be53c84061",
+			derHex:  "30460221008cffe8135167c79891207bb80b93889f05b738c9a9dc3441a1f19143f5e65706022100cd749988824a28eadb2548a751827464aca7e39f558ea6642cd9c574fd0ad917",
+		},
+		{
+			keyType: KeyTypeK256,
+			privHex: "1b301ac3d39c5ea4df333c1d96974a0fae334b4226b308e6ed57b79175915495",
+			derHex:  "3045022005ad11efdfc005d52a118069f6e7726a5d8d7160b41a772e65fdddfbee6522b4022100b31936ff6a7576562fc820bc1a310790287e28311c295f291b662cbfdddedae9",
+		},
+	} {
+		privBytes, _ := hex.DecodeString(tc.privHex)
+		der, _ := hex.DecodeString(tc.derHex)
+		priv, err := ParsePrivateBytes(tc.keyType, privBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := priv.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sig, err := DERToCompact(der)
+		assert.NoError(err)
+		assert.ErrorIs(pub.HashAndVerify(msg, sig), ErrInvalidSignature)
+		assert.NoError(pub.HashAndVerifyLenient(msg, sig))
+
+		lowS, err := NormalizeLowS(tc.keyType, sig)
+		assert.NoError(err)
+		assert.NotEqual(sig, lowS)
+		assert.NoError(pub.HashAndVerify(msg, lowS))
+
+		// DER output is canonical: the high-S signature re-encodes to the original bytes
+		reDER, err := CompactToDER(sig)
+		assert.NoError(err)
+		assert.Equal(der, reDER)
+	}
+
+	// invalid inputs
+	_, err = CompactToDER(make([]byte, 63))
+	assert.Error(err)
+	_, err = CompactToDER(make([]byte, 64))
+	assert.Error(err)
+	sig, err := privP256.HashAndSign(msg)
+	assert.NoError(err)
+	der, err := CompactToDER(sig)
+	assert.NoError(err)
+	_, err = DERToCompact(append(der, 0x00))
+	assert.Error(err)
+	_, err = DERToCompact(der[:len(der)-1])
+	assert.Error(err)
+	_, err = NormalizeLowS(KeyTypeEd25519, sig)
+	assert.ErrorIs(err, ErrUnsupportedKeyType)
+}
+
+func TestSignVerifyDigest(t *testing.T) {
+	assert := assert.New(t)
+
+	type digestSigner interface {
+		PrivateKey
+		SignDigest(digest []byte) ([]byte, error)
+	}
+	type digestVerifier interface {
+		PublicKey
+		VerifyDigest(digest, sig []byte) error
+	}
+
+	privP256, err := GeneratePrivateKeyP256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privK256, err := GeneratePrivateKeyK256()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte("hello atproto")
+	digest := sha256.Sum256(content)
+	for _, priv := range []digestSigner{privP256, privK256} {
+		pk, err := priv.PublicKey()
+		assert.NoError(err)
+		pub := pk.(digestVerifier)
+
+		// digest signatures are compatible with the hashing methods
+		sig, err := priv.SignDigest(digest[:])
+		assert.NoError(err)
+		assert.NoError(pub.HashAndVerify(content, sig))
+		assert.NoError(pub.VerifyDigest(digest[:], sig))
+
+		sig, err = priv.HashAndSign(content)
+		assert.NoError(err)
+		assert.NoError(pub.VerifyDigest(digest[:], sig))
+
+		// no implicit hashing
+		assert.ErrorIs(pub.VerifyDigest(make([]byte, 32), sig), ErrInvalidSignature)
+
+		// digest length is checked
+		_, err = priv.SignDigest(content)
+		assert.Error(err)
+		assert.Error(pub.VerifyDigest(digest[:31], sig))
+	}
+}
+
+func TestVerifyBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	privP256, err := GeneratePrivateKeyP256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privK256, err := GeneratePrivateKeyK256()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := []VerifyItem{}
+	for i, priv := range []PrivateKey{privP256, privK256, privP256, privK256} {
+		pub, err := priv.PublicKey()
+		assert.NoError(err)
+		content := []byte{byte(i), 'x'}
+		sig, err := priv.HashAndSign(content)
+		assert.NoError(err)
+		items = append(items, VerifyItem{PublicKey: pub, Content: content, Sig: sig})
+	}
+
+	for _, opts := range []BatchOpts{{}, {Workers: 1}, {Workers: 100, FailFast: true}} {
+		errs := VerifyBatch(items, opts)
+		assert.Equal(len(items), len(errs))
+		for _, err := range errs {
+			assert.NoError(err)
+		}
+	}
+	assert.Empty(VerifyBatch(nil, BatchOpts{}))
+
+	// wrong content for one item; no public key for another
+	bad := append([]VerifyItem{}, items...)
+	bad[1].Content = []byte("other")
+	bad[2].PublicKey = nil
+	errs := VerifyBatch(bad, BatchOpts{})
+	assert.NoError(errs[0])
+	assert.ErrorIs(errs[1], ErrInvalidSignature)
+	assert.Error(errs[2])
+	assert.NoError(errs[3])
+
+	// with a single worker, items are verified in order, so everything after the first failure is skipped
+	errs = VerifyBatch(bad, BatchOpts{Workers: 1, FailFast: true})
+	assert.NoError(errs[0])
+	assert.ErrorIs(errs[1], ErrInvalidSignature)
+	assert.ErrorIs(errs[2], ErrBatchAborted)
+	assert.ErrorIs(errs[3], ErrBatchAborted)
+}
+
+func TestRemoteSigner(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := []byte("hello atproto")
+
+	// high-S DER signature from OpenSSL (same fixture as TestSignatureDER) is normalized
+	privBytes, _ := hex.DecodeString("5c611ee4a225ce314ac581d57069400747e82446a017a5a8d5a173be53c84061")
+	der, _ := hex.DecodeString("30460221008cffe8135167c79891207bb80b93889f05b738c9a9dc3441a1f19143f5e65706022100cd749988824a28eadb2548a751827464aca7e39f558ea6642cd9c574fd0ad917")
+	local, err := ParsePrivateBytesP256(privBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := local.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := NewRemoteSigner(pub, func(digest []byte) ([]byte, error) {
+		return der, nil
+	})
+	assert.NoError(err)
+	assert.Equal(KeyTypeP256, remote.Type())
+	assert.True(remote.Equal(local))
+	sig, err := remote.HashAndSign(msg)
+	assert.NoError(err)
+	assert.NoError(pub.HashAndVerify(msg, sig))
+
+	// compact signatures are passed through
+	privK256, err := GeneratePrivateKeyK256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubK256, err := privK256.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err = NewRemoteSigner(pubK256, privK256.SignDigest)
+	assert.NoError(err)
+	assert.False(remote.Equal(local))
+	sig, err = remote.HashAndSign(msg)
+	assert.NoError(err)
+	assert.NoError(pubK256.HashAndVerify(msg, sig))
+
+	// signer for the wrong key, or failing signer
+	remote, err = NewRemoteSigner(pubK256, local.SignDigest)
+	assert.NoError(err)
+	_, err = remote.HashAndSign(msg)
+	assert.ErrorContains(err, "did not verify")
+	remote, err = NewRemoteSigner(pubK256, func(digest []byte) ([]byte, error) {
+		return nil, errors.New("kms unavailable")
+	})
+	assert.NoError(err)
+	_, err = remote.HashAndSign(msg)
+	assert.ErrorContains(err, "kms unavailable")
+
+	// Ed25519 signs content directly, so can not be used with a digest signer
+	privEd, err := GeneratePrivateKeyEd25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubEd, err := privEd.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewRemoteSigner(pubEd, local.SignDigest)
+	assert.ErrorIs(err, ErrUnsupportedKeyType)
+}
+
+func TestParseMultibaseKey(t *testing.T) {
+	assert := assert.New(t)
+
+	vectors := []struct {
+		didKey    string
+		codec     uint64
+		keyType   string
+		prefixHex string
+	}{
+		// from testdata/w3c_didkey_*.json
+		{"did:key:zDnaeTiq1PdzvZXUaMdezchcMJQpBdH2VN4pgrrEhMCCbmwSb", 0x1200, KeyTypeP256, "8024"},
+		{"did:key:zQ3shokFTS3brHcDQrn82RUDfCZESWL1ZdCEJwekUDPQiYBme", 0xe7, KeyTypeK256, "e701"},
+		{"did:key:z6MkiTBz1ymuepAQ4HEHYSF1H8quG5GLVVQR3djdX3mDooWp", 0xed, KeyTypeEd25519, "ed01"},
+	}
+	for _, v := range vectors {
+		pub, codec, err := ParseDIDKeyMulticodec(v.didKey)
+		assert.NoError(err, v.didKey)
+		assert.Equal(v.codec, codec)
+		assert.Equal(v.keyType, pub.Type())
+		assert.Equal(v.didKey, pub.DIDKey())
+
+		mb := strings.TrimPrefix(v.didKey, "did:key:")
+		pub, codec, err = ParseMultibaseKey(mb)
+		assert.NoError(err)
+		assert.Equal(v.codec, codec)
+		assert.Equal(mb, pub.Multibase())
+		raw, err := base58.Decode(mb[1:])
+		assert.NoError(err)
+		assert.Equal(v.prefixHex, hex.EncodeToString(raw[:2]))
+	}
+	assert.Equal(MulticodecP256Pub, vectors[0].codec)
+	assert.Equal(MulticodecK256Pub, vectors[1].codec)
+
+	k256 := strings.TrimPrefix(vectors[1].didKey, "did:key:z")
+	raw, _ := base58.Decode(k256)
+
+	// non-base58btc multibase prefixes are rejected explicitly
+	_, _, err := ParseMultibaseKey("f" + hex.EncodeToString(raw))
+	assert.ErrorContains(err, "only base58btc")
+	_, _, err = ParseMultibaseKey("")
+	assert.Error(err)
+	_, _, err = ParseDIDKeyMulticodec("did:web:example.com")
+	assert.Error(err)
+
+	// non-minimal varint encoding of 0xE7
+	_, _, err = ParseMultibaseKey("z" + base58.Encode(append([]byte{0xE7, 0x81, 0x00}, raw[2:]...)))
+	assert.ErrorContains(err, "varint")
+
+	// unknown and private key multicodecs
+	_, _, err = ParseMultibaseKey("z" + base58.Encode(append([]byte{0xEC, 0x01}, make([]byte, 32)...)))
+	assert.ErrorIs(err, ErrUnsupportedKeyType)
+	priv, err := GeneratePrivateKeyK256()
+	assert.NoError(err)
+	_, _, err = ParseMultibaseKey(priv.Multibase())
+	assert.ErrorIs(err, ErrUnsupportedKeyType)
+
+	// truncated key bytes
+	_, _, err = ParseMultibaseKey("z" + base58.Encode(raw[:20]))
+	assert.Error(err)
+}
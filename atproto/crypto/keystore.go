@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Indicates that a [KeyStore] does not hold a signing key for the requested identifier.
+var ErrKeyNotFound = errors.New("crypto: signing key not found")
+
+// Interface for a multi-tenant signing service, holding private keys indexed by an identifier (usually a DID).
+//
+// Implementations should not reveal which identifiers they hold through response timing: a request for a missing key should take about as long as a successful signature. The naive pattern of looking up a key and returning early when it is missing lets a remote caller enumerate the tenants of the service.
+type KeyStore interface {
+	// Hashes and signs content with the key for the given identifier. Returns [ErrKeyNotFound] if there is no such key.
+	Sign(id string, content []byte) ([]byte, error)
+}
+
+// In-memory [KeyStore] implementation. Safe for concurrent use.
+//
+// When a key is not found, a signature is still computed with a "decoy" key (and discarded) before returning [ErrKeyNotFound], so that missing and present keys take similar time. This is best-effort: the decoy is a P-256 key, and K-256 signing has different performance, so services wanting the strongest guarantee should hold keys of a single type matching the decoy. Map lookup itself does not depend on whether the key is present in any meaningful way.
+type MemKeyStore struct {
+	lk    sync.RWMutex
+	keys  map[string]PrivateKey
+	decoy PrivateKey
+}
+
+var _ KeyStore = (*MemKeyStore)(nil)
+
+// Creates an empty [MemKeyStore], generating a fresh decoy key.
+func NewMemKeyStore() (*MemKeyStore, error) {
+	decoy, err := GeneratePrivateKeyP256()
+	if err != nil {
+		return nil, fmt.Errorf("generating key store decoy key: %w", err)
+	}
+	return &MemKeyStore{
+		keys:  make(map[string]PrivateKey),
+		decoy: decoy,
+	}, nil
+}
+
+// Adds or replaces the signing key for an identifier.
+func (ks *MemKeyStore) Add(id string, key PrivateKey) {
+	ks.lk.Lock()
+	defer ks.lk.Unlock()
+	ks.keys[id] = key
+}
+
+// Removes the signing key for an identifier, if present.
+func (ks *MemKeyStore) Remove(id string) {
+	ks.lk.Lock()
+	defer ks.lk.Unlock()
+	delete(ks.keys, id)
+}
+
+func (ks *MemKeyStore) Sign(id string, content []byte) ([]byte, error) {
+	ks.lk.RLock()
+	key, ok := ks.keys[id]
+	ks.lk.RUnlock()
+
+	if !ok {
+		// do the same work as a successful signature, then discard it
+		_, _ = ks.decoy.HashAndSign(content)
+		return nil, ErrKeyNotFound
+	}
+	return key.HashAndSign(content)
+}
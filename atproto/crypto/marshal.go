@@ -0,0 +1,279 @@
+package crypto
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// One-byte key type tags, prefixed to key bytes in the binary encoding used by MarshalBinary and UnmarshalBinary. These values are part of the serialized format, and must not change.
+var binaryKeyTags = map[string]byte{
+	KeyTypeP256:    0x01,
+	KeyTypeK256:    0x02,
+	KeyTypeEd25519: 0x03,
+}
+
+var (
+	_ encoding.BinaryMarshaler   = (*PrivateKeyP256)(nil)
+	_ encoding.BinaryUnmarshaler = (*PrivateKeyP256)(nil)
+	_ encoding.BinaryMarshaler   = (*PrivateKeyK256)(nil)
+	_ encoding.BinaryUnmarshaler = (*PrivateKeyK256)(nil)
+	_ encoding.BinaryMarshaler   = (*PrivateKeyEd25519)(nil)
+	_ encoding.BinaryUnmarshaler = (*PrivateKeyEd25519)(nil)
+	_ encoding.BinaryMarshaler   = (*PublicKeyP256)(nil)
+	_ encoding.BinaryUnmarshaler = (*PublicKeyP256)(nil)
+	_ encoding.BinaryMarshaler   = (*PublicKeyK256)(nil)
+	_ encoding.BinaryUnmarshaler = (*PublicKeyK256)(nil)
+	_ encoding.BinaryMarshaler   = (*PublicKeyEd25519)(nil)
+	_ encoding.BinaryUnmarshaler = (*PublicKeyEd25519)(nil)
+	_ json.Marshaler             = (*PublicKeyP256)(nil)
+	_ json.Unmarshaler           = (*PublicKeyP256)(nil)
+	_ json.Marshaler             = (*PublicKeyK256)(nil)
+	_ json.Unmarshaler           = (*PublicKeyK256)(nil)
+	_ json.Marshaler             = (*PublicKeyEd25519)(nil)
+	_ json.Unmarshaler           = (*PublicKeyEd25519)(nil)
+)
+
+func marshalKeyBinary(keyType string, raw []byte) []byte {
+	return append([]byte{binaryKeyTags[keyType]}, raw...)
+}
+
+// Returns the key type indicated by the tag byte of a binary key encoding, and the remaining key bytes
+func splitKeyBinary(data []byte) (string, []byte, error) {
+	if len(data) < 1 {
+		return "", nil, fmt.Errorf("crypto: empty binary key encoding")
+	}
+	for keyType, tag := range binaryKeyTags {
+		if data[0] == tag {
+			return keyType, data[1:], nil
+		}
+	}
+	return "", nil, fmt.Errorf("%w (unknown binary key tag: 0x%02x)", ErrUnsupportedKeyType, data[0])
+}
+
+// Checks that a binary key encoding is for the expected key type, and returns the key bytes
+func unwrapKeyBinary(keyType string, data []byte) ([]byte, error) {
+	tagType, raw, err := splitKeyBinary(data)
+	if err != nil {
+		return nil, err
+	}
+	if tagType != keyType {
+		return nil, fmt.Errorf("crypto: binary key encoding is for a %s key, expected %s", tagType, keyType)
+	}
+	return raw, nil
+}
+
+// Loads a private key from the binary encoding returned by MarshalBinary methods: a one-byte key type tag, followed by the raw key bytes (as returned by PrivateKeyExportable.Bytes).
+func ParsePrivateBinary(data []byte) (PrivateKeyExportable, error) {
+	keyType, raw, err := splitKeyBinary(data)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateBytes(keyType, raw)
+}
+
+// Loads a public key from the binary encoding returned by MarshalBinary methods: a one-byte key type tag, followed by the "compressed" key bytes (as returned by PublicKey.Bytes). Elliptic curve points are checked to be on the curve.
+func ParsePublicBinary(data []byte) (PublicKey, error) {
+	keyType, raw, err := splitKeyBinary(data)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicBytes(keyType, raw)
+}
+
+// Parses a JSON string containing a did:key or multibase public key, and checks that it is the expected key type. Returns nil for JSON null.
+func unmarshalPublicJSON(keyType string, data []byte) (PublicKey, error) {
+	if string(data) == "null" {
+		return nil, nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("crypto: public key JSON must be a string: %w", err)
+	}
+	var pub PublicKey
+	var err error
+	if strings.HasPrefix(s, "did:key:") {
+		pub, err = ParsePublicDIDKey(s)
+	} else {
+		pub, err = ParsePublicMultibase(s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pub.Type() != keyType {
+		return nil, fmt.Errorf("crypto: JSON public key is a %s key, expected %s", pub.Type(), keyType)
+	}
+	return pub, nil
+}
+
+// Implements [encoding.BinaryMarshaler] (which is also used by encoding/gob): a one-byte key type tag, followed by the raw key bytes.
+//
+// The output contains secret key material.
+func (k *PrivateKeyP256) MarshalBinary() ([]byte, error) {
+	return marshalKeyBinary(KeyTypeP256, k.Bytes()), nil
+}
+
+// Implements [encoding.BinaryUnmarshaler], for the format returned by MarshalBinary. Returns an error if the encoding is for a different key type.
+func (k *PrivateKeyP256) UnmarshalBinary(data []byte) error {
+	raw, err := unwrapKeyBinary(KeyTypeP256, data)
+	if err != nil {
+		return err
+	}
+	if err := checkKeyLength(privateKeyLengths, KeyTypeP256, raw); err != nil {
+		return err
+	}
+	parsed, err := ParsePrivateBytesP256(raw)
+	if err != nil {
+		return err
+	}
+	*k = *parsed
+	return nil
+}
+
+// Implements [encoding.BinaryMarshaler] (which is also used by encoding/gob): a one-byte key type tag, followed by the raw key bytes.
+//
+// The output contains secret key material.
+func (k *PrivateKeyK256) MarshalBinary() ([]byte, error) {
+	return marshalKeyBinary(KeyTypeK256, k.Bytes()), nil
+}
+
+// Implements [encoding.BinaryUnmarshaler], for the format returned by MarshalBinary. Returns an error if the encoding is for a different key type.
+func (k *PrivateKeyK256) UnmarshalBinary(data []byte) error {
+	raw, err := unwrapKeyBinary(KeyTypeK256, data)
+	if err != nil {
+		return err
+	}
+	if err := checkKeyLength(privateKeyLengths, KeyTypeK256, raw); err != nil {
+		return err
+	}
+	parsed, err := ParsePrivateBytesK256(raw)
+	if err != nil {
+		return err
+	}
+	*k = *parsed
+	return nil
+}
+
+// Implements [encoding.BinaryMarshaler] (which is also used by encoding/gob): a one-byte key type tag, followed by the raw key bytes (the 32-byte seed).
+//
+// The output contains secret key material.
+func (k *PrivateKeyEd25519) MarshalBinary() ([]byte, error) {
+	return marshalKeyBinary(KeyTypeEd25519, k.Bytes()), nil
+}
+
+// Implements [encoding.BinaryUnmarshaler], for the format returned by MarshalBinary. Returns an error if the encoding is for a different key type.
+func (k *PrivateKeyEd25519) UnmarshalBinary(data []byte) error {
+	raw, err := unwrapKeyBinary(KeyTypeEd25519, data)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParsePrivateBytesEd25519(raw)
+	if err != nil {
+		return err
+	}
+	*k = *parsed
+	return nil
+}
+
+// Implements [encoding.BinaryMarshaler] (which is also used by encoding/gob): a one-byte key type tag, followed by the "compressed" key bytes.
+func (k *PublicKeyP256) MarshalBinary() ([]byte, error) {
+	return marshalKeyBinary(KeyTypeP256, k.Bytes()), nil
+}
+
+// Implements [encoding.BinaryUnmarshaler], for the format returned by MarshalBinary. Returns an error if the encoding is for a different key type, or the point is not on the curve.
+func (k *PublicKeyP256) UnmarshalBinary(data []byte) error {
+	raw, err := unwrapKeyBinary(KeyTypeP256, data)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParsePublicBytes(KeyTypeP256, raw)
+	if err != nil {
+		return err
+	}
+	*k = *parsed.(*PublicKeyP256)
+	return nil
+}
+
+// Implements [json.Marshaler], as a did:key string.
+func (k *PublicKeyP256) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.DIDKey())
+}
+
+// Implements [json.Unmarshaler]. Accepts a did:key or multibase string, which must be a P-256 key.
+func (k *PublicKeyP256) UnmarshalJSON(data []byte) error {
+	pub, err := unmarshalPublicJSON(KeyTypeP256, data)
+	if err != nil || pub == nil {
+		return err
+	}
+	*k = *pub.(*PublicKeyP256)
+	return nil
+}
+
+// Implements [encoding.BinaryMarshaler] (which is also used by encoding/gob): a one-byte key type tag, followed by the "compressed" key bytes.
+func (k *PublicKeyK256) MarshalBinary() ([]byte, error) {
+	return marshalKeyBinary(KeyTypeK256, k.Bytes()), nil
+}
+
+// Implements [encoding.BinaryUnmarshaler], for the format returned by MarshalBinary. Returns an error if the encoding is for a different key type, or the point is not on the curve.
+func (k *PublicKeyK256) UnmarshalBinary(data []byte) error {
+	raw, err := unwrapKeyBinary(KeyTypeK256, data)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParsePublicBytes(KeyTypeK256, raw)
+	if err != nil {
+		return err
+	}
+	*k = *parsed.(*PublicKeyK256)
+	return nil
+}
+
+// Implements [json.Marshaler], as a did:key string.
+func (k *PublicKeyK256) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.DIDKey())
+}
+
+// Implements [json.Unmarshaler]. Accepts a did:key or multibase string, which must be a K-256 key.
+func (k *PublicKeyK256) UnmarshalJSON(data []byte) error {
+	pub, err := unmarshalPublicJSON(KeyTypeK256, data)
+	if err != nil || pub == nil {
+		return err
+	}
+	*k = *pub.(*PublicKeyK256)
+	return nil
+}
+
+// Implements [encoding.BinaryMarshaler] (which is also used by encoding/gob): a one-byte key type tag, followed by the key bytes.
+func (k *PublicKeyEd25519) MarshalBinary() ([]byte, error) {
+	return marshalKeyBinary(KeyTypeEd25519, k.Bytes()), nil
+}
+
+// Implements [encoding.BinaryUnmarshaler], for the format returned by MarshalBinary. Returns an error if the encoding is for a different key type.
+func (k *PublicKeyEd25519) UnmarshalBinary(data []byte) error {
+	raw, err := unwrapKeyBinary(KeyTypeEd25519, data)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParsePublicBytes(KeyTypeEd25519, raw)
+	if err != nil {
+		return err
+	}
+	*k = *parsed.(*PublicKeyEd25519)
+	return nil
+}
+
+// Implements [json.Marshaler], as a did:key string.
+func (k *PublicKeyEd25519) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.DIDKey())
+}
+
+// Implements [json.Unmarshaler]. Accepts a did:key or multibase string, which must be an Ed25519 key.
+func (k *PublicKeyEd25519) UnmarshalJSON(data []byte) error {
+	pub, err := unmarshalPublicJSON(KeyTypeEd25519, data)
+	if err != nil || pub == nil {
+		return err
+	}
+	*k = *pub.(*PublicKeyEd25519)
+	return nil
+}
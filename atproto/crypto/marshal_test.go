@@ -0,0 +1,171 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	privP256, err := GeneratePrivateKeyP256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privK256, err := GeneratePrivateKeyK256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privEd25519, err := GeneratePrivateKeyEd25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, priv := range []PrivateKeyExportable{privP256, privK256, privEd25519} {
+		enc, err := priv.(encoding.BinaryMarshaler).MarshalBinary()
+		assert.NoError(err)
+		assert.Equal(binaryKeyTags[priv.Type()], enc[0])
+		assert.Equal(priv.Bytes(), enc[1:])
+
+		parsed, err := ParsePrivateBinary(enc)
+		assert.NoError(err)
+		assert.True(priv.Equal(parsed))
+
+		pub, err := priv.PublicKey()
+		assert.NoError(err)
+		enc, err = pub.(encoding.BinaryMarshaler).MarshalBinary()
+		assert.NoError(err)
+		assert.Equal(pub.Bytes(), enc[1:])
+
+		parsedPub, err := ParsePublicBinary(enc)
+		assert.NoError(err)
+		assert.True(pub.Equal(parsedPub))
+	}
+
+	// concrete types unmarshal in place
+	enc, _ := privK256.MarshalBinary()
+	var k PrivateKeyK256
+	assert.NoError(k.UnmarshalBinary(enc))
+	assert.True(privK256.Equal(&k))
+
+	// tag must match the concrete type
+	var p PrivateKeyP256
+	assert.Error(p.UnmarshalBinary(enc))
+
+	// unknown tag, or empty
+	_, err = ParsePrivateBinary(append([]byte{0x7F}, privP256.Bytes()...))
+	assert.ErrorIs(err, ErrUnsupportedKeyType)
+	_, err = ParsePublicBinary(nil)
+	assert.Error(err)
+
+	// wrong length, and points not on the curve (x >= field prime)
+	_, err = ParsePrivateBinary([]byte{binaryKeyTags[KeyTypeP256], 0x01})
+	assert.Error(err)
+	notOnCurve := append([]byte{0x02}, bytes.Repeat([]byte{0xFF}, 32)...)
+	var pubP256 PublicKeyP256
+	assert.Error(pubP256.UnmarshalBinary(append([]byte{binaryKeyTags[KeyTypeP256]}, notOnCurve...)))
+	var pubK256 PublicKeyK256
+	assert.Error(pubK256.UnmarshalBinary(append([]byte{binaryKeyTags[KeyTypeK256]}, notOnCurve...)))
+}
+
+type keyConfig struct {
+	Name       string
+	Signing    *PrivateKeyK256
+	Rotation   *PublicKeyP256
+	Interop    *PublicKeyEd25519
+	Unassigned *PublicKeyK256
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	privK256, err := GeneratePrivateKeyK256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privP256, err := GeneratePrivateKeyP256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubP256, err := privP256.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privEd25519, err := GeneratePrivateKeyEd25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubEd25519, err := privEd25519.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := keyConfig{
+		Name:     "example",
+		Signing:  privK256,
+		Rotation: pubP256.(*PublicKeyP256),
+		Interop:  pubEd25519.(*PublicKeyEd25519),
+	}
+	var buf bytes.Buffer
+	assert.NoError(gob.NewEncoder(&buf).Encode(&cfg))
+
+	var out keyConfig
+	assert.NoError(gob.NewDecoder(&buf).Decode(&out))
+	assert.Equal("example", out.Name)
+	assert.True(privK256.Equal(out.Signing))
+	assert.True(pubP256.Equal(out.Rotation))
+	assert.True(pubEd25519.Equal(out.Interop))
+	assert.Nil(out.Unassigned)
+}
+
+func TestPublicKeyJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	didKey := "did:key:zDnaembgSGUhZULN2Caob4HLJPaxBh92N7rtH21TErzqf8HQo"
+	pub, err := ParsePublicDIDKey(didKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type config struct {
+		Key *PublicKeyP256 `json:"key"`
+	}
+	out, err := json.Marshal(config{Key: pub.(*PublicKeyP256)})
+	assert.NoError(err)
+	assert.Equal(`{"key":"`+didKey+`"}`, string(out))
+
+	var cfg config
+	assert.NoError(json.Unmarshal(out, &cfg))
+	assert.True(pub.Equal(cfg.Key))
+
+	// bare multibase is also accepted
+	cfg = config{}
+	assert.NoError(json.Unmarshal([]byte(`{"key":"`+pub.Multibase()+`"}`), &cfg))
+	assert.True(pub.Equal(cfg.Key))
+
+	cfg = config{}
+	assert.NoError(json.Unmarshal([]byte(`{"key":null}`), &cfg))
+	assert.Nil(cfg.Key)
+
+	// key type must match the field type
+	privK256, err := GeneratePrivateKeyK256()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubK256, err := privK256.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(json.Unmarshal([]byte(`{"key":"`+pubK256.DIDKey()+`"}`), &cfg))
+	var k PublicKeyK256
+	assert.NoError(json.Unmarshal([]byte(`"`+pubK256.DIDKey()+`"`), &k))
+	assert.True(pubK256.Equal(&k))
+
+	assert.Error(json.Unmarshal([]byte(`{"key":"did:key:zinvalid"}`), &cfg))
+	assert.Error(json.Unmarshal([]byte(`{"key":123}`), &cfg))
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"crypto/x509"
 	"fmt"
 	"math/big"
@@ -67,13 +68,39 @@ func ParsePrivateBytesP256(data []byte) (*PrivateKeyP256, error) {
 	return &PrivateKeyP256{privP256: *skECDSA, privP256ecdh: skECDH}, nil
 }
 
+// Returns [KeyTypeP256]
+func (k *PrivateKeyP256) Type() string {
+	return KeyTypeP256
+}
+
 // Checks if the two private keys are the same. Note that the naive == operator does not work for most equality checks.
+//
+// The comparison of secret key material is constant-time (when both keys are P-256).
 func (k *PrivateKeyP256) Equal(other PrivateKey) bool {
 	otherP256, ok := other.(*PrivateKeyP256)
-	if ok {
-		return k.privP256.Equal(&otherP256.privP256)
+	if !ok || otherP256 == nil {
+		return false
 	}
-	return false
+	// a wiped key is not equal to anything, including itself
+	if k.privP256ecdh == nil || otherP256.privP256ecdh == nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(k.Bytes(), otherP256.Bytes()) == 1
+}
+
+// Best-effort clearing of secret key material from memory. The key must not be used after calling this method.
+//
+// The secret scalar held for ECDSA signing is overwritten with zeros. The separate copy held by the stdlib ECDH key type can not be zeroed from outside that package, so the reference is dropped and left to the garbage collector. Go does not provide guarantees against copies made by the runtime (eg, during stack growth or GC), so this reduces but does not eliminate the window in which secret material is present in memory.
+func (k *PrivateKeyP256) Wipe() {
+	if k.privP256.D != nil {
+		words := k.privP256.D.Bits()
+		for i := range words {
+			words[i] = 0
+		}
+		k.privP256.D.SetInt64(0)
+	}
+	k.privP256 = ecdsa.PrivateKey{}
+	k.privP256ecdh = nil
 }
 
 // Serializes the secret key material in to a raw binary format, which can be parsed by [ParsePrivateBytesP256].
@@ -91,6 +118,11 @@ func (k *PrivateKeyP256) Multibase() string {
 	return "z" + base58.Encode(kbytes)
 }
 
+// JSON Web Key (JWK) representation of this private key, with curve "P-256", including the secret key material ("d"). The output can be parsed with [ParsePrivateJWK].
+func (k *PrivateKeyP256) JWK() (*JWK, error) {
+	return privateJWK(k)
+}
+
 // Outputs the [PublicKey] corresponding to this [PrivateKeyP256]; it will be a [PublicKeyP256].
 func (k *PrivateKeyP256) PublicKey() (PublicKey, error) {
 	pkECDSA, ok := k.privP256.Public().(*ecdsa.PublicKey)
@@ -109,7 +141,17 @@ func (k *PrivateKeyP256) PublicKey() (PublicKey, error) {
 // NIST ECDSA signatures can have a "malleability" issue, meaning that there are multiple valid signatures for the same content with the same signing key. This method always returns a "low-S" signature, as required by atproto.
 func (k *PrivateKeyP256) HashAndSign(content []byte) ([]byte, error) {
 	hash := sha256.Sum256(content)
-	r, s, err := ecdsa.Sign(rand.Reader, &k.privP256, hash[:])
+	return k.SignDigest(hash[:])
+}
+
+// Signs a pre-computed 32-byte digest, returning a binary signature. Unlike HashAndSign(), no hashing is done.
+//
+// This is for callers who manage hashing themselves (eg, with a different hash function in non-atproto contexts). For atproto data, use HashAndSign(). Returns an error if the digest is not 32 bytes. The signature is "low-S", the same as HashAndSign().
+func (k *PrivateKeyP256) SignDigest(digest []byte) ([]byte, error) {
+	if len(digest) != sha256.Size {
+		return nil, fmt.Errorf("crypto: digest must be %d bytes, got len=%d", sha256.Size, len(digest))
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, &k.privP256, digest)
 	if err != nil {
 		return nil, fmt.Errorf("crypto error signing with P-256/secp256r1 private key: %w", err)
 	}
@@ -120,6 +162,22 @@ func (k *PrivateKeyP256) HashAndSign(content []byte) ([]byte, error) {
 	return sig, nil
 }
 
+// Performs an Elliptic Curve Diffie-Hellman (ECDH) exchange with another P-256 public key, returning the shared secret.
+//
+// The secret is the x-coordinate of the shared point, 32 bytes long, as specified in SEC 1, Version 2.0, Section 3.3.1. It should not be used directly as a symmetric key; pass it through a key derivation function (eg, HKDF) first.
+//
+// This is not part of the atproto signing path. Reusing a signing key for encryption is possible, but calling code should consider whether separate keys would be more appropriate.
+func (k *PrivateKeyP256) SharedSecret(pub *PublicKeyP256) ([]byte, error) {
+	if k.privP256ecdh == nil {
+		return nil, fmt.Errorf("P-256/secp256r1 private key has been wiped")
+	}
+	pubECDH, err := pub.pubP256.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("invalid P-256/secp256r1 public key for ECDH: %w", err)
+	}
+	return k.privP256ecdh.ECDH(pubECDH)
+}
+
 // Loads a [PublicKeyP256] raw bytes, as exported by the PublicKey.Bytes method. This is the "compressed" curve format.
 //
 // Calling code needs to know the key type ahead of time, and must remove any string encoding (hex encoding, base64, etc) before calling this function.
@@ -170,6 +228,11 @@ func ParsePublicUncompressedBytesP256(data []byte) (*PublicKeyP256, error) {
 	return &pub, nil
 }
 
+// Returns [KeyTypeP256]
+func (k *PublicKeyP256) Type() string {
+	return KeyTypeP256
+}
+
 // Checks if the two public keys are the same. Note that the naive == operator does not work for most equality checks.
 func (k *PublicKeyP256) Equal(other PublicKey) bool {
 	otherP256, ok := other.(*PublicKeyP256)
@@ -205,6 +268,16 @@ func (k *PublicKeyP256) Bytes() []byte {
 // This method requires a "low-S" signature, as specified by atproto.
 func (k *PublicKeyP256) HashAndVerify(content, sig []byte) error {
 	hash := sha256.Sum256(content)
+	return k.VerifyDigest(hash[:], sig)
+}
+
+// Verifies a signature against a pre-computed 32-byte digest. Unlike HashAndVerify(), no hashing is done.
+//
+// This is for callers who manage hashing themselves. For atproto data, use HashAndVerify(). Returns an error if the digest is not 32 bytes. Like HashAndVerify(), this method requires a "low-S" signature.
+func (k *PublicKeyP256) VerifyDigest(digest, sig []byte) error {
+	if len(digest) != sha256.Size {
+		return fmt.Errorf("crypto: digest must be %d bytes, got len=%d", sha256.Size, len(digest))
+	}
 	// parseP256Sig
 	if len(sig) != 64 {
 		return fmt.Errorf("crypto: P-256 signatures must be 64 bytes, got len=%d", len(sig))
@@ -214,7 +287,7 @@ func (k *PublicKeyP256) HashAndVerify(content, sig []byte) error {
 	r.SetBytes(sig[:32])
 	s.SetBytes(sig[32:])
 
-	if !ecdsa.Verify(&k.pubP256, hash[:], r, s) {
+	if !ecdsa.Verify(&k.pubP256, digest, r, s) {
 		return ErrInvalidSignature
 	}
 
@@ -247,6 +320,22 @@ func (k *PublicKeyP256) HashAndVerifyLenient(content, sig []byte) error {
 	return nil
 }
 
+// Verifies signatures which may not be "low-S" normalized, such as those produced in browsers by WebCrypto (which emits raw `[R | S]` P-256 signatures without normalization).
+//
+// WARNING: this relaxes the atproto low-S requirement, and should only be used when bridging browser-originated signatures. Strict verification is attempted first; signatures which only pass with the requirement relaxed are counted (see [NonCanonicalSignaturesAccepted]), so operators can see how often this happens.
+//
+// Signatures must already be decoded to the 64-byte compact encoding (ASN.1 DER signatures, as used by WebAuthn, are not supported).
+func (k *PublicKeyP256) HashAndVerifyNonCanonical(content, sig []byte) error {
+	if err := k.HashAndVerify(content, sig); err == nil {
+		return nil
+	}
+	if err := k.HashAndVerifyLenient(content, sig); err != nil {
+		return err
+	}
+	nonCanonicalAccepted.Add(1)
+	return nil
+}
+
 // Multibase string encoding of the public key, including a multicodec indicator and compressed curve bytes serialization
 func (k *PublicKeyP256) Multibase() string {
 	kbytes := k.Bytes()
@@ -265,3 +354,15 @@ func (k *PublicKeyP256) Multibase() string {
 func (k *PublicKeyP256) DIDKey() string {
 	return "did:key:" + k.Multibase()
 }
+
+// DID document verificationMethod entry for this key, as atproto expects: "Multikey" type, with the given "id" (eg, "did:plc:abc123#atproto") and "controller" (the DID), and the key in "publicKeyMultibase" format.
+//
+// The output can be parsed with [ParsePublicVerificationMethod].
+func (k *PublicKeyP256) VerificationMethod(id, controller string) map[string]any {
+	return verificationMethod(k, id, controller)
+}
+
+// JSON Web Key (JWK) representation of this public key, with curve "P-256". The output can be parsed with [ParsePublicJWK].
+func (k *PublicKeyP256) JWK() (*JWK, error) {
+	return publicJWK(k)
+}
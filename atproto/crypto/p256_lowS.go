@@ -3,11 +3,22 @@ package crypto
 import (
 	"crypto/elliptic"
 	"math/big"
+	"sync/atomic"
 )
 
 var curveN_P256 *big.Int = elliptic.P256().Params().N
 var curveHalfOrder_P256 *big.Int = new(big.Int).Rsh(curveN_P256, 1)
 
+// count of signatures accepted by PublicKeyP256.HashAndVerifyNonCanonical() which were not "low-S"
+var nonCanonicalAccepted atomic.Int64
+
+// Returns the number of (high-S) signatures accepted by [PublicKeyP256.HashAndVerifyNonCanonical] since process start, which would have been rejected by strict verification.
+//
+// This is a warning metric: it is a plain counter (this package has no metrics dependency), intended to be exported by callers to their metrics system, eg as a Prometheus CounterFunc.
+func NonCanonicalSignaturesAccepted() int64 {
+	return nonCanonicalAccepted.Load()
+}
+
 // Checks if 'S' value from a P-256 signature is "low-S".
 // un-reviewed, un-safe code from: https://github.com/golang/go/issues/54549
 func sigSIsLowS_P256(s *big.Int) bool {
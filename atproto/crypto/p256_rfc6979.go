@@ -0,0 +1,103 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// First hashes the raw bytes, then signs the digest using a deterministic nonce (RFC 6979), returning a binary signature.
+//
+// For a given key and content, the output is always the same, which is useful for reproducible test vectors and for environments with poor entropy. Signatures are "low-S", 64 bytes long, and verify with [PublicKeyP256.HashAndVerify] like any other signature.
+//
+// The nonce is derived with HMAC-SHA-256 as specified in RFC 6979 section 3.2. Note that the final modular arithmetic uses math/big, which is not constant-time; [PrivateKeyP256.HashAndSign] should be preferred when a good source of randomness is available.
+func (k *PrivateKeyP256) HashAndSignDeterministic(content []byte) ([]byte, error) {
+	hash := sha256.Sum256(content)
+	n := curveN_P256
+	d := k.privP256.D
+	e := new(big.Int).SetBytes(hash[:])
+
+	nextK := rfc6979NonceGenerator(d, hash[:])
+	for {
+		nonce := nextK()
+
+		// compute the nonce point using the constant-time ECDH scalar multiplication; X coordinate is bytes [1:33] of uncompressed encoding
+		kBytes := make([]byte, 32)
+		nonce.FillBytes(kBytes)
+		kPriv, err := ecdh.P256().NewPrivateKey(kBytes)
+		if err != nil {
+			return nil, fmt.Errorf("crypto error deriving P-256 deterministic nonce point: %w", err)
+		}
+		point := kPriv.PublicKey().Bytes()
+		r := new(big.Int).SetBytes(point[1:33])
+		r.Mod(r, n)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		// s = k^-1 * (e + r*d) mod n
+		s := new(big.Int).Mul(r, d)
+		s.Add(s, e)
+		s.Mod(s, n)
+		s.Mul(s, new(big.Int).ModInverse(nonce, n))
+		s.Mod(s, n)
+		if s.Sign() == 0 {
+			continue
+		}
+
+		s = sigSToLowS_P256(s)
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig, nil
+	}
+}
+
+// Returns a function which yields successive RFC 6979 nonce candidates for P-256 with HMAC-SHA-256, for the given private scalar and message digest.
+func rfc6979NonceGenerator(x *big.Int, digest []byte) func() *big.Int {
+	n := curveN_P256
+
+	// int2octets(x) and bits2octets(h1); qlen and hlen are both 256 bits, so no bit truncation is needed
+	xBytes := make([]byte, 32)
+	x.FillBytes(xBytes)
+	h := new(big.Int).SetBytes(digest)
+	h.Mod(h, n)
+	hBytes := make([]byte, 32)
+	h.FillBytes(hBytes)
+
+	mac := func(key []byte, parts ...[]byte) []byte {
+		m := hmac.New(sha256.New, key)
+		for _, p := range parts {
+			m.Write(p)
+		}
+		return m.Sum(nil)
+	}
+
+	V := make([]byte, 32)
+	for i := range V {
+		V[i] = 0x01
+	}
+	K := make([]byte, 32)
+	K = mac(K, V, []byte{0x00}, xBytes, hBytes)
+	V = mac(K, V)
+	K = mac(K, V, []byte{0x01}, xBytes, hBytes)
+	V = mac(K, V)
+
+	first := true
+	return func() *big.Int {
+		for {
+			if !first {
+				K = mac(K, V, []byte{0x00})
+				V = mac(K, V)
+			}
+			first = false
+			V = mac(K, V)
+			candidate := new(big.Int).SetBytes(V)
+			if candidate.Sign() > 0 && candidate.Cmp(n) < 0 {
+				return candidate
+			}
+		}
+	}
+}
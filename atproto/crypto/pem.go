@@ -0,0 +1,156 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// PEM block type for unencrypted PKCS #8 private keys (RFC 5958)
+const pemTypePrivateKey = "PRIVATE KEY"
+
+var (
+	// id-ecPublicKey (RFC 5480)
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	// secp256r1, aka prime256v1 (RFC 5480)
+	oidCurveP256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	// secp256k1 (SEC 2)
+	oidCurveK256 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+// PKCS #8 PrivateKeyInfo. Optional attributes are not supported.
+type pkcs8PrivateKey struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// SEC 1 ECPrivateKey (RFC 5915), as embedded in PKCS #8
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// Serializes the private key as a PEM-encoded PKCS #8 "PRIVATE KEY" block, with the curve (secp256r1) recorded as the named curve parameter. The output can be parsed with [ParsePrivatePEM], and is compatible with OpenSSL and other common tools.
+//
+// The output contains secret key material, and should be stored with appropriate file permissions.
+func (k *PrivateKeyP256) MarshalPEM() ([]byte, error) {
+	pub, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return marshalPrivatePEM(oidCurveP256, k.Bytes(), pub.UncompressedBytes())
+}
+
+// Serializes the private key as a PEM-encoded PKCS #8 "PRIVATE KEY" block, with the curve (secp256k1) recorded as the named curve parameter. The output can be parsed with [ParsePrivatePEM], and is compatible with OpenSSL and other common tools.
+//
+// The output contains secret key material, and should be stored with appropriate file permissions.
+func (k *PrivateKeyK256) MarshalPEM() ([]byte, error) {
+	pub, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return marshalPrivatePEM(oidCurveK256, k.Bytes(), pub.UncompressedBytes())
+}
+
+func marshalPrivatePEM(curve asn1.ObjectIdentifier, priv, pubUncompressed []byte) ([]byte, error) {
+	ecDER, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: priv,
+		PublicKey:  asn1.BitString{Bytes: pubUncompressed, BitLength: 8 * len(pubUncompressed)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("crypto: encoding EC private key: %w", err)
+	}
+	curveDER, err := asn1.Marshal(curve)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: encoding curve identifier: %w", err)
+	}
+	der, err := asn1.Marshal(pkcs8PrivateKey{
+		Algo: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPublicKeyECDSA,
+			Parameters: asn1.RawValue{FullBytes: curveDER},
+		},
+		PrivateKey: ecDER,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("crypto: encoding PKCS #8 private key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypePrivateKey, Bytes: der}), nil
+}
+
+// Loads a private key from a PEM-encoded PKCS #8 "PRIVATE KEY" block, as output by the MarshalPEM methods. The curve recorded in the key determines which implementation is returned (see the Type() method): currently P-256 ([PrivateKeyP256]) or K-256 ([PrivateKeyK256]).
+//
+// Only the first PEM block in the input is read. Other PEM block types (including public keys, certificates, encrypted private keys, and the "EC PRIVATE KEY" SEC 1 format) are rejected. Keys with other algorithms or curves return an error wrapping [ErrUnsupportedKeyType], which includes the detected algorithm or curve identifier.
+//
+// If the key includes the optional public key, it must match the private key.
+func ParsePrivatePEM(data []byte) (PrivateKeyExportable, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("crypto: no PEM block found")
+	}
+	if block.Type != pemTypePrivateKey {
+		return nil, fmt.Errorf("crypto: PEM block type is %q, expected unencrypted %q", block.Type, pemTypePrivateKey)
+	}
+
+	var p8 pkcs8PrivateKey
+	if rest, err := asn1.Unmarshal(block.Bytes, &p8); err != nil {
+		return nil, fmt.Errorf("crypto: parsing PKCS #8 private key: %w", err)
+	} else if len(rest) > 0 {
+		return nil, errors.New("crypto: trailing data after PKCS #8 private key")
+	}
+	if !p8.Algo.Algorithm.Equal(oidPublicKeyECDSA) {
+		return nil, fmt.Errorf("%w: PKCS #8 key algorithm %s", ErrUnsupportedKeyType, p8.Algo.Algorithm)
+	}
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(p8.Algo.Parameters.FullBytes, &curve); err != nil {
+		return nil, fmt.Errorf("crypto: PKCS #8 EC key does not have a named curve: %w", err)
+	}
+
+	var ec ecPrivateKey
+	if _, err := asn1.Unmarshal(p8.PrivateKey, &ec); err != nil {
+		return nil, fmt.Errorf("crypto: parsing EC private key: %w", err)
+	}
+	if ec.Version != 1 {
+		return nil, fmt.Errorf("crypto: unsupported EC private key version: %d", ec.Version)
+	}
+	if len(ec.NamedCurveOID) > 0 && !ec.NamedCurveOID.Equal(curve) {
+		return nil, fmt.Errorf("crypto: EC private key curve (%s) does not match PKCS #8 curve (%s)", ec.NamedCurveOID, curve)
+	}
+	// the scalar should be fixed-width, but some encoders strip leading zeros
+	if len(ec.PrivateKey) > 32 {
+		return nil, fmt.Errorf("crypto: EC private key is too long: %d bytes", len(ec.PrivateKey))
+	}
+	d := make([]byte, 32)
+	copy(d[32-len(ec.PrivateKey):], ec.PrivateKey)
+
+	var priv PrivateKeyExportable
+	var err error
+	switch {
+	case curve.Equal(oidCurveP256):
+		priv, err = ParsePrivateBytesP256(d)
+	case curve.Equal(oidCurveK256):
+		priv, err = ParsePrivateBytesK256(d)
+	default:
+		return nil, fmt.Errorf("%w: EC curve %s", ErrUnsupportedKeyType, curve)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ec.PublicKey.Bytes) > 0 {
+		pub, err := priv.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(ec.PublicKey.RightAlign(), pub.UncompressedBytes()) && !bytes.Equal(ec.PublicKey.RightAlign(), pub.Bytes()) {
+			return nil, fmt.Errorf("crypto: public key in PEM does not match %s private key", pub.Type())
+		}
+	}
+	return priv, nil
+}
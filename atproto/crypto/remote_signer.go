@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// Function signature for an external signing service, such as a cloud KMS or HSM. It receives a 32-byte SHA-256 digest, and returns an ECDSA signature of that digest, either in compact (`[R | S]`, 64 bytes) or ASN.1 DER encoding.
+type DigestSigner func(digest []byte) ([]byte, error)
+
+// [PrivateKey] implementation which delegates signing to an external service (eg, a KMS or HSM), so that secret key material never needs to be held in process memory. Only P-256 and K-256 keys are supported.
+//
+// This type does not implement [PrivateKeyExportable]. It can be used anywhere the atproto stack takes a [PrivateKey], such as signing repo commits or service auth tokens.
+//
+// Signatures returned by the signing function are converted to compact encoding, normalized to "low-S" (which most KMS services do not do), and verified against the public key before being returned. A signing function configured with the wrong key will return an error, instead of producing signatures which fail verification later.
+type RemoteSigner struct {
+	pub  PublicKey
+	sign DigestSigner
+}
+
+var _ PrivateKey = (*RemoteSigner)(nil)
+
+// Creates a [RemoteSigner] for the given public key. The public key is usually fetched once from the signing service, or configured alongside it.
+func NewRemoteSigner(pub PublicKey, sign DigestSigner) (*RemoteSigner, error) {
+	if pub == nil || sign == nil {
+		return nil, errors.New("crypto: remote signer requires a public key and signing function")
+	}
+	switch pub.Type() {
+	case KeyTypeP256, KeyTypeK256:
+	default:
+		return nil, fmt.Errorf("%w: remote signer key type %q", ErrUnsupportedKeyType, pub.Type())
+	}
+	return &RemoteSigner{pub: pub, sign: sign}, nil
+}
+
+// Returns the key type of the public key: [KeyTypeP256] or [KeyTypeK256]
+func (k *RemoteSigner) Type() string {
+	return k.pub.Type()
+}
+
+// Returns the public key provided when the signer was created. Does not contact the signing service.
+func (k *RemoteSigner) PublicKey() (PublicKey, error) {
+	return k.pub, nil
+}
+
+// Checks if the other key has the same public key. As the secret key material is not available, this is the only comparison possible; a [RemoteSigner] is equal to a local private key with the same public key.
+func (k *RemoteSigner) Equal(other PrivateKey) bool {
+	if other == nil {
+		return false
+	}
+	otherPub, err := other.PublicKey()
+	if err != nil {
+		return false
+	}
+	return k.pub.Equal(otherPub)
+}
+
+// Hashes the raw bytes using SHA-256, then signs the digest with the remote signing function. Always returns a compact, "low-S" signature.
+func (k *RemoteSigner) HashAndSign(content []byte) ([]byte, error) {
+	hash := sha256.Sum256(content)
+	raw, err := k.sign(hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("crypto: remote signing failed: %w", err)
+	}
+	sig := raw
+	if len(raw) != signatureLength {
+		sig, err = DERToCompact(raw)
+		if err != nil {
+			return nil, fmt.Errorf("crypto: remote signer returned unexpected signature encoding (%d bytes): %w", len(raw), err)
+		}
+	}
+	sig, err = NormalizeLowS(k.pub.Type(), sig)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.pub.HashAndVerify(content, sig); err != nil {
+		return nil, fmt.Errorf("crypto: remote signature did not verify against public key: %w", err)
+	}
+	return sig, nil
+}
@@ -0,0 +1,156 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/mr-tron/base58"
+)
+
+// Length in bytes of the compact (`[R | S]`) signature encoding, for all currently supported curves.
+const signatureLength = 64
+
+var ErrUnknownSignatureEncoding = errors.New("crypto: signature string did not decode to 64 bytes with any known encoding")
+
+// Attempts to decode a string-encoded signature, returning all distinct candidate byte encodings which are the expected length.
+//
+// Encodings are tried in the following order:
+//
+//   - multibase, if the string starts with a supported prefix: 'z' (base58btc), 'u' (base64url), 'U' (base64url with padding), 'm' (base64), 'M' (base64 with padding)
+//   - base64url, without and then with padding
+//   - base64 (standard alphabet), without and then with padding
+//   - raw bytes (the string itself is the signature)
+//
+// Multiple candidates can be returned, because some strings are valid in more than one encoding (eg, a base64url string which happens to start with 'z').
+func decodeSignatureCandidates(sig string) [][]byte {
+	out := [][]byte{}
+	add := func(b []byte, err error) {
+		if err != nil || len(b) != signatureLength {
+			return
+		}
+		for _, prev := range out {
+			if bytes.Equal(prev, b) {
+				return
+			}
+		}
+		out = append(out, b)
+	}
+
+	if len(sig) > 1 {
+		body := sig[1:]
+		switch sig[0] {
+		case 'z':
+			add(base58.Decode(body))
+		case 'u':
+			add(base64.RawURLEncoding.DecodeString(body))
+		case 'U':
+			add(base64.URLEncoding.DecodeString(body))
+		case 'm':
+			add(base64.RawStdEncoding.DecodeString(body))
+		case 'M':
+			add(base64.StdEncoding.DecodeString(body))
+		}
+	}
+	add(base64.RawURLEncoding.DecodeString(sig))
+	add(base64.URLEncoding.DecodeString(sig))
+	add(base64.RawStdEncoding.DecodeString(sig))
+	add(base64.StdEncoding.DecodeString(sig))
+	add([]byte(sig), nil)
+	return out
+}
+
+// Same as PublicKey.HashAndVerify(), but takes a string-encoded signature instead of raw bytes.
+//
+// The signature encoding is auto-detected: a multibase prefix is tried first, then base64url (unpadded and padded), then standard base64 (unpadded and padded), and finally the string is interpreted as raw bytes. Each decoding is verified with pub.HashAndVerify, so the same rules apply (eg, "low-S" signatures for P-256 and K-256). Returns an error if no encoding yields a 64-byte signature.
+func HashAndVerifyEncoded(pub PublicKey, content []byte, sig string) error {
+	candidates := decodeSignatureCandidates(sig)
+	if len(candidates) == 0 {
+		return fmt.Errorf("%w (string len=%d)", ErrUnknownSignatureEncoding, len(sig))
+	}
+	for _, raw := range candidates {
+		if err := pub.HashAndVerify(content, raw); err == nil {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// ASN.1 structure of a DER-encoded ECDSA signature (RFC 3279, section 2.2.3)
+type ecdsaSignatureDER struct {
+	R, S *big.Int
+}
+
+// Converts a compact (`[R | S]`, 64 bytes) ECDSA signature, as returned by HashAndSign, to ASN.1 DER encoding, as expected by some HSMs and verification libraries.
+//
+// The encoding is the same for P-256 and K-256 signatures. The signature values are not otherwise modified; in particular, "low-S" signatures stay "low-S".
+func CompactToDER(sig []byte) ([]byte, error) {
+	if len(sig) != signatureLength {
+		return nil, fmt.Errorf("crypto: compact signature has wrong length: %d", len(sig))
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Sign() == 0 || s.Sign() == 0 {
+		return nil, fmt.Errorf("crypto: compact signature has zero value")
+	}
+	return asn1.Marshal(ecdsaSignatureDER{R: r, S: s})
+}
+
+// Converts an ASN.1 DER-encoded ECDSA signature to the compact (`[R | S]`, 64 bytes) encoding used by atproto, and expected by HashAndVerify.
+//
+// The encoding is the same for P-256 and K-256 signatures. Note that DER signatures from external sources (eg, HSMs or OpenSSL) are frequently "high-S", which atproto does not accept; this function does not know the curve, so can not fix that. Use [NormalizeLowS] on the output.
+func DERToCompact(der []byte) ([]byte, error) {
+	var sig ecdsaSignatureDER
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: invalid DER signature: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("crypto: invalid DER signature: trailing data")
+	}
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+		return nil, fmt.Errorf("crypto: invalid DER signature: non-positive value")
+	}
+	if sig.R.BitLen() > 256 || sig.S.BitLen() > 256 {
+		return nil, fmt.Errorf("crypto: invalid DER signature: value too large")
+	}
+	out := make([]byte, signatureLength)
+	sig.R.FillBytes(out[:32])
+	sig.S.FillBytes(out[32:])
+	return out, nil
+}
+
+// order of the secp256k1 curve group
+var curveN_K256, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", 16)
+
+// Converts a compact (`[R | S]`, 64 bytes) ECDSA signature to the "low-S" form required by atproto, if it is not already. The curve order differs between key types, so the type is required ([KeyTypeP256] or [KeyTypeK256]).
+//
+// Both forms are valid ECDSA signatures for the same content and key; this does not require or check the key. Returns a new slice; the input is not modified.
+func NormalizeLowS(keyType string, sig []byte) ([]byte, error) {
+	var n *big.Int
+	switch keyType {
+	case KeyTypeP256:
+		n = curveN_P256
+	case KeyTypeK256:
+		n = curveN_K256
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedKeyType, keyType)
+	}
+	if len(sig) != signatureLength {
+		return nil, fmt.Errorf("crypto: compact signature has wrong length: %d", len(sig))
+	}
+	s := new(big.Int).SetBytes(sig[32:])
+	if s.Sign() == 0 || s.Cmp(n) >= 0 {
+		return nil, fmt.Errorf("crypto: signature S value out of range")
+	}
+	out := make([]byte, signatureLength)
+	copy(out, sig)
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s.Sub(n, s)
+		s.FillBytes(out[32:])
+	}
+	return out, nil
+}
@@ -0,0 +1,49 @@
+package crypto
+
+import (
+	"fmt"
+
+	"github.com/mr-tron/base58"
+)
+
+// shared implementation of VerificationMethod() for all key types
+func verificationMethod(pub PublicKey, id, controller string) map[string]any {
+	return map[string]any{
+		"id":                 id,
+		"type":               "Multikey",
+		"controller":         controller,
+		"publicKeyMultibase": pub.Multibase(),
+	}
+}
+
+// Parses a [PublicKey] from a DID document verificationMethod entry (as a generic JSON object).
+//
+// The current "Multikey" type is supported, as well as the legacy "EcdsaSecp256r1VerificationKey2019" and "EcdsaSecp256k1VerificationKey2019" types (which use uncompressed key bytes, with no multicodec prefix). The "id" and "controller" fields are not checked.
+func ParsePublicVerificationMethod(vm map[string]any) (PublicKey, error) {
+	vmType, ok := vm["type"].(string)
+	if !ok {
+		return nil, fmt.Errorf("crypto: verificationMethod missing type")
+	}
+	mb, ok := vm["publicKeyMultibase"].(string)
+	if !ok {
+		return nil, fmt.Errorf("crypto: verificationMethod missing publicKeyMultibase")
+	}
+	switch vmType {
+	case "Multikey":
+		return ParsePublicMultibase(mb)
+	case "EcdsaSecp256r1VerificationKey2019", "EcdsaSecp256k1VerificationKey2019":
+		if len(mb) < 2 || mb[0] != 'z' {
+			return nil, fmt.Errorf("crypto: not a multibase base58btc string")
+		}
+		keyBytes, err := base58.Decode(mb[1:])
+		if err != nil {
+			return nil, fmt.Errorf("crypto: not a multibase base58btc string")
+		}
+		if vmType == "EcdsaSecp256r1VerificationKey2019" {
+			return ParsePublicUncompressedBytesP256(keyBytes)
+		}
+		return ParsePublicUncompressedBytesK256(keyBytes)
+	default:
+		return nil, fmt.Errorf("crypto: unsupported verificationMethod type: %s", vmType)
+	}
+}
@@ -25,6 +25,7 @@ func TestDIDKeyFixtures(t *testing.T) {
 	}{
 		{path: "testdata/w3c_didkey_P256.json", keyType: "P256"},
 		{path: "testdata/w3c_didkey_K256.json", keyType: "K256"},
+		{path: "testdata/w3c_didkey_Ed25519.json", keyType: "Ed25519"},
 	}
 
 	for _, batch := range fixtureBatches {
@@ -76,6 +77,8 @@ func testDIDKeyFixture(t *testing.T, row DIDKeyFixture, keyType string) {
 		priv, err = ParsePrivateBytesP256(raw)
 	case "K256":
 		priv, err = ParsePrivateBytesK256(raw)
+	case "Ed25519":
+		priv, err = ParsePrivateBytesEd25519(raw)
 	default:
 		t.Fatal("impossible key type")
 	}
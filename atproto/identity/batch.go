@@ -0,0 +1,58 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// Maximum number of concurrent DID resolutions done by [LookupDIDs].
+var BatchLookupConcurrency int64 = 16
+
+// Resolves a batch of DIDs using the provided directory, returning all the identities which resolved successfully.
+//
+// Duplicate DIDs in the input are only looked up once, and distinct DIDs are resolved concurrently (up to [BatchLookupConcurrency] at a time). There is no batch resolution endpoint for did:plc or did:web, so this fans out to individual [Directory.LookupDID] calls; wrapping the directory with caching or request coalescing means these lookups can be shared with other callers.
+//
+// If any lookups fail, the returned error wraps all of the individual failures, and the map still contains every identity which did resolve. A cancelled context stops any remaining lookups from starting.
+func LookupDIDs(ctx context.Context, dir Directory, dids []syntax.DID) (map[syntax.DID]*Identity, error) {
+	out := make(map[syntax.DID]*Identity, len(dids))
+	var errs []error
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+
+	sem := semaphore.NewWeighted(BatchLookupConcurrency)
+	seen := make(map[syntax.DID]bool, len(dids))
+	for _, did := range dids {
+		if seen[did] {
+			continue
+		}
+		seen[did] = true
+
+		if err := sem.Acquire(ctx, 1); err != nil {
+			mtx.Lock()
+			errs = append(errs, err)
+			mtx.Unlock()
+			break
+		}
+		wg.Add(1)
+		go func(did syntax.DID) {
+			defer wg.Done()
+			defer sem.Release(1)
+			ident, err := dir.LookupDID(ctx, did)
+			mtx.Lock()
+			defer mtx.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("resolving %s: %w", did, err))
+				return
+			}
+			out[did] = ident
+		}(did)
+	}
+	wg.Wait()
+	return out, errors.Join(errs...)
+}
@@ -0,0 +1,51 @@
+package identity
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// wraps a directory, counting DID lookups
+type countingDirectory struct {
+	MockDirectory
+	didLookups atomic.Int64
+}
+
+func (d *countingDirectory) LookupDID(ctx context.Context, did syntax.DID) (*Identity, error) {
+	d.didLookups.Add(1)
+	return d.MockDirectory.LookupDID(ctx, did)
+}
+
+func TestLookupDIDs(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	dir := countingDirectory{MockDirectory: NewMockDirectory()}
+	id1 := Identity{DID: syntax.DID("did:plc:abc111"), Handle: syntax.Handle("one.example.com")}
+	id2 := Identity{DID: syntax.DID("did:plc:abc222"), Handle: syntax.Handle("two.example.com")}
+	dir.Insert(id1)
+	dir.Insert(id2)
+
+	out, err := LookupDIDs(ctx, &dir, []syntax.DID{id1.DID, id2.DID, id1.DID, id1.DID})
+	assert.NoError(err)
+	assert.Equal(2, len(out))
+	assert.Equal(id1.Handle, out[id1.DID].Handle)
+	assert.Equal(id2.Handle, out[id2.DID].Handle)
+	assert.Equal(int64(2), dir.didLookups.Load())
+
+	// partial failure returns the identities which did resolve
+	missing := syntax.DID("did:plc:abc333")
+	out, err = LookupDIDs(ctx, &dir, []syntax.DID{id1.DID, missing})
+	assert.ErrorIs(err, ErrDIDNotFound)
+	assert.Equal(1, len(out))
+	assert.NotNil(out[id1.DID])
+
+	out, err = LookupDIDs(ctx, &dir, nil)
+	assert.NoError(err)
+	assert.Empty(out)
+}
@@ -0,0 +1,92 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CoalescingDirectory is an implementation of identity.Directory which merges concurrent lookups of the same identifier in to a single request to the inner directory.
+//
+// Distinct identifiers are looked up in parallel. Results (including errors) are shared with all callers waiting on the same in-flight request, but nothing is cached after the request completes. This is useful in front of a directory without its own coalescing, or behind a cache to limit thundering-herd misses.
+type CoalescingDirectory struct {
+	Inner Directory
+
+	// Timeout bounds each shared request to the inner directory. Shared requests don't inherit any caller's deadline, so this is what stops a hung lookup from being joined indefinitely. Zero means the default (30 seconds).
+	Timeout time.Duration
+
+	didGroup    singleflight.Group
+	handleGroup singleflight.Group
+}
+
+var _ Directory = (*CoalescingDirectory)(nil)
+
+func NewCoalescingDirectory(inner Directory) *CoalescingDirectory {
+	return &CoalescingDirectory{
+		Inner: inner,
+	}
+}
+
+const defaultCoalescingTimeout = 30 * time.Second
+
+// Runs 'fn' once per key for concurrent callers. The inner request is detached from the context of the caller which happened to start it, so that one caller cancelling (or having a short deadline) doesn't fail the lookup for the others; instead it is bounded by 'timeout'. Each caller still returns early if their own context is done.
+func coalesce(ctx context.Context, group *singleflight.Group, key string, timeout time.Duration, fn func(ctx context.Context) (*Identity, error)) (*Identity, bool, error) {
+	if timeout <= 0 {
+		timeout = defaultCoalescingTimeout
+	}
+	ch := group.DoChan(key, func() (any, error) {
+		inner, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+		return fn(inner)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Shared, res.Err
+		}
+		return res.Val.(*Identity), res.Shared, nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+func (d *CoalescingDirectory) LookupDID(ctx context.Context, did syntax.DID) (*Identity, error) {
+	ident, shared, err := coalesce(ctx, &d.didGroup, did.String(), d.Timeout, func(ctx context.Context) (*Identity, error) {
+		return d.Inner.LookupDID(ctx, did)
+	})
+	if shared {
+		didResolution.WithLabelValues("coalescing", "coalesced").Inc()
+	}
+	return ident, err
+}
+
+func (d *CoalescingDirectory) LookupHandle(ctx context.Context, h syntax.Handle) (*Identity, error) {
+	h = h.Normalize()
+	ident, shared, err := coalesce(ctx, &d.handleGroup, h.String(), d.Timeout, func(ctx context.Context) (*Identity, error) {
+		return d.Inner.LookupHandle(ctx, h)
+	})
+	if shared {
+		handleResolution.WithLabelValues("coalescing", "coalesced").Inc()
+	}
+	return ident, err
+}
+
+func (d *CoalescingDirectory) Lookup(ctx context.Context, a syntax.AtIdentifier) (*Identity, error) {
+	handle, err := a.AsHandle()
+	if nil == err { // if not an error, is a handle
+		return d.LookupHandle(ctx, handle)
+	}
+	did, err := a.AsDID()
+	if nil == err { // if not an error, is a DID
+		return d.LookupDID(ctx, did)
+	}
+	return nil, fmt.Errorf("at-identifier neither a Handle nor a DID")
+}
+
+func (d *CoalescingDirectory) Purge(ctx context.Context, atid syntax.AtIdentifier) error {
+	return d.Inner.Purge(ctx, atid)
+}
@@ -0,0 +1,146 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// directory which blocks all DID lookups until released (or the context is done), counting calls
+type blockingDirectory struct {
+	MockDirectory
+	release chan struct{}
+	calls   atomic.Int64
+	err     error
+}
+
+func (d *blockingDirectory) LookupDID(ctx context.Context, did syntax.DID) (*Identity, error) {
+	d.calls.Add(1)
+	select {
+	case <-d.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return d.MockDirectory.LookupDID(ctx, did)
+}
+
+func newBlockingDirectory(idents ...Identity) *blockingDirectory {
+	d := blockingDirectory{MockDirectory: NewMockDirectory(), release: make(chan struct{})}
+	for _, ident := range idents {
+		d.Insert(ident)
+	}
+	return &d
+}
+
+// does n concurrent lookups of the same DID, releasing the inner directory once they have all started
+func lookupConcurrently(dir Directory, inner *blockingDirectory, did syntax.DID, n int) ([]*Identity, []error) {
+	idents := make([]*Identity, n)
+	errs := make([]error, n)
+	var started, wg sync.WaitGroup
+	started.Add(n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			started.Done()
+			idents[i], errs[i] = dir.LookupDID(context.Background(), did)
+		}(i)
+	}
+	started.Wait()
+	for inner.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// give the remaining goroutines time to join the in-flight request
+	time.Sleep(50 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+	return idents, errs
+}
+
+func TestCoalescingDirectory(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	n := 50
+	id1 := Identity{DID: syntax.DID("did:plc:abc111"), Handle: syntax.Handle("one.example.com")}
+	id2 := Identity{DID: syntax.DID("did:plc:abc222"), Handle: syntax.Handle("two.example.com")}
+
+	// concurrent lookups of the same DID share a single inner request
+	inner := newBlockingDirectory(id1)
+	dir := NewCoalescingDirectory(inner)
+	idents, errs := lookupConcurrently(dir, inner, id1.DID, n)
+	for i := 0; i < n; i++ {
+		assert.NoError(errs[i])
+		assert.Equal(id1.Handle, idents[i].Handle)
+	}
+	assert.Equal(int64(1), inner.calls.Load())
+
+	// errors are passed to all waiters, and not cached after the request completes
+	failErr := errors.New("upstream failure")
+	inner = newBlockingDirectory(id1)
+	inner.err = failErr
+	dir = NewCoalescingDirectory(inner)
+	_, errs = lookupConcurrently(dir, inner, id1.DID, n)
+	for i := 0; i < n; i++ {
+		assert.ErrorIs(errs[i], failErr)
+	}
+	assert.Equal(int64(1), inner.calls.Load())
+	inner.err = nil
+	ident, err := dir.LookupDID(ctx, id1.DID)
+	assert.NoError(err)
+	assert.Equal(id1.DID, ident.DID)
+	assert.Equal(int64(2), inner.calls.Load())
+
+	// distinct DIDs are not serialized behind each other
+	inner = newBlockingDirectory(id1, id2)
+	dir = NewCoalescingDirectory(inner)
+	var wg sync.WaitGroup
+	for _, did := range []syntax.DID{id1.DID, id2.DID} {
+		wg.Add(1)
+		go func(did syntax.DID) {
+			defer wg.Done()
+			_, err := dir.LookupDID(ctx, did)
+			assert.NoError(err)
+		}(did)
+	}
+	for inner.calls.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(inner.release)
+	wg.Wait()
+
+	// a caller giving up returns early, without failing the in-flight lookup for others
+	inner = newBlockingDirectory(id1)
+	dir = NewCoalescingDirectory(inner)
+	cctx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		_, err := dir.LookupDID(cctx, id1.DID)
+		done <- err
+	}()
+	for inner.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	assert.ErrorIs(<-done, context.Canceled)
+	close(inner.release)
+	ident, err = dir.LookupDID(ctx, id1.DID)
+	assert.NoError(err)
+	assert.Equal(id1.DID, ident.DID)
+
+	// shared requests don't inherit caller deadlines, but are bounded by Timeout
+	inner = newBlockingDirectory(id1)
+	dir = NewCoalescingDirectory(inner)
+	dir.Timeout = 50 * time.Millisecond
+	_, err = dir.LookupDID(ctx, id1.DID)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+}
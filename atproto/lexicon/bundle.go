@@ -0,0 +1,78 @@
+package lexicon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Writes all schemas in the catalog to a single JSON document: an array of SchemaFile objects, sorted by NSID.
+//
+// The output can be loaded with [BaseCatalog.LoadBundle]. Schemas are grouped back in to one SchemaFile per NSID, but file-level metadata (such as the top-level description) is not retained by the catalog, so is not included.
+func (c *BaseCatalog) ExportBundle(w io.Writer) error {
+	files := make(map[string]*SchemaFile)
+	for ref, s := range c.schemas {
+		parts := strings.SplitN(ref, "#", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("unexpected schema reference in catalog: %s", ref)
+		}
+		sf, ok := files[parts[0]]
+		if !ok {
+			sf = &SchemaFile{
+				Lexicon: 1,
+				ID:      parts[0],
+				Defs:    make(map[string]SchemaDef),
+			}
+			files[parts[0]] = sf
+		}
+		sf.Defs[parts[1]] = SchemaDef{Inner: s.Def}
+	}
+
+	bundle := make([]*SchemaFile, 0, len(files))
+	for _, sf := range files {
+		bundle = append(bundle, sf)
+	}
+	sort.Slice(bundle, func(i, j int) bool { return bundle[i].ID < bundle[j].ID })
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bundle)
+}
+
+// Loads all schemas from a single JSON bundle document, as written by [BaseCatalog.ExportBundle].
+//
+// Returns an error if any schema file in the bundle is invalid, or any definition already exists in the catalog (or appears twice in the bundle). The catalog is only modified if the entire bundle is valid.
+func (c *BaseCatalog) LoadBundle(r io.Reader) error {
+	var bundle []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return fmt.Errorf("failed to parse lexicon bundle: %w", err)
+	}
+	var schemas []Schema
+	seen := make(map[string]bool)
+	for _, b := range bundle {
+		var sf SchemaFile
+		if err := json.Unmarshal(b, &sf); err != nil {
+			return err
+		}
+		if err := c.checkUnknownKeys(b); err != nil {
+			return fmt.Errorf("%s: %w", sf.ID, err)
+		}
+		fileSchemas, err := parseSchemaFile(sf)
+		if err != nil {
+			return err
+		}
+		for _, s := range fileSchemas {
+			if _, ok := c.schemas[s.ID]; ok || seen[s.ID] {
+				return fmt.Errorf("catalog already contained a schema with name: %s", s.ID)
+			}
+			seen[s.ID] = true
+		}
+		schemas = append(schemas, fileSchemas...)
+	}
+	for _, s := range schemas {
+		c.schemas[s.ID] = s
+	}
+	return nil
+}
@@ -1,15 +1,18 @@
 package lexicon
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"io/fs"
 	"log/slog"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
 )
 
 // Interface type for a resolver or container of lexicon schemas, and methods for validating generic data against those schemas.
@@ -18,11 +21,35 @@ type Catalog interface {
 	Resolve(ref string) (*Schema, error)
 }
 
+// Indicates a loop of schema references which can be followed without consuming any data (eg, a ref to a ref back to the first), which would cause validation to recurse forever.
+var ErrCircularRef = errors.New("circular lexicon schema reference")
+
+// Indicates that lazy schema resolution (see [BaseCatalog.ResolverFunc]) was nested more than maxResolveDepth levels deep.
+var ErrResolveDepth = errors.New("lexicon schema resolution nested too deeply")
+
+// limit on nested calls to BaseCatalog.ResolverFunc (eg, a resolver which itself resolves references while loading a schema)
+const maxResolveDepth = 8
+
 // Trivial in-memory Lexicon Catalog implementation.
 type BaseCatalog struct {
+	// If true, schema files loaded from JSON (directories, filesystems, and bundles) which have keys not recognized by this package are rejected. By default, unknown keys are ignored, for forwards-compatibility with additions to the Lexicon language. See [UnknownSchemaKeys].
+	RejectUnknownKeys bool
+
+	// Optional hook, called when a reference is resolved for an NSID which has no definitions in the catalog. The returned schema file (which must have a matching ID) is added to the catalog, so each NSID is only fetched once. Errors are not cached, and the hook is called again on the next miss. [NetworkResolverFunc] returns a hook which fetches schemas from the network.
+	//
+	// Resolve does not take a context, so the hook is passed context.Background(). Lazily adding schemas modifies the catalog, so a catalog with this hook set is not safe for concurrent use.
+	ResolverFunc func(ctx context.Context, nsid syntax.NSID) (*SchemaFile, error)
+
+	// Optional sink for record validation statistics (see [MetricsCatalog]). Must be safe for concurrent use if the catalog is used concurrently.
+	ValidationMetrics ValidationMetrics
+
 	schemas map[string]Schema
+	// NSIDs currently being fetched by ResolverFunc, to detect re-entrant resolution
+	resolving map[syntax.NSID]bool
 }
 
+var _ MetricsCatalog = (*BaseCatalog)(nil)
+
 // Creates a new empty BaseCatalog
 func NewBaseCatalog() BaseCatalog {
 	return BaseCatalog{
@@ -38,36 +65,212 @@ func (c *BaseCatalog) Resolve(ref string) (*Schema, error) {
 		return nil, fmt.Errorf("tried to resolve empty string name")
 	}
 	// default to #main if name doesn't have a fragment
-	if !strings.Contains(ref, "#") {
-		ref = ref + "#main"
-	}
+	ref = normalizeRef(ref)
 	s, ok := c.schemas[ref]
 	if !ok {
+		if c.ResolverFunc != nil {
+			return c.resolveMissing(ref)
+		}
 		return nil, fmt.Errorf("schema not found in catalog: %s", ref)
 	}
 	return &s, nil
 }
 
+// Fetches the schema file for a (normalized) reference using ResolverFunc, adds it to the catalog, and returns the referenced definition.
+func (c *BaseCatalog) resolveMissing(ref string) (*Schema, error) {
+	nsidStr, _, _ := strings.Cut(ref, "#")
+	nsid, err := syntax.ParseNSID(nsidStr)
+	if err != nil {
+		return nil, err
+	}
+	// only fetch NSIDs which are not loaded at all; a missing fragment in a loaded file is just a miss
+	prefix := nsid.String() + "#"
+	for name := range c.schemas {
+		if strings.HasPrefix(name, prefix) {
+			return nil, fmt.Errorf("schema not found in catalog: %s", ref)
+		}
+	}
+	if c.resolving[nsid] {
+		return nil, fmt.Errorf("%w: schema resolution for %s depends on itself", ErrCircularRef, nsid)
+	}
+	if len(c.resolving) >= maxResolveDepth {
+		return nil, fmt.Errorf("%w: %s", ErrResolveDepth, nsid)
+	}
+	if c.resolving == nil {
+		c.resolving = make(map[syntax.NSID]bool)
+	}
+	c.resolving[nsid] = true
+	defer delete(c.resolving, nsid)
+
+	sf, err := c.ResolverFunc(context.Background(), nsid)
+	if err != nil {
+		return nil, fmt.Errorf("resolving lexicon schema %s: %w", nsid, err)
+	}
+	if sf == nil {
+		return nil, fmt.Errorf("schema not found in catalog: %s", ref)
+	}
+	if sf.ID != nsid.String() {
+		return nil, fmt.Errorf("lexicon ID does not match NSID: %s != %s", sf.ID, nsid)
+	}
+	if err := c.AddSchemaFile(*sf); err != nil {
+		return nil, err
+	}
+	s, ok := c.schemas[ref]
+	if !ok {
+		return nil, fmt.Errorf("schema not found in resolved lexicon: %s", ref)
+	}
+	return &s, nil
+}
+
+// Returns the ValidationMetrics field, implementing [MetricsCatalog]
+func (c *BaseCatalog) Metrics() ValidationMetrics {
+	return c.ValidationMetrics
+}
+
+// Returns the fully-qualified references (NSID with '#' fragment) of all schemas in the catalog, in sorted order.
+func (c *BaseCatalog) Refs() []string {
+	out := make([]string, 0, len(c.schemas))
+	for ref := range c.schemas {
+		out = append(out, ref)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Returns all schemas in the catalog, keyed by fully-qualified reference (NSID with '#' fragment).
+func (c *BaseCatalog) ResolveAll() map[string]*Schema {
+	out := make(map[string]*Schema, len(c.schemas))
+	for ref, s := range c.schemas {
+		out[ref] = &s
+	}
+	return out
+}
+
+// Checks the catalog as a whole: that every reference (including union variants) resolves to a schema in the catalog, and that there are no circular reference chains ([ErrCircularRef]).
+//
+// Individual schema files are checked when they are added, but references between files can only be checked once the full set is loaded. This is intended to be called at startup, to catch bad sets of Lexicons before any data is validated against them.
+func (c *BaseCatalog) Validate() error {
+	for _, ref := range c.Refs() {
+		s := c.schemas[ref]
+		for _, target := range schemaRefs(s.Def) {
+			if _, err := c.Resolve(target); err != nil {
+				return fmt.Errorf("reference from %s: %w", ref, err)
+			}
+		}
+	}
+
+	// only refs and unions at the top level of a definition are followed without consuming data, so those are the edges which can form problematic cycles. references nested in objects or arrays always descend in to the data, which is finite.
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(c.schemas))
+	var visit func(ref string, path []string) error
+	visit = func(ref string, path []string) error {
+		path = append(path, ref)
+		switch state[ref] {
+		case done:
+			return nil
+		case visiting:
+			// trim the path to just the cycle
+			for i, p := range path {
+				if p == ref {
+					path = path[i:]
+					break
+				}
+			}
+			return fmt.Errorf("%w: %s", ErrCircularRef, strings.Join(path, " -> "))
+		}
+		state[ref] = visiting
+		var next []string
+		switch v := c.schemas[ref].Def.(type) {
+		case SchemaRef:
+			next = []string{v.fullRef}
+		case SchemaUnion:
+			next = v.fullRefs
+		}
+		for _, target := range next {
+			if err := visit(normalizeRef(target), path); err != nil {
+				return err
+			}
+		}
+		state[ref] = done
+		return nil
+	}
+	for _, ref := range c.Refs() {
+		if err := visit(ref, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adds the default '#main' fragment to a reference, if it doesn't have one
+func normalizeRef(ref string) string {
+	if !strings.Contains(ref, "#") {
+		return ref + "#main"
+	}
+	return ref
+}
+
 // Inserts a schema loaded from a JSON file in to the catalog.
+//
+// Returns an error if any definition in the file is invalid, or already exists in the catalog. The catalog is only modified if the entire file is valid.
 func (c *BaseCatalog) AddSchemaFile(sf SchemaFile) error {
+	schemas, err := parseSchemaFile(sf)
+	if err != nil {
+		return err
+	}
+	for _, s := range schemas {
+		if _, ok := c.schemas[s.ID]; ok {
+			return fmt.Errorf("catalog already contained a schema with name: %s", s.ID)
+		}
+	}
+	for _, s := range schemas {
+		c.schemas[s.ID] = s
+	}
+	return nil
+}
+
+// Inserts a schema loaded from a JSON file in to the catalog, replacing any existing definitions for the same NSID.
+//
+// All existing definitions under the file's NSID are removed, including any which are not present in the new file. This is intended for reloading edited Lexicons during development. The catalog is only modified if the entire file is valid.
+func (c *BaseCatalog) ReplaceSchemaFile(sf SchemaFile) error {
+	schemas, err := parseSchemaFile(sf)
+	if err != nil {
+		return err
+	}
+	prefix := sf.ID + "#"
+	for name := range c.schemas {
+		if strings.HasPrefix(name, prefix) {
+			delete(c.schemas, name)
+		}
+	}
+	for _, s := range schemas {
+		c.schemas[s.ID] = s
+	}
+	return nil
+}
+
+// Checks all the definitions in a schema file, and returns them as fully-qualified Schema objects, without modifying any catalog.
+func parseSchemaFile(sf SchemaFile) ([]Schema, error) {
 	if sf.Lexicon != 1 {
-		return fmt.Errorf("unsupported lexicon language version: %d", sf.Lexicon)
+		return nil, fmt.Errorf("unsupported lexicon language version: %d", sf.Lexicon)
 	}
 	base := sf.ID
+	out := make([]Schema, 0, len(sf.Defs))
 	for frag, def := range sf.Defs {
 		if len(frag) == 0 || strings.Contains(frag, "#") || strings.Contains(frag, ".") {
 			// TODO: more validation here?
-			return fmt.Errorf("schema name invalid: %s", frag)
+			return nil, fmt.Errorf("schema name invalid: %s", frag)
 		}
 		name := base + "#" + frag
-		if _, ok := c.schemas[name]; ok {
-			return fmt.Errorf("catalog already contained a schema with name: %s", name)
-		}
 		// "A file can have at most one definition with one of the "primary" types. Primary types should always have the name main. It is possible for main to describe a non-primary type."
 		switch s := def.Inner.(type) {
 		case SchemaRecord, SchemaQuery, SchemaProcedure, SchemaSubscription:
 			if frag != "main" {
-				return fmt.Errorf("record, query, procedure, and subscription types must be 'main', not: %s", frag)
+				return nil, fmt.Errorf("record, query, procedure, and subscription types must be 'main', not: %s", frag)
 			}
 		case SchemaToken:
 			// add fully-qualified name to token
@@ -76,15 +279,14 @@ func (c *BaseCatalog) AddSchemaFile(sf SchemaFile) error {
 		}
 		def.SetBase(base)
 		if err := def.CheckSchema(); err != nil {
-			return err
+			return nil, err
 		}
-		s := Schema{
+		out = append(out, Schema{
 			ID:  name,
 			Def: def.Inner,
-		}
-		c.schemas[name] = s
+		})
 	}
-	return nil
+	return out, nil
 }
 
 // internal helper for loading JSON files from bytes
@@ -93,6 +295,9 @@ func (c *BaseCatalog) addSchemaFromBytes(b []byte) error {
 	if err := json.Unmarshal(b, &sf); err != nil {
 		return err
 	}
+	if err := c.checkUnknownKeys(b); err != nil {
+		return fmt.Errorf("%s: %w", sf.ID, err)
+	}
 	if err := c.AddSchemaFile(sf); err != nil {
 		return err
 	}
@@ -101,34 +306,16 @@ func (c *BaseCatalog) addSchemaFromBytes(b []byte) error {
 
 // Recursively loads all '.json' files from a directory in to the catalog.
 func (c *BaseCatalog) LoadDirectory(dirPath string) error {
-	walkFunc := func(p string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			return nil
-		}
-		if !strings.HasSuffix(p, ".json") {
-			return nil
-		}
-		slog.Debug("loading Lexicon schema file", "path", p)
-		f, err := os.Open(p)
-		if err != nil {
-			return err
-		}
-		defer func() { _ = f.Close() }()
-
-		b, err := io.ReadAll(f)
-		if err != nil {
-			return err
-		}
-		return c.addSchemaFromBytes(b)
-	}
-	return filepath.WalkDir(dirPath, walkFunc)
+	return c.LoadFS(os.DirFS(dirPath), ".")
 }
 
 // Recursively loads all '.json' files from an embed.FS
 func (c *BaseCatalog) LoadEmbedFS(efs embed.FS) error {
+	return c.LoadFS(efs, ".")
+}
+
+// Recursively loads all '.json' files from a filesystem (such as an embed.FS), starting at the root path, in to the catalog.
+func (c *BaseCatalog) LoadFS(fsys fs.FS, root string) error {
 	walkFunc := func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -140,12 +327,12 @@ func (c *BaseCatalog) LoadEmbedFS(efs embed.FS) error {
 			return nil
 		}
 
-		slog.Debug("loading embedded Lexicon schema file", "path", p)
-		b, err := efs.ReadFile(p)
+		slog.Debug("loading Lexicon schema file", "path", p)
+		b, err := fs.ReadFile(fsys, p)
 		if err != nil {
 			return err
 		}
 		return c.addSchemaFromBytes(b)
 	}
-	return fs.WalkDir(efs, ".", walkFunc)
+	return fs.WalkDir(fsys, root, walkFunc)
 }
@@ -1,8 +1,18 @@
 package lexicon
 
 import (
+	"bytes"
+	"context"
 	"embed"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -39,3 +49,442 @@ func TestDirCatalog(t *testing.T) {
 	_, err = cat.Resolve("example.lexicon.notThere")
 	assert.Error(err)
 }
+
+func TestCatalogMergeFrom(t *testing.T) {
+	assert := assert.New(t)
+
+	cat := NewBaseCatalog()
+	assert.NoError(cat.LoadDirectory("testdata/catalog"))
+
+	// identical duplicates are accepted
+	other := NewBaseCatalog()
+	assert.NoError(other.LoadEmbedFS(embedDir))
+	conflicts, err := cat.MergeFrom(&other)
+	assert.NoError(err)
+	assert.Empty(conflicts)
+
+	// new schemas are added, and conflicting schemas are reported but not overwritten
+	desc := "a different description"
+	extra := NewBaseCatalog()
+	assert.NoError(extra.AddSchemaFile(SchemaFile{
+		Lexicon: 1,
+		ID:      "example.lexicon.merged",
+		Defs: map[string]SchemaDef{
+			"main": {Inner: SchemaToken{Type: "token"}},
+		},
+	}))
+	assert.NoError(extra.AddSchemaFile(SchemaFile{
+		Lexicon: 1,
+		ID:      "example.lexicon.query",
+		Defs: map[string]SchemaDef{
+			"main": {Inner: SchemaToken{Type: "token", Description: &desc}},
+		},
+	}))
+	conflicts, err = cat.MergeFrom(&extra)
+	assert.NoError(err)
+	assert.Equal(1, len(conflicts))
+	assert.Equal("example.lexicon.query#main", conflicts[0].ID)
+
+	_, err = cat.Resolve("example.lexicon.merged")
+	assert.NoError(err)
+	s, err := cat.Resolve("example.lexicon.query")
+	assert.NoError(err)
+	_, ok := s.Def.(SchemaQuery)
+	assert.True(ok)
+}
+
+func TestCatalogBundle(t *testing.T) {
+	assert := assert.New(t)
+
+	cat := NewBaseCatalog()
+	assert.NoError(cat.LoadDirectory("testdata/catalog"))
+
+	var buf bytes.Buffer
+	assert.NoError(cat.ExportBundle(&buf))
+
+	loaded := NewBaseCatalog()
+	assert.NoError(loaded.LoadBundle(&buf))
+	assert.Equal(len(cat.schemas), len(loaded.schemas))
+
+	conflicts, err := loaded.MergeFrom(&cat)
+	assert.NoError(err)
+	assert.Empty(conflicts)
+	assert.Equal(len(cat.schemas), len(loaded.schemas))
+
+	// a bundle with any invalid or conflicting file is not partially loaded
+	partial := NewBaseCatalog()
+	assert.Error(partial.LoadBundle(strings.NewReader(`[
+		{"lexicon": 1, "id": "example.lexicon.first", "defs": {"main": {"type": "token"}}},
+		{"lexicon": 2, "id": "example.lexicon.second", "defs": {"main": {"type": "token"}}}
+	]`)))
+	assert.Empty(partial.schemas)
+	assert.Error(partial.LoadBundle(strings.NewReader(`[
+		{"lexicon": 1, "id": "example.lexicon.first", "defs": {"main": {"type": "token"}}},
+		{"lexicon": 1, "id": "example.lexicon.first", "defs": {"main": {"type": "token"}}}
+	]`)))
+	assert.Empty(partial.schemas)
+}
+
+func TestCatalogReplaceSchemaFile(t *testing.T) {
+	assert := assert.New(t)
+
+	cat := NewBaseCatalog()
+	assert.NoError(cat.AddSchemaFile(SchemaFile{
+		Lexicon: 1,
+		ID:      "example.lexicon.replace",
+		Defs: map[string]SchemaDef{
+			"main":  {Inner: SchemaToken{Type: "token"}},
+			"other": {Inner: SchemaToken{Type: "token"}},
+		},
+	}))
+
+	// regular add fails on duplicate
+	desc := "replaced"
+	next := SchemaFile{
+		Lexicon: 1,
+		ID:      "example.lexicon.replace",
+		Defs: map[string]SchemaDef{
+			"main": {Inner: SchemaToken{Type: "token", Description: &desc}},
+		},
+	}
+	assert.Error(cat.AddSchemaFile(next))
+
+	assert.NoError(cat.ReplaceSchemaFile(next))
+	s, err := cat.Resolve("example.lexicon.replace")
+	assert.NoError(err)
+	tok, ok := s.Def.(SchemaToken)
+	assert.True(ok)
+	assert.Equal(&desc, tok.Description)
+	assert.NoError(tok.Validate("example.lexicon.replace#main"))
+	_, err = cat.Resolve("example.lexicon.replace#other")
+	assert.Error(err)
+
+	// invalid file leaves catalog unchanged
+	bad := SchemaFile{
+		Lexicon: 1,
+		ID:      "example.lexicon.replace",
+		Defs: map[string]SchemaDef{
+			"main":  {Inner: SchemaToken{Type: "token"}},
+			"query": {Inner: SchemaQuery{Type: "query"}},
+		},
+	}
+	assert.Error(cat.ReplaceSchemaFile(bad))
+	s, err = cat.Resolve("example.lexicon.replace")
+	assert.NoError(err)
+	assert.Equal(&desc, s.Def.(SchemaToken).Description)
+}
+
+func TestCatalogRefs(t *testing.T) {
+	assert := assert.New(t)
+
+	cat := NewBaseCatalog()
+	assert.NoError(cat.LoadDirectory("testdata/catalog"))
+
+	refs := cat.Refs()
+	assert.True(sort.StringsAreSorted(refs))
+	assert.Contains(refs, "example.lexicon.query#main")
+	assert.Contains(refs, "com.atproto.label.defs#label")
+
+	all := cat.ResolveAll()
+	assert.Equal(len(refs), len(all))
+	for _, ref := range refs {
+		s, err := cat.Resolve(ref)
+		assert.NoError(err)
+		assert.Equal(ref, s.ID)
+		assert.Equal(s, all[ref])
+	}
+}
+
+func TestFSCatalog(t *testing.T) {
+	assert := assert.New(t)
+
+	b, err := os.ReadFile("testdata/catalog/query.json")
+	assert.NoError(err)
+	fsys := fstest.MapFS{
+		"lexicons/example/query.json": &fstest.MapFile{Data: b},
+		"lexicons/README.md":          &fstest.MapFile{Data: []byte("not a lexicon")},
+		"other/broken.json":           &fstest.MapFile{Data: []byte("{")},
+	}
+
+	cat := NewBaseCatalog()
+	assert.NoError(cat.LoadFS(fsys, "lexicons"))
+
+	_, err = cat.Resolve("example.lexicon.query")
+	assert.NoError(err)
+
+	cat = NewBaseCatalog()
+	assert.Error(cat.LoadFS(fsys, "."))
+}
+
+func TestCatalogValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	// the test catalog is self-consistent
+	cat := NewBaseCatalog()
+	assert.NoError(cat.LoadDirectory("testdata/catalog"))
+	assert.NoError(cat.Validate())
+
+	// dangling reference
+	dangling := NewBaseCatalog()
+	assert.NoError(dangling.addSchemaFromBytes([]byte(`{
+		"lexicon": 1,
+		"id": "example.lexicon.dangling",
+		"defs": {
+			"main": { "type": "object", "properties": { "a": { "type": "ref", "ref": "example.lexicon.missing" } } }
+		}
+	}`)))
+	assert.ErrorContains(dangling.Validate(), "example.lexicon.missing")
+
+	// cycle of refs across two files
+	cyclic := NewBaseCatalog()
+	assert.NoError(cyclic.addSchemaFromBytes([]byte(`{
+		"lexicon": 1,
+		"id": "example.lexicon.cycleA",
+		"defs": {
+			"main": { "type": "ref", "ref": "example.lexicon.cycleB#thing" }
+		}
+	}`)))
+	assert.NoError(cyclic.addSchemaFromBytes([]byte(`{
+		"lexicon": 1,
+		"id": "example.lexicon.cycleB",
+		"defs": {
+			"thing": { "type": "ref", "ref": "example.lexicon.cycleA" },
+			"rec": {
+				"type": "object",
+				"properties": { "a": { "type": "ref", "ref": "example.lexicon.cycleA" } }
+			}
+		}
+	}`)))
+	err := cyclic.Validate()
+	assert.ErrorIs(err, ErrCircularRef)
+	assert.ErrorContains(err, "example.lexicon.cycleA#main -> example.lexicon.cycleB#thing -> example.lexicon.cycleA#main")
+
+	// validation against the cycle errors instead of recursing forever
+	rec, err := cyclic.Resolve("example.lexicon.cycleB#rec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = validateData(&cyclic, rec.Def, map[string]any{"a": map[string]any{}}, 0)
+	assert.ErrorIs(err, ErrCircularRef)
+
+	// recursion through object fields is not a cycle
+	nested := NewBaseCatalog()
+	assert.NoError(nested.addSchemaFromBytes([]byte(`{
+		"lexicon": 1,
+		"id": "example.lexicon.tree",
+		"defs": {
+			"node": {
+				"type": "object",
+				"properties": {
+					"children": { "type": "array", "items": { "type": "ref", "ref": "#node" } },
+					"next": { "type": "union", "refs": ["#node"] }
+				}
+			}
+		}
+	}`)))
+	assert.NoError(nested.Validate())
+}
+
+func TestCatalogUnknownKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	schema := []byte(`{
+		"lexicon": 1,
+		"id": "example.lexicon.future",
+		"revision": 3,
+		"newTopLevel": true,
+		"defs": {
+			"obj": {
+				"type": "object",
+				"properties": {
+					"text": { "type": "string", "maxLength": 100, "maxWords": 10 }
+				}
+			},
+			"main": {
+				"type": "procedure",
+				"input": { "encoding": "application/json", "compression": "zstd" },
+				"errors": [{ "name": "Oops", "code": 400 }]
+			}
+		}
+	}`)
+
+	unknown, err := UnknownSchemaKeys(schema)
+	assert.NoError(err)
+	assert.Equal([]string{
+		"defs.main.errors[0].code",
+		"defs.main.input.compression",
+		"defs.obj.properties.text.maxWords",
+		"newTopLevel",
+	}, unknown)
+
+	// tolerated by default
+	cat := NewBaseCatalog()
+	assert.NoError(cat.addSchemaFromBytes(schema))
+
+	// rejected in strict mode
+	strict := NewBaseCatalog()
+	strict.RejectUnknownKeys = true
+	err = strict.addSchemaFromBytes(schema)
+	assert.ErrorContains(err, "defs.obj.properties.text.maxWords")
+	_, err = strict.Resolve("example.lexicon.future")
+	assert.Error(err)
+
+	// the test catalog only uses recognized keys
+	assert.NoError(strict.LoadDirectory("testdata/catalog"))
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	assert := assert.New(t)
+
+	a := []byte(`{
+		"lexicon": 1,
+		"id": "example.lexicon.fingerprint",
+		"defs": {
+			"main": {
+				"type": "object",
+				"required": ["text"],
+				"properties": {
+					"text": { "type": "string", "maxLength": 100 },
+					"count": { "type": "integer", "minimum": 0 }
+				}
+			}
+		}
+	}`)
+	// same schema, with keys re-ordered and different whitespace
+	b := []byte(`{"defs":{"main":{"properties":{"count":{"minimum":0,"type":"integer"},"text":{"maxLength":100,"type":"string"}},"required":["text"],"type":"object"}},"id":"example.lexicon.fingerprint","lexicon":1}`)
+	// a changed constraint
+	c := []byte(`{"lexicon":1,"id":"example.lexicon.fingerprint","defs":{"main":{"type":"object","required":["text"],"properties":{"text":{"type":"string","maxLength":200},"count":{"type":"integer","minimum":0}}}}}`)
+
+	fingerprint := func(schema []byte) string {
+		cat := NewBaseCatalog()
+		assert.NoError(cat.addSchemaFromBytes(schema))
+		s, err := cat.Resolve("example.lexicon.fingerprint")
+		assert.NoError(err)
+		return s.Fingerprint()
+	}
+
+	fa := fingerprint(a)
+	assert.Len(fa, 64)
+	assert.Equal(fa, fingerprint(b))
+	assert.NotEqual(fa, fingerprint(c))
+}
+
+func TestMultiCatalog(t *testing.T) {
+	assert := assert.New(t)
+
+	core := NewBaseCatalog()
+	assert.NoError(core.LoadDirectory("testdata/catalog"))
+
+	// app catalog overrides one core schema, and adds another
+	desc := "override"
+	app := NewBaseCatalog()
+	assert.NoError(app.AddSchemaFile(SchemaFile{
+		Lexicon: 1,
+		ID:      "example.lexicon.query",
+		Defs: map[string]SchemaDef{
+			"main": {Inner: SchemaToken{Type: "token", Description: &desc}},
+		},
+	}))
+	assert.NoError(app.AddSchemaFile(SchemaFile{
+		Lexicon: 1,
+		ID:      "example.app.thing",
+		Defs: map[string]SchemaDef{
+			"main": {Inner: SchemaToken{Type: "token"}},
+		},
+	}))
+
+	cat := NewMultiCatalog(&app, &core)
+	s, err := cat.Resolve("example.lexicon.query")
+	assert.NoError(err)
+	_, ok := s.Def.(SchemaToken)
+	assert.True(ok)
+	_, err = cat.Resolve("example.app.thing")
+	assert.NoError(err)
+	_, err = cat.Resolve("example.lexicon.record")
+	assert.NoError(err)
+	_, err = cat.Resolve("example.lexicon.notThere")
+	assert.ErrorContains(err, "not found in any catalog")
+	_, err = cat.Resolve("")
+	assert.Error(err)
+
+	// priority order matters
+	s, err = NewMultiCatalog(&core, &app).Resolve("example.lexicon.query")
+	assert.NoError(err)
+	_, ok = s.Def.(SchemaQuery)
+	assert.True(ok)
+
+	// merging from a multi-catalog applies the same priority
+	merged := NewBaseCatalog()
+	conflicts, err := merged.MergeFrom(cat)
+	assert.NoError(err)
+	assert.Empty(conflicts)
+	s, err = merged.Resolve("example.lexicon.query")
+	assert.NoError(err)
+	_, ok = s.Def.(SchemaToken)
+	assert.True(ok)
+	assert.Equal(len(core.Refs())+1, len(merged.Refs()))
+}
+
+func TestCatalogResolverFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	parse := func(raw string) SchemaFile {
+		var sf SchemaFile
+		if err := json.Unmarshal([]byte(raw), &sf); err != nil {
+			t.Fatal(err)
+		}
+		return sf
+	}
+	cat := NewBaseCatalog()
+	assert.NoError(cat.AddSchemaFile(parse(`{"lexicon": 1, "id": "example.app.record", "defs": {"main": {"type": "record", "key": "any", "record": {"type": "object", "required": ["thing"], "properties": {"thing": {"type": "ref", "ref": "example.other.defs#thing"}}}}}}`)))
+	rec := map[string]any{"$type": "example.app.record", "thing": map[string]any{"name": "hello"}}
+
+	// without a hook, missing schemas are errors
+	assert.Error(ValidateRecord(&cat, rec, "example.app.record", 0))
+
+	fetches := 0
+	cat.ResolverFunc = func(ctx context.Context, nsid syntax.NSID) (*SchemaFile, error) {
+		fetches++
+		switch nsid {
+		case "example.other.defs":
+			sf := parse(`{"lexicon": 1, "id": "example.other.defs", "defs": {"thing": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}}}}`)
+			return &sf, nil
+		case "example.other.wrong":
+			sf := parse(`{"lexicon": 1, "id": "example.other.defs", "defs": {}}`)
+			return &sf, nil
+		}
+		return nil, errors.New("no such lexicon")
+	}
+	assert.NoError(ValidateRecord(&cat, rec, "example.app.record", 0))
+	assert.NoError(ValidateRecord(&cat, rec, "example.app.record", 0))
+	assert.Equal(1, fetches)
+	assert.Error(ValidateRecord(&cat, map[string]any{"$type": "example.app.record", "thing": map[string]any{}}, "example.app.record", 0))
+
+	// a missing fragment in an already-loaded NSID is not re-fetched
+	_, err := cat.Resolve("example.other.defs#missing")
+	assert.ErrorContains(err, "not found")
+	assert.Equal(1, fetches)
+
+	// resolver errors and mismatched IDs are not cached
+	_, err = cat.Resolve("example.other.unknown")
+	assert.ErrorContains(err, "no such lexicon")
+	_, err = cat.Resolve("example.other.unknown")
+	assert.Error(err)
+	assert.Equal(3, fetches)
+	_, err = cat.Resolve("example.other.wrong")
+	assert.ErrorContains(err, "does not match")
+
+	// re-entrant resolution is bounded
+	cat.ResolverFunc = func(ctx context.Context, nsid syntax.NSID) (*SchemaFile, error) {
+		_, err := cat.Resolve(nsid.String())
+		return nil, err
+	}
+	_, err = cat.Resolve("example.loop.self")
+	assert.ErrorIs(err, ErrCircularRef)
+	cat.ResolverFunc = func(ctx context.Context, nsid syntax.NSID) (*SchemaFile, error) {
+		_, err := cat.Resolve(nsid.String() + "x")
+		return nil, err
+	}
+	_, err = cat.Resolve("example.loop.deep")
+	assert.ErrorIs(err, ErrResolveDepth)
+}
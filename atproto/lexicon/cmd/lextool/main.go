@@ -27,6 +27,16 @@ func main() {
 			Name:   "load-directory",
 			Usage:  "try recursively loading all the schemas from a directory",
 			Action: runLoadDirectory,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "strict",
+					Usage: "reject schema files with unrecognized keys",
+				},
+				&cli.BoolFlag{
+					Name:  "validate",
+					Usage: "also check that all references resolve within the directory, and that there are no circular references (fails for partial sets of schemas which refer to others)",
+				},
+			},
 		},
 		&cli.Command{
 			Name:   "validate-record",
@@ -80,10 +90,16 @@ func runLoadDirectory(cctx *cli.Context) error {
 	}
 
 	c := lexicon.NewBaseCatalog()
+	c.RejectUnknownKeys = cctx.Bool("strict")
 	err := c.LoadDirectory(p)
 	if err != nil {
 		return err
 	}
+	if cctx.Bool("validate") {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
 
 	fmt.Println("success!")
 	return nil
@@ -0,0 +1,26 @@
+package lexicon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Computes a stable fingerprint of the schema definition, as a hex-encoded SHA-256 hash.
+//
+// The hash is over a canonical JSON serialization of the schema ID and parsed definition tree: object keys are in a fixed order, and there is no insignificant whitespace, so the fingerprint does not depend on the formatting or key order of the source Lexicon file. Unknown keys (which are ignored during parsing) do not contribute. The order of array elements (eg, 'required', or union 'refs') is significant.
+//
+// Returns an empty string if the definition can not be serialized, which does not happen for schemas loaded from Lexicon JSON.
+//
+// Intended for cache keys and change detection. The exact serialization may change between versions of this package, so fingerprints should not be persisted long-term or compared across implementations.
+func (s *Schema) Fingerprint() string {
+	b, err := json.Marshal(struct {
+		ID  string `json:"id"`
+		Def any    `json:"def"`
+	}{ID: s.ID, Def: s.Def})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,339 @@
+package lexicon
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// JSON Schema dialect used for all exported schemas
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Translates this schema definition to a JSON Schema (draft 2020-12) document, describing the atproto JSON representation of matching data.
+//
+// References to other definitions (including unions) are emitted as '$ref' URIs of the form 'lex:<nsid>#<name>'. These can be resolved against the bundled document returned by [BaseCatalog.ToJSONSchema]; this method does not resolve them.
+//
+// Lexicon constraints which have no JSON Schema equivalent (eg, string grapheme limits, 'knownValues', blob mimetypes, and the query/procedure/subscription types) are preserved as 'x-lexicon-*' annotation keywords, not dropped.
+func (s *Schema) ToJSONSchema() ([]byte, error) {
+	out, err := jsonSchemaDef(s.Def)
+	if err != nil {
+		return nil, fmt.Errorf("exporting %s: %w", s.ID, err)
+	}
+	out["$schema"] = jsonSchemaDialect
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// Exports all schemas in the catalog as a single bundled JSON Schema (draft 2020-12) document.
+//
+// Each Lexicon (NSID) becomes an embedded schema resource under the top-level '$defs', with '$id' 'lex:<nsid>'. Each definition within that Lexicon is under the resource's own '$defs', with an '$anchor' of the definition name. This means the 'lex:<nsid>#<name>' references emitted by [Schema.ToJSONSchema] resolve within the bundle.
+//
+// Returns an error if any reference does not resolve to a schema in the catalog.
+func (c *BaseCatalog) ToJSONSchema() ([]byte, error) {
+	lexicons := map[string]map[string]any{}
+	for _, ref := range c.Refs() {
+		s := c.schemas[ref]
+		for _, target := range schemaRefs(s.Def) {
+			if _, err := c.Resolve(target); err != nil {
+				return nil, fmt.Errorf("exporting %s: %w", ref, err)
+			}
+		}
+		def, err := jsonSchemaDef(s.Def)
+		if err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", ref, err)
+		}
+		nsid, name, _ := strings.Cut(ref, "#")
+		def["$anchor"] = name
+		if lexicons[nsid] == nil {
+			lexicons[nsid] = map[string]any{}
+		}
+		lexicons[nsid][name] = def
+	}
+	defs := map[string]any{}
+	for nsid, lexDefs := range lexicons {
+		defs[nsid] = map[string]any{
+			"$id":   "lex:" + nsid,
+			"$defs": lexDefs,
+		}
+	}
+	return json.MarshalIndent(map[string]any{
+		"$schema": jsonSchemaDialect,
+		"$defs":   defs,
+	}, "", "  ")
+}
+
+// Returns all the fully-qualified references (from refs and unions) in a schema definition, recursively.
+func schemaRefs(def any) []string {
+	var out []string
+	var walk func(def any)
+	walkProps := func(props map[string]SchemaDef) {
+		for _, p := range props {
+			walk(p.Inner)
+		}
+	}
+	walkBody := func(b *SchemaBody) {
+		if b != nil && b.Schema != nil {
+			walk(b.Schema.Inner)
+		}
+	}
+	walk = func(def any) {
+		switch v := def.(type) {
+		case SchemaRecord:
+			walkProps(v.Record.Properties)
+		case SchemaQuery:
+			walkProps(v.Parameters.Properties)
+			walkBody(v.Output)
+		case SchemaProcedure:
+			walkProps(v.Parameters.Properties)
+			walkBody(v.Input)
+			walkBody(v.Output)
+		case SchemaSubscription:
+			walkProps(v.Parameters.Properties)
+			if v.Message != nil {
+				walk(v.Message.Schema.Inner)
+			}
+		case SchemaArray:
+			walk(v.Items.Inner)
+		case SchemaObject:
+			walkProps(v.Properties)
+		case SchemaParams:
+			walkProps(v.Properties)
+		case SchemaRef:
+			out = append(out, v.fullRef)
+		case SchemaUnion:
+			out = append(out, v.fullRefs...)
+		}
+	}
+	walk(def)
+	return out
+}
+
+// JSON Schema reference for a fully-qualified lexicon reference
+func jsonSchemaRef(fullRef string) map[string]any {
+	if !strings.Contains(fullRef, "#") {
+		fullRef = fullRef + "#main"
+	}
+	return map[string]any{"$ref": "lex:" + fullRef}
+}
+
+// the '$type' value which identifies data of the referenced type, eg in unions
+func lexiconTypeName(fullRef string) string {
+	return strings.TrimSuffix(fullRef, "#main")
+}
+
+// sets an output key only if the (pointer) value is non-nil
+func setOptional[T any](out map[string]any, key string, val *T) {
+	if val != nil {
+		out[key] = *val
+	}
+}
+
+func jsonSchemaObject(s SchemaObject) (map[string]any, error) {
+	props := map[string]any{}
+	for k, def := range s.Properties {
+		p, err := jsonSchemaDef(def.Inner)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", k, err)
+		}
+		if s.IsNullable(k) {
+			p = map[string]any{"anyOf": []any{p, map[string]any{"type": "null"}}}
+		}
+		props[k] = p
+	}
+	out := map[string]any{
+		"type":       "object",
+		"properties": props,
+	}
+	setOptional(out, "description", s.Description)
+	if len(s.Required) > 0 {
+		out["required"] = s.Required
+	}
+	return out, nil
+}
+
+// the JSON representation of a CID link (in the atproto data model)
+func jsonSchemaCIDLink() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"$link": map[string]any{"type": "string"},
+		},
+		"required":             []string{"$link"},
+		"additionalProperties": false,
+	}
+}
+
+// API endpoints don't describe a single data value, so there is no JSON Schema equivalent. They are represented by a schema with only annotations.
+func jsonSchemaEndpoint(lexType string, description *string) map[string]any {
+	out := map[string]any{
+		"x-lexicon-type": lexType,
+		"$comment":       fmt.Sprintf("lexicon type '%s' is an API endpoint, and has no JSON Schema equivalent", lexType),
+	}
+	setOptional(out, "description", description)
+	return out
+}
+
+// Translates a single lexicon definition (one of the Schema* types) to a JSON Schema object
+func jsonSchemaDef(def any) (map[string]any, error) {
+	out := map[string]any{}
+	switch v := def.(type) {
+	case SchemaRecord:
+		rec, err := jsonSchemaObject(v.Record)
+		if err != nil {
+			return nil, err
+		}
+		out = rec
+		setOptional(out, "description", v.Description)
+		out["x-lexicon-type"] = "record"
+		out["x-lexicon-key"] = v.Key
+	case SchemaQuery:
+		return jsonSchemaEndpoint("query", v.Description), nil
+	case SchemaProcedure:
+		return jsonSchemaEndpoint("procedure", v.Description), nil
+	case SchemaSubscription:
+		return jsonSchemaEndpoint("subscription", v.Description), nil
+	case SchemaNull:
+		out["type"] = "null"
+		setOptional(out, "description", v.Description)
+	case SchemaBoolean:
+		out["type"] = "boolean"
+		setOptional(out, "description", v.Description)
+		setOptional(out, "default", v.Default)
+		setOptional(out, "const", v.Const)
+	case SchemaInteger:
+		out["type"] = "integer"
+		setOptional(out, "description", v.Description)
+		setOptional(out, "minimum", v.Minimum)
+		setOptional(out, "maximum", v.Maximum)
+		setOptional(out, "default", v.Default)
+		setOptional(out, "const", v.Const)
+		if len(v.Enum) > 0 {
+			out["enum"] = v.Enum
+		}
+	case SchemaString:
+		out["type"] = "string"
+		setOptional(out, "description", v.Description)
+		setOptional(out, "default", v.Default)
+		setOptional(out, "const", v.Const)
+		if len(v.Enum) > 0 {
+			out["enum"] = v.Enum
+		}
+		if v.Format != nil {
+			switch *v.Format {
+			case "datetime":
+				out["format"] = "date-time"
+			case "uri":
+				out["format"] = "uri"
+			default:
+				// no JSON Schema equivalent; keep the lexicon format name, which validators treat as an annotation
+				out["format"] = *v.Format
+			}
+		}
+		// lexicon string lengths are in UTF-8 bytes, while JSON Schema counts code points. a byte maximum is always a valid (looser) code point maximum, but the minimum is not.
+		setOptional(out, "maxLength", v.MaxLength)
+		setOptional(out, "x-lexicon-minLength", v.MinLength)
+		setOptional(out, "x-lexicon-maxLength", v.MaxLength)
+		setOptional(out, "x-lexicon-minGraphemes", v.MinGraphemes)
+		setOptional(out, "x-lexicon-maxGraphemes", v.MaxGraphemes)
+		if len(v.KnownValues) > 0 {
+			out["x-lexicon-knownValues"] = v.KnownValues
+		}
+	case SchemaBytes:
+		out["type"] = "object"
+		out["properties"] = map[string]any{
+			"$bytes": map[string]any{
+				"type":            "string",
+				"contentEncoding": "base64",
+			},
+		}
+		out["required"] = []string{"$bytes"}
+		out["additionalProperties"] = false
+		setOptional(out, "description", v.Description)
+		setOptional(out, "x-lexicon-minLength", v.MinLength)
+		setOptional(out, "x-lexicon-maxLength", v.MaxLength)
+	case SchemaCIDLink:
+		out = jsonSchemaCIDLink()
+		setOptional(out, "description", v.Description)
+	case SchemaArray:
+		items, err := jsonSchemaDef(v.Items.Inner)
+		if err != nil {
+			return nil, fmt.Errorf("array items: %w", err)
+		}
+		out["type"] = "array"
+		out["items"] = items
+		setOptional(out, "description", v.Description)
+		setOptional(out, "minItems", v.MinLength)
+		setOptional(out, "maxItems", v.MaxLength)
+	case SchemaObject:
+		return jsonSchemaObject(v)
+	case SchemaBlob:
+		size := map[string]any{"type": "integer", "minimum": 0}
+		setOptional(size, "maximum", v.MaxSize)
+		out["type"] = "object"
+		out["properties"] = map[string]any{
+			"$type":    map[string]any{"const": "blob"},
+			"ref":      jsonSchemaCIDLink(),
+			"mimeType": map[string]any{"type": "string"},
+			"size":     size,
+		}
+		out["required"] = []string{"$type", "ref", "mimeType", "size"}
+		setOptional(out, "description", v.Description)
+		if len(v.Accept) > 0 {
+			out["x-lexicon-accept"] = v.Accept
+		}
+	case SchemaParams:
+		return jsonSchemaObject(SchemaObject{
+			Description: v.Description,
+			Properties:  v.Properties,
+			Required:    v.Required,
+		})
+	case SchemaToken:
+		out["type"] = "string"
+		out["const"] = v.fullName
+		out["x-lexicon-type"] = "token"
+		setOptional(out, "description", v.Description)
+	case SchemaRef:
+		out = jsonSchemaRef(v.fullRef)
+		setOptional(out, "description", v.Description)
+	case SchemaUnion:
+		// union data is discriminated by '$type'
+		variants := []any{}
+		refs := append([]string{}, v.fullRefs...)
+		sort.Strings(refs)
+		for _, ref := range refs {
+			variant := jsonSchemaRef(ref)
+			variant["properties"] = map[string]any{
+				"$type": map[string]any{"const": lexiconTypeName(ref)},
+			}
+			variant["required"] = []string{"$type"}
+			variants = append(variants, variant)
+		}
+		if v.Closed == nil || !*v.Closed {
+			// open unions also allow objects of any other type
+			variants = append(variants, map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"$type": map[string]any{"not": map[string]any{"enum": typeNames(refs)}},
+				},
+				"required": []string{"$type"},
+			})
+		}
+		out["oneOf"] = variants
+		setOptional(out, "description", v.Description)
+	case SchemaUnknown:
+		out["type"] = "object"
+		setOptional(out, "description", v.Description)
+	default:
+		return nil, fmt.Errorf("unhandled schema type: %v", reflect.TypeOf(v))
+	}
+	return out, nil
+}
+
+func typeNames(refs []string) []string {
+	out := make([]string, len(refs))
+	for i, ref := range refs {
+		out[i] = lexiconTypeName(ref)
+	}
+	return out
+}
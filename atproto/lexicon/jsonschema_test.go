@@ -0,0 +1,84 @@
+package lexicon
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "overwrite golden files with test output")
+
+func TestRecordJSONSchemaGolden(t *testing.T) {
+	assert := assert.New(t)
+
+	cat := NewBaseCatalog()
+	if err := cat.LoadDirectory("testdata/catalog"); err != nil {
+		t.Fatal(err)
+	}
+	s, err := cat.Resolve("example.lexicon.record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := s.ToJSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goldenPath := "testdata/jsonschema/example.lexicon.record.json"
+	if *updateGolden {
+		if err := os.WriteFile(goldenPath, append(out, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(string(golden), string(out))
+}
+
+func TestCatalogJSONSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	cat := NewBaseCatalog()
+	if err := cat.LoadDirectory("testdata/catalog"); err != nil {
+		t.Fatal(err)
+	}
+	out, err := cat.ToJSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc map[string]any
+	assert.NoError(json.Unmarshal(out, &doc))
+	defs := doc["$defs"].(map[string]any)
+	lex := defs["example.lexicon.record"].(map[string]any)
+	assert.Equal("lex:example.lexicon.record", lex["$id"])
+	token := lex["$defs"].(map[string]any)["demoToken"].(map[string]any)
+	assert.Equal("demoToken", token["$anchor"])
+	assert.Equal("example.lexicon.record#demoToken", token["const"])
+
+	// API endpoints are represented explicitly, not dropped
+	query := defs["example.lexicon.query"].(map[string]any)["$defs"].(map[string]any)["main"].(map[string]any)
+	assert.Equal("query", query["x-lexicon-type"])
+
+	// references which don't resolve within the catalog are an error
+	bad := NewBaseCatalog()
+	assert.NoError(bad.AddSchemaFile(SchemaFile{
+		Lexicon: 1,
+		ID:      "example.lexicon.dangling",
+		Defs: map[string]SchemaDef{
+			"main": {Inner: SchemaObject{
+				Type: "object",
+				Properties: map[string]SchemaDef{
+					"other": {Inner: SchemaRef{Type: "ref", Ref: "example.lexicon.missing"}},
+				},
+			}},
+		},
+	}))
+	_, err = bad.ToJSONSchema()
+	assert.Error(err)
+}
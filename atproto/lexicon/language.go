@@ -13,6 +13,8 @@ import (
 )
 
 // Serialization helper type for top-level Lexicon schema JSON objects (files)
+//
+// Keys in the JSON which are not recognized (by this struct, or the Schema* type for each definition) are ignored when parsing, and are not retained. Use [UnknownSchemaKeys] or [BaseCatalog.RejectUnknownKeys] for strict checking.
 type SchemaFile struct {
 	Lexicon     int                  `json:"lexicon,const=1"`
 	ID          string               `json:"id"`
@@ -305,6 +307,33 @@ func (s *SchemaRecord) CheckSchema() error {
 	return s.Record.CheckSchema()
 }
 
+// Checks a record key against this schema's 'key' constraint: 'tid' requires a TID, 'nsid' requires an NSID, 'literal:<value>' requires exactly that value (eg, 'literal:self'), and 'any' allows any valid record key.
+func (s *SchemaRecord) ValidateKey(rkey syntax.RecordKey) error {
+	switch s.Key {
+	case "any":
+		return nil
+	case "tid":
+		if _, err := syntax.ParseTID(rkey.String()); err != nil {
+			return fmt.Errorf("record key must be a TID: %s", rkey)
+		}
+		return nil
+	case "nsid":
+		if _, err := syntax.ParseNSID(rkey.String()); err != nil {
+			return fmt.Errorf("record key must be an NSID: %s", rkey)
+		}
+		return nil
+	default:
+		literal, ok := strings.CutPrefix(s.Key, "literal:")
+		if !ok {
+			return fmt.Errorf("invalid record key specifier: %s", s.Key)
+		}
+		if rkey.String() != literal {
+			return fmt.Errorf("record key must be %q: %s", literal, rkey)
+		}
+		return nil
+	}
+}
+
 type SchemaQuery struct {
 	Type        string        `json:"type,const=query"`
 	Description *string       `json:"description,omitempty"`
@@ -495,17 +524,21 @@ func (s *SchemaInteger) Validate(d any) error {
 	if !ok {
 		return fmt.Errorf("expected an integer")
 	}
-	v := int(v64)
-	if s.Const != nil && v != *s.Const {
+	// compare as int64, so large values are not truncated on 32-bit platforms
+	v := v64
+	if s.Const != nil && v != int64(*s.Const) {
 		return fmt.Errorf("integer val didn't match constant (%d): %d", *s.Const, v)
 	}
-	if (s.Minimum != nil && v < *s.Minimum) || (s.Maximum != nil && v > *s.Maximum) {
-		return fmt.Errorf("integer val outside specified range: %d", v)
+	if s.Minimum != nil && v < int64(*s.Minimum) {
+		return fmt.Errorf("integer val below minimum (%d): %d", *s.Minimum, v)
+	}
+	if s.Maximum != nil && v > int64(*s.Maximum) {
+		return fmt.Errorf("integer val above maximum (%d): %d", *s.Maximum, v)
 	}
 	if len(s.Enum) != 0 {
 		inEnum := false
 		for _, e := range s.Enum {
-			if e == v {
+			if int64(e) == v {
 				inEnum = true
 				break
 			}
@@ -567,9 +600,12 @@ func (s *SchemaString) Validate(d any, flags ValidateFlags) error {
 	if s.Const != nil && v != *s.Const {
 		return fmt.Errorf("string val didn't match constant (%s): %s", *s.Const, v)
 	}
-	// TODO: is this actually counting UTF-8 length?
-	if (s.MinLength != nil && len(v) < *s.MinLength) || (s.MaxLength != nil && len(v) > *s.MaxLength) {
-		return fmt.Errorf("string length outside specified range: %d", len(v))
+	// minLength and maxLength count UTF-8 bytes (which is what len() returns for a Go string), not characters
+	if s.MinLength != nil && len(v) < *s.MinLength {
+		return fmt.Errorf("string length (UTF-8 bytes) below minLength (%d): %d", *s.MinLength, len(v))
+	}
+	if s.MaxLength != nil && len(v) > *s.MaxLength {
+		return fmt.Errorf("string length (UTF-8 bytes) above maxLength (%d): %d", *s.MaxLength, len(v))
 	}
 	if len(s.Enum) != 0 {
 		inEnum := false
@@ -584,68 +620,57 @@ func (s *SchemaString) Validate(d any, flags ValidateFlags) error {
 		}
 	}
 	if s.MinGraphemes != nil || s.MaxGraphemes != nil {
+		// extended grapheme clusters (Unicode UAX #29): an emoji with modifiers or ZWJ sequences, or a letter with combining marks, counts as one
 		lenG := uniseg.GraphemeClusterCount(v)
-		if (s.MinGraphemes != nil && lenG < *s.MinGraphemes) || (s.MaxGraphemes != nil && lenG > *s.MaxGraphemes) {
-			return fmt.Errorf("string length (graphemes) outside specified range: %d", lenG)
+		if s.MinGraphemes != nil && lenG < *s.MinGraphemes {
+			return fmt.Errorf("string length (graphemes) below minGraphemes (%d): %d", *s.MinGraphemes, lenG)
+		}
+		if s.MaxGraphemes != nil && lenG > *s.MaxGraphemes {
+			return fmt.Errorf("string length (graphemes) above maxGraphemes (%d): %d", *s.MaxGraphemes, lenG)
 		}
 	}
 	if s.Format != nil {
-		switch *s.Format {
-		case "at-identifier":
-			if _, err := syntax.ParseAtIdentifier(v); err != nil {
-				return err
-			}
-		case "at-uri":
-			if _, err := syntax.ParseATURI(v); err != nil {
-				return err
-			}
-		case "cid":
-			if _, err := syntax.ParseCID(v); err != nil {
-				return err
-			}
-		case "datetime":
-			if flags&AllowLenientDatetime != 0 {
-				if _, err := syntax.ParseDatetimeLenient(v); err != nil {
-					return err
-				}
-			} else {
-				if _, err := syntax.ParseDatetime(v); err != nil {
-					return err
-				}
-			}
-		case "did":
-			if _, err := syntax.ParseDID(v); err != nil {
-				return err
-			}
-		case "handle":
-			if _, err := syntax.ParseHandle(v); err != nil {
-				return err
-			}
-		case "nsid":
-			if _, err := syntax.ParseNSID(v); err != nil {
-				return err
-			}
-		case "uri":
-			if _, err := syntax.ParseURI(v); err != nil {
-				return err
-			}
-		case "language":
-			if _, err := syntax.ParseLanguage(v); err != nil {
-				return err
-			}
-		case "tid":
-			if _, err := syntax.ParseTID(v); err != nil {
-				return err
-			}
-		case "record-key":
-			if _, err := syntax.ParseRecordKey(v); err != nil {
-				return err
-			}
+		if err := validateStringFormat(*s.Format, v, flags); err != nil {
+			return fmt.Errorf("invalid %s string: %w", *s.Format, err)
 		}
 	}
 	return nil
 }
 
+// checks string syntax against a Lexicon string format, using the corresponding parser from the syntax package. Unknown formats are rejected by CheckSchema(), and pass here.
+func validateStringFormat(format, v string, flags ValidateFlags) error {
+	var err error
+	switch format {
+	case "at-identifier":
+		_, err = syntax.ParseAtIdentifier(v)
+	case "at-uri":
+		_, err = syntax.ParseATURI(v)
+	case "cid":
+		_, err = syntax.ParseCID(v)
+	case "datetime":
+		if flags&AllowLenientDatetime != 0 {
+			_, err = syntax.ParseDatetimeLenient(v)
+		} else {
+			_, err = syntax.ParseDatetime(v)
+		}
+	case "did":
+		_, err = syntax.ParseDID(v)
+	case "handle":
+		_, err = syntax.ParseHandle(v)
+	case "nsid":
+		_, err = syntax.ParseNSID(v)
+	case "uri":
+		_, err = syntax.ParseURI(v)
+	case "language":
+		_, err = syntax.ParseLanguage(v)
+	case "tid":
+		_, err = syntax.ParseTID(v)
+	case "record-key":
+		_, err = syntax.ParseRecordKey(v)
+	}
+	return err
+}
+
 type SchemaBytes struct {
 	Type        string  `json:"type,const=bytes"`
 	Description *string `json:"description,omitempty"`
@@ -45,3 +45,58 @@ func TestBasicLabelLexicon(t *testing.T) {
 
 	assert.Equal(beforeMap, afterMap)
 }
+
+func TestValueConstraints(t *testing.T) {
+	assert := assert.New(t)
+	ptr := func(v int) *int { return &v }
+	sptr := func(v string) *string { return &v }
+
+	// integers
+	si := SchemaInteger{Minimum: ptr(1), Maximum: ptr(10)}
+	assert.NoError(si.Validate(int64(1)))
+	assert.NoError(si.Validate(int64(10)))
+	assert.ErrorContains(si.Validate(int64(0)), "minimum")
+	assert.ErrorContains(si.Validate(int64(11)), "maximum")
+	assert.ErrorContains(si.Validate(int64(1)<<40), "maximum")
+	si = SchemaInteger{Enum: []int{2, 4}}
+	assert.NoError(si.Validate(int64(4)))
+	assert.ErrorContains(si.Validate(int64(3)), "enum")
+	si = SchemaInteger{Const: ptr(42)}
+	assert.NoError(si.Validate(int64(42)))
+	assert.ErrorContains(si.Validate(int64(43)), "constant")
+
+	// string lengths are UTF-8 bytes
+	ss := SchemaString{MinLength: ptr(2), MaxLength: ptr(4)}
+	assert.NoError(ss.Validate("ab", 0))
+	assert.NoError(ss.Validate("é", 0)) // precomposed, 2 bytes
+	assert.ErrorContains(ss.Validate("a", 0), "minLength")
+	assert.ErrorContains(ss.Validate("🇩🇪", 0), "maxLength") // 8 bytes
+	ss = SchemaString{Enum: []string{"fish", "bird"}, Const: sptr("fish")}
+	assert.NoError(ss.Validate("fish", 0))
+	assert.ErrorContains(ss.Validate("bird", 0), "constant")
+	ss = SchemaString{Enum: []string{"fish", "bird"}}
+	assert.ErrorContains(ss.Validate("cat", 0), "enum")
+
+	// graphemes are extended grapheme clusters
+	ss = SchemaString{MinGraphemes: ptr(1), MaxGraphemes: ptr(1)}
+	for _, v := range []string{
+		"a",
+		"e\u0301",            // combining acute accent
+		"\u1112\u1161\u11ab", // decomposed Hangul syllable
+		"🇩🇪",                 // regional indicator flag
+		"👍🏽",                 // skin tone modifier
+		"👨‍👩‍👧‍👦",            // ZWJ family sequence
+		"🏳️‍🌈",               // variation selector and ZWJ
+	} {
+		assert.NoError(ss.Validate(v, 0), v)
+	}
+	assert.ErrorContains(ss.Validate("", 0), "minGraphemes")
+	assert.ErrorContains(ss.Validate("ab", 0), "maxGraphemes")
+	assert.ErrorContains(ss.Validate("🇩🇪🇩🇪", 0), "maxGraphemes")
+
+	// arrays
+	sa := SchemaArray{Items: SchemaDef{Inner: SchemaInteger{}}, MinLength: ptr(1), MaxLength: ptr(2)}
+	assert.NoError(validateArray(nil, sa, []any{int64(1)}, 0))
+	assert.ErrorContains(validateArray(nil, sa, []any{}, 0), "minLength")
+	assert.ErrorContains(validateArray(nil, sa, []any{int64(1), int64(2), int64(3)}, 0), "maxLength")
+}
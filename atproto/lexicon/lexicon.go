@@ -3,6 +3,11 @@ package lexicon
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
 )
 
 // Boolean flags tweaking how Lexicon validation rules are interpreted.
@@ -20,6 +25,46 @@ const (
 // Combination of argument flags for less formal validation. Recommended for, eg, working with old/legacy data from 2023.
 var LenientMode ValidateFlags = AllowLegacyBlob | AllowLenientDatetime
 
+// Error returned by record validation when a problem was found nested inside the record data.
+//
+// 'Path' is a JSON-style path to the offending field, relative to the top of the record, like 'embed.images[2].alt'. Errors at the top level of the record are not wrapped.
+type ValidationError struct {
+	Path string
+	Err  error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("field %s: %s", e.Path, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// prefixes the path of a nested validation error with an object field name
+func wrapFieldError(field string, err error) error {
+	ve, ok := err.(*ValidationError)
+	if !ok {
+		return &ValidationError{Path: field, Err: err}
+	}
+	if strings.HasPrefix(ve.Path, "[") {
+		return &ValidationError{Path: field + ve.Path, Err: ve.Err}
+	}
+	return &ValidationError{Path: field + "." + ve.Path, Err: ve.Err}
+}
+
+// prefixes the path of a nested validation error with an array index
+func wrapIndexError(idx int, err error) error {
+	ve, ok := err.(*ValidationError)
+	if !ok {
+		return &ValidationError{Path: fmt.Sprintf("[%d]", idx), Err: err}
+	}
+	if strings.HasPrefix(ve.Path, "[") {
+		return &ValidationError{Path: fmt.Sprintf("[%d]%s", idx, ve.Path), Err: ve.Err}
+	}
+	return &ValidationError{Path: fmt.Sprintf("[%d].%s", idx, ve.Path), Err: ve.Err}
+}
+
 // Represents a Lexicon schema definition
 type Schema struct {
 	ID  string
@@ -31,11 +76,43 @@ type Schema struct {
 // 'recordData' is typed as 'any', but is expected to be 'map[string]any'
 // 'ref' is a reference to the schema type, as an NSID with optional fragment. For records, the '$type' must match 'ref'
 // 'flags' are parameters tweaking Lexicon validation rules. Zero value is default.
+//
+// Unions are validated according to the schema's 'closed' field. Closed unions reject any '$type' not listed in the schema. Open unions (the default if 'closed' is not set) accept unlisted types for forwards-compatibility: the data is validated if the type can be resolved from the catalog, and otherwise passes (unless the [StrictRecursiveValidation] flag is set).
+//
+// Problems found in nested fields are returned as a [*ValidationError], which includes the path to the field.
+//
+// If the catalog implements [MetricsCatalog] (eg, [BaseCatalog] with ValidationMetrics set), the outcome and time spent are reported to its metrics sink.
 func ValidateRecord(cat Catalog, recordData any, ref string, flags ValidateFlags) error {
-	return validateRecordConfig(cat, recordData, ref, flags)
+	return validateRecordObserved(cat, recordData, ref, flags)
 }
 
-func validateRecordConfig(cat Catalog, recordData any, ref string, flags ValidateFlags) error {
+// Same as [ValidateRecord], but returns every problem found in the record, instead of stopping at the first one. Intended for tools which show all errors to a Lexicon or record author at once; [ValidateRecord] is faster for invalid data, and should be used when only the pass/fail result is needed.
+//
+// Validation continues past an error in one field or array element to check the others, but does not descend in to data which failed a structural check (for example, an object field with the wrong type, or a union value without '$type'). Nested errors are [*ValidationError] with the path to the field; errors are sorted by path, with top-level errors first. Returns nil if the record is valid.
+func ValidateRecordAll(cat Catalog, recordData any, ref string, flags ValidateFlags) []error {
+	errs := collectRecord(cat, recordData, ref, flags, true)
+	sort.SliceStable(errs, func(i, j int) bool {
+		return validationErrorPath(errs[i]) < validationErrorPath(errs[j])
+	})
+	return errs
+}
+
+func validationErrorPath(err error) string {
+	if ve, ok := err.(*ValidationError); ok {
+		return ve.Path
+	}
+	return ""
+}
+
+// Same as [ValidateRecord], but takes the record's full repo path ('<collection>/<rkey>'), and also checks the record key against the schema's 'key' constraint (see [SchemaRecord.ValidateKey]).
+//
+// The collection NSID is used as the schema reference. Record key errors are returned before any record data validation.
+func ValidateRecordPath(cat Catalog, recordData any, path string, flags ValidateFlags) error {
+	collection, rkey, err := syntax.ParseRepoPath(path)
+	if err != nil {
+		return err
+	}
+	ref := collection.String()
 	def, err := cat.Resolve(ref)
 	if err != nil {
 		return err
@@ -44,67 +121,125 @@ func validateRecordConfig(cat Catalog, recordData any, ref string, flags Validat
 	if !ok {
 		return fmt.Errorf("schema is not of record type: %s", ref)
 	}
+	if err := s.ValidateKey(rkey); err != nil {
+		return err
+	}
+	return validateRecordObserved(cat, recordData, ref, flags)
+}
+
+// validates a record, reporting the outcome and time spent to the catalog's metrics sink, if it has one (see [MetricsCatalog])
+func validateRecordObserved(cat Catalog, recordData any, ref string, flags ValidateFlags) error {
+	mc, ok := cat.(MetricsCatalog)
+	if !ok || mc.Metrics() == nil {
+		return validateRecord(cat, recordData, ref, flags)
+	}
+	start := time.Now()
+	err := validateRecord(cat, recordData, ref, flags)
+	mc.Metrics().ObserveValidation(ref, time.Since(start), err)
+	return err
+}
+
+func validateRecord(cat Catalog, recordData any, ref string, flags ValidateFlags) error {
+	return firstError(collectRecord(cat, recordData, ref, flags, false))
+}
+
+func validateData(cat Catalog, def any, d any, flags ValidateFlags) error {
+	return firstError(collectData(cat, def, d, flags, false))
+}
+
+func validateArray(cat Catalog, s SchemaArray, arr []any, flags ValidateFlags) error {
+	return firstError(collectArray(cat, s, arr, flags, false))
+}
+
+func firstError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[0]
+}
+
+func errorList(err error) []error {
+	if err == nil {
+		return nil
+	}
+	return []error{err}
+}
+
+// The collect* functions return the validation errors found in data, with nested errors wrapped in [*ValidationError]. If 'all' is false, they stop at the first error (so at most one is returned). Otherwise they continue past errors in one field or array element to check the others, but do not descend in to data which failed a structural check (eg, wrong type, or a union without $type).
+func collectRecord(cat Catalog, recordData any, ref string, flags ValidateFlags, all bool) []error {
+	def, err := cat.Resolve(ref)
+	if err != nil {
+		return errorList(err)
+	}
+	s, ok := def.Def.(SchemaRecord)
+	if !ok {
+		return errorList(fmt.Errorf("schema is not of record type: %s", ref))
+	}
 	d, ok := recordData.(map[string]any)
 	if !ok {
-		return fmt.Errorf("record data is not object type")
+		return errorList(fmt.Errorf("record data is not object type"))
 	}
 	t, ok := d["$type"]
 	if !ok || t != ref {
-		return fmt.Errorf("record data missing $type, or didn't match expected NSID")
+		return errorList(fmt.Errorf("record data missing $type, or didn't match expected NSID"))
 	}
-	return validateObject(cat, s.Record, d, flags)
+	return collectObject(cat, s.Record, d, flags, all)
 }
 
-func validateData(cat Catalog, def any, d any, flags ValidateFlags) error {
+func collectData(cat Catalog, def any, d any, flags ValidateFlags, all bool) []error {
 	switch v := def.(type) {
 	case SchemaNull:
-		return v.Validate(d)
+		return errorList(v.Validate(d))
 	case SchemaBoolean:
-		return v.Validate(d)
+		return errorList(v.Validate(d))
 	case SchemaInteger:
-		return v.Validate(d)
+		return errorList(v.Validate(d))
 	case SchemaString:
-		return v.Validate(d, flags)
+		return errorList(v.Validate(d, flags))
 	case SchemaBytes:
-		return v.Validate(d)
+		return errorList(v.Validate(d))
 	case SchemaCIDLink:
-		return v.Validate(d)
+		return errorList(v.Validate(d))
 	case SchemaArray:
 		arr, ok := d.([]any)
 		if !ok {
-			return fmt.Errorf("expected an array, got: %s", reflect.TypeOf(d))
+			return errorList(fmt.Errorf("expected an array, got: %s", reflect.TypeOf(d)))
 		}
-		return validateArray(cat, v, arr, flags)
+		return collectArray(cat, v, arr, flags, all)
 	case SchemaObject:
 		obj, ok := d.(map[string]any)
 		if !ok {
-			return fmt.Errorf("expected an object, got: %s", reflect.TypeOf(d))
+			return errorList(fmt.Errorf("expected an object, got: %s", reflect.TypeOf(d)))
 		}
-		return validateObject(cat, v, obj, flags)
+		return collectObject(cat, v, obj, flags, all)
 	case SchemaBlob:
-		return v.Validate(d, flags)
+		return errorList(v.Validate(d, flags))
 	case SchemaRef:
 		// recurse
-		next, err := cat.Resolve(v.fullRef)
+		next, err := resolveRefChain(cat, v.fullRef)
 		if err != nil {
-			return err
+			return errorList(err)
 		}
-		return validateData(cat, next.Def, d, flags)
+		return collectData(cat, next.Def, d, flags, all)
 	case SchemaUnion:
-		return validateUnion(cat, v, d, flags)
+		return collectUnion(cat, v, d, flags, all, nil)
 	case SchemaUnknown:
-		return v.Validate(d)
+		return errorList(v.Validate(d))
 	case SchemaToken:
-		return v.Validate(d)
+		return errorList(v.Validate(d))
 	default:
-		return fmt.Errorf("unhandled schema type: %s", reflect.TypeOf(v))
+		return errorList(fmt.Errorf("unhandled schema type: %s", reflect.TypeOf(v)))
 	}
 }
 
-func validateObject(cat Catalog, s SchemaObject, d map[string]any, flags ValidateFlags) error {
+func collectObject(cat Catalog, s SchemaObject, d map[string]any, flags ValidateFlags, all bool) []error {
+	var errs []error
 	for _, k := range s.Required {
 		if _, ok := d[k]; !ok {
-			return fmt.Errorf("required field missing: %s", k)
+			errs = append(errs, fmt.Errorf("required field missing: %s", k))
+			if !all {
+				return errs
+			}
 		}
 	}
 	for k, def := range s.Properties {
@@ -112,56 +247,74 @@ func validateObject(cat Catalog, s SchemaObject, d map[string]any, flags Validat
 			if v == nil && s.IsNullable(k) {
 				continue
 			}
-			err := validateData(cat, def.Inner, v, flags)
-			if err != nil {
-				return err
+			for _, err := range collectData(cat, def.Inner, v, flags, all) {
+				errs = append(errs, wrapFieldError(k, err))
+			}
+			if !all && len(errs) > 0 {
+				return errs
 			}
 		}
 	}
-	return nil
+	return errs
 }
 
-func validateArray(cat Catalog, s SchemaArray, arr []any, flags ValidateFlags) error {
-	if (s.MinLength != nil && len(arr) < *s.MinLength) || (s.MaxLength != nil && len(arr) > *s.MaxLength) {
-		return fmt.Errorf("array length out of bounds: %d", len(arr))
+func collectArray(cat Catalog, s SchemaArray, arr []any, flags ValidateFlags, all bool) []error {
+	var errs []error
+	if s.MinLength != nil && len(arr) < *s.MinLength {
+		errs = append(errs, fmt.Errorf("array length below minLength (%d): %d", *s.MinLength, len(arr)))
 	}
-	for _, v := range arr {
-		err := validateData(cat, s.Items.Inner, v, flags)
-		if err != nil {
-			return err
+	if s.MaxLength != nil && len(arr) > *s.MaxLength {
+		errs = append(errs, fmt.Errorf("array length above maxLength (%d): %d", *s.MaxLength, len(arr)))
+	}
+	if !all && len(errs) > 0 {
+		return errs
+	}
+	for i, v := range arr {
+		for _, err := range collectData(cat, s.Items.Inner, v, flags, all) {
+			errs = append(errs, wrapIndexError(i, err))
+		}
+		if !all && len(errs) > 0 {
+			return errs
 		}
 	}
-	return nil
+	return errs
 }
 
-func validateUnion(cat Catalog, s SchemaUnion, d any, flags ValidateFlags) error {
+// 'visited' is the union variant $types already followed for this same data value. A variant which resolves to another union is checked against the same data again, so a variant $type seen twice means the schemas loop ([ErrCircularRef]) instead of making progress.
+func collectUnion(cat Catalog, s SchemaUnion, d any, flags ValidateFlags, all bool, visited []string) []error {
 	closed := s.Closed != nil && *s.Closed == true
 
 	obj, ok := d.(map[string]any)
 	if !ok {
-		return fmt.Errorf("union data is not object type")
+		return errorList(fmt.Errorf("union data is not object type"))
 	}
 	typeVal, ok := obj["$type"]
 	if !ok {
-		return fmt.Errorf("union data must have $type")
+		return errorList(fmt.Errorf("union data must have $type"))
 	}
 	t, ok := typeVal.(string)
 	if !ok {
-		return fmt.Errorf("union data must have string $type")
+		return errorList(fmt.Errorf("union data must have string $type"))
 	}
+	for _, prev := range visited {
+		if prev == t {
+			return errorList(fmt.Errorf("%w: union variant %s resolves back to a union containing itself", ErrCircularRef, t))
+		}
+	}
+	visited = append(visited, t)
 
 	for _, ref := range s.fullRefs {
 		if ref != t {
 			continue
 		}
-		def, err := cat.Resolve(ref)
+		def, err := resolveRefChain(cat, ref)
 		if err != nil {
-			return fmt.Errorf("could not resolve known union variant $type: %s", ref)
+			return errorList(fmt.Errorf("could not resolve known union variant $type %s: %w", ref, err))
 		}
-		return validateData(cat, def.Def, d, flags)
+		return collectVariant(cat, def.Def, d, flags, all, visited)
 	}
 	if closed {
-		return fmt.Errorf("data did not match any variant of closed union: %s", t)
+		return errorList(fmt.Errorf("data did not match any variant of closed union: %s", t))
 	}
 
 	// eagerly attempt validation of the open union type
@@ -169,10 +322,49 @@ func validateUnion(cat Catalog, s SchemaUnion, d any, flags ValidateFlags) error
 	def, err := cat.Resolve(t)
 	if err != nil {
 		if flags&StrictRecursiveValidation != 0 {
-			return fmt.Errorf("could not strictly validate open union variant $type: %s", t)
+			return errorList(fmt.Errorf("could not strictly validate open union variant $type: %s", t))
 		}
 		// by default, ignore validation of unknown open union data
 		return nil
 	}
-	return validateData(cat, def.Def, d, flags)
+	if _, ok := def.Def.(SchemaRef); ok {
+		def, err = resolveRefChain(cat, t)
+		if err != nil {
+			return errorList(err)
+		}
+	}
+	return collectVariant(cat, def.Def, d, flags, all, visited)
+}
+
+// Validates data against a resolved union variant definition, passing along the union variants already visited if the variant is itself a union
+func collectVariant(cat Catalog, def any, d any, flags ValidateFlags, all bool, visited []string) []error {
+	if u, ok := def.(SchemaUnion); ok {
+		return collectUnion(cat, u, d, flags, all, visited)
+	}
+	return collectData(cat, def, d, flags, all)
+}
+
+// Resolves a reference, and follows any chain of definitions which are themselves refs, until reaching a definition of another type.
+//
+// Returns [ErrCircularRef] if the chain loops back on itself. Without this check, a catalog containing such a loop would cause validation to recurse forever.
+func resolveRefChain(cat Catalog, ref string) (*Schema, error) {
+	visited := []string{}
+	for {
+		ref = normalizeRef(ref)
+		for i, prev := range visited {
+			if prev == ref {
+				return nil, fmt.Errorf("%w: %s", ErrCircularRef, strings.Join(append(visited[i:], ref), " -> "))
+			}
+		}
+		visited = append(visited, ref)
+		s, err := cat.Resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		next, ok := s.Def.(SchemaRef)
+		if !ok {
+			return s, nil
+		}
+		ref = next.fullRef
+	}
 }
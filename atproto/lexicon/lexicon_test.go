@@ -1,8 +1,11 @@
 package lexicon
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
 
+	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -45,3 +48,321 @@ func TestBasicCatalog(t *testing.T) {
 		0,
 	))
 }
+
+func TestValidationMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	cat := NewBaseCatalog()
+	if err := cat.LoadDirectory("testdata/catalog"); err != nil {
+		t.Fatal(err)
+	}
+
+	// no sink by default
+	assert.NoError(ValidateRecord(&cat, map[string]any{"$type": "example.lexicon.record", "integer": int64(1)}, "example.lexicon.record", 0))
+
+	metrics := NewBaseValidationMetrics()
+	cat.ValidationMetrics = metrics
+	assert.NoError(ValidateRecord(&cat, map[string]any{"$type": "example.lexicon.record", "integer": int64(1)}, "example.lexicon.record", 0))
+	assert.Error(ValidateRecord(&cat, map[string]any{"integer": int64(1)}, "example.lexicon.record", 0))
+	assert.Error(ValidateRecord(&cat, map[string]any{}, "example.lexicon.notThere", 0))
+	assert.NoError(ValidateRecordPath(&cat, map[string]any{"$type": "example.lexicon.record", "integer": int64(1)}, "example.lexicon.record/demo", 0))
+
+	stats := metrics.Snapshot()
+	assert.Equal(2, len(stats))
+	assert.Equal("example.lexicon.notThere", stats[0].Ref)
+	assert.Equal(int64(1), stats[0].Count)
+	assert.Equal(int64(1), stats[0].Failures)
+	assert.Equal("example.lexicon.record", stats[1].Ref)
+	assert.Equal(int64(3), stats[1].Count)
+	assert.Equal(int64(1), stats[1].Failures)
+}
+
+func TestValidationErrorPath(t *testing.T) {
+	assert := assert.New(t)
+
+	var sf SchemaFile
+	err := json.Unmarshal([]byte(`{
+  "lexicon": 1,
+  "id": "example.lexicon.nested",
+  "defs": {
+    "main": {
+      "type": "record",
+      "key": "tid",
+      "record": {
+        "type": "object",
+        "properties": {
+          "embed": {
+            "type": "union",
+            "refs": ["#images"]
+          }
+        }
+      }
+    },
+    "images": {
+      "type": "object",
+      "required": ["images"],
+      "properties": {
+        "images": {
+          "type": "array",
+          "items": { "type": "ref", "ref": "#image" }
+        }
+      }
+    },
+    "image": {
+      "type": "object",
+      "required": ["alt"],
+      "properties": {
+        "alt": { "type": "string" },
+        "tags": {
+          "type": "array",
+          "items": {
+            "type": "array",
+            "items": { "type": "string" }
+          }
+        }
+      }
+    }
+  }
+}`), &sf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := NewBaseCatalog()
+	if err := cat.AddSchemaFile(sf); err != nil {
+		t.Fatal(err)
+	}
+
+	record := func(images ...any) map[string]any {
+		return map[string]any{
+			"$type": "example.lexicon.nested",
+			"embed": map[string]any{
+				"$type":  "example.lexicon.nested#images",
+				"images": images,
+			},
+		}
+	}
+	image := func(alt any, tags ...any) map[string]any {
+		return map[string]any{"alt": alt, "tags": tags}
+	}
+
+	assert.NoError(ValidateRecord(&cat, record(image("one"), image("two", []any{"a", "b"})), "example.lexicon.nested", 0))
+
+	testCases := []struct {
+		record map[string]any
+		path   string
+	}{
+		{record: record(image("one"), image("two"), image(int64(3))), path: "embed.images[2].alt"},
+		{record: record(image("one", []any{"a"}, []any{"b", int64(2)})), path: "embed.images[0].tags[1][1]"},
+		{record: record(image("one"), map[string]any{}), path: "embed.images[1]"},
+	}
+	for _, tc := range testCases {
+		err := ValidateRecord(&cat, tc.record, "example.lexicon.nested", 0)
+		var ve *ValidationError
+		if assert.ErrorAs(err, &ve) {
+			assert.Equal(tc.path, ve.Path)
+		}
+	}
+
+	// errors at the top level of the record don't have a path
+	err = ValidateRecord(&cat, map[string]any{}, "example.lexicon.nested", 0)
+	assert.Error(err)
+	var ve *ValidationError
+	assert.False(errors.As(err, &ve))
+
+	// ValidateRecordAll reports every error, sorted by path
+	assert.Nil(ValidateRecordAll(&cat, record(image("one"), image("two", []any{"a", "b"})), "example.lexicon.nested", 0))
+	errs := ValidateRecordAll(&cat, record(
+		image(int64(1), []any{"a", int64(2)}, "not-an-array"),
+		image("two"),
+		map[string]any{},
+		image("four", []any{int64(3)}),
+	), "example.lexicon.nested", 0)
+	paths := []string{}
+	for _, err := range errs {
+		if assert.ErrorAs(err, &ve) {
+			paths = append(paths, ve.Path)
+		}
+	}
+	assert.Equal([]string{
+		"embed.images[0].alt",
+		"embed.images[0].tags[0][1]",
+		"embed.images[0].tags[1]",
+		"embed.images[2]",
+		"embed.images[3].tags[0][0]",
+	}, paths)
+	assert.Equal(1, len(ValidateRecordAll(&cat, map[string]any{}, "example.lexicon.nested", 0)))
+}
+
+func TestStringFormats(t *testing.T) {
+	assert := assert.New(t)
+
+	cat := NewBaseCatalog()
+	if err := cat.LoadDirectory("testdata/catalog"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := func(field, val string) map[string]any {
+		return map[string]any{
+			"$type":   "example.lexicon.record",
+			"integer": int64(1),
+			"formats": map[string]any{field: val},
+		}
+	}
+
+	testCases := []struct {
+		field   string
+		format  string
+		valid   string
+		invalid string
+	}{
+		{field: "did", format: "did", valid: "did:plc:abc123", invalid: "did:PLC:abc123"},
+		{field: "handle", format: "handle", valid: "handle.example.com", invalid: "handle"},
+		{field: "atidentifier", format: "at-identifier", valid: "did:web:example.com", invalid: "@handle.example.com"},
+		{field: "aturi", format: "at-uri", valid: "at://did:plc:abc123/app.bsky.feed.post/3kznmn7xqxl22", invalid: "https://example.com/post"},
+		{field: "nsid", format: "nsid", valid: "app.bsky.feed.post", invalid: "app.bsky"},
+		{field: "cid", format: "cid", valid: "bafyreiclp443lavogvhj3d2ob2cxbfuscni2k5jk7bebjzg7khl3esabwq", invalid: "bafy!"},
+		{field: "datetime", format: "datetime", valid: "2023-10-30T22:25:23.123Z", invalid: "2023-10-30"},
+		{field: "language", format: "language", valid: "pt-BR", invalid: "123"},
+		{field: "uri", format: "uri", valid: "https://example.com/path", invalid: "example"},
+		{field: "tid", format: "tid", valid: "3kznmn7xqxl22", invalid: "3kznmn7xqxl2"},
+		{field: "recordkey", format: "record-key", valid: "self", invalid: ".."},
+	}
+	for _, tc := range testCases {
+		assert.NoError(ValidateRecord(&cat, rec(tc.field, tc.valid), "example.lexicon.record", 0), tc.format)
+		err := ValidateRecord(&cat, rec(tc.field, tc.invalid), "example.lexicon.record", 0)
+		var ve *ValidationError
+		if assert.ErrorAs(err, &ve, tc.format) {
+			assert.Equal("formats."+tc.field, ve.Path)
+			assert.ErrorContains(err, "invalid "+tc.format+" string")
+		}
+	}
+
+	// lenient datetimes only pass with the flag
+	assert.Error(ValidateRecord(&cat, rec("datetime", "2023-10-30T22:25:23"), "example.lexicon.record", 0))
+	assert.NoError(ValidateRecord(&cat, rec("datetime", "2023-10-30T22:25:23"), "example.lexicon.record", AllowLenientDatetime))
+}
+
+func TestUnionOpenClosed(t *testing.T) {
+	assert := assert.New(t)
+
+	cat := NewBaseCatalog()
+	if err := cat.LoadDirectory("testdata/catalog"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := func(field string, val map[string]any) map[string]any {
+		return map[string]any{"$type": "example.lexicon.record", "integer": int64(1), field: val}
+	}
+	known := map[string]any{"$type": "example.lexicon.record#demoObject", "a": int64(1)}
+	unlisted := map[string]any{"$type": "example.lexicon.record#demoObjectTwo", "c": int64(1)}
+	unresolvable := map[string]any{"$type": "example.unknown.blah", "a": int64(1)}
+
+	// listed variants are accepted by both open and closed unions
+	assert.NoError(ValidateRecord(&cat, rec("union", known), "example.lexicon.record", 0))
+	assert.NoError(ValidateRecord(&cat, rec("closedUnion", known), "example.lexicon.record", 0))
+
+	// open unions tolerate unlisted variants, validating them if possible
+	assert.NoError(ValidateRecord(&cat, rec("union", unresolvable), "example.lexicon.record", 0))
+	assert.NoError(ValidateRecord(&cat, rec("union", map[string]any{"$type": "example.lexicon.record#stringFormats", "did": "did:web:example.com"}), "example.lexicon.record", 0))
+	assert.Error(ValidateRecord(&cat, rec("union", map[string]any{"$type": "example.lexicon.record#stringFormats", "did": "not-a-did"}), "example.lexicon.record", 0))
+	assert.Error(ValidateRecord(&cat, rec("union", unresolvable), "example.lexicon.record", StrictRecursiveValidation))
+
+	// closed unions reject unlisted variants, even if they resolve
+	assert.Error(ValidateRecord(&cat, rec("closedUnion", unlisted), "example.lexicon.record", 0))
+	assert.Error(ValidateRecord(&cat, rec("closedUnion", unresolvable), "example.lexicon.record", 0))
+}
+
+func TestRecordKeyConstraint(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		key  string
+		rkey string
+		ok   bool
+	}{
+		{key: "any", rkey: "3l3qo2vutsw2b", ok: true},
+		{key: "any", rkey: "self", ok: true},
+		{key: "any", rkey: "app.bsky.feed.post", ok: true},
+		{key: "tid", rkey: "3l3qo2vutsw2b", ok: true},
+		{key: "tid", rkey: "self", ok: false},
+		{key: "tid", rkey: "3l3qo2vutsw2bb", ok: false},
+		{key: "nsid", rkey: "app.bsky.feed.post", ok: true},
+		{key: "nsid", rkey: "3l3qo2vutsw2b", ok: false},
+		{key: "literal:self", rkey: "self", ok: true},
+		{key: "literal:self", rkey: "selfie", ok: false},
+		{key: "literal:self", rkey: "3l3qo2vutsw2b", ok: false},
+		{key: "bogus", rkey: "self", ok: false},
+	}
+	for _, tc := range testCases {
+		s := SchemaRecord{Key: tc.key}
+		err := s.ValidateKey(syntax.RecordKey(tc.rkey))
+		if tc.ok {
+			assert.NoError(err, "%s %s", tc.key, tc.rkey)
+		} else {
+			assert.Error(err, "%s %s", tc.key, tc.rkey)
+		}
+	}
+
+	cat := NewBaseCatalog()
+	if err := cat.LoadDirectory("testdata/catalog"); err != nil {
+		t.Fatal(err)
+	}
+	rec := map[string]any{"$type": "example.lexicon.record", "integer": int64(1)}
+	// this schema has key 'literal:demo'
+	assert.NoError(ValidateRecordPath(&cat, rec, "example.lexicon.record/demo", 0))
+	assert.ErrorContains(ValidateRecordPath(&cat, rec, "example.lexicon.record/3l3qo2vutsw2b", 0), "record key must be")
+	assert.Error(ValidateRecordPath(&cat, rec, "example.lexicon.record", 0))
+	// record data is still validated
+	assert.Error(ValidateRecordPath(&cat, map[string]any{"$type": "example.lexicon.record"}, "example.lexicon.record/demo", 0))
+}
+
+func TestUnionSelfReference(t *testing.T) {
+	assert := assert.New(t)
+
+	var sf SchemaFile
+	err := json.Unmarshal([]byte(`{
+  "lexicon": 1,
+  "id": "example.lexicon.loop",
+  "defs": {
+    "main": {
+      "type": "record",
+      "key": "tid",
+      "record": {
+        "type": "object",
+        "properties": {
+          "embed": {
+            "type": "union",
+            "refs": ["#inner"]
+          }
+        }
+      }
+    },
+    "inner": {
+      "type": "union",
+      "refs": ["#inner"]
+    }
+  }
+}`), &sf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := NewBaseCatalog()
+	if err := cat.AddSchemaFile(sf); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := map[string]any{
+		"$type": "example.lexicon.loop",
+		"embed": map[string]any{"$type": "example.lexicon.loop#inner"},
+	}
+	err = ValidateRecord(&cat, rec, "example.lexicon.loop", 0)
+	assert.ErrorIs(err, ErrCircularRef)
+	errs := ValidateRecordAll(&cat, rec, "example.lexicon.loop", 0)
+	assert.Equal(1, len(errs))
+	assert.ErrorIs(errs[0], ErrCircularRef)
+
+	// also via an open union which does not list the variant
+	errs = collectUnion(&cat, SchemaUnion{}, rec["embed"], 0, false, nil)
+	assert.Equal(1, len(errs))
+	assert.ErrorIs(errs[0], ErrCircularRef)
+}
@@ -0,0 +1,95 @@
+package lexicon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Describes a schema definition which exists in two catalogs with different content.
+type Conflict struct {
+	// Fully-qualified schema reference (NSID with '#' fragment)
+	ID string
+	// Definition already present in the destination catalog; this is the one retained after merging.
+	Existing Schema
+	// Conflicting definition from the catalog being merged in.
+	Incoming Schema
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("conflicting lexicon schema definitions: %s", c.ID)
+}
+
+// Returns canonical JSON encoding of a schema definition, used for equality checks.
+//
+// Go's JSON encoder emits struct fields in declaration order and object keys in sorted order, so the output is deterministic for a given schema.
+func canonicalSchemaJSON(s *Schema) ([]byte, error) {
+	b, err := json.Marshal(s.Def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lexicon schema %s: %w", s.ID, err)
+	}
+	return b, nil
+}
+
+// Returns the full set of schemas in a catalog, for catalog types which support enumeration.
+func catalogSchemas(cat Catalog) (map[string]Schema, error) {
+	switch c := cat.(type) {
+	case *BaseCatalog:
+		return c.schemas, nil
+	case *ResolvingCatalog:
+		return c.Base.schemas, nil
+	case *MultiCatalog:
+		// earlier catalogs take priority, so they are applied last
+		out := map[string]Schema{}
+		for i := len(c.Catalogs) - 1; i >= 0; i-- {
+			schemas, err := catalogSchemas(c.Catalogs[i])
+			if err != nil {
+				return nil, err
+			}
+			for id, s := range schemas {
+				out[id] = s
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("can not enumerate schemas of catalog type: %T", cat)
+	}
+}
+
+// Merges all the schemas from another catalog in to this catalog.
+//
+// Schemas which are already present with identical content (by canonical JSON encoding) are silently accepted. Schemas which are present with different content are not overwritten, and are instead returned as a list of conflicts (sorted by ID). An error is only returned if the other catalog could not be enumerated, or schemas could not be encoded, in which case this catalog is not modified.
+func (c *BaseCatalog) MergeFrom(other Catalog) ([]Conflict, error) {
+	schemas, err := catalogSchemas(other)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := []Conflict{}
+	additions := []Schema{}
+	for id, incoming := range schemas {
+		existing, ok := c.schemas[id]
+		if !ok {
+			additions = append(additions, incoming)
+			continue
+		}
+		a, err := canonicalSchemaJSON(&existing)
+		if err != nil {
+			return nil, err
+		}
+		b, err := canonicalSchemaJSON(&incoming)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(a, b) {
+			conflicts = append(conflicts, Conflict{ID: id, Existing: existing, Incoming: incoming})
+		}
+	}
+
+	for _, s := range additions {
+		c.schemas[s.ID] = s
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].ID < conflicts[j].ID })
+	return conflicts, nil
+}
@@ -0,0 +1,68 @@
+package lexicon
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Optional sink for statistics about record validation. See [MetricsCatalog].
+type ValidationMetrics interface {
+	// Called once per record validation, with the record schema reference, time spent, and validation result (nil on success).
+	ObserveValidation(ref string, duration time.Duration, err error)
+}
+
+// Catalogs which implement this interface have record validations ([ValidateRecord] and [ValidateRecordPath]) reported to the returned metrics sink. A nil sink disables reporting.
+type MetricsCatalog interface {
+	Catalog
+	Metrics() ValidationMetrics
+}
+
+// Aggregate validation statistics for a single schema reference.
+type SchemaValidationStats struct {
+	Ref       string
+	Count     int64
+	Failures  int64
+	TotalTime time.Duration
+}
+
+// Simple in-memory [ValidationMetrics] implementation, which accumulates counts, failures, and time spent per schema reference. Safe for concurrent use.
+type BaseValidationMetrics struct {
+	lk    sync.Mutex
+	stats map[string]*SchemaValidationStats
+}
+
+var _ ValidationMetrics = (*BaseValidationMetrics)(nil)
+
+func NewBaseValidationMetrics() *BaseValidationMetrics {
+	return &BaseValidationMetrics{
+		stats: make(map[string]*SchemaValidationStats),
+	}
+}
+
+func (m *BaseValidationMetrics) ObserveValidation(ref string, duration time.Duration, err error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	s, ok := m.stats[ref]
+	if !ok {
+		s = &SchemaValidationStats{Ref: ref}
+		m.stats[ref] = s
+	}
+	s.Count++
+	if err != nil {
+		s.Failures++
+	}
+	s.TotalTime += duration
+}
+
+// Returns a copy of the current statistics for all schema references, sorted by reference.
+func (m *BaseValidationMetrics) Snapshot() []SchemaValidationStats {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	out := make([]SchemaValidationStats, 0, len(m.stats))
+	for _, s := range m.stats {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Ref < out[j].Ref })
+	return out
+}
@@ -0,0 +1,34 @@
+package lexicon
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Catalog which layers several other catalogs, in priority order. For example, application-specific Lexicons can be loaded separately from (and override) the core atproto Lexicons.
+//
+// References are resolved against each catalog in order, and the first hit wins: schemas with the same reference in later catalogs are shadowed, not merged or compared. This differs from [BaseCatalog.MergeFrom], which reports conflicting definitions.
+type MultiCatalog struct {
+	Catalogs []Catalog
+}
+
+// Creates a catalog which resolves references against each of the provided catalogs in order, returning the first hit. See [MultiCatalog].
+func NewMultiCatalog(cats ...Catalog) Catalog {
+	return &MultiCatalog{Catalogs: cats}
+}
+
+// Returns the schema from the first catalog which resolves the reference. Errors from individual catalogs (including network errors from a [ResolvingCatalog]) do not stop later catalogs from being tried; an error is only returned if no catalog resolves the reference, and wraps the errors from each catalog.
+func (mc *MultiCatalog) Resolve(ref string) (*Schema, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("tried to resolve empty string name")
+	}
+	var errs []error
+	for _, cat := range mc.Catalogs {
+		s, err := cat.Resolve(ref)
+		if err == nil {
+			return s, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("schema not found in any catalog: %s: %w", ref, errors.Join(errs...))
+}
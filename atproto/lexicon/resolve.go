@@ -48,6 +48,13 @@ func ResolveLexiconSchemaFile(ctx context.Context, dir identity.Directory, nsid
 	return &sf, nil
 }
 
+// Returns a function for [BaseCatalog.ResolverFunc] which fetches schemas from the network, using [ResolveLexiconSchemaFile].
+func NetworkResolverFunc(dir identity.Directory) func(ctx context.Context, nsid syntax.NSID) (*SchemaFile, error) {
+	return func(ctx context.Context, nsid syntax.NSID) (*SchemaFile, error) {
+		return ResolveLexiconSchemaFile(ctx, dir, nsid)
+	}
+}
+
 // internal helper for fetching lexicon record as JSON bytes
 func resolveLexiconJSON(ctx context.Context, dir identity.Directory, nsid syntax.NSID) (*json.RawMessage, error) {
 	baseDir := identity.BaseDirectory{}
@@ -0,0 +1,146 @@
+package lexicon
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Go types corresponding to each schema definition 'type' value. This mirrors the parsing in SchemaDef.UnmarshalJSON.
+var schemaDefTypes = map[string]reflect.Type{
+	"record":       reflect.TypeOf(SchemaRecord{}),
+	"query":        reflect.TypeOf(SchemaQuery{}),
+	"procedure":    reflect.TypeOf(SchemaProcedure{}),
+	"subscription": reflect.TypeOf(SchemaSubscription{}),
+	"null":         reflect.TypeOf(SchemaNull{}),
+	"boolean":      reflect.TypeOf(SchemaBoolean{}),
+	"integer":      reflect.TypeOf(SchemaInteger{}),
+	"string":       reflect.TypeOf(SchemaString{}),
+	"bytes":        reflect.TypeOf(SchemaBytes{}),
+	"cid-link":     reflect.TypeOf(SchemaCIDLink{}),
+	"array":        reflect.TypeOf(SchemaArray{}),
+	"object":       reflect.TypeOf(SchemaObject{}),
+	"blob":         reflect.TypeOf(SchemaBlob{}),
+	"params":       reflect.TypeOf(SchemaParams{}),
+	"token":        reflect.TypeOf(SchemaToken{}),
+	"ref":          reflect.TypeOf(SchemaRef{}),
+	"union":        reflect.TypeOf(SchemaUnion{}),
+	"unknown":      reflect.TypeOf(SchemaUnknown{}),
+}
+
+var schemaDefType = reflect.TypeOf(SchemaDef{})
+
+// Finds any keys in a JSON Lexicon schema file which are not recognized by this package, returning them as JSON-style paths (eg, 'defs.main.record.properties.text.maxBytes'), in sorted order.
+//
+// Unknown keys do not cause an error when parsing a [SchemaFile]; they are ignored, and not retained. This allows older code to load schemas using newer (backwards-compatible) additions to the Lexicon language. This function can be used to opt in to stricter checking (eg, in CI), and is what [BaseCatalog.RejectUnknownKeys] uses.
+//
+// The recognized keys are the JSON fields of [SchemaFile] (plus the top-level 'revision'), and the Schema* type for each definition 'type' (eg, [SchemaString] for "string"), including nested bodies, messages, and errors. Definitions with an unrecognized 'type' are not inspected (they fail to parse regardless).
+func UnknownSchemaKeys(b []byte) ([]string, error) {
+	var raw any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	// 'revision' is part of the Lexicon language, but is not used by this package (so not parsed in to SchemaFile)
+	if obj, ok := raw.(map[string]any); ok {
+		delete(obj, "revision")
+	}
+	out := []string{}
+	collectUnknownKeys(raw, reflect.TypeOf(SchemaFile{}), "", &out)
+	sort.Strings(out)
+	return out, nil
+}
+
+// Returns the JSON field names of a struct type, mapped to the field types
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	out := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		out[name] = f.Type
+	}
+	return out
+}
+
+func joinKeyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Recursively walks generic JSON data in parallel with the Go type it would be parsed in to, recording the paths of any object keys which don't correspond to a struct field
+func collectUnknownKeys(raw any, t reflect.Type, path string, out *[]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == schemaDefType {
+		obj, ok := raw.(map[string]any)
+		if !ok {
+			return
+		}
+		typeName, _ := obj["type"].(string)
+		inner, ok := schemaDefTypes[typeName]
+		if !ok {
+			return
+		}
+		t = inner
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := raw.(map[string]any)
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for k, v := range obj {
+			ft, ok := fields[k]
+			if !ok {
+				*out = append(*out, joinKeyPath(path, k))
+				continue
+			}
+			collectUnknownKeys(v, ft, joinKeyPath(path, k), out)
+		}
+	case reflect.Map:
+		obj, ok := raw.(map[string]any)
+		if !ok {
+			return
+		}
+		for k, v := range obj {
+			collectUnknownKeys(v, t.Elem(), joinKeyPath(path, k), out)
+		}
+	case reflect.Slice:
+		arr, ok := raw.([]any)
+		if !ok {
+			return
+		}
+		for i, v := range arr {
+			collectUnknownKeys(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i), out)
+		}
+	}
+}
+
+// returns an error if strict checking is enabled and the schema file JSON has unrecognized keys
+func (c *BaseCatalog) checkUnknownKeys(b []byte) error {
+	if !c.RejectUnknownKeys {
+		return nil
+	}
+	unknown, err := UnknownSchemaKeys(b)
+	if err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("lexicon schema has unrecognized keys: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
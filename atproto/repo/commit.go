@@ -2,6 +2,7 @@ package repo
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 
 	"github.com/bluesky-social/indigo/atproto/crypto"
@@ -11,6 +12,9 @@ import (
 	"github.com/ipfs/go-cid"
 )
 
+// Returned (wrapped) by [Commit.VerifyStructure] when the commit version is not [ATPROTO_REPO_VERSION]
+var ErrUnsupportedRepoVersion = errors.New("unsupported repo version")
+
 // atproto repo commit object as a struct type. Can be used for direct CBOR or JSON serialization.
 type Commit struct {
 	DID     string   `json:"did" cborgen:"did"`
@@ -24,7 +28,7 @@ type Commit struct {
 // does basic checks that field values and syntax are correct
 func (c *Commit) VerifyStructure() error {
 	if c.Version != ATPROTO_REPO_VERSION {
-		return fmt.Errorf("unsupported repo version: %d", c.Version)
+		return fmt.Errorf("%w: %d", ErrUnsupportedRepoVersion, c.Version)
 	}
 	if len(c.Sig) == 0 {
 		return fmt.Errorf("empty commit signature")
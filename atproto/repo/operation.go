@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/ipfs/go-cid"
 )
 
+var ErrDuplicateOpPath = errors.New("duplicate path in operation list")
+
 // Metadata about update to a single record (key) in the repo.
 //
 // Used as an abstraction for creating or validating "commit diffs" (eg, `#commit` firehose events)
@@ -42,6 +45,36 @@ func (op *Operation) IsDelete() bool {
 	return false
 }
 
+// Returns the kind of operation: "create", "update", "delete", or "invalid"
+func (op *Operation) Action() string {
+	switch {
+	case op.IsCreate():
+		return "create"
+	case op.IsUpdate():
+		return "update"
+	case op.IsDelete():
+		return "delete"
+	default:
+		return "invalid"
+	}
+}
+
+// Error returned by [InvertOp] and [NormalizeOps], identifying the operation which failed.
+//
+// Op is a copy of the operation (including the Value and Prev CIDs), so the problematic commit diff can be reconstructed from logs.
+type OperationError struct {
+	Op    Operation
+	Cause error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Op.Action(), e.Op.Path, e.Cause)
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Cause
+}
+
 // Mutates the tree, returning a full `Operation`
 func ApplyOp(tree *mst.Tree, path string, val *cid.Cid) (*Operation, error) {
 	if val != nil {
@@ -91,7 +124,16 @@ func CheckOp(tree *mst.Tree, op *Operation) error {
 }
 
 // Applies the inversion of the `op` to the `tree`. This mutates the tree.
+//
+// Errors are of type [*OperationError].
 func InvertOp(tree *mst.Tree, op *Operation) error {
+	if err := invertOp(tree, op); err != nil {
+		return &OperationError{Op: *op, Cause: err}
+	}
+	return nil
+}
+
+func invertOp(tree *mst.Tree, op *Operation) error {
 	if op.IsCreate() {
 		prev, err := tree.Remove([]byte(op.Path))
 		if err != nil {
@@ -142,13 +184,15 @@ func (a opByPath) Less(i, j int) bool {
 }
 
 // re-orders operation list, and checks for duplicates
+//
+// Errors are of type [*OperationError], identifying the (second) duplicate operation.
 func NormalizeOps(list []Operation) ([]Operation, error) {
 	// TODO: can this just use the slice ref, instead of returning?
 
 	set := map[string]bool{}
 	for _, op := range list {
 		if _, ok := set[op.Path]; ok != false {
-			return nil, fmt.Errorf("duplicate path in operation list")
+			return nil, &OperationError{Op: op, Cause: ErrDuplicateOpPath}
 		}
 		set[op.Path] = true
 	}
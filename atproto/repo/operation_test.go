@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -99,6 +100,14 @@ func TestBasicOperation(t *testing.T) {
 	err = InvertOp(tree, op)
 	assert.NoError(err)
 	assert.Error(CheckOp(tree, op))
+
+	// inverting again fails, and the error identifies the op
+	err = InvertOp(tree, op)
+	var opErr *OperationError
+	assert.True(errors.As(err, &opErr))
+	assert.Equal("color/pink", opErr.Op.Path)
+	assert.Equal("update", opErr.Op.Action())
+	assert.Contains(err.Error(), "update color/pink")
 }
 
 func TestRandomOperations(t *testing.T) {
@@ -290,5 +299,9 @@ func TestNormalizeOps(t *testing.T) {
 		},
 	}
 	_, err = NormalizeOps(dupes)
-	assert.Error(err)
+	assert.ErrorIs(err, ErrDuplicateOpPath)
+	var opErr *OperationError
+	assert.True(errors.As(err, &opErr))
+	assert.Equal("create-BBB", opErr.Op.Path)
+	assert.Equal(c3, *opErr.Op.Prev)
 }
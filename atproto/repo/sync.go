@@ -88,7 +88,7 @@ func VerifyCommitMessage(ctx context.Context, msg *comatproto.SyncSubscribeRepos
 		}
 	}
 
-	ops, err := parseCommitOps(msg.Ops)
+	ops, err := ParseFirehoseOps(msg.Ops)
 	if err != nil {
 		return nil, err
 	}
@@ -122,8 +122,10 @@ func VerifyCommitMessage(ctx context.Context, msg *comatproto.SyncSubscribeRepos
 	return repo, nil
 }
 
-func parseCommitOps(ops []*comatproto.SyncSubscribeRepos_RepoOp) ([]Operation, error) {
-	//out := make([]Operation, len(ops))
+// Converts the ops list from a firehose #commit message in to repo operations.
+//
+// This is strict about the form of each op: creates must have a CID and no prev, deletes must have a prev and no CID, and updates must have both. Legacy ops without prev will fail to parse.
+func ParseFirehoseOps(ops []*comatproto.SyncSubscribeRepos_RepoOp) ([]Operation, error) {
 	out := []Operation{}
 	for _, rop := range ops {
 		switch rop.Action {
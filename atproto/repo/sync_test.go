@@ -9,6 +9,7 @@ import (
 
 	comatproto "github.com/bluesky-social/indigo/api/atproto"
 	"github.com/bluesky-social/indigo/atproto/repo/mst"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -54,3 +55,47 @@ func testCommitFile(t *testing.T, p string) {
 		mst.DebugPrintTree(repo.MST.Root, 0)
 	}
 }
+
+func TestParseFirehoseOps(t *testing.T) {
+	assert := assert.New(t)
+
+	c1 := lexutil.LexLink(randomCid())
+	c2 := lexutil.LexLink(randomCid())
+	path := "app.bsky.feed.post/3l3qo2vutsw2b"
+
+	testCases := []struct {
+		name  string
+		op    comatproto.SyncSubscribeRepos_RepoOp
+		valid bool
+	}{
+		{name: "create", op: comatproto.SyncSubscribeRepos_RepoOp{Action: "create", Path: path, Cid: &c1}, valid: true},
+		{name: "create without cid", op: comatproto.SyncSubscribeRepos_RepoOp{Action: "create", Path: path}},
+		{name: "create with prev", op: comatproto.SyncSubscribeRepos_RepoOp{Action: "create", Path: path, Cid: &c1, Prev: &c2}},
+		{name: "update", op: comatproto.SyncSubscribeRepos_RepoOp{Action: "update", Path: path, Cid: &c1, Prev: &c2}, valid: true},
+		{name: "update without cid", op: comatproto.SyncSubscribeRepos_RepoOp{Action: "update", Path: path, Prev: &c2}},
+		{name: "update without prev", op: comatproto.SyncSubscribeRepos_RepoOp{Action: "update", Path: path, Cid: &c1}},
+		{name: "delete", op: comatproto.SyncSubscribeRepos_RepoOp{Action: "delete", Path: path, Prev: &c2}, valid: true},
+		{name: "delete with cid", op: comatproto.SyncSubscribeRepos_RepoOp{Action: "delete", Path: path, Cid: &c1, Prev: &c2}},
+		{name: "delete without prev", op: comatproto.SyncSubscribeRepos_RepoOp{Action: "delete", Path: path}},
+		{name: "unknown action", op: comatproto.SyncSubscribeRepos_RepoOp{Action: "upsert", Path: path, Cid: &c1}},
+	}
+
+	for _, tc := range testCases {
+		ops, err := ParseFirehoseOps([]*comatproto.SyncSubscribeRepos_RepoOp{&tc.op})
+		if !tc.valid {
+			assert.Error(err, tc.name)
+			continue
+		}
+		if !assert.NoError(err, tc.name) {
+			continue
+		}
+		assert.Equal(1, len(ops), tc.name)
+		assert.Equal(path, ops[0].Path)
+		assert.Equal(tc.op.Action == "create" || tc.op.Action == "update", ops[0].Value != nil, tc.name)
+		assert.Equal(tc.op.Action == "delete" || tc.op.Action == "update", ops[0].Prev != nil, tc.name)
+	}
+
+	// a single bad op fails the whole list
+	_, err := ParseFirehoseOps([]*comatproto.SyncSubscribeRepos_RepoOp{&testCases[0].op, &testCases[1].op})
+	assert.Error(err)
+}
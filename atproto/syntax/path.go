@@ -6,21 +6,32 @@ import (
 	"strings"
 )
 
+var (
+	// Path did not have exactly two parts separated by a single slash
+	ErrRepoPathParts = errors.New("expected path to have two parts, separated by single slash")
+	// Collection part of the path was not a valid NSID
+	ErrRepoPathCollection = errors.New("collection part of path not a valid NSID")
+	// Record key part of the path was not valid. The error will also wrap one of the ErrRecordKey* values.
+	ErrRepoPathRecordKey = errors.New("record key part of path not valid")
+)
+
 // Parses an atproto repo path string in to "collection" (NSID) and record key parts.
 //
 // Does not return partial success: either both collection and record key are complete (and error is nil), or both are empty string (and error is not nil)
+//
+// Errors wrap one of ErrRepoPathParts, ErrRepoPathCollection, or ErrRepoPathRecordKey, for callers which need to distinguish between them.
 func ParseRepoPath(raw string) (NSID, RecordKey, error) {
 	parts := strings.SplitN(raw, "/", 3)
 	if len(parts) != 2 {
-		return "", "", errors.New("expected path to have two parts, separated by single slash")
+		return "", "", ErrRepoPathParts
 	}
 	nsid, err := ParseNSID(parts[0])
 	if err != nil {
-		return "", "", fmt.Errorf("collection part of path not a valid NSID: %w", err)
+		return "", "", fmt.Errorf("%w: %w", ErrRepoPathCollection, err)
 	}
 	rkey, err := ParseRecordKey(parts[1])
 	if err != nil {
-		return "", "", fmt.Errorf("record key part of path not valid: %w", err)
+		return "", "", fmt.Errorf("%w: %w", ErrRepoPathRecordKey, err)
 	}
 	return nsid, rkey, nil
 }
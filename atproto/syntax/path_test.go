@@ -1,6 +1,7 @@
 package syntax
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -39,3 +40,37 @@ func TestRepoPath(t *testing.T) {
 		assert.Equal("", rkey.String())
 	}
 }
+
+func TestRepoPathErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		path  string
+		err   error
+		inner error
+	}{
+		{path: "app.bsky.feed.post", err: ErrRepoPathParts},
+		{path: "app.bsky.feed.post/a/b", err: ErrRepoPathParts},
+		{path: "/asdf", err: ErrRepoPathCollection},
+		{path: "blob/asdf", err: ErrRepoPathCollection},
+		{path: "app.bsky.feed.post/", err: ErrRepoPathRecordKey, inner: ErrRecordKeyEmpty},
+		{path: "app.bsky.feed.post/.", err: ErrRepoPathRecordKey, inner: ErrRecordKeyReserved},
+		{path: "app.bsky.feed.post/..", err: ErrRepoPathRecordKey, inner: ErrRecordKeyReserved},
+		{path: "app.bsky.feed.post/!", err: ErrRepoPathRecordKey, inner: ErrRecordKeySyntax},
+		{path: "app.bsky.feed.post/" + strings.Repeat("a", 513), err: ErrRepoPathRecordKey, inner: ErrRecordKeyTooLong},
+	}
+	for _, tc := range testCases {
+		_, _, err := ParseRepoPath(tc.path)
+		assert.ErrorIs(err, tc.err, tc.path)
+		if tc.inner != nil {
+			assert.ErrorIs(err, tc.inner, tc.path)
+		}
+	}
+
+	// boundary record key lengths, and record keys which only contain dots
+	for _, rkey := range []string{"a", strings.Repeat("a", 512), "...", ".a", "a."} {
+		_, out, err := ParseRepoPath("app.bsky.feed.post/" + rkey)
+		assert.NoError(err)
+		assert.Equal(rkey, out.String())
+	}
+}
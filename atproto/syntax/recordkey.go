@@ -7,6 +7,13 @@ import (
 
 var recordKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9_~.:-]{1,512}$`)
 
+var (
+	ErrRecordKeyEmpty    = errors.New("expected record key, got empty string")
+	ErrRecordKeyTooLong  = errors.New("recordkey is too long (512 chars max)")
+	ErrRecordKeyReserved = errors.New("recordkey can not be '.' or '..'")
+	ErrRecordKeySyntax   = errors.New("recordkey syntax didn't validate via regex")
+)
+
 // String type which represents a syntaxtually valid RecordKey identifier, as could be included in an AT URI
 //
 // Always use [ParseRecordKey] instead of wrapping strings directly, especially when working with input.
@@ -14,18 +21,19 @@ var recordKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9_~.:-]{1,512}$`)
 // Syntax specification: https://atproto.com/specs/record-key
 type RecordKey string
 
+// Parses and validates a record key. Errors are one of the ErrRecordKey* values, so callers can distinguish failure modes with [errors.Is].
 func ParseRecordKey(raw string) (RecordKey, error) {
 	if raw == "" {
-		return "", errors.New("expected record key, got empty string")
+		return "", ErrRecordKeyEmpty
 	}
 	if len(raw) > 512 {
-		return "", errors.New("recordkey is too long (512 chars max)")
+		return "", ErrRecordKeyTooLong
 	}
-	if raw == "" || raw == "." || raw == ".." {
-		return "", errors.New("recordkey can not be empty, '.', or '..'")
+	if raw == "." || raw == ".." {
+		return "", ErrRecordKeyReserved
 	}
 	if !recordKeyRegex.MatchString(raw) {
-		return "", errors.New("recordkey syntax didn't validate via regex")
+		return "", ErrRecordKeySyntax
 	}
 	return RecordKey(raw), nil
 }
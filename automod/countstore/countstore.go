@@ -11,6 +11,7 @@ const (
 	PeriodTotal = "total"
 	PeriodDay   = "day"
 	PeriodHour  = "hour"
+	PeriodWeek  = "week"
 )
 
 // CountStore is an interface for storing incrementing event counts, bucketed into periods.
@@ -26,10 +27,13 @@ const (
 //
 // Incrementing -- both the "Increment" and "IncrementDistinct" variants -- increases
 // a count in each supported period bucket size.
-// In other words, one call to CountStore.Increment causes three increments internally:
-// one to the count for the hour, one to the count for the day, and one to the all-time count.
+// In other words, one call to CountStore.Increment causes four increments internally:
+// one to the count for the hour, one to the count for the day, one to the count for the (ISO 8601) week, and one to the all-time count.
+// Period buckets are aligned to UTC calendar boundaries; they are not rolling windows. See [PeriodForWindow] for mapping a window duration to a period.
 // The "IncrementPeriod" method allows only incrementing a single period bucket. Care must be taken to match the "GetCount" period with the incremented period when using this variant.
 //
+// The "Reset" method clears a counter (not "*Distinct" counts) in all of the current period buckets, as if it had never been incremented. Buckets for past periods are not affected. This is intended for administrative use, eg clearing a stuck de-duplication counter after an incident.
+//
 // The exact implementation and precision of the "*Distinct" methods may vary:
 // in the MemCountStore implementation, it is precise (it's based on large maps);
 // in the RedisCountStore implementation, it uses the Redis "pfcount" feature,
@@ -45,6 +49,7 @@ type CountStore interface {
 	GetCount(ctx context.Context, name, val, period string) (int, error)
 	Increment(ctx context.Context, name, val string) error
 	IncrementPeriod(ctx context.Context, name, val, period string) error
+	Reset(ctx context.Context, name, val string) error
 	// TODO: batch increment method
 	GetCountDistinct(ctx context.Context, name, bucket, period string) (int, error)
 	IncrementDistinct(ctx context.Context, name, bucket, val string) error
@@ -60,8 +65,34 @@ func periodBucket(name, val, period string) string {
 	case PeriodHour:
 		t := time.Now().UTC().Format(time.RFC3339)[0:13]
 		return fmt.Sprintf("%s/%s/%s", name, val, t)
+	case PeriodWeek:
+		year, week := time.Now().UTC().ISOWeek()
+		return fmt.Sprintf("%s/%s/%04d-W%02d", name, val, year, week)
 	default:
 		slog.Warn("unhandled counter period", "period", period)
 		return fmt.Sprintf("%s/%s", name, val)
 	}
 }
+
+// MaxWindow is the longest time window which [PeriodForWindow] maps to a period bucket that resets. Configured windows should be checked against this: longer windows would silently become all-time counts.
+const MaxWindow = 7 * 24 * time.Hour
+
+// Returns the shortest period which covers a time window of the given duration: [PeriodHour] for up to an hour, [PeriodDay] for up to a day, and [PeriodWeek] for up to [MaxWindow].
+//
+// Windows longer than [MaxWindow], or non-positive, return [PeriodTotal], which never resets. Callers should reject such windows when parsing configuration, rather than relying on this.
+//
+// Because period buckets reset on calendar boundaries, a count for the returned period approximates a rolling window: it may cover less than the full window (just after a bucket boundary), but never more than one period.
+func PeriodForWindow(window time.Duration) string {
+	switch {
+	case window <= 0:
+		return PeriodTotal
+	case window <= time.Hour:
+		return PeriodHour
+	case window <= 24*time.Hour:
+		return PeriodDay
+	case window <= MaxWindow:
+		return PeriodWeek
+	default:
+		return PeriodTotal
+	}
+}
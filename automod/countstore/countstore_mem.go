@@ -9,7 +9,7 @@ import (
 type MemCountStore struct {
 	// Counts is keyed by a string that is a munge of "{name}/{val}[/{period}]",
 	// where period is either absent (meaning all-time total)
-	// or a string describing that timeperiod (either "YYYY-MM-DD" or that plus a literal "T" and "HH").
+	// or a string describing that timeperiod (either "YYYY-MM-DD" or that plus a literal "T" and "HH", or an ISO week as "YYYY-Www").
 	//
 	// (Using a values for `name` and `val` with slashes in them is perhaps inadvisable, as it may be ambiguous.)
 	Counts         *xsync.MapOf[string, int]
@@ -32,7 +32,7 @@ func (s MemCountStore) GetCount(ctx context.Context, name, val, period string) (
 }
 
 func (s MemCountStore) Increment(ctx context.Context, name, val string) error {
-	for _, p := range []string{PeriodTotal, PeriodDay, PeriodHour} {
+	for _, p := range []string{PeriodTotal, PeriodWeek, PeriodDay, PeriodHour} {
 		if err := s.IncrementPeriod(ctx, name, val, p); err != nil {
 			return err
 		}
@@ -48,6 +48,13 @@ func (s MemCountStore) IncrementPeriod(ctx context.Context, name, val, period st
 	return nil
 }
 
+func (s MemCountStore) Reset(ctx context.Context, name, val string) error {
+	for _, p := range []string{PeriodTotal, PeriodWeek, PeriodDay, PeriodHour} {
+		s.Counts.Delete(periodBucket(name, val, p))
+	}
+	return nil
+}
+
 func (s MemCountStore) GetCountDistinct(ctx context.Context, name, bucket, period string) (int, error) {
 	v, ok := s.DistinctCounts.Load(periodBucket(name, bucket, period))
 	if !ok {
@@ -57,7 +64,7 @@ func (s MemCountStore) GetCountDistinct(ctx context.Context, name, bucket, perio
 }
 
 func (s MemCountStore) IncrementDistinct(ctx context.Context, name, bucket, val string) error {
-	for _, p := range []string{PeriodTotal, PeriodDay, PeriodHour} {
+	for _, p := range []string{PeriodTotal, PeriodWeek, PeriodDay, PeriodHour} {
 		k := periodBucket(name, bucket, p)
 		s.DistinctCounts.Compute(k, func(nested *xsync.MapOf[string, bool], _ bool) (*xsync.MapOf[string, bool], bool) {
 			if nested == nil {
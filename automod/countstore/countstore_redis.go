@@ -58,6 +58,10 @@ func (s *RedisCountStore) Increment(ctx context.Context, name, val string) error
 	multi.Incr(ctx, key)
 	multi.Expire(ctx, key, 48*time.Hour)
 
+	key = redisCountPrefix + periodBucket(name, val, PeriodWeek)
+	multi.Incr(ctx, key)
+	multi.Expire(ctx, key, 14*24*time.Hour)
+
 	key = redisCountPrefix + periodBucket(name, val, PeriodTotal)
 	multi.Incr(ctx, key)
 	// no expiration for total
@@ -80,12 +84,23 @@ func (s *RedisCountStore) IncrementPeriod(ctx context.Context, name, val, period
 		multi.Expire(ctx, key, 2*time.Hour)
 	case PeriodDay:
 		multi.Expire(ctx, key, 48*time.Hour)
+	case PeriodWeek:
+		multi.Expire(ctx, key, 14*24*time.Hour)
 	}
 
 	_, err := multi.Exec(ctx)
 	return err
 }
 
+// Deletes the counter keys for all current period buckets, in a single redis round-trip
+func (s *RedisCountStore) Reset(ctx context.Context, name, val string) error {
+	keys := []string{}
+	for _, p := range []string{PeriodHour, PeriodDay, PeriodWeek, PeriodTotal} {
+		keys = append(keys, redisCountPrefix+periodBucket(name, val, p))
+	}
+	return s.Client.Del(ctx, keys...).Err()
+}
+
 func (s *RedisCountStore) GetCountDistinct(ctx context.Context, name, val, period string) (int, error) {
 	key := redisDistinctPrefix + periodBucket(name, val, period)
 	c, err := s.Client.PFCount(ctx, key).Result()
@@ -112,6 +127,10 @@ func (s *RedisCountStore) IncrementDistinct(ctx context.Context, name, bucket, v
 	multi.PFAdd(ctx, key, val)
 	multi.Expire(ctx, key, 48*time.Hour)
 
+	key = redisDistinctPrefix + periodBucket(name, bucket, PeriodWeek)
+	multi.PFAdd(ctx, key, val)
+	multi.Expire(ctx, key, 14*24*time.Hour)
+
 	key = redisDistinctPrefix + periodBucket(name, bucket, PeriodTotal)
 	multi.PFAdd(ctx, key, val)
 	// no expiration for total
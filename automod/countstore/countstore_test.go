@@ -21,7 +21,7 @@ func TestMemCountStoreBasics(t *testing.T) {
 	assert.NoError(cs.Increment(ctx, "test1", "val1"))
 	assert.NoError(cs.Increment(ctx, "test1", "val1"))
 
-	for _, period := range []string{PeriodTotal, PeriodDay, PeriodHour} {
+	for _, period := range []string{PeriodTotal, PeriodWeek, PeriodDay, PeriodHour} {
 		c, err = cs.GetCount(ctx, "test1", "val1", period)
 		assert.NoError(err)
 		assert.Equal(2, c)
@@ -40,11 +40,24 @@ func TestMemCountStoreBasics(t *testing.T) {
 	assert.NoError(cs.IncrementDistinct(ctx, "test2", "val2", "two"))
 	assert.NoError(cs.IncrementDistinct(ctx, "test2", "val2", "three"))
 
-	for _, period := range []string{PeriodTotal, PeriodDay, PeriodHour} {
+	for _, period := range []string{PeriodTotal, PeriodWeek, PeriodDay, PeriodHour} {
 		c, err = cs.GetCountDistinct(ctx, "test2", "val2", period)
 		assert.NoError(err)
 		assert.Equal(3, c)
 	}
+
+	// reset clears all current buckets for one counter, but not others
+	assert.NoError(cs.Increment(ctx, "test1", "other"))
+	assert.NoError(cs.Reset(ctx, "test1", "val1"))
+	for _, period := range []string{PeriodTotal, PeriodWeek, PeriodDay, PeriodHour} {
+		c, err = cs.GetCount(ctx, "test1", "val1", period)
+		assert.NoError(err)
+		assert.Equal(0, c)
+		c, err = cs.GetCount(ctx, "test1", "other", period)
+		assert.NoError(err)
+		assert.Equal(1, c)
+	}
+	assert.NoError(cs.Reset(ctx, "test1", "never-incremented"))
 }
 
 func TestMemCountStoreConcurrent(t *testing.T) {
@@ -104,3 +117,16 @@ func TestMemCountStoreConcurrent(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal(1, c)
 }
+
+func TestPeriodForWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(PeriodTotal, PeriodForWindow(0))
+	assert.Equal(PeriodHour, PeriodForWindow(10*time.Minute))
+	assert.Equal(PeriodHour, PeriodForWindow(time.Hour))
+	assert.Equal(PeriodDay, PeriodForWindow(2*time.Hour))
+	assert.Equal(PeriodDay, PeriodForWindow(24*time.Hour))
+	assert.Equal(PeriodWeek, PeriodForWindow(72*time.Hour))
+	assert.Equal(PeriodWeek, PeriodForWindow(7*24*time.Hour))
+	assert.Equal(PeriodTotal, PeriodForWindow(30*24*time.Hour))
+}
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"testing"
+	"time"
 
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
 	appbsky "github.com/bluesky-social/indigo/api/bsky"
+	toolsozone "github.com/bluesky-social/indigo/api/ozone"
 	"github.com/bluesky-social/indigo/atproto/identity"
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/bluesky-social/indigo/automod/countstore"
@@ -56,3 +59,128 @@ func TestAccountReportDedupe(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal(1, reports)
 }
+
+func TestRecentReportDedupe(t *testing.T) {
+	assert := assert.New(t)
+	eng := EngineTestFixture()
+
+	did := syntax.DID("did:plc:abc111")
+	me := "did:plc:automod"
+	subject := AccountSubject(did)
+	spam := ReportReasonSpam
+	now := syntax.DatetimeNow().String()
+	old := time.Now().Add(-30 * 24 * time.Hour).UTC().Format(syntax.AtprotoDatetimeLayout)
+	evt := func(createdBy, createdAt string, reasonType *string, subjectDID string) *toolsozone.ModerationDefs_ModEventView {
+		return &toolsozone.ModerationDefs_ModEventView{
+			CreatedBy: createdBy,
+			CreatedAt: createdAt,
+			Event: &toolsozone.ModerationDefs_ModEventView_Event{
+				ModerationDefs_ModEventReport: &toolsozone.ModerationDefs_ModEventReport{ReportType: reasonType},
+			},
+			Subject: &toolsozone.ModerationDefs_ModEventView_Subject{
+				AdminDefs_RepoRef: &comatproto.AdminDefs_RepoRef{Did: subjectDID},
+			},
+		}
+	}
+
+	recent, err := latestReportTimes([]*toolsozone.ModerationDefs_ModEventView{
+		evt(me, now, &spam, did.String()),
+		// ignored: different creator, or different subject
+		evt("did:plc:other", now, nil, did.String()),
+		evt(me, now, nil, "did:plc:abc222"),
+	}, subject, me)
+	assert.NoError(err)
+	assert.Equal(1, len(recent))
+	assert.False(eng.reportIsFresh(recent, ModReport{ReasonType: spam}))
+	assert.True(eng.reportIsFresh(recent, ModReport{ReasonType: ReportReasonOther}))
+
+	// stale reports don't block, and reports without a reasonType match all reasons
+	recent, err = latestReportTimes([]*toolsozone.ModerationDefs_ModEventView{
+		evt(me, old, &spam, did.String()),
+		evt(me, now, nil, did.String()),
+	}, subject, me)
+	assert.NoError(err)
+	assert.False(eng.reportIsFresh(recent, ModReport{ReasonType: ReportReasonOther}))
+	delete(recent, "")
+	assert.True(eng.reportIsFresh(recent, ModReport{ReasonType: spam}))
+}
+
+func TestReportDupePeriods(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	eng := EngineTestFixture()
+	eng.Config.ReportDupePeriods = map[string]time.Duration{
+		ReportReasonSpam: time.Hour,
+		ReportReasonRude: 7 * 24 * time.Hour,
+	}
+
+	assert.Equal(time.Hour, eng.Config.reportDupePeriod(ReportReasonSpam))
+	assert.Equal(7*24*time.Hour, eng.Config.reportDupePeriod(ReportReasonRude))
+	assert.Equal(defaultReportDupePeriod, eng.Config.reportDupePeriod(ReportReasonOther))
+
+	// API-based check uses the per-reason window
+	recent := map[string]time.Time{
+		ReportReasonSpam: time.Now().Add(-2 * time.Hour),
+		ReportReasonRude: time.Now().Add(-2 * 24 * time.Hour),
+	}
+	assert.True(eng.reportIsFresh(recent, ModReport{ReasonType: ReportReasonSpam}))
+	assert.False(eng.reportIsFresh(recent, ModReport{ReasonType: ReportReasonRude}))
+
+	// counter-based check uses the matching period bucket
+	did := "did:plc:abc111"
+	reports := []ModReport{{ReasonType: ReportReasonSpam}, {ReasonType: ReportReasonRude}}
+	out, err := eng.dedupeReportActions(ctx, did, reports)
+	assert.NoError(err)
+	assert.Equal(2, len(out))
+	out, err = eng.dedupeReportActions(ctx, did, reports)
+	assert.NoError(err)
+	assert.Equal(0, len(out))
+
+	c, err := eng.Counters.GetCount(ctx, "automod-account-report-spam", did, countstore.PeriodHour)
+	assert.NoError(err)
+	assert.Equal(1, c)
+	c, err = eng.Counters.GetCount(ctx, "automod-account-report-rude", did, countstore.PeriodWeek)
+	assert.NoError(err)
+	assert.Equal(1, c)
+}
+
+func TestInspectAndResetReportDedupe(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	eng := EngineTestFixture()
+
+	did := "did:plc:abc111"
+	reports := []ModReport{{ReasonType: ReportReasonSpam}}
+	out, err := eng.dedupeReportActions(ctx, did, reports)
+	assert.NoError(err)
+	assert.Equal(1, len(out))
+
+	state, err := eng.InspectReportDedupe(ctx, did)
+	assert.NoError(err)
+	assert.Equal(6, len(state))
+	var spam ReportDedupeCount
+	for _, s := range state {
+		if s.ReasonType == ReportReasonSpam {
+			spam = s
+		} else {
+			assert.Equal(0, s.Count)
+		}
+	}
+	assert.Equal("automod-account-report-spam", spam.Counter)
+	assert.Equal(countstore.PeriodDay, spam.Period)
+	assert.Equal(1, spam.Count)
+
+	// suppressed until the counter is reset
+	out, err = eng.dedupeReportActions(ctx, did, reports)
+	assert.NoError(err)
+	assert.Equal(0, len(out))
+	assert.NoError(eng.ResetCounter(ctx, spam.Counter, did))
+	state, err = eng.InspectReportDedupe(ctx, did)
+	assert.NoError(err)
+	for _, s := range state {
+		assert.Equal(0, s.Count)
+	}
+	out, err = eng.dedupeReportActions(ctx, did, reports)
+	assert.NoError(err)
+	assert.Equal(1, len(out))
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bluesky-social/indigo/automod/countstore"
+)
+
+// Current report de-duplication counter state for a single subject and report reason, as returned by [Engine.InspectReportDedupe]
+type ReportDedupeCount struct {
+	ReasonType string
+	// Counter name, which can be passed to [Engine.ResetCounter] along with the subject
+	Counter string
+	// Counter period bucket consulted for this reason (see countstore.PeriodForWindow)
+	Period string
+	// A non-zero count means new reports for this reason are currently suppressed by the counter check
+	Count int
+}
+
+// Returns the report de-duplication counter state for a subject (an account DID, or a record AT-URI), for each known report reason.
+//
+// This only covers the counter-based check; reports can also be skipped by the check against the moderation service's recent reports. Intended for administrative inspection, eg after an incident.
+func (eng *Engine) InspectReportDedupe(ctx context.Context, subject string) ([]ReportDedupeCount, error) {
+	out := []ReportDedupeCount{}
+	for _, reasonType := range []string{ReportReasonSpam, ReportReasonViolation, ReportReasonMisleading, ReportReasonSexual, ReportReasonRude, ReportReasonOther} {
+		counterName := reportDedupeCounter(reasonType)
+		period := countstore.PeriodForWindow(eng.Config.reportDupePeriod(reasonType))
+		c, err := eng.Counters.GetCount(ctx, counterName, subject, period)
+		if err != nil {
+			return nil, fmt.Errorf("checking report de-dupe counts: %w", err)
+		}
+		out = append(out, ReportDedupeCount{
+			ReasonType: reasonType,
+			Counter:    counterName,
+			Period:     period,
+			Count:      c,
+		})
+	}
+	return out, nil
+}
+
+// Resets a counter to zero in all current period buckets. This can be used to clear a stuck de-duplication or circuit breaker state without waiting for the period to roll over. For example:
+//
+//   - report de-duplication for a subject: the Counter name from [Engine.InspectReportDedupe], with the subject as key
+//   - circuit breaker quota: name "automod-quota", with the breaker kind (eg, [CircuitBreakerReport]) as key
+//
+// Resetting a circuit breaker quota does not reset "automod-quota-trip", so the OnCircuitBreak hook is not called again for the same kind until the next day.
+func (eng *Engine) ResetCounter(ctx context.Context, name, key string) error {
+	if err := eng.Counters.Reset(ctx, name, key); err != nil {
+		return fmt.Errorf("resetting counter %s: %w", name, err)
+	}
+	eng.Logger.Info("reset counter", "name", name, "key", key)
+	return nil
+}
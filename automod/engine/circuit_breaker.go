@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bluesky-social/indigo/automod/countstore"
+)
+
+// Circuit breaker kinds. These are used as quota counter names, metric labels, and passed to [Engine.OnCircuitBreak].
+const (
+	CircuitBreakerReport    = "report"
+	CircuitBreakerTakedown  = "takedown"
+	CircuitBreakerModAction = "mod-action"
+)
+
+func (eng *Engine) circuitBreakerQuota(kind string) int {
+	switch kind {
+	case CircuitBreakerReport:
+		return eng.Config.quotaModReportDay()
+	case CircuitBreakerTakedown:
+		return eng.Config.quotaModTakedownDay()
+	default:
+		return eng.Config.quotaModActionDay()
+	}
+}
+
+// Shared circuit breaker implementation. Checks the daily quota for the given kind, and consumes one unit of quota if the action is allowed.
+//
+// Returns false if the breaker is tripped (action should not be persisted).
+func (eng *Engine) circuitBreak(ctx context.Context, kind string) (bool, error) {
+	c, err := eng.Counters.GetCount(ctx, "automod-quota", kind, countstore.PeriodDay)
+	if err != nil {
+		return false, fmt.Errorf("checking %s action quota: %w", kind, err)
+	}
+	quota := eng.circuitBreakerQuota(kind)
+	if c >= quota {
+		eng.Logger.Warn("CIRCUIT BREAKER: automod "+kind, "quota", quota)
+		if err := eng.recordCircuitBreak(ctx, kind, quota); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+	// don't consume quota for actions which won't be persisted
+	if !eng.Config.DryRun {
+		err = eng.Counters.Increment(ctx, "automod-quota", kind)
+		if err != nil {
+			return false, fmt.Errorf("incrementing %s action quota: %w", kind, err)
+		}
+	}
+	return true, nil
+}
+
+// Records the first trip of a circuit breaker each day: increments metrics, and calls the OnCircuitBreak hook (if configured).
+//
+// Trips are tracked in the counter store, so the hook is called once per day per kind, even across multiple engine instances sharing a counter store (modulo races).
+//
+// Nothing is recorded in dry-run mode: a dry-run engine doesn't consume quota, so any trip it sees was caused by other instances, which record it themselves.
+func (eng *Engine) recordCircuitBreak(ctx context.Context, kind string, quota int) error {
+	if eng.Config.DryRun {
+		return nil
+	}
+	trips, err := eng.Counters.GetCount(ctx, "automod-quota-trip", kind, countstore.PeriodDay)
+	if err != nil {
+		return fmt.Errorf("checking %s circuit breaker state: %w", kind, err)
+	}
+	if trips > 0 {
+		return nil
+	}
+	if err := eng.Counters.Increment(ctx, "automod-quota-trip", kind); err != nil {
+		return fmt.Errorf("recording %s circuit breaker trip: %w", kind, err)
+	}
+	circuitBreakerTripCount.WithLabelValues(kind).Inc()
+	if eng.OnCircuitBreak != nil {
+		eng.OnCircuitBreak(kind, int64(quota))
+	}
+	return nil
+}
+
+func (eng *Engine) circuitBreakReports(ctx context.Context, reports []ModReport) ([]ModReport, error) {
+	if len(reports) == 0 {
+		return []ModReport{}, nil
+	}
+	ok, err := eng.circuitBreak(ctx, CircuitBreakerReport)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []ModReport{}, nil
+	}
+	return reports, nil
+}
+
+func (eng *Engine) circuitBreakTakedown(ctx context.Context, takedown bool) (bool, error) {
+	if !takedown {
+		return false, nil
+	}
+	return eng.circuitBreak(ctx, CircuitBreakerTakedown)
+}
+
+// Combined circuit breaker for miscellaneous mod actions like: escalate, acknowledge
+func (eng *Engine) circuitBreakModAction(ctx context.Context, action bool) (bool, error) {
+	if !action {
+		return false, nil
+	}
+	return eng.circuitBreak(ctx, CircuitBreakerModAction)
+}
+
+// Returns the kinds of circuit breakers which are currently tripped (quota exhausted for the current day). Intended for use in health checks.
+func (eng *Engine) TrippedCircuitBreakers(ctx context.Context) ([]string, error) {
+	tripped := []string{}
+	for _, kind := range []string{CircuitBreakerReport, CircuitBreakerTakedown, CircuitBreakerModAction} {
+		c, err := eng.Counters.GetCount(ctx, "automod-quota", kind, countstore.PeriodDay)
+		if err != nil {
+			return nil, fmt.Errorf("checking %s action quota: %w", kind, err)
+		}
+		if c >= eng.circuitBreakerQuota(kind) {
+			tripped = append(tripped, kind)
+		}
+	}
+	return tripped, nil
+}
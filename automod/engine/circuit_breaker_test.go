@@ -114,3 +114,150 @@ func TestReportCircuitBreaker(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal(eng.Config.QuotaModReportDay, reports)
 }
+
+func TestDryRunNoSideEffects(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	eng := EngineTestFixture()
+	eng.Config.DryRun = true
+	dir := identity.NewMockDirectory()
+	eng.Directory = &dir
+	eng.Rules = RuleSet{
+		RecordRules: []RecordRuleFunc{
+			alwaysTakedownRecordRule,
+			alwaysReportRecordRule,
+			alwaysReportAccountRule,
+		},
+	}
+
+	cid1 := syntax.CID("cid123")
+	p1 := appbsky.FeedPost{Text: "some post blah"}
+	p1buf := new(bytes.Buffer)
+	assert.NoError(p1.MarshalCBOR(p1buf))
+	ident := identity.Identity{
+		DID:    syntax.DID("did:plc:abc111"),
+		Handle: syntax.Handle("handle.example.com"),
+	}
+	dir.Insert(ident)
+	op := RecordOp{
+		Action:     CreateOp,
+		DID:        ident.DID,
+		Collection: syntax.NSID("app.bsky.feed.post"),
+		RecordKey:  syntax.RecordKey("abc123"),
+		CID:        &cid1,
+		RecordCBOR: p1buf.Bytes(),
+	}
+	assert.NoError(eng.ProcessRecordOp(ctx, op))
+
+	// neither quotas nor report de-dupe state are consumed
+	for _, kind := range []string{"takedown", "report"} {
+		c, err := eng.Counters.GetCount(ctx, "automod-quota", kind, countstore.PeriodDay)
+		assert.NoError(err)
+		assert.Equal(0, c)
+	}
+	c, err := eng.Counters.GetCount(ctx, "automod-account-report-"+ReasonShortName(ReportReasonOther), ident.DID.String(), countstore.PeriodDay)
+	assert.NoError(err)
+	assert.Equal(0, c)
+}
+
+func TestCircuitBreakerTrip(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	eng := EngineTestFixture()
+	eng.Config.QuotaModTakedownDay = 3
+	dir := identity.NewMockDirectory()
+	eng.Directory = &dir
+	eng.Rules = RuleSet{
+		RecordRules: []RecordRuleFunc{
+			alwaysTakedownRecordRule,
+		},
+	}
+	trips := map[string]int64{}
+	eng.OnCircuitBreak = func(kind string, quota int64) {
+		trips[kind] += quota
+	}
+
+	tripped, err := eng.TrippedCircuitBreakers(ctx)
+	assert.NoError(err)
+	assert.Empty(tripped)
+
+	cid1 := syntax.CID("cid123")
+	p1 := appbsky.FeedPost{Text: "some post blah"}
+	p1buf := new(bytes.Buffer)
+	assert.NoError(p1.MarshalCBOR(p1buf))
+	for i := 0; i < 3*eng.Config.QuotaModTakedownDay; i++ {
+		ident := identity.Identity{
+			DID:    syntax.DID(fmt.Sprintf("did:plc:abc%d", i)),
+			Handle: syntax.Handle("handle.example.com"),
+		}
+		dir.Insert(ident)
+		op := RecordOp{
+			Action:     CreateOp,
+			DID:        ident.DID,
+			Collection: syntax.NSID("app.bsky.feed.post"),
+			RecordKey:  syntax.RecordKey("abc123"),
+			CID:        &cid1,
+			RecordCBOR: p1buf.Bytes(),
+		}
+		assert.NoError(eng.ProcessRecordOp(ctx, op))
+	}
+
+	// hook is only called on the first trip of the day
+	assert.Equal(map[string]int64{CircuitBreakerTakedown: 3}, trips)
+
+	tripped, err = eng.TrippedCircuitBreakers(ctx)
+	assert.NoError(err)
+	assert.Equal([]string{CircuitBreakerTakedown}, tripped)
+}
+
+func TestCircuitBreakerTripDryRun(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	eng := EngineTestFixture()
+	eng.Config.DryRun = true
+	eng.Config.QuotaModTakedownDay = 3
+	dir := identity.NewMockDirectory()
+	eng.Directory = &dir
+	eng.Rules = RuleSet{
+		RecordRules: []RecordRuleFunc{
+			alwaysTakedownRecordRule,
+		},
+	}
+	fired := 0
+	eng.OnCircuitBreak = func(kind string, quota int64) {
+		fired++
+	}
+
+	// quota used up by other (live) engine instances sharing the counter store
+	for i := 0; i < eng.Config.QuotaModTakedownDay; i++ {
+		assert.NoError(eng.Counters.Increment(ctx, "automod-quota", CircuitBreakerTakedown))
+	}
+
+	cid1 := syntax.CID("cid123")
+	p1 := appbsky.FeedPost{Text: "some post blah"}
+	p1buf := new(bytes.Buffer)
+	assert.NoError(p1.MarshalCBOR(p1buf))
+	ident := identity.Identity{
+		DID:    syntax.DID("did:plc:abc111"),
+		Handle: syntax.Handle("handle.example.com"),
+	}
+	dir.Insert(ident)
+	op := RecordOp{
+		Action:     CreateOp,
+		DID:        ident.DID,
+		Collection: syntax.NSID("app.bsky.feed.post"),
+		RecordKey:  syntax.RecordKey("abc123"),
+		CID:        &cid1,
+		RecordCBOR: p1buf.Bytes(),
+	}
+	assert.NoError(eng.ProcessRecordOp(ctx, op))
+
+	// the breaker is tripped, but the trip is not recorded or reported by the dry-run instance
+	tripped, err := eng.TrippedCircuitBreakers(ctx)
+	assert.NoError(err)
+	assert.Equal([]string{CircuitBreakerTakedown}, tripped)
+	assert.Equal(0, fired)
+	trips, err := eng.Counters.GetCount(ctx, "automod-quota-trip", CircuitBreakerTakedown, countstore.PeriodDay)
+	assert.NoError(err)
+	assert.Equal(0, trips)
+}
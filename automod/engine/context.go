@@ -246,6 +246,10 @@ func (c *AccountContext) RemoveAccountLabel(val string) {
 	c.effects.RemoveAccountLabel(val)
 }
 
+func (c *AccountContext) ApplyAccountLabelAction(a LabelAction) {
+	c.effects.ApplyAccountLabelAction(a)
+}
+
 func (c *AccountContext) AddAccountTag(val string) {
 	c.effects.AddAccountTag(val)
 }
@@ -278,6 +282,10 @@ func (c *RecordContext) RemoveRecordLabel(val string) {
 	c.effects.RemoveRecordLabel(val)
 }
 
+func (c *RecordContext) ApplyRecordLabelAction(a LabelAction) {
+	c.effects.ApplyRecordLabelAction(a)
+}
+
 func (c *RecordContext) AddRecordTag(val string) {
 	c.effects.AddRecordTag(val)
 }
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"sync"
+	"time"
 )
 
 type CounterRef struct {
@@ -16,6 +17,45 @@ type CounterDistinctRef struct {
 	Val    string
 }
 
+// A single label change, for [Effects.ApplyAccountLabelAction] and [Effects.ApplyRecordLabelAction].
+type LabelAction struct {
+	// Label value
+	Val string
+	// If set, the label is temporary, and will be removed by the moderation service after this time. Ozone tracks label durations in whole hours, so this is rounded up to the next hour. Ignored if Negate is true.
+	Expires *time.Time
+	// If true, the label is removed (negated) from the subject, instead of added
+	Negate bool
+}
+
+// adds a label to the list, with an optional expiration. A permanent add overrides any expiration, and otherwise the latest expiration wins. Caller must hold the Effects lock.
+func addLabelWithExpiration(labels []string, expirations map[string]time.Time, val string, expires *time.Time) ([]string, map[string]time.Time) {
+	exists := false
+	for _, v := range labels {
+		if v == val {
+			exists = true
+			break
+		}
+	}
+	prev, temporary := expirations[val]
+	switch {
+	case expires == nil:
+		delete(expirations, val)
+	case exists && !temporary:
+		// already added permanently
+	case temporary && !expires.After(prev):
+		// already added with a later expiration
+	default:
+		if expirations == nil {
+			expirations = make(map[string]time.Time)
+		}
+		expirations[val] = *expires
+	}
+	if !exists {
+		labels = append(labels, val)
+	}
+	return labels, expirations
+}
+
 // Mutable container for all the possible side-effects from rule execution.
 //
 // This single type tracks generic effects (eg, counter increments), account-level actions, and record-level actions (even for processing of account-level events which have no possible record-level effects).
@@ -30,6 +70,8 @@ type Effects struct {
 	AccountLabels []string
 	// Label values which should be removed from the overall account, as a result of rule execution.
 	RemovedAccountLabels []string
+	// Expiration times for temporary labels in "AccountLabels". Labels not in this map are permanent.
+	AccountLabelExpirations map[string]time.Time
 	// Moderation tags (similar to labels, but private) which should be applied to the overall account, as a result of rule execution.
 	AccountTags []string
 	// automod flags (metadata) which should be applied to the account as a result of rule execution.
@@ -44,8 +86,10 @@ type Effects struct {
 	AccountAcknowledge bool
 	// Same as "AccountLabels", but at record-level
 	RecordLabels []string
-	// Same as "RemovedRecordLabels", but at record-level
+	// Same as "RemovedAccountLabels", but at record-level
 	RemovedRecordLabels []string
+	// Same as "AccountLabelExpirations", but at record-level
+	RecordLabelExpirations map[string]time.Time
 	// Same as "AccountTags", but at record-level
 	RecordTags []string
 	// Same as "AccountFlags", but at record-level
@@ -94,12 +138,7 @@ func (e *Effects) IncrementDistinct(name, bucket, val string) {
 func (e *Effects) AddAccountLabel(val string) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	for _, v := range e.AccountLabels {
-		if v == val {
-			return
-		}
-	}
-	e.AccountLabels = append(e.AccountLabels, val)
+	e.AccountLabels, e.AccountLabelExpirations = addLabelWithExpiration(e.AccountLabels, e.AccountLabelExpirations, val, nil)
 }
 
 // Enqueues the provided label (string value) to be removed from the account at the end of rule processing.
@@ -114,6 +153,19 @@ func (e *Effects) RemoveAccountLabel(val string) {
 	e.RemovedAccountLabels = append(e.RemovedAccountLabels, val)
 }
 
+// Enqueues a label change for the account, at the end of rule processing. Negated actions are the same as RemoveAccountLabel(); otherwise the label is added, with an expiration if Expires is set.
+//
+// If the same label is added both with and without an expiration (including with AddAccountLabel), the label is permanent. If it is added with multiple expirations, the latest wins.
+func (e *Effects) ApplyAccountLabelAction(a LabelAction) {
+	if a.Negate {
+		e.RemoveAccountLabel(a.Val)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.AccountLabels, e.AccountLabelExpirations = addLabelWithExpiration(e.AccountLabels, e.AccountLabelExpirations, a.Val, a.Expires)
+}
+
 // Enqueues the provided label (string value) to be added to the account at the end of rule processing.
 func (e *Effects) AddAccountTag(val string) {
 	e.mu.Lock()
@@ -172,12 +224,7 @@ func (e *Effects) AcknowledgeAccount() {
 func (e *Effects) AddRecordLabel(val string) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	for _, v := range e.RecordLabels {
-		if v == val {
-			return
-		}
-	}
-	e.RecordLabels = append(e.RecordLabels, val)
+	e.RecordLabels, e.RecordLabelExpirations = addLabelWithExpiration(e.RecordLabels, e.RecordLabelExpirations, val, nil)
 }
 
 // Enqueues the provided label (string value) to be removed from the record at the end of rule processing.
@@ -192,6 +239,17 @@ func (e *Effects) RemoveRecordLabel(val string) {
 	e.RemovedRecordLabels = append(e.RemovedRecordLabels, val)
 }
 
+// Enqueues a label change for the record, at the end of rule processing. See [Effects.ApplyAccountLabelAction] for details.
+func (e *Effects) ApplyRecordLabelAction(a LabelAction) {
+	if a.Negate {
+		e.RemoveRecordLabel(a.Val)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.RecordLabels, e.RecordLabelExpirations = addLabelWithExpiration(e.RecordLabels, e.RecordLabelExpirations, a.Val, a.Expires)
+}
+
 // Enqueues the provided tag (string value) to be added to the record at the end of rule processing.
 func (e *Effects) AddRecordTag(val string) {
 	e.mu.Lock()
@@ -273,3 +331,78 @@ func (e *Effects) Notify(srv string) {
 func (e *Effects) Reject() {
 	e.RejectEvent = true
 }
+
+func appendUnique(list []string, val string) []string {
+	for _, v := range list {
+		if v == val {
+			return list
+		}
+	}
+	return append(list, val)
+}
+
+func appendReport(list []ModReport, r ModReport) []ModReport {
+	for _, v := range list {
+		if v.ReasonType == r.ReasonType {
+			return list
+		}
+	}
+	return append(list, r)
+}
+
+// Merges effects recorded separately (eg, by a single rule run with a timeout) in to these effects, with the same de-duplication as the individual methods. The caller must hold e.mu, and 'o' must no longer be in use.
+func (e *Effects) mergeLocked(o *Effects) {
+	e.CounterIncrements = append(e.CounterIncrements, o.CounterIncrements...)
+	e.CounterDistinctIncrements = append(e.CounterDistinctIncrements, o.CounterDistinctIncrements...)
+	for _, v := range o.AccountLabels {
+		var expires *time.Time
+		if t, ok := o.AccountLabelExpirations[v]; ok {
+			expires = &t
+		}
+		e.AccountLabels, e.AccountLabelExpirations = addLabelWithExpiration(e.AccountLabels, e.AccountLabelExpirations, v, expires)
+	}
+	for _, v := range o.RemovedAccountLabels {
+		e.RemovedAccountLabels = appendUnique(e.RemovedAccountLabels, v)
+	}
+	for _, v := range o.AccountTags {
+		e.AccountTags = appendUnique(e.AccountTags, v)
+	}
+	for _, v := range o.AccountFlags {
+		e.AccountFlags = appendUnique(e.AccountFlags, v)
+	}
+	for _, r := range o.AccountReports {
+		e.AccountReports = appendReport(e.AccountReports, r)
+	}
+	e.AccountTakedown = e.AccountTakedown || o.AccountTakedown
+	e.AccountEscalate = e.AccountEscalate || o.AccountEscalate
+	e.AccountAcknowledge = e.AccountAcknowledge || o.AccountAcknowledge
+	for _, v := range o.RecordLabels {
+		var expires *time.Time
+		if t, ok := o.RecordLabelExpirations[v]; ok {
+			expires = &t
+		}
+		e.RecordLabels, e.RecordLabelExpirations = addLabelWithExpiration(e.RecordLabels, e.RecordLabelExpirations, v, expires)
+	}
+	for _, v := range o.RemovedRecordLabels {
+		e.RemovedRecordLabels = appendUnique(e.RemovedRecordLabels, v)
+	}
+	for _, v := range o.RecordTags {
+		e.RecordTags = appendUnique(e.RecordTags, v)
+	}
+	for _, v := range o.RecordFlags {
+		e.RecordFlags = appendUnique(e.RecordFlags, v)
+	}
+	for _, r := range o.RecordReports {
+		e.RecordReports = appendReport(e.RecordReports, r)
+	}
+	e.RecordTakedown = e.RecordTakedown || o.RecordTakedown
+	e.RecordEscalate = e.RecordEscalate || o.RecordEscalate
+	e.RecordAcknowledge = e.RecordAcknowledge || o.RecordAcknowledge
+	for _, v := range o.BlobTakedowns {
+		e.BlobTakedowns = appendUnique(e.BlobTakedowns, v)
+	}
+	for _, v := range o.NotifyServices {
+		e.NotifyServices = appendUnique(e.NotifyServices, v)
+	}
+	e.RejectEvent = e.RejectEvent || o.RejectEvent
+}
@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	toolsozone "github.com/bluesky-social/indigo/api/ozone"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/bluesky-social/indigo/xrpc"
+)
+
+// OzoneEffectsSink implements [EffectsSink] by emitting moderation events to an ozone moderation service.
+type OzoneEffectsSink struct {
+	// requires admin auth; events are created by the authenticated DID
+	Client *xrpc.Client
+}
+
+var _ EffectsSink = (*OzoneEffectsSink)(nil)
+
+func NewOzoneEffectsSink(client *xrpc.Client) *OzoneEffectsSink {
+	return &OzoneEffectsSink{Client: client}
+}
+
+func (ms ModSubject) emitSubject() *toolsozone.ModerationEmitEvent_Input_Subject {
+	if ms.URI != nil {
+		ref := &comatproto.RepoStrongRef{
+			Uri: ms.URI.String(),
+		}
+		if ms.CID != nil {
+			ref.Cid = ms.CID.String()
+		}
+		return &toolsozone.ModerationEmitEvent_Input_Subject{
+			RepoStrongRef: ref,
+		}
+	}
+	return &toolsozone.ModerationEmitEvent_Input_Subject{
+		AdminDefs_RepoRef: &comatproto.AdminDefs_RepoRef{
+			Did: ms.DID.String(),
+		},
+	}
+}
+
+// the subject string used to filter ozone event queries
+func (ms ModSubject) queryString() string {
+	if ms.URI != nil {
+		return ms.URI.String()
+	}
+	return ms.DID.String()
+}
+
+// whether an ozone moderation event subject refers to this subject
+func (ms ModSubject) matchesEventSubject(subj *toolsozone.ModerationDefs_ModEventView_Subject) bool {
+	if subj == nil {
+		return false
+	}
+	if ms.URI != nil {
+		return subj.RepoStrongRef != nil && subj.RepoStrongRef.Uri == ms.URI.String()
+	}
+	return subj.AdminDefs_RepoRef != nil && subj.AdminDefs_RepoRef.Did == ms.DID.String()
+}
+
+func (s *OzoneEffectsSink) emit(ctx context.Context, subject ModSubject, evt *toolsozone.ModerationEmitEvent_Input_Event, blobCIDs []string) error {
+	_, err := toolsozone.ModerationEmitEvent(ctx, s.Client, &toolsozone.ModerationEmitEvent_Input{
+		CreatedBy:       s.Client.Auth.Did,
+		Event:           evt,
+		Subject:         subject.emitSubject(),
+		SubjectBlobCids: blobCIDs,
+	})
+	return err
+}
+
+func (s *OzoneEffectsSink) RecentReports(ctx context.Context, subject ModSubject, limit int64) (map[string]time.Time, error) {
+	resp, err := toolsozone.ModerationQueryEvents(
+		ctx,
+		s.Client,
+		nil,                   // addedLabels []string
+		nil,                   // addedTags []string
+		nil,                   // collections []string
+		"",                    // comment string
+		"",                    // createdAfter string
+		"",                    // createdBefore string
+		s.Client.Auth.Did,     // createdBy string
+		"",                    // cursor string
+		false,                 // hasComment bool
+		false,                 // includeAllUserRecords bool
+		limit,                 // limit int64
+		nil,                   // policies []string
+		nil,                   // removedLabels []string
+		nil,                   // removedTags []string
+		nil,                   // reportTypes []string
+		"",                    // sortDirection string
+		subject.queryString(), // subject string
+		"",                    // subjectType string
+		[]string{"tools.ozone.moderation.defs#modEventReport"}, // types []string
+	)
+	if err != nil {
+		return nil, err
+	}
+	return latestReportTimes(resp.Events, subject, s.Client.Auth.Did)
+}
+
+func latestReportTimes(events []*toolsozone.ModerationDefs_ModEventView, subject ModSubject, createdBy string) (map[string]time.Time, error) {
+	latest := map[string]time.Time{}
+	for _, modEvt := range events {
+		// defensively ensure that our query params worked correctly
+		if modEvt.Event == nil || modEvt.Event.ModerationDefs_ModEventReport == nil || modEvt.CreatedBy != createdBy || !subject.matchesEventSubject(modEvt.Subject) {
+			continue
+		}
+		created, err := syntax.ParseDatetime(modEvt.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		reasonType := ""
+		if modEvt.Event.ModerationDefs_ModEventReport.ReportType != nil {
+			reasonType = *modEvt.Event.ModerationDefs_ModEventReport.ReportType
+		}
+		if prev, ok := latest[reasonType]; !ok || created.Time().After(prev) {
+			latest[reasonType] = created.Time()
+		}
+	}
+	return latest, nil
+}
+
+func (s *OzoneEffectsSink) EmitReport(ctx context.Context, subject ModSubject, reasonType, comment string) error {
+	return s.emit(ctx, subject, &toolsozone.ModerationEmitEvent_Input_Event{
+		ModerationDefs_ModEventReport: &toolsozone.ModerationDefs_ModEventReport{
+			Comment:    &comment,
+			ReportType: &reasonType,
+		},
+	}, nil)
+}
+
+func (s *OzoneEffectsSink) EmitLabel(ctx context.Context, subject ModSubject, add, remove []string, expires *time.Time, comment string) error {
+	evt := &toolsozone.ModerationDefs_ModEventLabel{
+		CreateLabelVals: add,
+		NegateLabelVals: remove,
+		Comment:         &comment,
+	}
+	if expires != nil {
+		evt.DurationInHours = labelDurationHours(time.Until(*expires))
+	}
+	return s.emit(ctx, subject, &toolsozone.ModerationEmitEvent_Input_Event{
+		ModerationDefs_ModEventLabel: evt,
+	}, nil)
+}
+
+// ozone label durations are whole hours; round up, so labels are never removed early
+func labelDurationHours(d time.Duration) *int64 {
+	hours := int64((d + time.Hour - 1) / time.Hour)
+	if hours < 1 {
+		hours = 1
+	}
+	return &hours
+}
+
+func (s *OzoneEffectsSink) EmitTag(ctx context.Context, subject ModSubject, add []string, comment string) error {
+	return s.emit(ctx, subject, &toolsozone.ModerationEmitEvent_Input_Event{
+		ModerationDefs_ModEventTag: &toolsozone.ModerationDefs_ModEventTag{
+			Add:     add,
+			Remove:  []string{},
+			Comment: &comment,
+		},
+	}, nil)
+}
+
+func (s *OzoneEffectsSink) EmitTakedown(ctx context.Context, subject ModSubject, blobCIDs []string, comment string) error {
+	return s.emit(ctx, subject, &toolsozone.ModerationEmitEvent_Input_Event{
+		ModerationDefs_ModEventTakedown: &toolsozone.ModerationDefs_ModEventTakedown{
+			Comment: &comment,
+		},
+	}, blobCIDs)
+}
+
+func (s *OzoneEffectsSink) EmitEscalation(ctx context.Context, subject ModSubject, comment string) error {
+	return s.emit(ctx, subject, &toolsozone.ModerationEmitEvent_Input_Event{
+		ModerationDefs_ModEventEscalate: &toolsozone.ModerationDefs_ModEventEscalate{
+			Comment: &comment,
+		},
+	}, nil)
+}
+
+func (s *OzoneEffectsSink) EmitAcknowledge(ctx context.Context, subject ModSubject, comment string) error {
+	return s.emit(ctx, subject, &toolsozone.ModerationEmitEvent_Input_Event{
+		ModerationDefs_ModEventAcknowledge: &toolsozone.ModerationDefs_ModEventAcknowledge{
+			Comment: &comment,
+		},
+	}, nil)
+}
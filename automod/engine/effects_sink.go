@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// ModSubject identifies the subject of a moderation action: either an account, or a specific record (when URI is set).
+type ModSubject struct {
+	// for account actions; empty for record actions
+	DID syntax.DID
+	// for record actions; nil for account actions
+	URI *syntax.ATURI
+	CID *syntax.CID
+}
+
+func AccountSubject(did syntax.DID) ModSubject {
+	return ModSubject{DID: did}
+}
+
+func RecordSubject(uri syntax.ATURI, cid *syntax.CID) ModSubject {
+	return ModSubject{URI: &uri, CID: cid}
+}
+
+// "account" or "record"; also used as a metrics label
+func (ms ModSubject) kind() string {
+	if ms.URI != nil {
+		return "record"
+	}
+	return "account"
+}
+
+// EffectsSink is the backend which the engine persists moderation actions to.
+//
+// The engine does all de-duplication, circuit-breaking, metrics, and logging before calling the sink, so implementations only need to deliver the action. Each method includes a human-readable comment describing the action. The default implementation is [OzoneEffectsSink].
+type EffectsSink interface {
+	// Returns the most recent creation time of reports against the subject which were previously emitted by this sink, keyed by reasonType. Reports with no reasonType are keyed by the empty string, and match any reasonType. 'limit' bounds the number of prior reports considered.
+	RecentReports(ctx context.Context, subject ModSubject, limit int64) (map[string]time.Time, error)
+	EmitReport(ctx context.Context, subject ModSubject, reasonType, comment string) error
+	// If 'expires' is set, the added labels are temporary, and should be removed after that time. It is always nil when removing labels.
+	EmitLabel(ctx context.Context, subject ModSubject, add, remove []string, expires *time.Time, comment string) error
+	EmitTag(ctx context.Context, subject ModSubject, add []string, comment string) error
+	// 'blobCIDs' are any blobs to take down along with a record subject
+	EmitTakedown(ctx context.Context, subject ModSubject, blobCIDs []string, comment string) error
+	EmitEscalation(ctx context.Context, subject ModSubject, comment string) error
+	EmitAcknowledge(ctx context.Context, subject ModSubject, comment string) error
+}
+
+// Returns the configured EffectsSink, or an ozone sink if only OzoneClient is configured. Returns nil if neither is configured.
+func (eng *Engine) effectsSink() EffectsSink {
+	if eng.EffectsSink != nil {
+		return eng.EffectsSink
+	}
+	if eng.OzoneClient != nil {
+		return NewOzoneEffectsSink(eng.OzoneClient)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	appbsky "github.com/bluesky-social/indigo/api/bsky"
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type labelEvent struct {
+	subject ModSubject
+	add     []string
+	remove  []string
+	expires *time.Time
+}
+
+// records all emitted actions in memory, for tests
+type recordingSink struct {
+	reports   []ModSubject
+	takedowns []ModSubject
+	labels    []labelEvent
+}
+
+func (s *recordingSink) RecentReports(ctx context.Context, subject ModSubject, limit int64) (map[string]time.Time, error) {
+	return map[string]time.Time{}, nil
+}
+
+func (s *recordingSink) EmitReport(ctx context.Context, subject ModSubject, reasonType, comment string) error {
+	s.reports = append(s.reports, subject)
+	return nil
+}
+
+func (s *recordingSink) EmitLabel(ctx context.Context, subject ModSubject, add, remove []string, expires *time.Time, comment string) error {
+	s.labels = append(s.labels, labelEvent{subject: subject, add: add, remove: remove, expires: expires})
+	return nil
+}
+
+func (s *recordingSink) EmitTag(ctx context.Context, subject ModSubject, add []string, comment string) error {
+	return nil
+}
+
+func (s *recordingSink) EmitTakedown(ctx context.Context, subject ModSubject, blobCIDs []string, comment string) error {
+	s.takedowns = append(s.takedowns, subject)
+	return nil
+}
+
+func (s *recordingSink) EmitEscalation(ctx context.Context, subject ModSubject, comment string) error {
+	return nil
+}
+
+func (s *recordingSink) EmitAcknowledge(ctx context.Context, subject ModSubject, comment string) error {
+	return nil
+}
+
+func TestEffectsSink(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	eng := EngineTestFixture()
+	sink := &recordingSink{}
+	eng.EffectsSink = sink
+	dir := identity.NewMockDirectory()
+	eng.Directory = &dir
+	eng.Rules = RuleSet{
+		RecordRules: []RecordRuleFunc{
+			alwaysTakedownRecordRule,
+			alwaysReportAccountRule,
+		},
+	}
+
+	cid1 := syntax.CID("cid123")
+	p1 := appbsky.FeedPost{Text: "some post blah"}
+	p1buf := new(bytes.Buffer)
+	assert.NoError(p1.MarshalCBOR(p1buf))
+
+	// twice the takedown quota of events, with each account posting twice
+	for i := 0; i < 2*eng.Config.quotaModTakedownDay(); i++ {
+		ident := identity.Identity{
+			DID:    syntax.DID(fmt.Sprintf("did:plc:abc%d", i/2)),
+			Handle: syntax.Handle("handle.example.com"),
+		}
+		dir.Insert(ident)
+		op := RecordOp{
+			Action:     CreateOp,
+			DID:        ident.DID,
+			Collection: syntax.NSID("app.bsky.feed.post"),
+			RecordKey:  syntax.RecordKey(fmt.Sprintf("abc%d", i)),
+			CID:        &cid1,
+			RecordCBOR: p1buf.Bytes(),
+		}
+		assert.NoError(eng.ProcessRecordOp(ctx, op))
+	}
+
+	// takedowns are circuit-broken, and account reports are de-duplicated, before reaching the sink
+	assert.Equal(eng.Config.quotaModTakedownDay(), len(sink.takedowns))
+	for _, subj := range sink.takedowns {
+		assert.Equal("record", subj.kind())
+		assert.NotNil(subj.CID)
+	}
+	assert.Equal(eng.Config.quotaModTakedownDay(), len(sink.reports))
+	for _, subj := range sink.reports {
+		assert.Equal("account", subj.kind())
+	}
+}
+
+func TestEffectsSinkTemporaryLabels(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	eng := EngineTestFixture()
+	sink := &recordingSink{}
+	eng.EffectsSink = sink
+	dir := identity.NewMockDirectory()
+	eng.Directory = &dir
+
+	day := time.Now().Add(24 * time.Hour)
+	week := time.Now().Add(7 * 24 * time.Hour)
+	past := time.Now().Add(-time.Hour)
+	eng.Rules = RuleSet{
+		RecordRules: []RecordRuleFunc{
+			func(c *RecordContext) error {
+				c.ApplyAccountLabelAction(LabelAction{Val: "rate-limited", Expires: &day})
+				c.ApplyAccountLabelAction(LabelAction{Val: "new-account", Expires: &day})
+				c.ApplyAccountLabelAction(LabelAction{Val: "probation", Expires: &week})
+				c.ApplyAccountLabelAction(LabelAction{Val: "expired", Expires: &past})
+				c.AddAccountLabel("spam")
+				c.ApplyRecordLabelAction(LabelAction{Val: "hot-take", Expires: &day})
+				return nil
+			},
+		},
+	}
+
+	ident := identity.Identity{DID: syntax.DID("did:plc:abc111"), Handle: syntax.Handle("handle.example.com")}
+	dir.Insert(ident)
+	cid1 := syntax.CID("cid123")
+	p1 := appbsky.FeedPost{Text: "some post blah"}
+	p1buf := new(bytes.Buffer)
+	assert.NoError(p1.MarshalCBOR(p1buf))
+	assert.NoError(eng.ProcessRecordOp(ctx, RecordOp{
+		Action:     CreateOp,
+		DID:        ident.DID,
+		Collection: syntax.NSID("app.bsky.feed.post"),
+		RecordKey:  syntax.RecordKey("abc111"),
+		CID:        &cid1,
+		RecordCBOR: p1buf.Bytes(),
+	}))
+
+	// permanent labels first, then one event per expiration; already-expired labels are dropped
+	if assert.Equal(4, len(sink.labels)) {
+		assert.Equal([]string{"spam"}, sink.labels[0].add)
+		assert.Nil(sink.labels[0].expires)
+		assert.Equal([]string{"rate-limited", "new-account"}, sink.labels[1].add)
+		assert.True(day.Equal(*sink.labels[1].expires))
+		assert.Equal([]string{"probation"}, sink.labels[2].add)
+		assert.True(week.Equal(*sink.labels[2].expires))
+		assert.Equal("record", sink.labels[3].subject.kind())
+		assert.Equal([]string{"hot-take"}, sink.labels[3].add)
+	}
+}
+
+func TestLabelActionEffects(t *testing.T) {
+	assert := assert.New(t)
+
+	day := time.Now().Add(24 * time.Hour)
+	week := time.Now().Add(7 * 24 * time.Hour)
+	e := Effects{}
+
+	// latest expiration wins
+	e.ApplyAccountLabelAction(LabelAction{Val: "a", Expires: &day})
+	e.ApplyAccountLabelAction(LabelAction{Val: "a", Expires: &week})
+	e.ApplyAccountLabelAction(LabelAction{Val: "a", Expires: &day})
+	assert.Equal([]string{"a"}, e.AccountLabels)
+	assert.Equal(week, e.AccountLabelExpirations["a"])
+
+	// permanent adds win, in either order
+	e.AddAccountLabel("a")
+	e.ApplyAccountLabelAction(LabelAction{Val: "b"})
+	e.ApplyAccountLabelAction(LabelAction{Val: "b", Expires: &day})
+	assert.Equal([]string{"a", "b"}, e.AccountLabels)
+	assert.Empty(e.AccountLabelExpirations)
+
+	// negation is a removal
+	e.ApplyAccountLabelAction(LabelAction{Val: "c", Negate: true, Expires: &day})
+	e.ApplyRecordLabelAction(LabelAction{Val: "d", Negate: true})
+	assert.Equal([]string{"c"}, e.RemovedAccountLabels)
+	assert.Equal([]string{"d"}, e.RemovedRecordLabels)
+	assert.Empty(e.RecordLabels)
+
+	assert.Equal(int64(24), *labelDurationHours(24 * time.Hour))
+	assert.Equal(int64(25), *labelDurationHours(24*time.Hour + time.Second))
+	assert.Equal(int64(1), *labelDurationHours(time.Minute))
+}
@@ -34,10 +34,14 @@ type Engine struct {
 	BskyClient *xrpc.Client
 	// used to persist moderation actions in ozone moderation service; optional, admin auth
 	OzoneClient *xrpc.Client
+	// backend for persisting moderation actions (labels, reports, takedowns, etc); optional. if nil, actions are sent to OzoneClient (if configured)
+	EffectsSink EffectsSink
 	// used to fetch private account metadata from PDS or entryway; optional, admin auth
 	AdminClient *xrpc.Client
 	// used to fetch blobs from upstream PDS instances
 	BlobClient *http.Client
+	// optional callback, invoked the first time each day that a circuit breaker trips (action quota is exhausted). 'kind' is one of the CircuitBreaker* constants. Called synchronously during event processing, so should not block.
+	OnCircuitBreak func(kind string, quota int64)
 
 	// internal configuration
 	Config EngineConfig
@@ -46,14 +50,18 @@ type Engine struct {
 type EngineConfig struct {
 	// if enabled, account metadata is not hydrated for every event by default
 	SkipAccountMeta bool
-	// time period within which automod will not re-report an account for the same reasonType
+	// time period within which automod will not re-report an account for the same reasonType (default: 24 hours)
 	ReportDupePeriod time.Duration
-	// number of reports automod can file per day, for all subjects and types combined (circuit breaker)
+	// per-reasonType overrides of ReportDupePeriod, keyed by full reasonType (eg, ReportReasonSpam). See dedupeReportActions() for how this window is applied.
+	ReportDupePeriods map[string]time.Duration
+	// number of reports automod can file per day, for all subjects and types combined (circuit breaker; default: 10,000)
 	QuotaModReportDay int
-	// number of takedowns automod can action per day, for all subjects combined (circuit breaker)
+	// number of takedowns automod can action per day, for all subjects combined (circuit breaker; default: 200)
 	QuotaModTakedownDay int
-	// number of misc actions automod can do per day, for all subjects combined (circuit breaker)
+	// number of misc actions automod can do per day, for all subjects combined (circuit breaker; default: 2,000)
 	QuotaModActionDay int
+	// if enabled, moderation actions (labels, tags, flags, reports, takedowns, etc) are logged and counted in metrics, but not persisted. Report de-dupe and circuit breaker counts are checked but not incremented. Rule counters are still persisted.
+	DryRun bool
 
 	// timeout for record event processing (total, including all setup, rules, and teardown)
 	RecordEventTimeout time.Duration
@@ -61,6 +69,47 @@ type EngineConfig struct {
 	IdentityEventTimeout time.Duration
 	// timeout for event processing (total, including all setup, rules, and teardown)
 	OzoneEventTimeout time.Duration
+	// timeout for each individual rule invocation. A rule which exceeds this has its context cancelled, and is counted and logged as failed, while processing continues with the other rules. Zero means no per-rule timeout (event timeouts still apply)
+	RuleTimeout time.Duration
+}
+
+// defaults for zero-valued EngineConfig fields
+const (
+	defaultReportDupePeriod    = 24 * time.Hour
+	defaultQuotaModReportDay   = 10_000
+	defaultQuotaModTakedownDay = 200
+	defaultQuotaModActionDay   = 2_000
+)
+
+func (c *EngineConfig) reportDupePeriod(reasonType string) time.Duration {
+	if d := c.ReportDupePeriods[reasonType]; d > 0 {
+		return d
+	}
+	if c.ReportDupePeriod == 0 {
+		return defaultReportDupePeriod
+	}
+	return c.ReportDupePeriod
+}
+
+func (c *EngineConfig) quotaModReportDay() int {
+	if c.QuotaModReportDay == 0 {
+		return defaultQuotaModReportDay
+	}
+	return c.QuotaModReportDay
+}
+
+func (c *EngineConfig) quotaModTakedownDay() int {
+	if c.QuotaModTakedownDay == 0 {
+		return defaultQuotaModTakedownDay
+	}
+	return c.QuotaModTakedownDay
+}
+
+func (c *EngineConfig) quotaModActionDay() int {
+	if c.QuotaModActionDay == 0 {
+		return defaultQuotaModActionDay
+	}
+	return c.QuotaModActionDay
 }
 
 // Entrypoint for external code pushing #identity events in to the engine.
@@ -55,6 +55,26 @@ var actionNewAcknowledgeCount = promauto.NewCounterVec(prometheus.CounterOpts{
 	Help: "Number of new subjects acknowledged",
 }, []string{"type"})
 
+var actionDryRunCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "automod_dryrun_actions",
+	Help: "Number of moderation actions which would have been persisted, if not in dry-run mode",
+}, []string{"type", "action"})
+
+var rulePanicCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "automod_rule_panics",
+	Help: "Number of rule invocations which panicked (and were recovered)",
+}, []string{"rule"})
+
+var ruleTimeoutCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "automod_rule_timeouts",
+	Help: "Number of rule invocations which exceeded the per-rule timeout",
+}, []string{"rule"})
+
+var circuitBreakerTripCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "automod_circuit_breaker_trips",
+	Help: "Number of times a moderation action circuit breaker tripped (at most once per day per kind)",
+}, []string{"kind"})
+
 var accountMetaFetches = promauto.NewCounter(prometheus.CounterOpts{
 	Name: "automod_account_meta_fetches",
 	Help: "Number of account metadata reads (API calls)",
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	comatproto "github.com/bluesky-social/indigo/api/atproto"
 	toolsozone "github.com/bluesky-social/indigo/api/ozone"
 	"github.com/bluesky-social/indigo/automod/keyword"
 )
@@ -59,7 +58,7 @@ func (eng *Engine) persistAccountModActions(c *AccountContext) error {
 	newTags := dedupeTagActions(c.effects.AccountTags, existingTags)
 	newFlags := dedupeFlagActions(c.effects.AccountFlags, c.Account.AccountFlags)
 
-	// don't report the same account multiple times on the same day for the same reason. this is a quick check; we also query the mod service API just before creating the report.
+	// don't report the same account multiple times within the de-dupe window for the same reason. this is a quick check (see dedupeReportActions); we also query the mod service API just before creating the report.
 	partialReports, err := eng.dedupeReportActions(ctx, c.Account.Identity.DID.String(), c.effects.AccountReports)
 	if err != nil {
 		return fmt.Errorf("de-duplicating reports: %w", err)
@@ -93,6 +92,21 @@ func (eng *Engine) persistAccountModActions(c *AccountContext) error {
 		}
 	}
 
+	if eng.Config.DryRun {
+		logDryRunActions(c.Logger, "account", intendedActions{
+			Labels:        newLabels,
+			RemovedLabels: rmdLabels,
+			Tags:          newTags,
+			Flags:         newFlags,
+			Reports:       newReports,
+			Takedown:      newTakedown,
+			// we don't escalate if there is a takedown
+			Escalate:    newEscalation && !newTakedown,
+			Acknowledge: newAcknowledge,
+		})
+		return nil
+	}
+
 	anyModActions := newTakedown || newEscalation || newAcknowledge || len(newLabels) > 0 || len(rmdLabels) > 0 || len(newTags) > 0 || len(newFlags) > 0 || len(newReports) > 0
 	if anyModActions && eng.Notifier != nil {
 		for _, srv := range dedupeStrings(c.effects.NotifyServices) {
@@ -112,14 +126,15 @@ func (eng *Engine) persistAccountModActions(c *AccountContext) error {
 	}
 
 	// if we can't actually talk to service, bail out early
-	if eng.OzoneClient == nil {
+	sink := eng.effectsSink()
+	if sink == nil {
 		if anyModActions {
 			c.Logger.Warn("not persisting actions, mod service client not configured")
 		}
 		return nil
 	}
 
-	xrpcc := eng.OzoneClient
+	subject := AccountSubject(c.Account.Identity.DID)
 
 	if len(newLabels) > 0 || len(rmdLabels) > 0 {
 		c.Logger.Info("updating account labels", "newLabels", newLabels, "rmdLabels", rmdLabels)
@@ -127,23 +142,7 @@ func (eng *Engine) persistAccountModActions(c *AccountContext) error {
 			// note: WithLabelValues is a prometheus label, not an atproto label
 			actionNewLabelCount.WithLabelValues("account", val).Inc()
 		}
-		comment := "[automod]: auto-labeling account"
-		_, err := toolsozone.ModerationEmitEvent(ctx, xrpcc, &toolsozone.ModerationEmitEvent_Input{
-			CreatedBy: xrpcc.Auth.Did,
-			Event: &toolsozone.ModerationEmitEvent_Input_Event{
-				ModerationDefs_ModEventLabel: &toolsozone.ModerationDefs_ModEventLabel{
-					CreateLabelVals: newLabels,
-					NegateLabelVals: rmdLabels,
-					Comment:         &comment,
-				},
-			},
-			Subject: &toolsozone.ModerationEmitEvent_Input_Subject{
-				AdminDefs_RepoRef: &comatproto.AdminDefs_RepoRef{
-					Did: c.Account.Identity.DID.String(),
-				},
-			},
-		})
-		if err != nil {
+		if err := emitLabels(ctx, sink, subject, newLabels, rmdLabels, c.effects.AccountLabelExpirations, "[automod]: auto-labeling account"); err != nil {
 			c.Logger.Error("failed to create account labels", "err", err)
 		}
 	}
@@ -154,57 +153,21 @@ func (eng *Engine) persistAccountModActions(c *AccountContext) error {
 			// note: WithLabelValues is a prometheus label, not an atproto label
 			actionNewTagCount.WithLabelValues("account", val).Inc()
 		}
-		comment := "[automod]: auto-tagging account"
-		_, err := toolsozone.ModerationEmitEvent(ctx, xrpcc, &toolsozone.ModerationEmitEvent_Input{
-			CreatedBy: xrpcc.Auth.Did,
-			Event: &toolsozone.ModerationEmitEvent_Input_Event{
-				ModerationDefs_ModEventTag: &toolsozone.ModerationDefs_ModEventTag{
-					Add:     newTags,
-					Remove:  []string{},
-					Comment: &comment,
-				},
-			},
-			Subject: &toolsozone.ModerationEmitEvent_Input_Subject{
-				AdminDefs_RepoRef: &comatproto.AdminDefs_RepoRef{
-					Did: c.Account.Identity.DID.String(),
-				},
-			},
-		})
-		if err != nil {
+		if err := sink.EmitTag(ctx, subject, newTags, "[automod]: auto-tagging account"); err != nil {
 			c.Logger.Error("failed to create account tags", "err", err)
 		}
 	}
 
 	// reports are additionally de-duped when persisting the action, so track with a flag
-	createdReports := false
-	for _, mr := range newReports {
-		created, err := eng.createReportIfFresh(ctx, xrpcc, c.Account.Identity.DID, mr)
-		if err != nil {
-			c.Logger.Error("failed to create account report", "err", err)
-		}
-		if created {
-			createdReports = true
-		}
+	createdReports, err := eng.createReportsIfFresh(ctx, sink, subject, newReports)
+	if err != nil {
+		c.Logger.Error("failed to create account report", "err", err)
 	}
 
 	if newTakedown {
 		c.Logger.Warn("account-takedown")
 		actionNewTakedownCount.WithLabelValues("account").Inc()
-		comment := "[automod]: auto account-takedown"
-		_, err := toolsozone.ModerationEmitEvent(ctx, xrpcc, &toolsozone.ModerationEmitEvent_Input{
-			CreatedBy: xrpcc.Auth.Did,
-			Event: &toolsozone.ModerationEmitEvent_Input_Event{
-				ModerationDefs_ModEventTakedown: &toolsozone.ModerationDefs_ModEventTakedown{
-					Comment: &comment,
-				},
-			},
-			Subject: &toolsozone.ModerationEmitEvent_Input_Subject{
-				AdminDefs_RepoRef: &comatproto.AdminDefs_RepoRef{
-					Did: c.Account.Identity.DID.String(),
-				},
-			},
-		})
-		if err != nil {
+		if err := sink.EmitTakedown(ctx, subject, nil, "[automod]: auto account-takedown"); err != nil {
 			c.Logger.Error("failed to execute account takedown", "err", err)
 		}
 
@@ -215,21 +178,7 @@ func (eng *Engine) persistAccountModActions(c *AccountContext) error {
 	if newEscalation {
 		c.Logger.Info("account-escalate")
 		actionNewEscalationCount.WithLabelValues("account").Inc()
-		comment := "[automod]: auto account-escalation"
-		_, err := toolsozone.ModerationEmitEvent(ctx, xrpcc, &toolsozone.ModerationEmitEvent_Input{
-			CreatedBy: xrpcc.Auth.Did,
-			Event: &toolsozone.ModerationEmitEvent_Input_Event{
-				ModerationDefs_ModEventEscalate: &toolsozone.ModerationDefs_ModEventEscalate{
-					Comment: &comment,
-				},
-			},
-			Subject: &toolsozone.ModerationEmitEvent_Input_Subject{
-				AdminDefs_RepoRef: &comatproto.AdminDefs_RepoRef{
-					Did: c.Account.Identity.DID.String(),
-				},
-			},
-		})
-		if err != nil {
+		if err := sink.EmitEscalation(ctx, subject, "[automod]: auto account-escalation"); err != nil {
 			c.Logger.Error("failed to execute account escalation", "err", err)
 		}
 	}
@@ -237,21 +186,7 @@ func (eng *Engine) persistAccountModActions(c *AccountContext) error {
 	if newAcknowledge {
 		c.Logger.Info("account-acknowledge")
 		actionNewAcknowledgeCount.WithLabelValues("account").Inc()
-		comment := "[automod]: auto account-acknowledge"
-		_, err := toolsozone.ModerationEmitEvent(ctx, xrpcc, &toolsozone.ModerationEmitEvent_Input{
-			CreatedBy: xrpcc.Auth.Did,
-			Event: &toolsozone.ModerationEmitEvent_Input_Event{
-				ModerationDefs_ModEventAcknowledge: &toolsozone.ModerationDefs_ModEventAcknowledge{
-					Comment: &comment,
-				},
-			},
-			Subject: &toolsozone.ModerationEmitEvent_Input_Subject{
-				AdminDefs_RepoRef: &comatproto.AdminDefs_RepoRef{
-					Did: c.Account.Identity.DID.String(),
-				},
-			},
-		})
-		if err != nil {
+		if err := sink.EmitAcknowledge(ctx, subject, "[automod]: auto account-acknowledge"); err != nil {
 			c.Logger.Error("failed to execute account acknowledge", "err", err)
 		}
 	}
@@ -328,7 +263,7 @@ func (eng *Engine) persistRecordModActions(c *RecordContext) error {
 		newFlags = dedupeFlagActions(newFlags, existingFlags)
 	}
 
-	// don't report the same record multiple times on the same day for the same reason. this is a quick check; we also query the mod service API just before creating the report.
+	// don't report the same record multiple times within the de-dupe window for the same reason. this is a quick check (see dedupeReportActions); we also query the mod service API just before creating the report.
 	partialReports, err := eng.dedupeReportActions(ctx, atURI, c.effects.RecordReports)
 	if err != nil {
 		return fmt.Errorf("de-duplicating reports: %w", err)
@@ -350,6 +285,20 @@ func (eng *Engine) persistRecordModActions(c *RecordContext) error {
 		return fmt.Errorf("circuit-breaking acknowledge: %w", err)
 	}
 
+	if eng.Config.DryRun {
+		logDryRunActions(c.Logger, "record", intendedActions{
+			Labels:        newLabels,
+			RemovedLabels: rmdLabels,
+			Tags:          newTags,
+			Flags:         newFlags,
+			Reports:       newReports,
+			Takedown:      newTakedown,
+			Escalate:      newEscalation && !newTakedown,
+			Acknowledge:   newAcknowledge,
+		})
+		return nil
+	}
+
 	if newEscalation || newAcknowledge || newTakedown || len(newLabels) > 0 || len(rmdLabels) > 0 || len(newTags) > 0 || len(newFlags) > 0 || len(newReports) > 0 {
 		if eng.Notifier != nil {
 			for _, srv := range dedupeStrings(c.effects.NotifyServices) {
@@ -374,7 +323,8 @@ func (eng *Engine) persistRecordModActions(c *RecordContext) error {
 		return nil
 	}
 
-	if eng.OzoneClient == nil {
+	sink := eng.effectsSink()
+	if sink == nil {
 		c.Logger.Warn("not persisting actions because mod service client not configured")
 		return nil
 	}
@@ -383,34 +333,15 @@ func (eng *Engine) persistRecordModActions(c *RecordContext) error {
 		c.Logger.Warn("skipping record actions because CID is nil, can't construct strong ref")
 		return nil
 	}
-	cid := *c.RecordOp.CID
-	strongRef := comatproto.RepoStrongRef{
-		Cid: cid.String(),
-		Uri: atURI,
-	}
+	subject := RecordSubject(c.RecordOp.ATURI(), c.RecordOp.CID)
 
-	xrpcc := eng.OzoneClient
 	if len(newLabels) > 0 || len(rmdLabels) > 0 {
 		c.Logger.Info("updating record labels", "newLabels", newLabels, "rmdLabels", rmdLabels)
 		for _, val := range newLabels {
 			// note: WithLabelValues is a prometheus label, not an atproto label
 			actionNewLabelCount.WithLabelValues("record", val).Inc()
 		}
-		comment := "[automod]: auto-labeling record"
-		_, err := toolsozone.ModerationEmitEvent(ctx, xrpcc, &toolsozone.ModerationEmitEvent_Input{
-			CreatedBy: xrpcc.Auth.Did,
-			Event: &toolsozone.ModerationEmitEvent_Input_Event{
-				ModerationDefs_ModEventLabel: &toolsozone.ModerationDefs_ModEventLabel{
-					CreateLabelVals: newLabels,
-					NegateLabelVals: rmdLabels,
-					Comment:         &comment,
-				},
-			},
-			Subject: &toolsozone.ModerationEmitEvent_Input_Subject{
-				RepoStrongRef: &strongRef,
-			},
-		})
-		if err != nil {
+		if err := emitLabels(ctx, sink, subject, newLabels, rmdLabels, c.effects.RecordLabelExpirations, "[automod]: auto-labeling record"); err != nil {
 			c.Logger.Error("failed to create record label", "err", err)
 		}
 	}
@@ -421,49 +352,19 @@ func (eng *Engine) persistRecordModActions(c *RecordContext) error {
 			// note: WithLabelValues is a prometheus label, not an atproto label
 			actionNewTagCount.WithLabelValues("record", val).Inc()
 		}
-		comment := "[automod]: auto-tagging record"
-		_, err := toolsozone.ModerationEmitEvent(ctx, xrpcc, &toolsozone.ModerationEmitEvent_Input{
-			CreatedBy: xrpcc.Auth.Did,
-			Event: &toolsozone.ModerationEmitEvent_Input_Event{
-				ModerationDefs_ModEventTag: &toolsozone.ModerationDefs_ModEventTag{
-					Add:     newTags,
-					Remove:  []string{},
-					Comment: &comment,
-				},
-			},
-			Subject: &toolsozone.ModerationEmitEvent_Input_Subject{
-				RepoStrongRef: &strongRef,
-			},
-		})
-		if err != nil {
+		if err := sink.EmitTag(ctx, subject, newTags, "[automod]: auto-tagging record"); err != nil {
 			c.Logger.Error("failed to create record tag", "err", err)
 		}
 	}
 
-	for _, mr := range newReports {
-		_, err := eng.createRecordReportIfFresh(ctx, xrpcc, c.RecordOp.ATURI(), c.RecordOp.CID, mr)
-		if err != nil {
-			c.Logger.Error("failed to create record report", "err", err)
-		}
+	if _, err := eng.createReportsIfFresh(ctx, sink, subject, newReports); err != nil {
+		c.Logger.Error("failed to create record report", "err", err)
 	}
 
 	if newTakedown {
 		c.Logger.Warn("record-takedown")
 		actionNewTakedownCount.WithLabelValues("record").Inc()
-		comment := "[automod]: automated record-takedown"
-		_, err := toolsozone.ModerationEmitEvent(ctx, xrpcc, &toolsozone.ModerationEmitEvent_Input{
-			CreatedBy: xrpcc.Auth.Did,
-			Event: &toolsozone.ModerationEmitEvent_Input_Event{
-				ModerationDefs_ModEventTakedown: &toolsozone.ModerationDefs_ModEventTakedown{
-					Comment: &comment,
-				},
-			},
-			Subject: &toolsozone.ModerationEmitEvent_Input_Subject{
-				RepoStrongRef: &strongRef,
-			},
-			SubjectBlobCids: dedupeStrings(c.effects.BlobTakedowns),
-		})
-		if err != nil {
+		if err := sink.EmitTakedown(ctx, subject, dedupeStrings(c.effects.BlobTakedowns), "[automod]: automated record-takedown"); err != nil {
 			c.Logger.Error("failed to execute record takedown", "err", err)
 		}
 
@@ -474,19 +375,7 @@ func (eng *Engine) persistRecordModActions(c *RecordContext) error {
 	if newEscalation {
 		c.Logger.Warn("record-escalation")
 		actionNewEscalationCount.WithLabelValues("record").Inc()
-		comment := "[automod]: automated record-escalation"
-		_, err := toolsozone.ModerationEmitEvent(ctx, xrpcc, &toolsozone.ModerationEmitEvent_Input{
-			CreatedBy: xrpcc.Auth.Did,
-			Event: &toolsozone.ModerationEmitEvent_Input_Event{
-				ModerationDefs_ModEventEscalate: &toolsozone.ModerationDefs_ModEventEscalate{
-					Comment: &comment,
-				},
-			},
-			Subject: &toolsozone.ModerationEmitEvent_Input_Subject{
-				RepoStrongRef: &strongRef,
-			},
-		})
-		if err != nil {
+		if err := sink.EmitEscalation(ctx, subject, "[automod]: automated record-escalation"); err != nil {
 			c.Logger.Error("failed to execute record escalation", "err", err)
 		}
 	}
@@ -494,19 +383,7 @@ func (eng *Engine) persistRecordModActions(c *RecordContext) error {
 	if newAcknowledge {
 		c.Logger.Warn("record-acknowledge")
 		actionNewAcknowledgeCount.WithLabelValues("record").Inc()
-		comment := "[automod]: automated record-acknowledge"
-		_, err := toolsozone.ModerationEmitEvent(ctx, xrpcc, &toolsozone.ModerationEmitEvent_Input{
-			CreatedBy: xrpcc.Auth.Did,
-			Event: &toolsozone.ModerationEmitEvent_Input_Event{
-				ModerationDefs_ModEventAcknowledge: &toolsozone.ModerationDefs_ModEventAcknowledge{
-					Comment: &comment,
-				},
-			},
-			Subject: &toolsozone.ModerationEmitEvent_Input_Subject{
-				RepoStrongRef: &strongRef,
-			},
-		})
-		if err != nil {
+		if err := sink.EmitAcknowledge(ctx, subject, "[automod]: automated record-acknowledge"); err != nil {
 			c.Logger.Error("failed to execute record acknowledge", "err", err)
 		}
 	}
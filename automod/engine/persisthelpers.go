@@ -2,14 +2,13 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
 	"time"
 
-	comatproto "github.com/bluesky-social/indigo/api/atproto"
-	toolsozone "github.com/bluesky-social/indigo/api/ozone"
-	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/bluesky-social/indigo/automod/countstore"
-	"github.com/bluesky-social/indigo/xrpc"
 )
 
 func dedupeLabelActions(labels, existing, existingNegated []string) []string {
@@ -69,20 +68,70 @@ func dedupeFlagActions(flags, existing []string) []string {
 	return newFlags
 }
 
+// Emits label changes to the sink: permanent additions and all removals as a single event, then one event for each distinct expiration of temporary labels (the moderation service applies a single duration to all labels in an event). Temporary labels which have already expired are skipped.
+func emitLabels(ctx context.Context, sink EffectsSink, subject ModSubject, add, remove []string, expirations map[string]time.Time, comment string) error {
+	permanent := []string{}
+	temporary := []string{}
+	now := time.Now()
+	for _, val := range add {
+		exp, ok := expirations[val]
+		if !ok {
+			permanent = append(permanent, val)
+		} else if exp.After(now) {
+			temporary = append(temporary, val)
+		}
+	}
+
+	var errs []error
+	if len(permanent) > 0 || len(remove) > 0 {
+		if err := sink.EmitLabel(ctx, subject, permanent, remove, nil, comment); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	sort.SliceStable(temporary, func(i, j int) bool {
+		return expirations[temporary[i]].Before(expirations[temporary[j]])
+	})
+	for len(temporary) > 0 {
+		exp := expirations[temporary[0]]
+		n := 1
+		for n < len(temporary) && expirations[temporary[n]].Equal(exp) {
+			n++
+		}
+		if err := sink.EmitLabel(ctx, subject, temporary[:n], []string{}, &exp, comment); err != nil {
+			errs = append(errs, err)
+		}
+		temporary = temporary[n:]
+	}
+	return errors.Join(errs...)
+}
+
+// Counter name used for report de-duplication, by reasonType
+func reportDedupeCounter(reasonType string) string {
+	return "automod-account-report-" + ReasonShortName(reasonType)
+}
+
+// Report de-duplication happens in two stages, both using the same per-reasonType window from EngineConfig (ReportDupePeriods, falling back to ReportDupePeriod):
+//
+//   - this counter-based check runs first, before the circuit breaker. It is cheap, and shared by all engine instances using the same counter store. The window is rounded up to a calendar-aligned hour, day, or week counter bucket (see countstore.PeriodForWindow), so it is approximate: a repeat report just after a bucket boundary is not caught here.
+//   - createReportIfFresh() then checks the moderation service's recent reports (by this automod account) against the exact rolling window. This catches repeats across bucket boundaries, and reports persisted before the counters existed (eg, a new counter store).
+//
+// A report is only emitted if it passes both checks.
 func (eng *Engine) dedupeReportActions(ctx context.Context, subject string, reports []ModReport) ([]ModReport, error) {
 	newReports := []ModReport{}
 	for _, r := range reports {
-		counterName := "automod-account-report-" + ReasonShortName(r.ReasonType)
-		existing, err := eng.Counters.GetCount(ctx, counterName, subject, countstore.PeriodDay)
+		counterName := reportDedupeCounter(r.ReasonType)
+		existing, err := eng.Counters.GetCount(ctx, counterName, subject, countstore.PeriodForWindow(eng.Config.reportDupePeriod(r.ReasonType)))
 		if err != nil {
 			return nil, fmt.Errorf("checking report de-dupe counts: %w", err)
 		}
 		if existing > 0 {
 			eng.Logger.Debug("skipping account report due to counter", "existing", existing, "reason", ReasonShortName(r.ReasonType))
 		} else {
-			err = eng.Counters.Increment(ctx, counterName, subject)
-			if err != nil {
-				return nil, fmt.Errorf("incrementing report de-dupe count: %w", err)
+			if !eng.Config.DryRun {
+				err = eng.Counters.Increment(ctx, counterName, subject)
+				if err != nil {
+					return nil, fmt.Errorf("incrementing report de-dupe count: %w", err)
+				}
 			}
 			newReports = append(newReports, r)
 		}
@@ -90,235 +139,129 @@ func (eng *Engine) dedupeReportActions(ctx context.Context, subject string, repo
 	return newReports, nil
 }
 
-func (eng *Engine) circuitBreakReports(ctx context.Context, reports []ModReport) ([]ModReport, error) {
-	if len(reports) == 0 {
-		return []ModReport{}, nil
-	}
-	c, err := eng.Counters.GetCount(ctx, "automod-quota", "report", countstore.PeriodDay)
-	if err != nil {
-		return nil, fmt.Errorf("checking report action quota: %w", err)
-	}
+// Moderation actions which would be persisted for a single subject; used in dry-run mode
+type intendedActions struct {
+	Labels        []string
+	RemovedLabels []string
+	Tags          []string
+	Flags         []string
+	Reports       []ModReport
+	Takedown      bool
+	Escalate      bool
+	Acknowledge   bool
+}
 
-	quotaModReportDay := eng.Config.QuotaModReportDay
-	if quotaModReportDay == 0 {
-		quotaModReportDay = 10000
-	}
-	if c >= quotaModReportDay {
-		eng.Logger.Warn("CIRCUIT BREAKER: automod reports")
-		return []ModReport{}, nil
-	}
-	err = eng.Counters.Increment(ctx, "automod-quota", "report")
-	if err != nil {
-		return nil, fmt.Errorf("incrementing report action quota: %w", err)
-	}
-	return reports, nil
+func (a intendedActions) empty() bool {
+	return !a.Takedown && !a.Escalate && !a.Acknowledge && len(a.Labels) == 0 && len(a.RemovedLabels) == 0 && len(a.Tags) == 0 && len(a.Flags) == 0 && len(a.Reports) == 0
 }
 
-func (eng *Engine) circuitBreakTakedown(ctx context.Context, takedown bool) (bool, error) {
-	if !takedown {
-		return false, nil
-	}
-	c, err := eng.Counters.GetCount(ctx, "automod-quota", "takedown", countstore.PeriodDay)
-	if err != nil {
-		return false, fmt.Errorf("checking takedown action quota: %w", err)
-	}
-	quotaModTakedownDay := eng.Config.QuotaModTakedownDay
-	if quotaModTakedownDay == 0 {
-		quotaModTakedownDay = 200
+// Logs (and counts in metrics) the actions which would have been persisted for a subject, if not in dry-run mode
+func logDryRunActions(logger *slog.Logger, subjectType string, a intendedActions) {
+	if a.empty() {
+		return
 	}
-	if c >= quotaModTakedownDay {
-		eng.Logger.Warn("CIRCUIT BREAKER: automod takedowns")
-		return false, nil
-	}
-	err = eng.Counters.Increment(ctx, "automod-quota", "takedown")
-	if err != nil {
-		return false, fmt.Errorf("incrementing takedown action quota: %w", err)
+	reasons := make([]string, len(a.Reports))
+	for i, mr := range a.Reports {
+		reasons[i] = mr.ReasonType
 	}
-	return takedown, nil
-}
+	logger.Info("dry-run: not persisting mod actions", "subjectType", subjectType, "labels", a.Labels, "removedLabels", a.RemovedLabels, "tags", a.Tags, "flags", a.Flags, "reports", reasons, "takedown", a.Takedown, "escalate", a.Escalate, "acknowledge", a.Acknowledge)
 
-// Combined circuit breaker for miscellaneous mod actions like: escalate, acknowledge
-func (eng *Engine) circuitBreakModAction(ctx context.Context, action bool) (bool, error) {
-	if !action {
-		return false, nil
+	for _, kv := range []struct {
+		action string
+		n      int
+	}{
+		{"label", len(a.Labels)},
+		{"unlabel", len(a.RemovedLabels)},
+		{"tag", len(a.Tags)},
+		{"flag", len(a.Flags)},
+		{"report", len(a.Reports)},
+	} {
+		if kv.n > 0 {
+			actionDryRunCount.WithLabelValues(subjectType, kv.action).Add(float64(kv.n))
+		}
 	}
-	c, err := eng.Counters.GetCount(ctx, "automod-quota", "mod-action", countstore.PeriodDay)
-	if err != nil {
-		return false, fmt.Errorf("checking mod action quota: %w", err)
+	if a.Takedown {
+		actionDryRunCount.WithLabelValues(subjectType, "takedown").Inc()
 	}
-	quotaModActionDay := eng.Config.QuotaModActionDay
-	if quotaModActionDay == 0 {
-		quotaModActionDay = 2000
+	if a.Escalate {
+		actionDryRunCount.WithLabelValues(subjectType, "escalate").Inc()
 	}
-	if c >= quotaModActionDay {
-		eng.Logger.Warn("CIRCUIT BREAKER: automod action")
-		return false, nil
+	if a.Acknowledge {
+		actionDryRunCount.WithLabelValues(subjectType, "acknowledge").Inc()
 	}
-	err = eng.Counters.Increment(ctx, "automod-quota", "mod-action")
-	if err != nil {
-		return false, fmt.Errorf("incrementing mod action quota: %w", err)
+}
+
+// max number of prior report events fetched when de-duplicating a batch of reports against a single subject
+const reportDedupeBatchLimit = 100
+
+// Checks a report against the output of EffectsSink.RecentReports(), using the de-dupe window for the report's reasonType
+func (eng *Engine) reportIsFresh(recent map[string]time.Time, mr ModReport) bool {
+	dupePeriod := eng.Config.reportDupePeriod(mr.ReasonType)
+	for _, reasonType := range []string{mr.ReasonType, ""} {
+		if t, ok := recent[reasonType]; ok && time.Since(t) <= dupePeriod {
+			return false
+		}
 	}
-	return action, nil
+	return true
+}
+
+// not called in dry-run mode: persisting returns before reports are created, after logging them with logDryRunActions()
+func (eng *Engine) emitReport(ctx context.Context, sink EffectsSink, subject ModSubject, mr ModReport) error {
+	eng.Logger.Info("reporting "+subject.kind(), "reasonType", mr.ReasonType, "comment", mr.Comment)
+	actionNewReportCount.WithLabelValues(subject.kind()).Inc()
+	return sink.EmitReport(ctx, subject, mr.ReasonType, "[automod] "+mr.Comment)
 }
 
 // Creates a moderation report, but checks first if there was a similar recent one, and skips if so.
 //
 // Returns a bool indicating if a new report was created.
-func (eng *Engine) createReportIfFresh(ctx context.Context, xrpcc *xrpc.Client, did syntax.DID, mr ModReport) (bool, error) {
-	// before creating a report, query to see if automod has already reported this account in the past week for the same reason
-	// NOTE: this is running in an inner loop (if there are multiple reports), which is a bit inefficient, but seems acceptable
-
-	resp, err := toolsozone.ModerationQueryEvents(
-		ctx,
-		xrpcc,
-		nil,            // addedLabels []string
-		nil,            // addedTags []string
-		nil,            // collections []string
-		"",             // comment string
-		"",             // createdAfter string
-		"",             // createdBefore string
-		xrpcc.Auth.Did, // createdBy string
-		"",             // cursor string
-		false,          // hasComment bool
-		false,          // includeAllUserRecords bool
-		5,              // limit int64
-		nil,            // policies []string
-		nil,            // removedLabels []string
-		nil,            // removedTags []string
-		nil,            // reportTypes []string
-		"",             // sortDirection string
-		did.String(),   // subject string
-		"",             // subjectType string
-		[]string{"tools.ozone.moderation.defs#modEventReport"}, // types []string
-	)
-
+func (eng *Engine) createReportIfFresh(ctx context.Context, sink EffectsSink, subject ModSubject, mr ModReport) (bool, error) {
+	// before creating a report, query to see if automod has already reported this subject recently for the same reason
+	recent, err := sink.RecentReports(ctx, subject, 5)
 	if err != nil {
 		return false, err
 	}
-	for _, modEvt := range resp.Events {
-		// defensively ensure that our query params worked correctly
-		if modEvt.Event.ModerationDefs_ModEventReport == nil || modEvt.CreatedBy != xrpcc.Auth.Did || modEvt.Subject.AdminDefs_RepoRef == nil || modEvt.Subject.AdminDefs_RepoRef.Did != did.String() || (modEvt.Event.ModerationDefs_ModEventReport.ReportType != nil && *modEvt.Event.ModerationDefs_ModEventReport.ReportType != mr.ReasonType) {
-			continue
-		}
-		// igonre if older
-		created, err := syntax.ParseDatetime(modEvt.CreatedAt)
-		if err != nil {
-			return false, err
-		}
-		reportDupePeriod := eng.Config.ReportDupePeriod
-		if reportDupePeriod == 0 {
-			reportDupePeriod = 1 * 24 * time.Hour
-		}
-		if time.Since(created.Time()) > reportDupePeriod {
-			continue
-		}
-
+	if !eng.reportIsFresh(recent, mr) {
 		// there is a recent report which is similar to this one
-		eng.Logger.Info("skipping duplicate account report due to API check")
+		eng.Logger.Info("skipping duplicate report due to API check", "subjectType", subject.kind())
 		return false, nil
 	}
-
-	eng.Logger.Info("reporting account", "reasonType", mr.ReasonType, "comment", mr.Comment)
-	actionNewReportCount.WithLabelValues("account").Inc()
-	comment := "[automod] " + mr.Comment
-	_, err = toolsozone.ModerationEmitEvent(ctx, xrpcc, &toolsozone.ModerationEmitEvent_Input{
-		CreatedBy: xrpcc.Auth.Did,
-		Event: &toolsozone.ModerationEmitEvent_Input_Event{
-			ModerationDefs_ModEventReport: &toolsozone.ModerationDefs_ModEventReport{
-				Comment:    &comment,
-				ReportType: &mr.ReasonType,
-			},
-		},
-		Subject: &toolsozone.ModerationEmitEvent_Input_Subject{
-			AdminDefs_RepoRef: &comatproto.AdminDefs_RepoRef{
-				Did: did.String(),
-			},
-		},
-	})
-	if err != nil {
+	if err := eng.emitReport(ctx, sink, subject, mr); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-// Create a moderation report, but checks first if there was a similar recent one, and skips if so.
+// Batch version of createReportIfFresh(), for multiple reports against the same subject.
 //
-// Returns a bool indicating if a new report was created.
+// Recent reports are fetched once for all candidates, instead of once per report. Reports within the batch are also de-duplicated against each other. Failure to emit one report does not prevent the others from being attempted.
 //
-// TODO: merge this with createReportIfFresh()
-func (eng *Engine) createRecordReportIfFresh(ctx context.Context, xrpcc *xrpc.Client, uri syntax.ATURI, cid *syntax.CID, mr ModReport) (bool, error) {
-	// before creating a report, query to see if automod has already reported this account in the past week for the same reason
-	// NOTE: this is running in an inner loop (if there are multiple reports), which is a bit inefficient, but seems acceptable
+// Returns a bool indicating if any new report was created.
+func (eng *Engine) createReportsIfFresh(ctx context.Context, sink EffectsSink, subject ModSubject, reports []ModReport) (bool, error) {
+	if len(reports) == 0 {
+		return false, nil
+	}
+	if len(reports) == 1 {
+		return eng.createReportIfFresh(ctx, sink, subject, reports[0])
+	}
 
-	resp, err := toolsozone.ModerationQueryEvents(
-		ctx,
-		xrpcc,
-		nil,            // addedLabels []string
-		nil,            // addedTags []string
-		nil,            // collections []string
-		"",             // comment string
-		"",             // createdAfter string
-		"",             // createdBefore string
-		xrpcc.Auth.Did, // createdBy string
-		"",             // cursor string
-		false,          // hasComment bool
-		false,          // includeAllUserRecords bool
-		5,              // limit int64
-		nil,            // policies []string
-		nil,            // removedLabels []string
-		nil,            // removedTags []string
-		nil,            // reportTypes []string
-		"",             // sortDirection string
-		uri.String(),   // subject string
-		"",             // subjectType string
-		[]string{"tools.ozone.moderation.defs#modEventReport"}, // types []string
-	)
+	recent, err := sink.RecentReports(ctx, subject, reportDedupeBatchLimit)
 	if err != nil {
 		return false, err
 	}
-	for _, modEvt := range resp.Events {
-		// defensively ensure that our query params worked correctly
-		if modEvt.Event.ModerationDefs_ModEventReport == nil || modEvt.CreatedBy != xrpcc.Auth.Did || modEvt.Subject.RepoStrongRef == nil || modEvt.Subject.RepoStrongRef.Uri != uri.String() || (modEvt.Event.ModerationDefs_ModEventReport.ReportType != nil && *modEvt.Event.ModerationDefs_ModEventReport.ReportType != mr.ReasonType) {
+	created := false
+	var errs []error
+	for _, mr := range reports {
+		if !eng.reportIsFresh(recent, mr) {
+			eng.Logger.Info("skipping duplicate report due to API check", "subjectType", subject.kind(), "reasonType", mr.ReasonType)
 			continue
 		}
-		// igonre if older
-		created, err := syntax.ParseDatetime(modEvt.CreatedAt)
-		if err != nil {
-			return false, err
-		}
-		reportDupePeriod := eng.Config.ReportDupePeriod
-		if reportDupePeriod == 0 {
-			reportDupePeriod = 1 * 24 * time.Hour
-		}
-		if time.Since(created.Time()) > reportDupePeriod {
+		if err := eng.emitReport(ctx, sink, subject, mr); err != nil {
+			errs = append(errs, err)
 			continue
 		}
-
-		// there is a recent report which is similar to this one
-		eng.Logger.Info("skipping duplicate account report due to API check")
-		return false, nil
+		recent[mr.ReasonType] = time.Now()
+		created = true
 	}
-
-	eng.Logger.Info("reporting record", "reasonType", mr.ReasonType, "comment", mr.Comment)
-	actionNewReportCount.WithLabelValues("record").Inc()
-	comment := "[automod] " + mr.Comment
-	_, err = toolsozone.ModerationEmitEvent(ctx, xrpcc, &toolsozone.ModerationEmitEvent_Input{
-		CreatedBy: xrpcc.Auth.Did,
-		Event: &toolsozone.ModerationEmitEvent_Input_Event{
-			ModerationDefs_ModEventReport: &toolsozone.ModerationDefs_ModEventReport{
-				Comment:    &comment,
-				ReportType: &mr.ReasonType,
-			},
-		},
-		Subject: &toolsozone.ModerationEmitEvent_Input_Subject{
-			RepoStrongRef: &comatproto.RepoStrongRef{
-				Uri: uri.String(),
-				Cid: cid.String(),
-			},
-		},
-	})
-	if err != nil {
-		return false, err
-	}
-	return true, nil
+	return created, errors.Join(errs...)
 }
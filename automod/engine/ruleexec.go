@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Returned (wrapped) when an individual rule does not complete within [EngineConfig.RuleTimeout]
+var ErrRuleTimeout = errors.New("automod rule timed out")
+
+// Returned (wrapped) when an individual rule panics. The panic is recovered, and other rules continue to run.
+var ErrRulePanic = errors.New("automod rule panicked")
+
+// implemented by all the rule context types, via the embedded BaseContext
+func (c *BaseContext) baseContext() *BaseContext {
+	return c
+}
+
+// Returns a short name for a rule function, like "rules.BadHashtagsPostRule", for logs and metric labels
+func ruleName(rule any) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(rule).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// Invokes a single rule with panic recovery and, if configured, a per-rule timeout. 'rule' is the original rule function (used for naming), and 'f' invokes it with the given context.
+//
+// With a timeout, the rule runs in a separate goroutine against its own copy of the context, with separate Effects, and a Ctx which is cancelled at the deadline. Only if the rule completes in time are its effects (and any Err) merged back in to the original context. If the deadline passes first, this returns without waiting for the rule to finish, and anything the rule records is discarded. Rules may be run concurrently (eg, blob rules), so the copy and merge are done while holding the shared Effects lock.
+func callRule[C any, P interface {
+	*C
+	baseContext() *BaseContext
+}](c P, rule any, f func(P) error) error {
+	base := c.baseContext()
+	timeout := base.engine.Config.RuleTimeout
+	if timeout <= 0 {
+		return guardRule(base, rule, func() error { return f(c) })
+	}
+
+	ctx, cancel := context.WithTimeout(base.Ctx, timeout)
+	defer cancel()
+	rc := P(new(C))
+	base.effects.mu.Lock()
+	*rc = *c
+	base.effects.mu.Unlock()
+	rcBase := rc.baseContext()
+	rcBase.Ctx = ctx
+	rcBase.Err = nil
+	rcBase.effects = &Effects{}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- guardRule(base, rule, func() error { return f(rc) })
+	}()
+	select {
+	case err := <-done:
+		base.effects.mu.Lock()
+		base.effects.mergeLocked(rcBase.effects)
+		if base.Err == nil {
+			base.Err = rcBase.Err
+		}
+		base.effects.mu.Unlock()
+		return err
+	case <-ctx.Done():
+		name := ruleName(rule)
+		if base.Ctx.Err() != nil {
+			// the overall event timed out (or was cancelled); not specific to this rule
+			return fmt.Errorf("rule %s: %w", name, base.Ctx.Err())
+		}
+		ruleTimeoutCount.WithLabelValues(name).Inc()
+		return fmt.Errorf("%w: %s (after %s)", ErrRuleTimeout, name, timeout)
+	}
+}
+
+// Runs 'f', recovering any panic as an error
+func guardRule(base *BaseContext, rule any, f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			name := ruleName(rule)
+			rulePanicCount.WithLabelValues(name).Inc()
+			base.Logger.Error("automod rule panic", "rule", name, "err", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("%w: %s: %v", ErrRulePanic, name, r)
+		}
+	}()
+	return f()
+}
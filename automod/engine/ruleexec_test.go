@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func panickyRule(c *RecordContext) error {
+	var m map[string]bool
+	m["boom"] = true
+	return nil
+}
+
+func labelingRule(c *RecordContext) error {
+	c.AddRecordLabel("checked")
+	return nil
+}
+
+func TestRuleIsolation(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	eng := EngineTestFixture()
+	am := AccountMeta{Identity: &identity.Identity{DID: syntax.DID("did:plc:abc111")}}
+	op := RecordOp{
+		Action:     DeleteOp,
+		DID:        am.Identity.DID,
+		Collection: syntax.NSID("app.bsky.feed.post"),
+		RecordKey:  syntax.RecordKey("abc123"),
+	}
+
+	// a panicking rule is recovered and counted, and later rules still run
+	eng.Rules = RuleSet{RecordDeleteRules: []RecordRuleFunc{panickyRule, labelingRule}}
+	panics := rulePanicCount.WithLabelValues("engine.panickyRule")
+	before := testutil.ToFloat64(panics)
+	rc := NewRecordContext(ctx, &eng, am, op)
+	assert.NoError(eng.Rules.CallRecordDeleteRules(&rc))
+	assert.Equal(before+1, testutil.ToFloat64(panics))
+	assert.Equal([]string{"checked"}, ExtractEffects(&rc.BaseContext).RecordLabels)
+
+	// same with a per-rule timeout, when the rule runs in a separate goroutine
+	eng.Config.RuleTimeout = time.Second
+	rc = NewRecordContext(ctx, &eng, am, op)
+	assert.NoError(eng.Rules.CallRecordDeleteRules(&rc))
+	assert.Equal(before+2, testutil.ToFloat64(panics))
+	assert.Equal([]string{"checked"}, ExtractEffects(&rc.BaseContext).RecordLabels)
+
+	// a slow rule is cancelled and counted, without blocking later rules
+	eng.Config.RuleTimeout = 50 * time.Millisecond
+	cancelled := make(chan bool, 1)
+	slowRule := func(c *RecordContext) error {
+		select {
+		case <-c.Ctx.Done():
+			cancelled <- true
+		case <-time.After(10 * time.Second):
+			cancelled <- false
+		}
+		return nil
+	}
+	eng.Rules = RuleSet{RecordDeleteRules: []RecordRuleFunc{slowRule, labelingRule}}
+	timeouts := ruleTimeoutCount.WithLabelValues(ruleName(slowRule))
+	before = testutil.ToFloat64(timeouts)
+	rc = NewRecordContext(ctx, &eng, am, op)
+	start := time.Now()
+	assert.NoError(eng.Rules.CallRecordDeleteRules(&rc))
+	assert.Less(time.Since(start), 5*time.Second)
+	assert.Equal(before+1, testutil.ToFloat64(timeouts))
+	assert.Equal([]string{"checked"}, ExtractEffects(&rc.BaseContext).RecordLabels)
+	assert.True(<-cancelled)
+
+	// errors recorded on the rule's copy of the context are propagated
+	eng.Rules = RuleSet{RecordDeleteRules: []RecordRuleFunc{func(c *RecordContext) error {
+		c.Err = ErrRulePanic
+		return nil
+	}}}
+	rc = NewRecordContext(ctx, &eng, am, op)
+	assert.NoError(eng.Rules.CallRecordDeleteRules(&rc))
+	assert.ErrorIs(rc.Err, ErrRulePanic)
+
+	// errors are returned from callRule directly
+	err := callRule(&rc, panickyRule, panickyRule)
+	assert.ErrorIs(err, ErrRulePanic)
+	err = callRule(&rc, slowRule, slowRule)
+	assert.ErrorIs(err, ErrRuleTimeout)
+	<-cancelled
+}
+
+func TestBlobRulesTimeout(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	eng := EngineTestFixture()
+	eng.Config.RuleTimeout = 100 * time.Millisecond
+	am := AccountMeta{Identity: &identity.Identity{DID: syntax.DID("did:plc:abc111")}}
+	op := RecordOp{
+		Action:     CreateOp,
+		DID:        am.Identity.DID,
+		Collection: syntax.NSID("app.bsky.feed.post"),
+		RecordKey:  syntax.RecordKey("abc123"),
+	}
+
+	// several blob rules run concurrently, each against its own copy of the context
+	finished := make(chan struct{})
+	var rules []BlobRuleFunc
+	for _, label := range []string{"one", "two", "three", "four"} {
+		rules = append(rules, func(c *RecordContext, blob lexutil.LexBlob, data []byte) error {
+			c.AddRecordLabel(label)
+			c.AddRecordFlag("blob-checked")
+			c.Increment("blob-rule", label)
+			return nil
+		})
+	}
+	rules = append(rules, func(c *RecordContext, blob lexutil.LexBlob, data []byte) error {
+		c.Err = ErrRulePanic
+		return nil
+	})
+	// the effects of a rule which times out are discarded, even if it keeps running
+	rules = append(rules, func(c *RecordContext, blob lexutil.LexBlob, data []byte) error {
+		<-c.Ctx.Done()
+		c.AddRecordLabel("late")
+		c.TakedownRecord()
+		close(finished)
+		return nil
+	})
+	rs := RuleSet{BlobRules: rules}
+
+	rc := NewRecordContext(ctx, &eng, am, op)
+	err := rs.processBlob(&rc, lexutil.LexBlob{MimeType: "image/png"}, []byte{0x01})
+	assert.ErrorIs(err, ErrRuleTimeout)
+	<-finished
+
+	eff := ExtractEffects(&rc.BaseContext)
+	assert.ElementsMatch([]string{"one", "two", "three", "four"}, eff.RecordLabels)
+	assert.Equal([]string{"blob-checked"}, eff.RecordFlags)
+	assert.Equal(4, len(eff.CounterIncrements))
+	assert.False(eff.RecordTakedown)
+	assert.ErrorIs(rc.Err, ErrRulePanic)
+}
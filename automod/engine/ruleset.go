@@ -22,10 +22,12 @@ type RuleSet struct {
 }
 
 // Executes all the various record-related rules. Only dispatches execution, does no other de-dupe or pre/post processing.
+//
+// Each rule is run with panic recovery and the per-rule timeout (see [EngineConfig.RuleTimeout]); a failing rule is logged, and does not prevent other rules from running. The same is true of the other Call*Rules methods.
 func (r *RuleSet) CallRecordRules(c *RecordContext) error {
 	// first the generic rules
 	for _, f := range r.RecordRules {
-		err := f(c)
+		err := callRule(c, f, f)
 		if err != nil {
 			c.Logger.Error("record rule execution failed", "err", err)
 		}
@@ -38,7 +40,7 @@ func (r *RuleSet) CallRecordRules(c *RecordContext) error {
 			return fmt.Errorf("failed to parse app.bsky.feed.post record: %v", err)
 		}
 		for _, f := range r.PostRules {
-			err := f(c, &post)
+			err := callRule(c, f, func(c *RecordContext) error { return f(c, &post) })
 			if err != nil {
 				c.Logger.Error("post rule execution failed", "err", err)
 			}
@@ -49,7 +51,7 @@ func (r *RuleSet) CallRecordRules(c *RecordContext) error {
 			return fmt.Errorf("failed to parse app.bsky.actor.profile record: %v", err)
 		}
 		for _, f := range r.ProfileRules {
-			err := f(c, &profile)
+			err := callRule(c, f, func(c *RecordContext) error { return f(c, &profile) })
 			if err != nil {
 				c.Logger.Error("profile rule execution failed", "err", err)
 			}
@@ -70,7 +72,7 @@ func (r *RuleSet) CallRecordRules(c *RecordContext) error {
 // NOTE: this will probably be removed and merged in to `CallRecordRules`
 func (r *RuleSet) CallRecordDeleteRules(c *RecordContext) error {
 	for _, f := range r.RecordDeleteRules {
-		err := f(c)
+		err := callRule(c, f, f)
 		if err != nil {
 			c.Logger.Error("record delete rule execution failed", "err", err)
 		}
@@ -81,7 +83,7 @@ func (r *RuleSet) CallRecordDeleteRules(c *RecordContext) error {
 // Executes rules for identity update events.
 func (r *RuleSet) CallIdentityRules(c *AccountContext) error {
 	for _, f := range r.IdentityRules {
-		err := f(c)
+		err := callRule(c, f, f)
 		if err != nil {
 			c.Logger.Error("identity rule execution failed", "err", err)
 		}
@@ -92,7 +94,7 @@ func (r *RuleSet) CallIdentityRules(c *AccountContext) error {
 // Executes rules for account update events.
 func (r *RuleSet) CallAccountRules(c *AccountContext) error {
 	for _, f := range r.AccountRules {
-		err := f(c)
+		err := callRule(c, f, f)
 		if err != nil {
 			c.Logger.Error("account rule execution failed", "err", err)
 		}
@@ -102,7 +104,7 @@ func (r *RuleSet) CallAccountRules(c *AccountContext) error {
 
 func (r *RuleSet) CallOzoneEventRules(c *OzoneEventContext) error {
 	for _, f := range r.OzoneEventRules {
-		err := f(c)
+		err := callRule(c, f, f)
 		if err != nil {
 			c.Logger.Error("ozone event rule execution failed", "err", err)
 		}
@@ -159,7 +161,7 @@ func (r *RuleSet) processBlob(c *RecordContext, blob lexutil.LexBlob, data []byt
 		wg.Add(1)
 		go func(brf BlobRuleFunc) {
 			defer wg.Done()
-			err := brf(c, blob, data)
+			err := callRule(c, brf, func(c *RecordContext) error { return brf(c, blob, data) })
 			if err != nil {
 				errChan <- err
 				return
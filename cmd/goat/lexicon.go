@@ -333,8 +333,11 @@ func runLexValidate(cctx *cli.Context) error {
 		}
 	}
 
-	if err := lexicon.ValidateRecord(&cat, recordData, nsid.String(), flags); err != nil {
-		return err
+	if errs := lexicon.ValidateRecordAll(&cat, recordData, nsid.String(), flags); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Println(err)
+		}
+		return fmt.Errorf("invalid %s record: %d problem(s) found", nsid, len(errs))
 	}
 	fmt.Printf("valid %s record\n", nsid)
 	return nil
@@ -18,6 +18,8 @@ import (
 	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/bluesky-social/indigo/automod/capture"
 	"github.com/bluesky-social/indigo/automod/consumer"
+	"github.com/bluesky-social/indigo/automod/countstore"
+	"github.com/bluesky-social/indigo/automod/engine"
 
 	"github.com/carlmjohnson/versioninfo"
 	_ "github.com/joho/godotenv/autoload"
@@ -151,10 +153,15 @@ func run(args []string) error {
 		},
 		&cli.DurationFlag{
 			Name:    "report-dupe-period",
-			Usage:   "time period within which automod will not re-report an account for the same reasonType",
+			Usage:   "time period within which automod will not re-report an account for the same reasonType (at most 168h)",
 			EnvVars: []string{"HEPA_REPORT_DUPE_PERIOD"},
 			Value:   1 * 24 * time.Hour,
 		},
+		&cli.StringSliceFlag{
+			Name:    "report-dupe-periods",
+			Usage:   "per-reasonType overrides of report-dupe-period, as reason=duration (eg, 'spam=1h,rude=168h'); reason is a short name or full reasonType",
+			EnvVars: []string{"HEPA_REPORT_DUPE_PERIODS"},
+		},
 		&cli.IntFlag{
 			Name:    "quota-mod-report-day",
 			Usage:   "number of reports automod can file per day, for all subjects and types combined (circuit breaker)",
@@ -173,6 +180,11 @@ func run(args []string) error {
 			EnvVars: []string{"HEPA_QUOTA_MOD_ACTION_DAY"},
 			Value:   2000,
 		},
+		&cli.BoolFlag{
+			Name:    "dry-run",
+			Usage:   "log moderation actions instead of persisting them (no reports, labels, takedowns, etc)",
+			EnvVars: []string{"HEPA_DRY_RUN"},
+		},
 		&cli.DurationFlag{
 			Name:    "record-event-timeout",
 			Usage:   "total processing time for record events (including setup, rules, and persisting)",
@@ -191,6 +203,12 @@ func run(args []string) error {
 			EnvVars: []string{"HEPA_OZONE_EVENT_TIMEOUT"},
 			Value:   30 * time.Second,
 		},
+		&cli.DurationFlag{
+			Name:    "rule-timeout",
+			Usage:   "processing time for each individual rule invocation, after which the rule is cancelled and counted as failed (zero for no limit)",
+			EnvVars: []string{"HEPA_RULE_TIMEOUT"},
+			Value:   5 * time.Second,
+		},
 	}
 
 	app.Commands = []*cli.Command{
@@ -275,6 +293,14 @@ var runCmd = &cli.Command{
 			return fmt.Errorf("failed to configure identity directory: %v", err)
 		}
 
+		if err := checkReportDupePeriod(cctx.Duration("report-dupe-period")); err != nil {
+			return fmt.Errorf("invalid report-dupe-period: %w", err)
+		}
+		reportDupePeriods, err := parseReportDupePeriods(cctx.StringSlice("report-dupe-periods"))
+		if err != nil {
+			return err
+		}
+
 		srv, err := NewServer(
 			dir,
 			Config{
@@ -296,12 +322,15 @@ var runCmd = &cli.Command{
 				PreScreenHost:        cctx.String("prescreen-host"),
 				PreScreenToken:       cctx.String("prescreen-token"),
 				ReportDupePeriod:     cctx.Duration("report-dupe-period"),
+				ReportDupePeriods:    reportDupePeriods,
 				QuotaModReportDay:    cctx.Int("quota-mod-report-day"),
 				QuotaModTakedownDay:  cctx.Int("quota-mod-takedown-day"),
 				QuotaModActionDay:    cctx.Int("quota-mod-action-day"),
+				DryRun:               cctx.Bool("dry-run"),
 				RecordEventTimeout:   cctx.Duration("record-event-timeout"),
 				IdentityEventTimeout: cctx.Duration("identity-event-timeout"),
 				OzoneEventTimeout:    cctx.Duration("ozone-event-timeout"),
+				RuleTimeout:          cctx.Duration("rule-timeout"),
 			},
 		)
 		if err != nil {
@@ -366,6 +395,45 @@ var runCmd = &cli.Command{
 	},
 }
 
+// parseReportDupePeriods parses 'reason=duration' pairs (from the report-dupe-periods flag) in to a map keyed by full reasonType. Reasons may be given as short names or full reasonTypes.
+func parseReportDupePeriods(pairs []string) (map[string]time.Duration, error) {
+	reasons := []string{engine.ReportReasonSpam, engine.ReportReasonViolation, engine.ReportReasonMisleading, engine.ReportReasonSexual, engine.ReportReasonRude, engine.ReportReasonOther}
+	out := map[string]time.Duration{}
+	for _, pair := range pairs {
+		name, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid report dupe period (expected reason=duration): %s", pair)
+		}
+		reasonType := ""
+		for _, r := range reasons {
+			if name == r || name == engine.ReasonShortName(r) {
+				reasonType = r
+				break
+			}
+		}
+		if reasonType == "" {
+			return nil, fmt.Errorf("unknown report reason in dupe period: %s", name)
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid report dupe period for %s: %w", name, err)
+		}
+		if err := checkReportDupePeriod(d); err != nil {
+			return nil, fmt.Errorf("invalid report dupe period for %s: %w", name, err)
+		}
+		out[reasonType] = d
+	}
+	return out, nil
+}
+
+// report dupe periods are counted in hour, day, or week buckets (see countstore.PeriodForWindow), so windows outside that range are rejected instead of silently becoming all-time counts
+func checkReportDupePeriod(d time.Duration) error {
+	if d <= 0 || d > countstore.MaxWindow {
+		return fmt.Errorf("%s is not between zero and %s", d, countstore.MaxWindow)
+	}
+	return nil
+}
+
 // for simple commands, not long-running daemons
 func configEphemeralServer(cctx *cli.Context) (*Server, error) {
 	// NOTE: using stderr not stdout because some commands print to stdout
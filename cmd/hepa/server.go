@@ -51,12 +51,15 @@ type Config struct {
 	PreScreenHost        string
 	PreScreenToken       string
 	ReportDupePeriod     time.Duration
+	ReportDupePeriods    map[string]time.Duration
 	QuotaModReportDay    int
 	QuotaModTakedownDay  int
 	QuotaModActionDay    int
+	DryRun               bool
 	RecordEventTimeout   time.Duration
 	IdentityEventTimeout time.Duration
 	OzoneEventTimeout    time.Duration
+	RuleTimeout          time.Duration
 }
 
 func NewServer(dir identity.Directory, config Config) (*Server, error) {
@@ -219,12 +222,15 @@ func NewServer(dir identity.Directory, config Config) (*Server, error) {
 		BlobClient:  blobClient,
 		Config: engine.EngineConfig{
 			ReportDupePeriod:     config.ReportDupePeriod,
+			ReportDupePeriods:    config.ReportDupePeriods,
 			QuotaModReportDay:    config.QuotaModReportDay,
 			QuotaModTakedownDay:  config.QuotaModTakedownDay,
 			QuotaModActionDay:    config.QuotaModActionDay,
+			DryRun:               config.DryRun,
 			RecordEventTimeout:   config.RecordEventTimeout,
 			IdentityEventTimeout: config.IdentityEventTimeout,
 			OzoneEventTimeout:    config.OzoneEventTimeout,
+			RuleTimeout:          config.RuleTimeout,
 		},
 	}
 
@@ -34,6 +34,27 @@ func (bgs *BGS) handleAdminGetSubsEnabled(e echo.Context) error {
 	})
 }
 
+func (bgs *BGS) handleAdminSetMaintenanceMode(e echo.Context) error {
+	enabled, err := strconv.ParseBool(e.QueryParam("enabled"))
+	if err != nil {
+		return &echo.HTTPError{
+			Code:    400,
+			Message: err.Error(),
+		}
+	}
+
+	bgs.SetMaintenanceMode(enabled)
+	return e.JSON(200, map[string]bool{
+		"enabled": enabled,
+	})
+}
+
+func (bgs *BGS) handleAdminGetMaintenanceMode(e echo.Context) error {
+	return e.JSON(200, map[string]bool{
+		"enabled": bgs.MaintenanceMode(),
+	})
+}
+
 func (bgs *BGS) handleAdminGetNewPDSPerDayRateLimit(e echo.Context) error {
 	limit := bgs.slurper.GetNewPDSPerDayLimit()
 	return e.JSON(200, map[string]int64{
@@ -233,6 +254,7 @@ type consumer struct {
 	UserAgent      string    `json:"user_agent"`
 	EventsConsumed uint64    `json:"events_consumed"`
 	ConnectedAt    time.Time `json:"connected_at"`
+	Backlog        int       `json:"backlog"`
 }
 
 func (bgs *BGS) handleAdminListConsumers(e echo.Context) error {
@@ -251,6 +273,7 @@ func (bgs *BGS) handleAdminListConsumers(e echo.Context) error {
 			UserAgent:      c.UserAgent,
 			EventsConsumed: uint64(m.Counter.GetValue()),
 			ConnectedAt:    c.ConnectedAt,
+			Backlog:        c.Backlog(),
 		})
 	}
 
@@ -329,8 +352,8 @@ type bannedDomains struct {
 }
 
 func (bgs *BGS) handleAdminListDomainBans(c echo.Context) error {
-	var all []DomainBan
-	if err := bgs.db.Find(&all).Error; err != nil {
+	all, err := bgs.ListDomainBans(c.Request().Context())
+	if err != nil {
 		return err
 	}
 
@@ -346,6 +369,7 @@ func (bgs *BGS) handleAdminListDomainBans(c echo.Context) error {
 
 type banDomainBody struct {
 	Domain string
+	Reason string
 }
 
 func (bgs *BGS) handleAdminBanDomain(c echo.Context) error {
@@ -354,18 +378,13 @@ func (bgs *BGS) handleAdminBanDomain(c echo.Context) error {
 		return err
 	}
 
-	// Check if the domain is already banned
-	var existing DomainBan
-	if err := bgs.db.Where("domain = ?", body.Domain).First(&existing).Error; err == nil {
-		return &echo.HTTPError{
-			Code:    400,
-			Message: "domain is already banned",
+	if err := bgs.AddDomainBan(c.Request().Context(), body.Domain, body.Reason); err != nil {
+		if errors.Is(err, ErrDomainAlreadyBanned) || errors.Is(err, ErrInvalidBanDomain) {
+			return &echo.HTTPError{
+				Code:    400,
+				Message: err.Error(),
+			}
 		}
-	}
-
-	if err := bgs.db.Create(&DomainBan{
-		Domain: body.Domain,
-	}).Error; err != nil {
 		return err
 	}
 
@@ -380,7 +399,13 @@ func (bgs *BGS) handleAdminUnbanDomain(c echo.Context) error {
 		return err
 	}
 
-	if err := bgs.db.Where("domain = ?", body.Domain).Delete(&DomainBan{}).Error; err != nil {
+	if err := bgs.RemoveDomainBan(c.Request().Context(), body.Domain); err != nil {
+		if errors.Is(err, ErrInvalidBanDomain) {
+			return &echo.HTTPError{
+				Code:    400,
+				Message: err.Error(),
+			}
+		}
 		return err
 	}
 
@@ -16,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	comatproto "github.com/bluesky-social/indigo/api/atproto"
@@ -24,7 +25,7 @@ import (
 	"github.com/bluesky-social/indigo/xrpc"
 
 	"github.com/gorilla/websocket"
-	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/hashicorp/golang-lru/v2/expirable"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	promclient "github.com/prometheus/client_golang/prometheus"
@@ -54,20 +55,21 @@ type BGS struct {
 	// pieces that abstract the need for explicit ssl checks
 	ssl bool
 
-	// extUserLk serializes a section of syncPDSAccount()
-	// TODO: at some point we will want to lock specific DIDs, this lock as is
-	// is overly broad, but i dont expect it to be a bottleneck for now
-	extUserLk sync.Mutex
+	// extUserLks serializes a section of syncPDSAccount() per-DID
+	extUserLks *didLocks
 
 	validator *Validator
 
+	// see SetMaintenanceMode; the slurper holds its own copy for upstream subscriptions
+	maintenance atomic.Bool
+
 	// Management of Socket Consumers
 	consumersLk    sync.RWMutex
 	nextConsumerID uint64
 	consumers      map[uint64]*SocketConsumer
 
 	// Account cache
-	userCache *lru.Cache[string, *Account]
+	userCache *expirable.LRU[string, *Account]
 
 	// nextCrawlers gets forwarded POST /xrpc/com.atproto.sync.requestCrawl
 	nextCrawlers []*url.URL
@@ -76,6 +78,9 @@ type BGS struct {
 	log               *slog.Logger
 	inductionTraceLog *slog.Logger
 
+	// rejectSink receives sampled rejected commits; nil unless BGSConfig.RejectSampleRate is set
+	rejectSink RejectSink
+
 	config BGSConfig
 }
 
@@ -84,6 +89,16 @@ type SocketConsumer struct {
 	RemoteAddr  string
 	ConnectedAt time.Time
 	EventsSent  promclient.Counter
+
+	sub *events.Subscriber
+}
+
+// Returns the number of events queued for this consumer which have not yet been sent.
+func (c *SocketConsumer) Backlog() int {
+	if c.sub == nil {
+		return 0
+	}
+	return c.sub.Backlog()
 }
 
 type BGSConfig struct {
@@ -100,8 +115,43 @@ type BGSConfig struct {
 
 	// AdminToken checked against "Authorization: Bearer {}" header
 	AdminToken string
+
+	// AccountCacheTTL bounds how long an account can be served from the in-process cache before being re-read from the database. Zero means no expiry.
+	AccountCacheTTL time.Duration
+
+	// MaxReconnectBackoff caps the delay between reconnection attempts to a single upstream host. Zero means the Slurper default.
+	MaxReconnectBackoff time.Duration
+
+	// DomainBanExactMatch restricts domain bans to the exact hostname. By default, a ban also covers all subdomains.
+	DomainBanExactMatch bool
+
+	// AccountCacheSize is the maximum number of accounts held in the in-process cache. Zero means the default (1,000,000).
+	AccountCacheSize int
+
+	// ConsumerWriteTimeout is how long writing a single event to a firehose consumer may take before the consumer is evicted as stuck. Zero means no limit.
+	ConsumerWriteTimeout time.Duration
+
+	// UserAgent is sent on outbound requests to upstream hosts, including both XRPC requests and firehose subscription handshakes. Empty means the library defaults.
+	UserAgent string
+
+	// HostHeaders, if set, returns extra HTTP headers (eg, an auth token) for outbound requests to the given upstream hostname, including both XRPC requests and firehose subscription handshakes. It is called for each request or dial, so values can change over time.
+	HostHeaders func(hostname string) map[string]string
+
+	// EnableWSCompression negotiates websocket compression (permessage-deflate) with firehose consumers which request it. Consumers which don't request it are unaffected.
+	EnableWSCompression bool
+
+	// RejectSampleRate is the fraction (0.0 to 1.0) of #commit messages rejected by verification which are passed to RejectSink, with their CAR slice, for offline debugging. Zero disables sampling.
+	RejectSampleRate float64
+
+	// RejectSink stores sampled rejected commits. If nil (and RejectSampleRate is set), a DBRejectSink is used, keeping RejectSamplesPerReason samples for each failure reason.
+	RejectSink RejectSink
+
+	// RejectSamplesPerReason is passed to NewDBRejectSink when RejectSink is not set. Zero means the default (100).
+	RejectSamplesPerReason int
 }
 
+const defaultAccountCacheSize = 1_000_000
+
 func DefaultBGSConfig() *BGSConfig {
 	return &BGSConfig{
 		SSL:               true,
@@ -129,8 +179,6 @@ func NewBGS(db *gorm.DB, validator *Validator, evtman *events.EventManager, didd
 		panic(err)
 	}
 
-	uc, _ := lru.New[string, *Account](1_000_000)
-
 	bgs := &BGS{
 		db: db,
 
@@ -142,7 +190,8 @@ func NewBGS(db *gorm.DB, validator *Validator, evtman *events.EventManager, didd
 		consumersLk: sync.RWMutex{},
 		consumers:   make(map[uint64]*SocketConsumer),
 
-		userCache: uc,
+		userCache:  newAccountCache(config),
+		extUserLks: newDIDLocks(),
 
 		log: slog.Default().With("system", "bgs"),
 
@@ -151,11 +200,28 @@ func NewBGS(db *gorm.DB, validator *Validator, evtman *events.EventManager, didd
 		inductionTraceLog: config.InductionTraceLog,
 	}
 
+	if config.RejectSampleRate > 0 {
+		bgs.rejectSink = config.RejectSink
+		if bgs.rejectSink == nil {
+			sink, err := NewDBRejectSink(db, config.RejectSamplesPerReason)
+			if err != nil {
+				return nil, err
+			}
+			bgs.rejectSink = sink
+		}
+	}
+
 	slOpts := DefaultSlurperOptions()
 	slOpts.SSL = config.SSL
 	slOpts.DefaultRepoLimit = config.DefaultRepoLimit
 	slOpts.ConcurrencyPerPDS = config.ConcurrencyPerPDS
 	slOpts.MaxQueuePerPDS = config.MaxQueuePerPDS
+	if config.MaxReconnectBackoff > 0 {
+		slOpts.MaxReconnectBackoff = config.MaxReconnectBackoff
+	}
+	slOpts.DomainBanExactMatch = config.DomainBanExactMatch
+	slOpts.UserAgent = config.UserAgent
+	slOpts.HostHeaders = config.HostHeaders
 	slOpts.Logger = bgs.log
 	s, err := NewSlurper(db, bgs.handleFedEvent, slOpts)
 	if err != nil {
@@ -253,6 +319,7 @@ func (bgs *BGS) StartWithListener(listen net.Listener, logWriter io.Writer) erro
 	e.GET("/xrpc/com.atproto.sync.listRepos", bgs.HandleComAtprotoSyncListRepos)
 	e.GET("/xrpc/com.atproto.sync.getRepo", bgs.HandleComAtprotoSyncGetRepo) // just returns 3xx redirect to source PDS
 	e.GET("/xrpc/com.atproto.sync.getLatestCommit", bgs.HandleComAtprotoSyncGetLatestCommit)
+	e.GET("/xrpc/com.atproto.sync.getRepoStatus", bgs.HandleComAtprotoSyncGetRepoStatus)
 	e.GET("/xrpc/_health", bgs.HandleHealthCheck)
 	e.GET("/_health", bgs.HandleHealthCheck)
 	e.GET("/", bgs.HandleHomeMessage)
@@ -266,6 +333,8 @@ func (bgs *BGS) StartWithListener(listen net.Listener, logWriter io.Writer) erro
 	admin.POST("/subs/setEnabled", bgs.handleAdminSetSubsEnabled)
 	admin.POST("/subs/killUpstream", bgs.handleAdminKillUpstreamConn)
 	admin.POST("/subs/setPerDayLimit", bgs.handleAdminSetNewPDSPerDayRateLimit)
+	admin.GET("/maintenance", bgs.handleAdminGetMaintenanceMode)
+	admin.POST("/maintenance", bgs.handleAdminSetMaintenanceMode)
 
 	// Domain-related Admin API
 	admin.GET("/subs/listDomainBans", bgs.handleAdminListDomainBans)
@@ -306,6 +375,45 @@ func (bgs *BGS) Shutdown() []error {
 	return errs
 }
 
+// SetMaintenanceMode enables or disables maintenance mode, eg during database migrations or deploys. While enabled, new upstream host subscriptions and new firehose consumers are rejected (consumers get HTTP 503 before the websocket upgrade). Existing upstream subscriptions and consumers are not disconnected.
+//
+// The state is reported by the relay_maintenance_mode gauge, and is not persisted across restarts.
+func (bgs *BGS) SetMaintenanceMode(on bool) {
+	bgs.maintenance.Store(on)
+	bgs.slurper.SetMaintenanceMode(on)
+	if on {
+		maintenanceModeGauge.Set(1)
+	} else {
+		maintenanceModeGauge.Set(0)
+	}
+	bgs.log.Warn("maintenance mode changed", "enabled", on)
+}
+
+// MaintenanceMode returns true if the relay is in maintenance mode. See [BGS.SetMaintenanceMode].
+func (bgs *BGS) MaintenanceMode() bool {
+	return bgs.maintenance.Load()
+}
+
+// HostStatus returns the current state of each upstream host subscription
+func (bgs *BGS) HostStatus() []HostStatusInfo {
+	return bgs.slurper.HostStatus()
+}
+
+// HostLag returns the last received and last processed sequence numbers for an upstream host, or zeros if there is no active subscription. See [Slurper.HostLag].
+func (bgs *BGS) HostLag(hostname string) (received, processed int64) {
+	return bgs.slurper.HostLag(hostname)
+}
+
+// DisconnectHost drops the subscription to a single upstream host, leaving other hosts untouched
+func (bgs *BGS) DisconnectHost(ctx context.Context, host string) error {
+	return bgs.slurper.DisconnectHost(ctx, host)
+}
+
+// ReconnectHost re-establishes the subscription to a single upstream host, from its last persisted cursor
+func (bgs *BGS) ReconnectHost(ctx context.Context, host string) error {
+	return bgs.slurper.ReconnectHost(ctx, host)
+}
+
 type HealthStatus struct {
 	Status  string `json:"status"`
 	Message string `json:"msg,omitempty"`
@@ -472,8 +580,24 @@ func (bgs *BGS) cleanupConsumer(id uint64) {
 	delete(bgs.consumers, id)
 }
 
+// Replays this relay's own persisted outbound events (firehose), starting after the given cursor, passing each event to the sink in order.
+//
+// Returns nil once caught up with the most recently persisted event, so this can be used to bootstrap a mirror relay or new downstream consumer before switching to the live firehose (using the sequence number of the last replayed event as a cursor). Returns ctx.Err() if the context is cancelled, and any error returned by the sink stops replay and is returned as-is.
+func (bgs *BGS) ReplayFrom(ctx context.Context, cursor int64, sink func(evt *events.XRPCStreamEvent) error) error {
+	return bgs.events.Replay(ctx, cursor, sink)
+}
+
+// Returns the firehose cursor (sequence number) from which playback starts at the given time, for replaying events since a point in time. See [events.EventManager.SeqForTime] for how times before the earliest persisted event, and after the most recent one, are handled.
+func (bgs *BGS) SeqForTime(ctx context.Context, t time.Time) (int64, error) {
+	return bgs.events.SeqForTime(ctx, t)
+}
+
 // GET+websocket /xrpc/com.atproto.sync.subscribeRepos
 func (bgs *BGS) EventsHandler(c echo.Context) error {
+	if bgs.MaintenanceMode() {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, ErrMaintenanceMode.Error())
+	}
+
 	var since *int64
 	if sinceVal := c.QueryParam("cursor"); sinceVal != "" {
 		sval, err := strconv.ParseInt(sinceVal, 10, 64)
@@ -482,14 +606,50 @@ func (bgs *BGS) EventsHandler(c echo.Context) error {
 		}
 		since = &sval
 	}
+	// non-standard alternative to 'cursor': a datetime, resolved to a cursor before upgrading
+	if timeVal := c.QueryParam("cursorTime"); timeVal != "" {
+		if since != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "cursor and cursorTime can not both be specified")
+		}
+		dt, err := syntax.ParseDatetimeLenient(timeVal)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid cursorTime: %s", err))
+		}
+		sval, err := bgs.SeqForTime(c.Request().Context(), dt.Time())
+		if err != nil {
+			return fmt.Errorf("resolving cursorTime: %w", err)
+		}
+		since = &sval
+	}
+
+	// optional server-side filtering; checked before upgrading, so errors are plain HTTP responses
+	filter, err := events.NewEventFilter(c.QueryParams()["wantedCollections"], c.QueryParams()["wantedDids"])
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
 
 	ctx, cancel := context.WithCancel(c.Request().Context())
 	defer cancel()
 
-	conn, err := websocket.Upgrade(c.Response(), c.Request(), c.Response().Header(), 10<<10, 10<<10)
+	// compression is only used if the consumer offers it in the handshake
+	compressed := bgs.config.EnableWSCompression && offersWSCompression(c.Request())
+	compressedLabel := strconv.FormatBool(compressed)
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:    10 << 10,
+		WriteBufferSize:   10 << 10,
+		EnableCompression: bgs.config.EnableWSCompression,
+		// public endpoint; any origin is allowed
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	wireWriter := &countingResponseWriter{
+		ResponseWriter: c.Response(),
+		counter:        eventsBytesSent.WithLabelValues("wire", compressedLabel),
+	}
+	conn, err := upgrader.Upgrade(wireWriter, c.Request(), c.Response().Header())
 	if err != nil {
 		return fmt.Errorf("upgrading websocket: %w", err)
 	}
+	payloadBytes := eventsBytesSent.WithLabelValues("payload", compressedLabel)
 
 	defer conn.Close()
 
@@ -549,17 +709,19 @@ func (bgs *BGS) EventsHandler(c echo.Context) error {
 
 	ident := c.RealIP() + "-" + c.Request().UserAgent()
 
-	evts, cleanup, err := bgs.events.Subscribe(ctx, ident, func(evt *events.XRPCStreamEvent) bool { return true }, since)
+	sub, err := bgs.events.SubscribeWithStatus(ctx, ident, filter.Func(), since)
 	if err != nil {
 		return err
 	}
-	defer cleanup()
+	defer sub.Close()
+	evts := sub.Events()
 
 	// Keep track of the consumer for metrics and admin endpoints
 	consumer := SocketConsumer{
 		RemoteAddr:  c.RealIP(),
 		UserAgent:   c.Request().UserAgent(),
 		ConnectedAt: time.Now(),
+		sub:         sub,
 	}
 	sentCounter := eventsSentCounter.WithLabelValues(consumer.RemoteAddr, consumer.UserAgent)
 	consumer.EventsSent = sentCounter
@@ -573,21 +735,33 @@ func (bgs *BGS) EventsHandler(c echo.Context) error {
 		"user_agent", consumer.UserAgent,
 	)
 
-	logger.Info("new consumer", "cursor", since)
+	logger.Info("new consumer", "cursor", since, "filtered", filter != nil)
 
 	for {
 		select {
 		case evt, ok := <-evts:
 			if !ok {
+				if reason := sub.EvictReason(); reason != "" {
+					logger.Warn("evicting slow consumer", "reason", reason, "backlog", sub.Backlog())
+					closeSlowConsumer(conn)
+					return nil
+				}
 				logger.Error("event stream closed unexpectedly")
 				return nil
 			}
 
-			wc, err := conn.NextWriter(websocket.BinaryMessage)
+			if bgs.config.ConsumerWriteTimeout > 0 {
+				if err := conn.SetWriteDeadline(time.Now().Add(bgs.config.ConsumerWriteTimeout)); err != nil {
+					return err
+				}
+			}
+
+			nw, err := conn.NextWriter(websocket.BinaryMessage)
 			if err != nil {
 				logger.Error("failed to get next writer", "err", err)
 				return err
 			}
+			wc := &countingWriteCloser{WriteCloser: nw, counter: payloadBytes}
 
 			if evt.Preserialized != nil {
 				_, err = wc.Write(evt.Preserialized)
@@ -595,10 +769,20 @@ func (bgs *BGS) EventsHandler(c echo.Context) error {
 				err = evt.Serialize(wc)
 			}
 			if err != nil {
+				if isTimeout(err) {
+					sub.Evict(events.EvictReasonWriteTimeout)
+					logger.Warn("evicting slow consumer", "reason", events.EvictReasonWriteTimeout, "backlog", sub.Backlog())
+					return nil
+				}
 				return fmt.Errorf("failed to write event: %w", err)
 			}
 
 			if err := wc.Close(); err != nil {
+				if isTimeout(err) {
+					sub.Evict(events.EvictReasonWriteTimeout)
+					logger.Warn("evicting slow consumer", "reason", events.EvictReasonWriteTimeout, "backlog", sub.Backlog())
+					return nil
+				}
 				logger.Warn("failed to flush-close our event write", "err", err)
 				return nil
 			}
@@ -613,51 +797,55 @@ func (bgs *BGS) EventsHandler(c echo.Context) error {
 	}
 }
 
-// domainIsBanned checks if the given host is banned, starting with the host
-// itself, then checking every parent domain up to the tld
-func (s *BGS) domainIsBanned(ctx context.Context, host string) (bool, error) {
-	// ignore ports when checking for ban status
-	hostport := strings.Split(host, ":")
-
-	segments := strings.Split(hostport[0], ".")
-
-	// TODO: use normalize method once that merges
-	var cleaned []string
-	for _, s := range segments {
-		if s == "" {
-			continue
-		}
-		s = strings.ToLower(s)
-
-		cleaned = append(cleaned, s)
+// Configures an XRPC client for requests to an upstream host: applies ApplyPDSClientSettings (if configured), then the configured user-agent and any per-host headers
+func (bgs *BGS) applyHostClientSettings(c *xrpc.Client) {
+	if bgs.config.ApplyPDSClientSettings != nil {
+		bgs.config.ApplyPDSClientSettings(c)
 	}
-	segments = cleaned
-
-	for i := 0; i < len(segments)-1; i++ {
-		dchk := strings.Join(segments[i:], ".")
-		found, err := s.findDomainBan(ctx, dchk)
-		if err != nil {
-			return false, err
-		}
+	bgs.applyHostIdentity(c)
+}
 
-		if found {
-			return true, nil
-		}
+// Sets the configured user-agent and per-host headers on an XRPC client, without other client settings
+func (bgs *BGS) applyHostIdentity(c *xrpc.Client) {
+	if bgs.config.UserAgent != "" {
+		ua := bgs.config.UserAgent
+		c.UserAgent = &ua
 	}
-	return false, nil
+	if bgs.config.HostHeaders == nil {
+		return
+	}
+	hostname := c.Host
+	if u, err := url.Parse(c.Host); err == nil && u.Host != "" {
+		hostname = u.Host
+	}
+	extra := bgs.config.HostHeaders(hostname)
+	if len(extra) == 0 {
+		return
+	}
+	headers := make(map[string]string, len(c.Headers)+len(extra))
+	for k, v := range c.Headers {
+		headers[k] = v
+	}
+	for k, v := range extra {
+		headers[k] = v
+	}
+	c.Headers = headers
 }
 
-func (s *BGS) findDomainBan(ctx context.Context, host string) (bool, error) {
-	var db DomainBan
-	if err := s.db.Find(&db, "domain = ?", host).Error; err != nil {
-		return false, err
-	}
+// Sends a websocket close frame to a consumer being evicted for falling behind. Uses the "policy violation" close code (1008), with the same reason as the error frame sent on the stream.
+func closeSlowConsumer(conn *websocket.Conn) {
+	msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, events.ErrorConsumerTooSlow)
+	_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(5*time.Second))
+}
 
-	if db.ID == 0 {
-		return false, nil
-	}
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
 
-	return true, nil
+// domainIsBanned checks if the given host is covered by a domain ban
+func (s *BGS) domainIsBanned(ctx context.Context, host string) (bool, error) {
+	return s.slurper.hostIsBanned(ctx, host)
 }
 
 var ErrNotFound = errors.New("not found")
@@ -673,14 +861,26 @@ func (bgs *BGS) DidToUid(ctx context.Context, did string) (models.Uid, error) {
 	return xu.ID, nil
 }
 
+// newAccountCache returns the in-process account cache, sized and expiring per AccountCacheSize and AccountCacheTTL
+func newAccountCache(config *BGSConfig) *expirable.LRU[string, *Account] {
+	cacheSize := config.AccountCacheSize
+	if cacheSize <= 0 {
+		cacheSize = defaultAccountCacheSize
+	}
+	// expirable.LRU treats a non-positive TTL as no expiry
+	return expirable.NewLRU[string, *Account](cacheSize, nil, config.AccountCacheTTL)
+}
+
 func (bgs *BGS) lookupUserByDid(ctx context.Context, did string) (*Account, error) {
 	ctx, span := tracer.Start(ctx, "lookupUserByDid")
 	defer span.End()
 
 	cu, ok := bgs.userCache.Get(did)
 	if ok {
+		accountCacheHits.Inc()
 		return cu, nil
 	}
+	accountCacheMisses.Inc()
 
 	var u Account
 	if err := bgs.db.Find(&u, "did = ?", did).Error; err != nil {
@@ -740,6 +940,10 @@ func (bgs *BGS) handleFedEvent(ctx context.Context, host *models.PDS, env *event
 		// Flush any cached DID documents for this user
 		bgs.purgeDidCache(ctx, env.RepoIdentity.Did)
 
+		if err := bgs.validator.HandleIdentity(ctx, host, env.RepoIdentity); err != nil {
+			return fmt.Errorf("invalid identity event: %w", err)
+		}
+
 		// Refetch the DID doc and update our cached keys and handle etc.
 		account, err := bgs.syncPDSAccount(ctx, env.RepoIdentity.Did, host, nil)
 		if err != nil {
@@ -774,9 +978,12 @@ func (bgs *BGS) handleFedEvent(ctx context.Context, host *models.PDS, env *event
 		}
 		bgs.log.Info("bgs got account event", "did", env.RepoAccount.Did)
 
-		if !env.RepoAccount.Active && env.RepoAccount.Status == nil {
-			accountVerifyWarnings.WithLabelValues(host.Host, "nostat").Inc()
-			return nil
+		if err := bgs.validator.HandleAccount(ctx, host, env.RepoAccount); err != nil {
+			if errors.Is(err, ErrAccountMissingStatus) {
+				// counted as a warning, but not passed on
+				return nil
+			}
+			return fmt.Errorf("invalid account event: %w", err)
 		}
 
 		// Flush any cached DID documents for this user
@@ -960,6 +1167,7 @@ func (bgs *BGS) handleCommit(ctx context.Context, host *models.PDS, evt *comatpr
 	if err != nil {
 		bgs.inductionTraceLog.Error("commit bad", "seq", evt.Seq, "pseq", dbPrevSeqStr, "pdsHost", host.Host, "repo", evt.Repo, "prev", evtPrevDataStr, "dbprev", dbPrevRootStr, "err", err)
 		bgs.log.Warn("failed handling event", "err", err, "pdsHost", host.Host, "seq", evt.Seq, "repo", account.Did, "commit", evt.Commit.String())
+		bgs.sampleRejectedCommit(ctx, host.Host, evt, err)
 		repoCommitsResultCounter.WithLabelValues(host.Host, "err").Inc()
 		return fmt.Errorf("handle user event failed: %w", err)
 	} else {
@@ -1113,7 +1321,7 @@ func (bgs *BGS) syncPDSAccount(ctx context.Context, did string, host *models.PDS
 
 		// Do a trivial API request against the PDS to verify that it exists
 		pclient := &xrpc.Client{Host: durl.String()}
-		bgs.config.ApplyPDSClientSettings(pclient)
+		bgs.applyHostClientSettings(pclient)
 		cfg, err := comatproto.ServerDescribeServer(ctx, pclient)
 		if err != nil {
 			// TODO: failing this shouldn't halt our indexing
@@ -1150,8 +1358,8 @@ func (bgs *BGS) syncPDSAccount(ctx context.Context, did string, host *models.PDS
 	}
 
 	// this lock just governs the lower half of this function
-	bgs.extUserLk.Lock()
-	defer bgs.extUserLk.Unlock()
+	unlock := bgs.extUserLks.lock(did)
+	defer unlock()
 
 	if cachedAccount == nil {
 		cachedAccount, err = bgs.lookupUserByDid(ctx, did)
@@ -1256,3 +1464,65 @@ func (bgs *BGS) GetRepoRoot(ctx context.Context, user models.Uid) (cid.Cid, erro
 		return cid.Cid{}, fmt.Errorf("user prev db err, %w", err)
 	}
 }
+
+// AccountStatus returns the rev and MST root CID of the most recent verified #commit or #sync for an account.
+//
+// This is the commit subset of GetRepoStatus(). Returns ErrNotFound if the account is unknown, and ErrUserStatusUnavailable if no verified commit has been seen for it yet.
+func (bgs *BGS) AccountStatus(ctx context.Context, did syntax.DID) (string, cid.Cid, error) {
+	status, err := bgs.GetRepoStatus(ctx, did)
+	if err != nil {
+		return "", cid.Cid{}, err
+	}
+	if !status.Root.Defined() {
+		return "", cid.Cid{}, ErrUserStatusUnavailable
+	}
+	return status.Rev, status.Root, nil
+}
+
+// RepoStatus is the relay's view of a single repo, as returned by [BGS.GetRepoStatus]. It only contains metadata from account events and verified commits; the relay does not store repo contents, and can not serve records.
+type RepoStatus struct {
+	DID syntax.DID
+
+	// Active is false if the account was taken down at this relay, or if the upstream host reported a non-active status
+	Active bool
+
+	// Status is the reason the account is not active (eg, "takendown", "deactivated"). Empty if Active is true.
+	Status string
+
+	// Rev and Root (MST root CID) are from the most recent verified #commit or #sync. Rev is empty, and Root undefined, if none has been seen yet.
+	Rev  string
+	Root cid.Cid
+}
+
+// GetRepoStatus returns the current status of a repo, without contacting the upstream host. This is the data served by com.atproto.sync.getRepoStatus.
+//
+// Returns ErrNotFound if the account is unknown.
+func (bgs *BGS) GetRepoStatus(ctx context.Context, did syntax.DID) (*RepoStatus, error) {
+	u, err := bgs.lookupUserByDid(ctx, did.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	out := RepoStatus{DID: did, Active: true}
+	ustatus := u.GetUpstreamStatus()
+	if u.GetTakenDown() {
+		// local takedown overrides the upstream status, the same as for #account events
+		out.Active = false
+		out.Status = events.AccountStatusTakendown
+	} else if ustatus != "" && ustatus != events.AccountStatusActive {
+		out.Active = false
+		out.Status = ustatus
+	}
+
+	var prevState AccountPreviousState
+	err = bgs.db.First(&prevState, u.ID).Error
+	if err == nil {
+		out.Rev = prevState.Rev
+		out.Root = prevState.Cid.CID
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("user prev db err, %w", err)
+	}
+	return &out, nil
+}
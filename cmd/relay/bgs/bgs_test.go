@@ -0,0 +1,589 @@
+package bgs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/cmd/relay/events"
+	"github.com/bluesky-social/indigo/cmd/relay/models"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+	"github.com/bluesky-social/indigo/xrpc"
+
+	"github.com/gorilla/websocket"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/ipfs/go-cid"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// minimal in-memory events.EventPersistence, for testing playback and broadcast
+type memPersister struct {
+	evts      []*events.XRPCStreamEvent
+	broadcast func(*events.XRPCStreamEvent)
+}
+
+func (mp *memPersister) Persist(ctx context.Context, e *events.XRPCStreamEvent) error {
+	mp.evts = append(mp.evts, e)
+	if mp.broadcast != nil {
+		mp.broadcast(e)
+	}
+	return nil
+}
+
+func (mp *memPersister) Playback(ctx context.Context, since int64, cb func(*events.XRPCStreamEvent) error) error {
+	for _, e := range mp.evts {
+		if e.Sequence() <= since {
+			continue
+		}
+		if err := cb(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mp *memPersister) TakeDownRepo(ctx context.Context, usr models.Uid) error { return nil }
+func (mp *memPersister) Flush(context.Context) error                            { return nil }
+func (mp *memPersister) Shutdown(context.Context) error                         { return nil }
+func (mp *memPersister) SetEventBroadcaster(f func(*events.XRPCStreamEvent)) {
+	mp.broadcast = f
+}
+
+func TestReplayFrom(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	mp := &memPersister{}
+	for seq := int64(1); seq <= 5; seq++ {
+		mp.evts = append(mp.evts, &events.XRPCStreamEvent{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Seq: seq}})
+	}
+	bgs := &BGS{events: events.NewEventManager(mp)}
+
+	// replays in order from the cursor, and stops at the tip
+	seen := []int64{}
+	err := bgs.ReplayFrom(ctx, 2, func(evt *events.XRPCStreamEvent) error {
+		seen = append(seen, evt.Sequence())
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal([]int64{3, 4, 5}, seen)
+
+	// sink errors stop replay
+	errStop := errors.New("stop")
+	seen = []int64{}
+	err = bgs.ReplayFrom(ctx, 0, func(evt *events.XRPCStreamEvent) error {
+		seen = append(seen, evt.Sequence())
+		if len(seen) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	assert.ErrorIs(err, errStop)
+	assert.Equal([]int64{1, 2}, seen)
+
+	// context cancellation stops replay
+	cctx, cancel := context.WithCancel(ctx)
+	seen = []int64{}
+	err = bgs.ReplayFrom(cctx, 0, func(evt *events.XRPCStreamEvent) error {
+		seen = append(seen, evt.Sequence())
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal([]int64{1}, seen)
+}
+
+func TestSeqForTime(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	mp := &memPersister{}
+	times := []string{"2024-09-18T00:00:00Z", "2024-09-18T01:00:00Z", "2024-09-18T02:00:00Z", "2024-09-18T03:00:00Z"}
+	for i, ts := range times {
+		mp.evts = append(mp.evts, &events.XRPCStreamEvent{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Seq: int64(i + 11), Time: ts}})
+	}
+	bgs := &BGS{events: events.NewEventManager(mp)}
+
+	at := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	for _, tc := range []struct {
+		at  string
+		seq int64
+	}{
+		{"2024-01-01T00:00:00Z", 0},  // before retention: replay everything
+		{"2024-09-18T00:00:00Z", 0},  // exactly the first event
+		{"2024-09-18T01:30:00Z", 12}, // between events: starts with the next event
+		{"2024-09-18T02:00:00Z", 12}, // exact match is included
+		{"2024-09-18T03:00:01Z", 14}, // after the tip: live events only
+	} {
+		seq, err := bgs.SeqForTime(ctx, at(tc.at))
+		assert.NoError(err)
+		assert.Equal(tc.seq, seq, tc.at)
+	}
+
+	// the resolved cursor is used by the subscription handler
+	bgs.consumers = map[uint64]*SocketConsumer{}
+	bgs.log = slog.Default()
+	e := echo.New()
+	e.GET("/xrpc/com.atproto.sync.subscribeRepos", bgs.EventsHandler)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/xrpc/com.atproto.sync.subscribeRepos"
+
+	for _, q := range []string{"?cursorTime=yesterday", "?cursorTime=2024-09-18T01:30:00Z&cursor=11"} {
+		_, resp, err := websocket.DefaultDialer.Dial(url+q, nil)
+		assert.Error(err)
+		assert.Equal(http.StatusBadRequest, resp.StatusCode, q)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?cursorTime=2024-09-18T01:30:00Z", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var seqs []int64
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for i := 0; i < 2; i++ {
+		_, msg, err := conn.ReadMessage()
+		assert.NoError(err)
+		var evt events.XRPCStreamEvent
+		assert.NoError(evt.Deserialize(bytes.NewReader(msg)))
+		seqs = append(seqs, evt.Sequence())
+	}
+	assert.Equal([]int64{13, 14}, seqs)
+}
+
+func TestSlowConsumerEviction(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	evtman := events.NewEventManager(&memPersister{})
+	evtman.SetSubscriberBufferSize(3)
+
+	sub, err := evtman.SubscribeWithStatus(ctx, "test", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	consumer := SocketConsumer{sub: sub}
+
+	addEvent := func(seq int64) {
+		assert.NoError(evtman.AddEvent(ctx, &events.XRPCStreamEvent{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Seq: seq}}))
+	}
+
+	// backlog grows while the consumer isn't reading
+	addEvent(1)
+	addEvent(2)
+	assert.Equal(2, consumer.Backlog())
+	assert.Equal("", sub.EvictReason())
+
+	// overflowing the buffer evicts the consumer
+	addEvent(3)
+	addEvent(4)
+	assert.Equal(events.EvictReasonBufferFull, sub.EvictReason())
+
+	// buffered events are still delivered, followed by an error frame, then the channel is closed
+	var seqs []int64
+	var errFrame string
+	timeout := time.After(10 * time.Second)
+	for done := false; !done; {
+		select {
+		case evt, ok := <-sub.Events():
+			if !ok {
+				done = true
+				break
+			}
+			if evt.Error != nil {
+				errFrame = evt.Error.Error
+				continue
+			}
+			seqs = append(seqs, evt.Sequence())
+		case <-timeout:
+			t.Fatal("timed out waiting for evicted subscription to close")
+		}
+	}
+	assert.Equal([]int64{1, 2, 3}, seqs)
+	assert.Equal(events.ErrorConsumerTooSlow, errFrame)
+}
+
+func TestEventsHandlerCompression(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	evtman := events.NewEventManager(&memPersister{})
+	bgs := &BGS{
+		events:    evtman,
+		config:    BGSConfig{EnableWSCompression: true},
+		consumers: map[uint64]*SocketConsumer{},
+		log:       slog.Default(),
+	}
+	e := echo.New()
+	e.GET("/xrpc/com.atproto.sync.subscribeRepos", bgs.EventsHandler)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/xrpc/com.atproto.sync.subscribeRepos"
+
+	var seq int64
+	receive := func(dialer *websocket.Dialer) string {
+		conn, resp, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+			bgs.consumersLk.RLock()
+			n := len(bgs.consumers)
+			bgs.consumersLk.RUnlock()
+			if n > 0 {
+				break
+			}
+			if time.Since(start) > 10*time.Second {
+				t.Fatal("timed out waiting for consumer")
+			}
+		}
+		// repetitive content, which compresses well
+		handle := strings.Repeat("a", 200) + ".example.com"
+		for i := 0; i < 10; i++ {
+			seq++
+			evt := &events.XRPCStreamEvent{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Seq: seq, Did: "did:plc:abc123", Handle: &handle}}
+			assert.NoError(evtman.AddEvent(ctx, evt))
+		}
+		for i := 0; i < 10; i++ {
+			_, msg, err := conn.ReadMessage()
+			assert.NoError(err)
+			assert.Contains(string(msg), handle)
+		}
+		return resp.Header.Get("Sec-WebSocket-Extensions")
+	}
+	waitDisconnect := func() {
+		for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+			bgs.consumersLk.RLock()
+			n := len(bgs.consumers)
+			bgs.consumersLk.RUnlock()
+			if n == 0 {
+				return
+			}
+		}
+		t.Fatal("timed out waiting for consumer to disconnect")
+	}
+
+	// consumer which supports compression
+	payloadBefore := testutil.ToFloat64(eventsBytesSent.WithLabelValues("payload", "true"))
+	wireBefore := testutil.ToFloat64(eventsBytesSent.WithLabelValues("wire", "true"))
+	ext := receive(&websocket.Dialer{EnableCompression: true})
+	assert.Contains(ext, "permessage-deflate")
+	waitDisconnect()
+	payload := testutil.ToFloat64(eventsBytesSent.WithLabelValues("payload", "true")) - payloadBefore
+	wire := testutil.ToFloat64(eventsBytesSent.WithLabelValues("wire", "true")) - wireBefore
+	assert.Greater(payload, 2000.0)
+	assert.Less(wire, payload)
+
+	// consumer which doesn't support compression is unaffected
+	ext = receive(&websocket.Dialer{})
+	assert.Equal("", ext)
+	waitDisconnect()
+}
+
+func TestEventsHandlerFilter(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	evtman := events.NewEventManager(&memPersister{})
+	bgs := &BGS{
+		events:    evtman,
+		consumers: map[uint64]*SocketConsumer{},
+		log:       slog.Default(),
+	}
+	e := echo.New()
+	e.GET("/xrpc/com.atproto.sync.subscribeRepos", bgs.EventsHandler)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/xrpc/com.atproto.sync.subscribeRepos"
+
+	// invalid filters are rejected before the upgrade
+	_, resp, err := websocket.DefaultDialer.Dial(url+"?wantedCollections=post", nil)
+	assert.Error(err)
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?wantedCollections=app.bsky.feed.post&wantedDids=did:plc:aaa", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		bgs.consumersLk.RLock()
+		n := len(bgs.consumers)
+		bgs.consumersLk.RUnlock()
+		if n > 0 {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("timed out waiting for consumer")
+		}
+	}
+
+	commit := func(seq int64, did, path string) *events.XRPCStreamEvent {
+		return &events.XRPCStreamEvent{RepoCommit: &comatproto.SyncSubscribeRepos_Commit{
+			Seq:    seq,
+			Repo:   did,
+			Commit: lexutil.LexLink(cid.MustParse("bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm")),
+			Rev:    "3l3qo2vutsw2b",
+			Time:   "2024-09-18T00:00:00.000Z",
+			Blocks: []byte{},
+			Ops:    []*comatproto.SyncSubscribeRepos_RepoOp{{Action: "delete", Path: path}},
+		}}
+	}
+	assert.NoError(evtman.AddEvent(ctx, commit(1, "did:plc:bbb", "app.bsky.feed.post/3l3qo2vutsw2b")))
+	assert.NoError(evtman.AddEvent(ctx, commit(2, "did:plc:aaa", "app.bsky.feed.like/3l3qo2vutsw2b")))
+	assert.NoError(evtman.AddEvent(ctx, &events.XRPCStreamEvent{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Seq: 3, Did: "did:plc:bbb"}}))
+	assert.NoError(evtman.AddEvent(ctx, commit(4, "did:plc:aaa", "app.bsky.feed.post/3l3qo2vutsw2b")))
+
+	var seqs []int64
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for i := 0; i < 2; i++ {
+		_, msg, err := conn.ReadMessage()
+		assert.NoError(err)
+		var evt events.XRPCStreamEvent
+		assert.NoError(evt.Deserialize(bytes.NewReader(msg)))
+		seqs = append(seqs, evt.Sequence())
+	}
+	assert.Equal([]int64{3, 4}, seqs)
+}
+
+func TestApplyHostClientSettings(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	srvHost := strings.TrimPrefix(srv.URL, "http://")
+
+	bgs := &BGS{config: BGSConfig{
+		UserAgent: "relay-test/1.0",
+		ApplyPDSClientSettings: func(c *xrpc.Client) {
+			c.Headers = map[string]string{"x-ratelimit-bypass": "abc"}
+		},
+		HostHeaders: func(hostname string) map[string]string {
+			if hostname == srvHost {
+				return map[string]string{"Authorization": "Bearer secret"}
+			}
+			return nil
+		},
+	}}
+
+	c := &xrpc.Client{Host: srv.URL}
+	bgs.applyHostClientSettings(c)
+	assert.NoError(c.Do(ctx, xrpc.Query, "", "com.atproto.server.describeServer", nil, nil, nil))
+	assert.Equal("relay-test/1.0", got.Get("User-Agent"))
+	assert.Equal("Bearer secret", got.Get("Authorization"))
+	assert.Equal("abc", got.Get("x-ratelimit-bypass"))
+
+	// headers are per-host
+	c = &xrpc.Client{Host: "https://other.example.com"}
+	bgs.applyHostClientSettings(c)
+	assert.Equal("relay-test/1.0", *c.UserAgent)
+	assert.Empty(c.Headers["Authorization"])
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	bgs := &BGS{
+		slurper:   &Slurper{active: map[string]*activeSub{}, log: slog.Default()},
+		events:    events.NewEventManager(&memPersister{}),
+		consumers: map[uint64]*SocketConsumer{},
+		log:       slog.Default(),
+	}
+	e := echo.New()
+	e.GET("/xrpc/com.atproto.sync.subscribeRepos", bgs.EventsHandler)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/xrpc/com.atproto.sync.subscribeRepos"
+
+	bgs.SetMaintenanceMode(true)
+	assert.True(bgs.MaintenanceMode())
+	assert.Equal(1.0, testutil.ToFloat64(maintenanceModeGauge))
+
+	// new consumers and new upstream subscriptions are rejected
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.Error(err)
+	assert.Equal(http.StatusServiceUnavailable, resp.StatusCode)
+	assert.ErrorIs(bgs.slurper.SubscribeToPds(ctx, "pds.example.com", true, true, nil), ErrMaintenanceMode)
+	assert.ErrorIs(bgs.ReconnectHost(ctx, "pds.example.com"), ErrMaintenanceMode)
+
+	// already-active hosts are a no-op, not an error
+	bgs.slurper.active["active.example.com"] = &activeSub{}
+	assert.NoError(bgs.slurper.SubscribeToPds(ctx, "active.example.com", true, false, nil))
+
+	bgs.SetMaintenanceMode(false)
+	assert.Equal(0.0, testutil.ToFloat64(maintenanceModeGauge))
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestGetRepoStatus(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "relay.sqlite")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(Account{}, AccountPreviousState{}); err != nil {
+		t.Fatal(err)
+	}
+	bgs := &BGS{
+		db:        db,
+		userCache: expirable.NewLRU[string, *Account](100, nil, 0),
+		log:       slog.Default(),
+	}
+
+	root := cid.MustParse("bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm")
+	active := Account{Did: "did:plc:aaa", UpstreamStatus: events.AccountStatusActive}
+	noCommit := Account{Did: "did:plc:bbb"}
+	deactivated := Account{Did: "did:plc:ccc", UpstreamStatus: events.AccountStatusDeactivated}
+	takendown := Account{Did: "did:plc:ddd", UpstreamStatus: events.AccountStatusActive, TakenDown: true}
+	for _, acc := range []*Account{&active, &noCommit, &deactivated, &takendown} {
+		assert.NoError(db.Create(acc).Error)
+	}
+	for _, acc := range []*Account{&active, &deactivated, &takendown} {
+		assert.NoError(bgs.upsertPrevState(acc.ID, &root, "3l3qo2vutsw2b", 1))
+	}
+
+	status, err := bgs.GetRepoStatus(ctx, "did:plc:aaa")
+	assert.NoError(err)
+	assert.Equal(RepoStatus{DID: "did:plc:aaa", Active: true, Rev: "3l3qo2vutsw2b", Root: root}, *status)
+
+	status, err = bgs.GetRepoStatus(ctx, "did:plc:bbb")
+	assert.NoError(err)
+	assert.True(status.Active)
+	assert.Empty(status.Rev)
+	assert.False(status.Root.Defined())
+
+	status, err = bgs.GetRepoStatus(ctx, "did:plc:ccc")
+	assert.NoError(err)
+	assert.False(status.Active)
+	assert.Equal(events.AccountStatusDeactivated, status.Status)
+
+	status, err = bgs.GetRepoStatus(ctx, "did:plc:ddd")
+	assert.NoError(err)
+	assert.False(status.Active)
+	assert.Equal(events.AccountStatusTakendown, status.Status)
+
+	_, err = bgs.GetRepoStatus(ctx, "did:plc:zzz")
+	assert.ErrorIs(err, ErrNotFound)
+
+	// AccountStatus is the commit subset of the same lookup
+	rev, accRoot, err := bgs.AccountStatus(ctx, "did:plc:ddd")
+	assert.NoError(err)
+	assert.Equal("3l3qo2vutsw2b", rev)
+	assert.Equal(root, accRoot)
+	_, _, err = bgs.AccountStatus(ctx, "did:plc:bbb")
+	assert.ErrorIs(err, ErrUserStatusUnavailable)
+	_, _, err = bgs.AccountStatus(ctx, "did:plc:zzz")
+	assert.ErrorIs(err, ErrNotFound)
+
+	// XRPC endpoint: rev is only included for active repos
+	e := echo.New()
+	e.GET("/xrpc/com.atproto.sync.getRepoStatus", bgs.HandleComAtprotoSyncGetRepoStatus)
+	get := func(did string) (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/xrpc/com.atproto.sync.getRepoStatus?did="+did, nil))
+		var body map[string]any
+		assert.NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec.Code, body
+	}
+	code, body := get("did:plc:aaa")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(map[string]any{"did": "did:plc:aaa", "active": true, "rev": "3l3qo2vutsw2b"}, body)
+	code, body = get("did:plc:ccc")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(map[string]any{"did": "did:plc:ccc", "active": false, "status": "deactivated"}, body)
+	code, _ = get("did:plc:zzz")
+	assert.Equal(http.StatusNotFound, code)
+	code, _ = get("not-a-did")
+	assert.Equal(http.StatusBadRequest, code)
+}
+
+func TestAccountCache(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "relay.sqlite")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(Account{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, did := range []string{"did:plc:aaa", "did:plc:bbb", "did:plc:ccc"} {
+		assert.NoError(db.Create(&Account{Did: did}).Error)
+	}
+
+	// expects the given number of cache hits and misses from looking up each DID in order
+	lookup := func(bgs *BGS, hits, misses float64, dids ...string) {
+		hitsBefore := testutil.ToFloat64(accountCacheHits)
+		missesBefore := testutil.ToFloat64(accountCacheMisses)
+		for _, did := range dids {
+			u, err := bgs.lookupUserByDid(ctx, did)
+			assert.NoError(err)
+			assert.Equal(did, u.Did)
+		}
+		assert.Equal(hits, testutil.ToFloat64(accountCacheHits)-hitsBefore, dids)
+		assert.Equal(misses, testutil.ToFloat64(accountCacheMisses)-missesBefore, dids)
+	}
+
+	// default size holds every account
+	bgs := &BGS{db: db, userCache: newAccountCache(&BGSConfig{}), log: slog.Default()}
+	lookup(bgs, 0, 3, "did:plc:aaa", "did:plc:bbb", "did:plc:ccc")
+	lookup(bgs, 3, 0, "did:plc:aaa", "did:plc:bbb", "did:plc:ccc")
+
+	// least-recently-used accounts are evicted beyond AccountCacheSize
+	bgs = &BGS{db: db, userCache: newAccountCache(&BGSConfig{AccountCacheSize: 2}), log: slog.Default()}
+	lookup(bgs, 0, 3, "did:plc:aaa", "did:plc:bbb", "did:plc:ccc")
+	lookup(bgs, 1, 1, "did:plc:ccc", "did:plc:aaa")
+	assert.Equal(2, bgs.userCache.Len())
+
+	// missing accounts are not cached
+	_, err = bgs.lookupUserByDid(ctx, "did:plc:zzz")
+	assert.ErrorIs(err, gorm.ErrRecordNotFound)
+	assert.Equal(2, bgs.userCache.Len())
+
+	// entries are re-read from the database after AccountCacheTTL
+	bgs = &BGS{db: db, userCache: newAccountCache(&BGSConfig{AccountCacheTTL: 50 * time.Millisecond}), log: slog.Default()}
+	lookup(bgs, 0, 1, "did:plc:aaa")
+	assert.NoError(db.Model(Account{}).Where("did = ?", "did:plc:aaa").Update("upstream_status", events.AccountStatusDeactivated).Error)
+	lookup(bgs, 1, 0, "did:plc:aaa")
+	time.Sleep(100 * time.Millisecond)
+	lookup(bgs, 0, 1, "did:plc:aaa")
+	u, err := bgs.lookupUserByDid(ctx, "did:plc:aaa")
+	assert.NoError(err)
+	assert.Equal(events.AccountStatusDeactivated, u.GetUpstreamStatus())
+}
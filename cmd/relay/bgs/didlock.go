@@ -0,0 +1,49 @@
+package bgs
+
+import (
+	"sync"
+)
+
+// didLocks serializes work per-DID, while allowing work on distinct DIDs to proceed concurrently.
+// Entries are reference counted and removed once the last holder or waiter releases, so the map stays bounded by the number of DIDs in flight.
+type didLocks struct {
+	lk    sync.Mutex
+	locks map[string]*didLock
+}
+
+type didLock struct {
+	lk sync.Mutex
+	// number of holders plus waiters; protected by didLocks.lk
+	refs int
+}
+
+func newDIDLocks() *didLocks {
+	return &didLocks{
+		locks: make(map[string]*didLock),
+	}
+}
+
+// lock blocks until the lock for did is held, and returns a function which releases it
+func (dl *didLocks) lock(did string) func() {
+	dl.lk.Lock()
+	l, ok := dl.locks[did]
+	if !ok {
+		l = &didLock{}
+		dl.locks[did] = l
+	}
+	l.refs++
+	dl.lk.Unlock()
+
+	l.lk.Lock()
+
+	return func() {
+		l.lk.Unlock()
+
+		dl.lk.Lock()
+		defer dl.lk.Unlock()
+		l.refs--
+		if l.refs == 0 {
+			delete(dl.locks, did)
+		}
+	}
+}
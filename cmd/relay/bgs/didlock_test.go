@@ -0,0 +1,74 @@
+package bgs
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDIDLocks(t *testing.T) {
+	assert := assert.New(t)
+
+	dl := newDIDLocks()
+
+	// same DID is serialized
+	var inside atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := dl.lock("did:example:one")
+			defer unlock()
+			assert.Equal(int32(1), inside.Add(1))
+			time.Sleep(time.Millisecond)
+			inside.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	// distinct DIDs do not block each other
+	unlockA := dl.lock("did:example:a")
+	unlockB := dl.lock("did:example:b")
+	unlockB()
+	unlockA()
+
+	// entries are cleaned up once released
+	assert.Equal(0, len(dl.locks))
+}
+
+// simulates the database work done while holding the lock in syncPDSAccount()
+func simulatedAccountSync() {
+	time.Sleep(50 * time.Microsecond)
+}
+
+func BenchmarkAccountSyncGlobalLock(b *testing.B) {
+	var lk sync.Mutex
+	var n atomic.Int64
+	b.SetParallelism(16)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = fmt.Sprintf("did:example:%d", n.Add(1))
+			lk.Lock()
+			simulatedAccountSync()
+			lk.Unlock()
+		}
+	})
+}
+
+func BenchmarkAccountSyncDIDLocks(b *testing.B) {
+	dl := newDIDLocks()
+	var n atomic.Int64
+	b.SetParallelism(16)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			unlock := dl.lock(fmt.Sprintf("did:example:%d", n.Add(1)))
+			simulatedAccountSync()
+			unlock()
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package bgs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrDomainAlreadyBanned = errors.New("domain is already banned")
+var ErrInvalidBanDomain = errors.New("invalid domain for ban")
+var ErrHostBanned = errors.New("host is covered by a domain ban")
+
+// normalizes a domain (or hostname) for storage and comparison in the ban list
+func normalizeBanDomain(domain string) (string, error) {
+	d := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if d == "" || strings.ContainsAny(d, ":/ ") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidBanDomain, domain)
+	}
+	return d, nil
+}
+
+// ListDomainBans returns all current domain bans, ordered by domain
+func (bgs *BGS) ListDomainBans(ctx context.Context) ([]DomainBan, error) {
+	var all []DomainBan
+	if err := bgs.db.WithContext(ctx).Order("domain").Find(&all).Error; err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// AddDomainBan bans a domain (and any subdomains, unless DomainBanExactMatch is configured), then disconnects from any currently subscribed hosts which are covered by the ban.
+//
+// Returns ErrDomainAlreadyBanned if there is an existing ban for the exact domain.
+func (bgs *BGS) AddDomainBan(ctx context.Context, domain, reason string) error {
+	d, err := normalizeBanDomain(domain)
+	if err != nil {
+		return err
+	}
+	found, err := bgs.slurper.findDomainBan(ctx, d)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("%w: %s", ErrDomainAlreadyBanned, d)
+	}
+	if err := bgs.db.WithContext(ctx).Create(&DomainBan{
+		Domain: d,
+		Reason: reason,
+	}).Error; err != nil {
+		return err
+	}
+	return bgs.evictBannedHosts(ctx)
+}
+
+// RemoveDomainBan removes the ban for an exact domain. Bans on parent domains are not affected.
+//
+// Hosts which were disconnected by the ban are not automatically re-subscribed.
+func (bgs *BGS) RemoveDomainBan(ctx context.Context, domain string) error {
+	d, err := normalizeBanDomain(domain)
+	if err != nil {
+		return err
+	}
+	return bgs.db.WithContext(ctx).Where("domain = ?", d).Delete(&DomainBan{}).Error
+}
+
+// disconnects from any actively subscribed hosts which are covered by a domain ban
+func (bgs *BGS) evictBannedHosts(ctx context.Context) error {
+	var errs []error
+	for _, host := range bgs.slurper.GetActiveList() {
+		banned, err := bgs.domainIsBanned(ctx, host)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !banned {
+			continue
+		}
+		bgs.log.Warn("disconnecting from banned host", "host", host)
+		if err := bgs.slurper.DisconnectHost(ctx, host); err != nil && !errors.Is(err, ErrNoActiveConnection) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Returns the domains which a ban could be recorded under, for the given host. The port (if any) is ignored.
+//
+// With exact matching, this is just the normalized hostname. Otherwise it is the hostname and every parent domain, stopping short of the TLD (TLDs can not be banned).
+func banCandidates(host string, exact bool) []string {
+	// ignore ports when checking for ban status
+	hostname, _, _ := strings.Cut(host, ":")
+	var segments []string
+	for _, seg := range strings.Split(hostname, ".") {
+		if seg == "" {
+			continue
+		}
+		segments = append(segments, strings.ToLower(seg))
+	}
+	if len(segments) == 0 {
+		return nil
+	}
+	if exact {
+		return []string{strings.Join(segments, ".")}
+	}
+	var out []string
+	for i := 0; i < len(segments)-1; i++ {
+		out = append(out, strings.Join(segments[i:], "."))
+	}
+	return out
+}
+
+// hostIsBanned checks if the given host is covered by a domain ban
+func (s *Slurper) hostIsBanned(ctx context.Context, host string) (bool, error) {
+	candidates := banCandidates(host, s.domainBanExactMatch)
+	if len(candidates) == 0 {
+		return false, nil
+	}
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&DomainBan{}).Where("domain IN ?", candidates).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// findDomainBan checks for a ban on exactly the given (normalized) domain
+func (s *Slurper) findDomainBan(ctx context.Context, domain string) (bool, error) {
+	var db DomainBan
+	if err := s.db.WithContext(ctx).Find(&db, "domain = ?", domain).Error; err != nil {
+		return false, err
+	}
+	return db.ID != 0, nil
+}
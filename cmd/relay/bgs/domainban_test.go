@@ -0,0 +1,115 @@
+package bgs
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/bluesky-social/indigo/cmd/relay/models"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func testDomainBanBGS(t *testing.T) *BGS {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "relay.sqlite")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(DomainBan{}, models.PDS{}); err != nil {
+		t.Fatal(err)
+	}
+	return &BGS{
+		db:      db,
+		slurper: &Slurper{db: db, active: make(map[string]*activeSub)},
+		log:     slog.Default(),
+	}
+}
+
+// registers an active subscription which exits as soon as it is cancelled
+func addTestSub(s *Slurper, host string) *activeSub {
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := &activeSub{pds: &models.PDS{Host: host}, ctx: ctx, done: make(chan struct{})}
+	sub.cancel = cancel
+	// like subscribeWithRedialer(), clean up asynchronously once cancelled
+	go func() {
+		<-ctx.Done()
+		s.lk.Lock()
+		delete(s.active, host)
+		s.lk.Unlock()
+		close(sub.done)
+	}()
+	s.active[host] = sub
+	return sub
+}
+
+func TestDomainBans(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	bgs := testDomainBanBGS(t)
+
+	addTestSub(bgs.slurper, "pds.example.com")
+	addTestSub(bgs.slurper, "pds.example.org")
+
+	assert.NoError(bgs.AddDomainBan(ctx, "Example.COM.", "spam"))
+	assert.ErrorIs(bgs.AddDomainBan(ctx, "example.com", ""), ErrDomainAlreadyBanned)
+	assert.ErrorIs(bgs.AddDomainBan(ctx, "", ""), ErrInvalidBanDomain)
+
+	bans, err := bgs.ListDomainBans(ctx)
+	assert.NoError(err)
+	assert.Equal(1, len(bans))
+	assert.Equal("example.com", bans[0].Domain)
+	assert.Equal("spam", bans[0].Reason)
+
+	// matching host was disconnected; others were not
+	assert.Equal([]string{"pds.example.org"}, bgs.slurper.GetActiveList())
+
+	assert.NoError(bgs.RemoveDomainBan(ctx, "example.com"))
+	bans, err = bgs.ListDomainBans(ctx)
+	assert.NoError(err)
+	assert.Empty(bans)
+}
+
+func TestBanCandidates(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"pds.example.com", "example.com"}, banCandidates("PDS.Example.com:443", false))
+	assert.Equal([]string{"pds.example.com"}, banCandidates("PDS.Example.com:443", true))
+	assert.Equal([]string{"localhost"}, banCandidates("localhost", true))
+	assert.Empty(banCandidates("localhost", false))
+	assert.Empty(banCandidates("", false))
+}
+
+func TestHostIsBanned(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	bgs := testDomainBanBGS(t)
+	s := bgs.slurper
+
+	assert.NoError(bgs.AddDomainBan(ctx, "example.com", ""))
+	assert.NoError(bgs.AddDomainBan(ctx, "pds.example.org", ""))
+
+	for _, exact := range []bool{false, true} {
+		s.domainBanExactMatch = exact
+		for host, banned := range map[string]bool{
+			"example.com":          true,
+			"example.com:2470":     true,
+			"pds.example.org":      true,
+			"other.example.org":    false,
+			"example.org":          false,
+			"pds.example.com":      !exact,
+			"deep.pds.example.com": !exact,
+			"notexample.com":       false,
+		} {
+			found, err := s.hostIsBanned(ctx, host)
+			assert.NoError(err)
+			assert.Equal(banned, found, "host=%s exact=%v", host, exact)
+		}
+	}
+
+	// banned hosts can't be subscribed to
+	s.domainBanExactMatch = false
+	assert.ErrorIs(s.SubscribeToPds(ctx, "pds.example.com", true, true, nil), ErrHostBanned)
+}
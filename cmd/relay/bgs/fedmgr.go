@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"log/slog"
 	"math/rand"
+	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/RussellLuo/slidingwindow"
@@ -18,6 +21,7 @@ import (
 
 	"github.com/gorilla/websocket"
 	pq "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
 	"gorm.io/gorm"
 )
 
@@ -46,11 +50,22 @@ type Slurper struct {
 	newSubsDisabled bool
 	trustedDomains  []string
 
+	// when set, no new host subscriptions are started; existing subscriptions (including their reconnects) are unaffected
+	maintenance atomic.Bool
+
 	shutdownChan   chan bool
 	shutdownResult chan []error
 
 	ssl bool
 
+	maxReconnectBackoff time.Duration
+
+	// if true, domain bans only match the exact hostname, not subdomains
+	domainBanExactMatch bool
+
+	userAgent   string
+	hostHeaders func(hostname string) map[string]string
+
 	log *slog.Logger
 }
 
@@ -69,6 +84,18 @@ type SlurperOptions struct {
 	ConcurrencyPerPDS     int64
 	MaxQueuePerPDS        int64
 
+	// MaxReconnectBackoff caps the (exponential, jittered) delay between reconnection attempts to a single host
+	MaxReconnectBackoff time.Duration
+
+	// DomainBanExactMatch restricts domain bans to the exact hostname. By default, a ban also covers all subdomains (a ban on example.com blocks pds.example.com)
+	DomainBanExactMatch bool
+
+	// UserAgent is sent in the websocket handshake when subscribing to hosts. Empty means the websocket library default.
+	UserAgent string
+
+	// HostHeaders, if set, returns extra HTTP headers (eg, an auth token) to send in the websocket handshake when subscribing to the given hostname
+	HostHeaders func(hostname string) map[string]string
+
 	Logger *slog.Logger
 }
 
@@ -81,6 +108,7 @@ func DefaultSlurperOptions() *SlurperOptions {
 		DefaultRepoLimit:      100,
 		ConcurrencyPerPDS:     100,
 		MaxQueuePerPDS:        1_000,
+		MaxReconnectBackoff:   30 * time.Second,
 
 		Logger: slog.Default(),
 	}
@@ -91,12 +119,110 @@ type activeSub struct {
 	lk     sync.RWMutex
 	ctx    context.Context
 	cancel func()
+	// done is closed once the subscription goroutine has exited and all in-flight events have been processed
+	done chan struct{}
+
+	// connection state, protected by lk
+	connected   bool
+	connectedAt time.Time
+	eventCount  int64
+	lastErr     error
+	lastErrAt   time.Time
+	retryAt     time.Time
+
+	// worker pool for the current connection, protected by lk; nil when not connected
+	pool *parallel.Scheduler
 }
 
 func (sub *activeSub) updateCursor(curs int64) {
 	sub.lk.Lock()
 	defer sub.lk.Unlock()
 	sub.pds.Cursor = curs
+	sub.eventCount++
+}
+
+// updateReceived records the sequence number of an event read from the host, before it is processed
+func (sub *activeSub) updateReceived(seq int64) {
+	sub.lk.Lock()
+	defer sub.lk.Unlock()
+	sub.pds.LastReceivedSeq = seq
+}
+
+// lag returns the last received and last processed sequence numbers
+func (sub *activeSub) lag() (received, processed int64) {
+	sub.lk.RLock()
+	defer sub.lk.RUnlock()
+	return sub.pds.LastReceivedSeq, sub.pds.Cursor
+}
+
+func (sub *activeSub) setConnected() {
+	sub.lk.Lock()
+	defer sub.lk.Unlock()
+	sub.connected = true
+	sub.connectedAt = time.Now()
+	sub.eventCount = 0
+}
+
+func (sub *activeSub) setPool(pool *parallel.Scheduler) {
+	sub.lk.Lock()
+	defer sub.lk.Unlock()
+	sub.pool = pool
+}
+
+func (sub *activeSub) setRetryAt(t time.Time) {
+	sub.lk.Lock()
+	defer sub.lk.Unlock()
+	sub.retryAt = t
+}
+
+func (sub *activeSub) setDisconnected(err error) {
+	sub.lk.Lock()
+	defer sub.lk.Unlock()
+	sub.connected = false
+	sub.pool = nil
+	if err != nil {
+		sub.lastErr = err
+		sub.lastErrAt = time.Now()
+	}
+}
+
+// HostStatusInfo is a point-in-time snapshot of a single upstream subscription
+type HostStatusInfo struct {
+	Host      string
+	Connected bool
+	Cursor    int64
+	// LastReceivedSeq is the most recent sequence number read from the host; the difference from Cursor is events received but not yet processed
+	LastReceivedSeq int64
+	// EventsPerSecond is the average event rate since the current connection was established (zero if not connected)
+	EventsPerSecond float64
+	LastError       string
+	LastErrorAt     time.Time
+	// RetryIn is the time until the next reconnection attempt, when waiting in reconnection backoff (otherwise zero)
+	RetryIn time.Duration
+}
+
+func (sub *activeSub) status() HostStatusInfo {
+	sub.lk.RLock()
+	defer sub.lk.RUnlock()
+	info := HostStatusInfo{
+		Host:            sub.pds.Host,
+		Connected:       sub.connected,
+		Cursor:          sub.pds.Cursor,
+		LastReceivedSeq: sub.pds.LastReceivedSeq,
+		LastErrorAt:     sub.lastErrAt,
+	}
+	if sub.connected {
+		if elapsed := time.Since(sub.connectedAt).Seconds(); elapsed > 0 {
+			info.EventsPerSecond = float64(sub.eventCount) / elapsed
+		}
+	}
+	if sub.lastErr != nil {
+		info.LastError = sub.lastErr.Error()
+	}
+	if !sub.retryAt.IsZero() {
+		info.RetryIn = max(time.Until(sub.retryAt), 0)
+	}
+	return info
 }
 
 func NewSlurper(db *gorm.DB, cb IndexCallback, opts *SlurperOptions) (*Slurper, error) {
@@ -119,10 +245,23 @@ func NewSlurper(db *gorm.DB, cb IndexCallback, opts *SlurperOptions) (*Slurper,
 		ConcurrencyPerPDS:     opts.ConcurrencyPerPDS,
 		MaxQueuePerPDS:        opts.MaxQueuePerPDS,
 		ssl:                   opts.SSL,
+		maxReconnectBackoff:   opts.MaxReconnectBackoff,
+		domainBanExactMatch:   opts.DomainBanExactMatch,
+		userAgent:             opts.UserAgent,
+		hostHeaders:           opts.HostHeaders,
 		shutdownChan:          make(chan bool),
 		shutdownResult:        make(chan []error),
 		log:                   opts.Logger,
 	}
+	if s.maxReconnectBackoff <= 0 {
+		s.maxReconnectBackoff = DefaultSlurperOptions().MaxReconnectBackoff
+	}
+	if s.ConcurrencyPerPDS <= 0 {
+		s.ConcurrencyPerPDS = DefaultSlurperOptions().ConcurrencyPerPDS
+	}
+	if s.MaxQueuePerPDS <= 0 {
+		s.MaxQueuePerPDS = DefaultSlurperOptions().MaxQueuePerPDS
+	}
 	if err := s.loadConfig(); err != nil {
 		return nil, err
 	}
@@ -269,6 +408,15 @@ func (s *Slurper) GetNewSubsDisabledState() bool {
 	return s.newSubsDisabled
 }
 
+// SetMaintenanceMode enables or disables maintenance mode. While enabled, new host subscriptions (from crawl requests, admin requests, or ReconnectHost) fail with ErrMaintenanceMode. Existing subscriptions keep running and reconnecting as usual. This state is not persisted.
+func (s *Slurper) SetMaintenanceMode(on bool) {
+	s.maintenance.Store(on)
+}
+
+func (s *Slurper) MaintenanceMode() bool {
+	return s.maintenance.Load()
+}
+
 func (s *Slurper) SetNewPDSPerDayLimit(limit int64) error {
 	s.lk.Lock()
 	defer s.lk.Unlock()
@@ -340,6 +488,9 @@ func (s *Slurper) GetTrustedDomains() []string {
 
 var ErrNewSubsDisabled = fmt.Errorf("new subscriptions temporarily disabled")
 
+// ErrMaintenanceMode is returned when starting a new host subscription while the relay is in maintenance mode
+var ErrMaintenanceMode = errors.New("relay is in maintenance mode; new connections are temporarily disabled")
+
 // Checks whether a host is allowed to be subscribed to
 // must be called with the slurper lock held
 func (s *Slurper) canSlurpHost(host string) bool {
@@ -376,6 +527,18 @@ func (s *Slurper) SubscribeToPds(ctx context.Context, host string, reg bool, adm
 		return nil
 	}
 
+	if s.maintenance.Load() {
+		return fmt.Errorf("subscribing to %q: %w", host, ErrMaintenanceMode)
+	}
+
+	banned, err := s.hostIsBanned(ctx, host)
+	if err != nil {
+		return err
+	}
+	if banned {
+		return fmt.Errorf("subscribing to %q: %w", host, ErrHostBanned)
+	}
+
 	var peering models.PDS
 	if err := s.db.Find(&peering, "host = ?", host).Error; err != nil {
 		return err
@@ -427,6 +590,7 @@ func (s *Slurper) SubscribeToPds(ctx context.Context, host string, reg bool, adm
 		pds:    &peering,
 		ctx:    ctx,
 		cancel: cancel,
+		done:   make(chan struct{}),
 	}
 	s.active[host] = &sub
 
@@ -448,21 +612,40 @@ func (s *Slurper) RestartAll() error {
 
 	for _, pds := range all {
 		pds := pds
+		s.resumeSub(&pds)
+	}
 
-		ctx, cancel := context.WithCancel(context.Background())
-		sub := activeSub{
-			pds:    &pds,
-			ctx:    ctx,
-			cancel: cancel,
-		}
-		s.active[pds.Host] = &sub
+	return nil
+}
 
-		// Check if we've already got a limiter for this PDS
-		s.GetOrCreateLimiters(pds.ID, int64(pds.RateLimit), pds.HourlyEventLimit, pds.DailyEventLimit)
-		go s.subscribeWithRedialer(ctx, &pds, &sub, false)
+// resumeSub starts a subscription to an existing host, from its persisted cursor. Caller must hold s.lk
+func (s *Slurper) resumeSub(pds *models.PDS) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := activeSub{
+		pds:    pds,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
 	}
+	s.active[pds.Host] = &sub
 
-	return nil
+	// Check if we've already got a limiter for this PDS
+	s.GetOrCreateLimiters(pds.ID, int64(pds.RateLimit), pds.HourlyEventLimit, pds.DailyEventLimit)
+	go s.subscribeWithRedialer(ctx, pds, &sub, false)
+}
+
+// dialHeader returns the HTTP headers for a websocket subscription handshake to the given host
+func (s *Slurper) dialHeader(hostname string) http.Header {
+	header := http.Header{}
+	if s.userAgent != "" {
+		header.Set("User-Agent", s.userAgent)
+	}
+	if s.hostHeaders != nil {
+		for k, v := range s.hostHeaders(hostname) {
+			header.Set(k, v)
+		}
+	}
+	return header
 }
 
 func (s *Slurper) subscribeWithRedialer(ctx context.Context, host *models.PDS, sub *activeSub, newHost bool) {
@@ -470,7 +653,15 @@ func (s *Slurper) subscribeWithRedialer(ctx context.Context, host *models.PDS, s
 		s.lk.Lock()
 		defer s.lk.Unlock()
 
-		delete(s.active, host.Host)
+		// a reconnect may have already replaced this subscription
+		if s.active[host.Host] == sub {
+			delete(s.active, host.Host)
+			hostLastReceivedSeq.DeleteLabelValues(host.Host)
+			hostSeqLag.DeleteLabelValues(host.Host)
+			hostWorkersInFlight.DeleteLabelValues(host.Host)
+			hostQueueDepth.DeleteLabelValues(host.Host)
+		}
+		close(sub.done)
 	}()
 
 	d := websocket.Dialer{
@@ -491,14 +682,23 @@ func (s *Slurper) subscribeWithRedialer(ctx context.Context, host *models.PDS, s
 
 	connectedInbound.Inc()
 	defer connectedInbound.Dec()
-	// TODO:? maybe keep a gauge of 'in retry backoff' sources?
 
+	// backoff counts consecutive failed or short-lived connections, and determines the delay before redialing
 	var backoff int
+	// dialFailures counts failed dials since events were last received, for disabling hosts which appear to be offline
+	var dialFailures int
 	for {
-		select {
-		case <-ctx.Done():
+		if !s.waitForReconnect(ctx, sub, backoff) {
+			return
+		}
+
+		// the host may have been banned since the subscription started
+		banned, err := s.hostIsBanned(ctx, host.Host)
+		if err != nil {
+			s.log.Error("failed to check domain ban status", "pdsHost", host.Host, "err", err)
+		} else if banned {
+			s.log.Warn("not connecting to banned host", "pdsHost", host.Host)
 			return
-		default:
 		}
 
 		var url string
@@ -507,13 +707,14 @@ func (s *Slurper) subscribeWithRedialer(ctx context.Context, host *models.PDS, s
 		} else {
 			url = fmt.Sprintf("%s://%s/xrpc/com.atproto.sync.subscribeRepos?cursor=%d", protocol, host.Host, cursor)
 		}
-		con, res, err := d.DialContext(ctx, url, nil)
+		con, res, err := d.DialContext(ctx, url, s.dialHeader(host.Host))
 		if err != nil {
+			sub.setDisconnected(err)
 			s.log.Warn("dialing failed", "pdsHost", host.Host, "err", err, "backoff", backoff)
-			time.Sleep(sleepForBackoff(backoff))
 			backoff++
+			dialFailures++
 
-			if backoff > 15 {
+			if dialFailures > 15 {
 				s.log.Warn("pds does not appear to be online, disabling for now", "pdsHost", host.Host)
 				if err := s.db.Model(&models.PDS{}).Where("id = ?", host.ID).Update("registered", false).Error; err != nil {
 					s.log.Error("failed to unregister failing pds", "err", err)
@@ -528,31 +729,67 @@ func (s *Slurper) subscribeWithRedialer(ctx context.Context, host *models.PDS, s
 		s.log.Info("event subscription response", "code", res.StatusCode, "url", url)
 
 		curCursor := cursor
-		if err := s.handleConnection(ctx, host, con, &cursor, sub); err != nil {
+		connStart := time.Now()
+		sub.setConnected()
+		err = s.handleConnection(ctx, host, con, &cursor, sub)
+		sub.setDisconnected(err)
+		if err != nil {
 			if errors.Is(err, ErrTimeoutShutdown) {
 				s.log.Info("shutting down pds subscription after timeout", "host", host.Host, "time", EventsTimeout)
 				return
 			}
 			s.log.Warn("connection to failed", "host", host.Host, "err", err)
-			// TODO: measure the last N connection error times and if they're coming too fast reconnect slower or don't reconnect and wait for requestCrawl
 		}
 
 		if cursor > curCursor {
+			dialFailures = 0
+		}
+		// only a connection which stayed up for a while resets backoff; a host which accepts connections then quickly drops them gets the same backoff as one which refuses them
+		if time.Since(connStart) >= sustainedConnectionTime {
 			backoff = 0
+		} else {
+			backoff++
 		}
 	}
 }
 
-func sleepForBackoff(b int) time.Duration {
-	if b == 0 {
-		return 0
+// a connection which lasts at least this long resets reconnection backoff
+const sustainedConnectionTime = 30 * time.Second
+
+// waitForReconnect sleeps for the reconnection backoff period, recording the expected retry time on the subscription.
+// Returns false if the context was cancelled while waiting.
+func (s *Slurper) waitForReconnect(ctx context.Context, sub *activeSub, attempt int) bool {
+	delay := reconnectBackoff(attempt, s.maxReconnectBackoff)
+	if delay <= 0 {
+		return ctx.Err() == nil
 	}
+	sub.setRetryAt(time.Now().Add(delay))
+	defer sub.setRetryAt(time.Time{})
 
-	if b < 10 {
-		return (time.Duration(b) * 2) + (time.Millisecond * time.Duration(rand.Intn(1000)))
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
 	}
+}
 
-	return time.Second * 30
+// reconnectBackoff returns an exponentially increasing delay (starting at one second, capped at maxDelay) for the given number of consecutive failed attempts, with random jitter over the upper half of the range so that many hosts (or many relays) don't redial in lock-step.
+func reconnectBackoff(attempt int, maxDelay time.Duration) time.Duration {
+	if attempt <= 0 {
+		return 0
+	}
+	d := time.Second
+	for i := 1; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+	if d > maxDelay {
+		d = maxDelay
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
 }
 
 var ErrTimeoutShutdown = fmt.Errorf("timed out waiting for new events")
@@ -687,17 +924,42 @@ func (s *Slurper) handleConnection(ctx context.Context, host *models.PDS, con *w
 	instrumentedRSC := events.NewInstrumentedRepoStreamCallbacks(limiters, rsc.EventHandler)
 
 	pool := parallel.NewScheduler(
-		100,
-		1_000,
+		int(s.ConcurrencyPerPDS),
+		int(s.MaxQueuePerPDS),
 		con.RemoteAddr().String(),
 		instrumentedRSC.EventHandler,
 	)
-	return events.HandleRepoStream(ctx, con, pool, nil)
+	sub.setPool(pool)
+	return events.HandleRepoStream(ctx, con, &receivedSeqScheduler{
+		Scheduler: pool,
+		sub:       sub,
+		queueFull: hostQueueFullCounter.WithLabelValues(host.Host),
+	}, nil)
+}
+
+// receivedSeqScheduler records the sequence number of each event as it is read from the upstream connection, before it is queued for processing
+type receivedSeqScheduler struct {
+	*parallel.Scheduler
+	sub *activeSub
+	// incremented when an event arrives while the worker pool queue depth is at MaxQueuePerPDS
+	queueFull prometheus.Counter
+}
+
+func (rs *receivedSeqScheduler) AddWork(ctx context.Context, repo string, val *events.XRPCStreamEvent) error {
+	if seq, ok := val.GetSequence(); ok {
+		rs.sub.updateReceived(seq)
+	}
+	if rs.Scheduler.QueueFull() {
+		rs.queueFull.Inc()
+	}
+	return rs.Scheduler.AddWork(ctx, repo, val)
 }
 
 type cursorSnapshot struct {
-	id     uint
-	cursor int64
+	id       uint
+	host     string
+	cursor   int64
+	received int64
 }
 
 // flushCursors updates the PDS cursors in the DB for all active subscriptions
@@ -713,19 +975,28 @@ func (s *Slurper) flushCursors(ctx context.Context) []error {
 	for _, sub := range s.active {
 		sub.lk.RLock()
 		cursors = append(cursors, cursorSnapshot{
-			id:     sub.pds.ID,
-			cursor: sub.pds.Cursor,
+			id:       sub.pds.ID,
+			host:     sub.pds.Host,
+			cursor:   sub.pds.Cursor,
+			received: sub.pds.LastReceivedSeq,
 		})
 		sub.lk.RUnlock()
 	}
 	s.lk.Unlock()
 
+	s.recordPoolSaturation()
+
 	errs := []error{}
 	okcount := 0
 
 	tx := s.db.WithContext(ctx).Begin()
 	for _, cursor := range cursors {
-		if err := tx.WithContext(ctx).Model(models.PDS{}).Where("id = ?", cursor.id).UpdateColumn("cursor", cursor.cursor).Error; err != nil {
+		hostLastReceivedSeq.WithLabelValues(cursor.host).Set(float64(cursor.received))
+		hostSeqLag.WithLabelValues(cursor.host).Set(float64(max(cursor.received-cursor.cursor, 0)))
+		if err := tx.WithContext(ctx).Model(models.PDS{}).Where("id = ?", cursor.id).UpdateColumns(map[string]any{
+			"cursor":            cursor.cursor,
+			"last_received_seq": cursor.received,
+		}).Error; err != nil {
 			errs = append(errs, err)
 		} else {
 			okcount++
@@ -740,6 +1011,38 @@ func (s *Slurper) flushCursors(ctx context.Context) []error {
 	return errs
 }
 
+// recordPoolSaturation updates the per-host worker pool gauges, and the percentage of connected hosts whose queue is at capacity
+func (s *Slurper) recordPoolSaturation() {
+	pools := make(map[string]*parallel.Scheduler)
+	s.lk.Lock()
+	for host, sub := range s.active {
+		sub.lk.RLock()
+		pools[host] = sub.pool
+		sub.lk.RUnlock()
+	}
+	s.lk.Unlock()
+
+	connected, full := 0, 0
+	for host, pool := range pools {
+		if pool == nil {
+			hostWorkersInFlight.WithLabelValues(host).Set(0)
+			hostQueueDepth.WithLabelValues(host).Set(0)
+			continue
+		}
+		connected++
+		if pool.QueueFull() {
+			full++
+		}
+		hostWorkersInFlight.WithLabelValues(host).Set(float64(pool.InFlight()))
+		hostQueueDepth.WithLabelValues(host).Set(float64(pool.QueueDepth()))
+	}
+	if connected > 0 {
+		hostsQueueFullPercent.Set(100 * float64(full) / float64(connected))
+	} else {
+		hostsQueueFullPercent.Set(0)
+	}
+}
+
 func (s *Slurper) GetActiveList() []string {
 	s.lk.Lock()
 	defer s.lk.Unlock()
@@ -751,6 +1054,37 @@ func (s *Slurper) GetActiveList() []string {
 	return out
 }
 
+// HostStatus returns the connection state of every active upstream subscription, sorted by hostname.
+// This only reads in-memory subscription state; cursors may be ahead of what has been flushed to the database.
+func (s *Slurper) HostStatus() []HostStatusInfo {
+	s.lk.Lock()
+	subs := make([]*activeSub, 0, len(s.active))
+	for _, sub := range s.active {
+		subs = append(subs, sub)
+	}
+	s.lk.Unlock()
+
+	out := make([]HostStatusInfo, 0, len(subs))
+	for _, sub := range subs {
+		out = append(out, sub.status())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Host < out[j].Host })
+	return out
+}
+
+// HostLag returns the most recent sequence number received from the host, and the most recent sequence number processed. The difference is the number of events queued for processing: a growing gap means the relay is falling behind, while an unchanging received sequence means the host is quiet (or disconnected).
+//
+// Returns zeros if there is no active subscription to the host. Values are in-memory, and may be ahead of what has been flushed to the database.
+func (s *Slurper) HostLag(hostname string) (received, processed int64) {
+	s.lk.Lock()
+	sub, ok := s.active[hostname]
+	s.lk.Unlock()
+	if !ok {
+		return 0, 0
+	}
+	return sub.lag()
+}
+
 var ErrNoActiveConnection = fmt.Errorf("no active connection to host")
 
 func (s *Slurper) KillUpstreamConnection(host string, block bool) error {
@@ -772,3 +1106,66 @@ func (s *Slurper) KillUpstreamConnection(host string, block bool) error {
 
 	return nil
 }
+
+// DisconnectHost tears down the subscription to a single upstream host, without blocking it.
+// It waits (until ctx is done) for events already received from the host to finish processing, then persists the host's cursor.
+// The host will be re-subscribed on relay restart, or by ReconnectHost().
+func (s *Slurper) DisconnectHost(ctx context.Context, host string) error {
+	s.lk.Lock()
+	sub, ok := s.active[host]
+	if !ok {
+		s.lk.Unlock()
+		return fmt.Errorf("disconnecting %q: %w", host, ErrNoActiveConnection)
+	}
+	sub.cancel()
+	s.lk.Unlock()
+
+	select {
+	case <-sub.done:
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for %q subscription to drain: %w", host, ctx.Err())
+	}
+
+	sub.lk.RLock()
+	cursor := sub.pds.Cursor
+	sub.lk.RUnlock()
+	if err := s.db.WithContext(ctx).Model(models.PDS{}).Where("id = ?", sub.pds.ID).UpdateColumn("cursor", cursor).Error; err != nil {
+		return fmt.Errorf("failed to persist cursor for %q: %w", host, err)
+	}
+	return nil
+}
+
+// ReconnectHost disconnects from a single upstream host (if connected), then re-subscribes, resuming from the last persisted cursor.
+// Blocked hosts are not reconnected.
+func (s *Slurper) ReconnectHost(ctx context.Context, host string) error {
+	// checked before disconnecting, so the existing connection is not dropped
+	if s.maintenance.Load() {
+		return fmt.Errorf("reconnecting %q: %w", host, ErrMaintenanceMode)
+	}
+
+	if err := s.DisconnectHost(ctx, host); err != nil && !errors.Is(err, ErrNoActiveConnection) {
+		return err
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if _, ok := s.active[host]; ok {
+		// something else re-subscribed in the meanwhile
+		return nil
+	}
+
+	var pds models.PDS
+	if err := s.db.WithContext(ctx).Find(&pds, "host = ?", host).Error; err != nil {
+		return err
+	}
+	if pds.ID == 0 {
+		return fmt.Errorf("reconnecting %q: unknown host", host)
+	}
+	if pds.Blocked {
+		return fmt.Errorf("cannot reconnect to blocked pds")
+	}
+
+	s.resumeSub(&pds)
+	return nil
+}
@@ -0,0 +1,251 @@
+package bgs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/cmd/relay/events"
+	"github.com/bluesky-social/indigo/cmd/relay/events/schedulers/parallel"
+	"github.com/bluesky-social/indigo/cmd/relay/models"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestHostStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	s := &Slurper{active: make(map[string]*activeSub)}
+	a := &activeSub{pds: &models.PDS{Host: "a.example.com"}}
+	b := &activeSub{pds: &models.PDS{Host: "b.example.com"}}
+	s.active["b.example.com"] = b
+	s.active["a.example.com"] = a
+
+	a.setConnected()
+	a.updateCursor(10)
+	a.updateCursor(11)
+	b.setDisconnected(errors.New("dial failed"))
+
+	status := s.HostStatus()
+	assert.Equal(2, len(status))
+	assert.Equal("a.example.com", status[0].Host)
+	assert.True(status[0].Connected)
+	assert.Equal(int64(11), status[0].Cursor)
+	assert.True(status[0].EventsPerSecond > 0)
+	assert.Empty(status[0].LastError)
+
+	assert.Equal("b.example.com", status[1].Host)
+	assert.False(status[1].Connected)
+	assert.Equal("dial failed", status[1].LastError)
+	assert.Equal(0.0, status[1].EventsPerSecond)
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	maxDelay := 30 * time.Second
+	assert.Equal(time.Duration(0), reconnectBackoff(0, maxDelay))
+	for attempt, base := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 5: 16 * time.Second, 6: maxDelay, 100: maxDelay} {
+		for i := 0; i < 20; i++ {
+			d := reconnectBackoff(attempt, maxDelay)
+			assert.True(d >= base/2 && d <= base, "attempt %d: %s", attempt, d)
+		}
+	}
+}
+
+func TestHostStatusRetryIn(t *testing.T) {
+	assert := assert.New(t)
+
+	s := &Slurper{active: make(map[string]*activeSub)}
+	a := &activeSub{pds: &models.PDS{Host: "a.example.com"}}
+	s.active["a.example.com"] = a
+
+	a.setRetryAt(time.Now().Add(10 * time.Second))
+	status := s.HostStatus()
+	assert.True(status[0].RetryIn > 9*time.Second && status[0].RetryIn <= 10*time.Second)
+
+	a.setRetryAt(time.Time{})
+	assert.Equal(time.Duration(0), s.HostStatus()[0].RetryIn)
+}
+
+func TestDialHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	s := &Slurper{}
+	assert.Empty(s.dialHeader("pds.example.com"))
+
+	s.userAgent = "relay-test/1.0"
+	s.hostHeaders = func(hostname string) map[string]string {
+		if hostname == "pds.example.com" {
+			return map[string]string{"Authorization": "Bearer secret"}
+		}
+		return nil
+	}
+	h := s.dialHeader("pds.example.com")
+	assert.Equal("relay-test/1.0", h.Get("User-Agent"))
+	assert.Equal("Bearer secret", h.Get("Authorization"))
+
+	h = s.dialHeader("other.example.com")
+	assert.Equal("relay-test/1.0", h.Get("User-Agent"))
+	assert.Empty(h.Get("Authorization"))
+}
+
+func TestHostLag(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	s := &Slurper{active: make(map[string]*activeSub)}
+	a := &activeSub{pds: &models.PDS{Host: "a.example.com"}}
+	s.active["a.example.com"] = a
+
+	pool := parallel.NewScheduler(1, 10, "test", func(context.Context, *events.XRPCStreamEvent) error { return nil })
+	defer pool.Shutdown()
+	sched := &receivedSeqScheduler{Scheduler: pool, sub: a, queueFull: hostQueueFullCounter.WithLabelValues("a.example.com")}
+	for seq := int64(1); seq <= 5; seq++ {
+		assert.NoError(sched.AddWork(ctx, "did:plc:abc123", &events.XRPCStreamEvent{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Seq: seq}}))
+	}
+	a.updateCursor(3)
+
+	received, processed := s.HostLag("a.example.com")
+	assert.Equal(int64(5), received)
+	assert.Equal(int64(3), processed)
+	assert.Equal(int64(5), s.HostStatus()[0].LastReceivedSeq)
+
+	received, processed = s.HostLag("other.example.com")
+	assert.Equal(int64(0), received)
+	assert.Equal(int64(0), processed)
+}
+
+func TestPoolSaturation(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	s := &Slurper{active: make(map[string]*activeSub)}
+	a := &activeSub{pds: &models.PDS{Host: "a.example.com"}}
+	b := &activeSub{pds: &models.PDS{Host: "b.example.com"}}
+	s.active["a.example.com"] = a
+	s.active["b.example.com"] = b
+
+	// a single worker, blocked until released
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	pool := parallel.NewScheduler(1, 2, "test", func(context.Context, *events.XRPCStreamEvent) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	a.setPool(pool)
+	queueFull := hostQueueFullCounter.WithLabelValues("a.example.com")
+	fullBefore := testutil.ToFloat64(queueFull)
+	sched := &receivedSeqScheduler{Scheduler: pool, sub: a, queueFull: queueFull}
+	evt := func(seq int64) *events.XRPCStreamEvent {
+		return &events.XRPCStreamEvent{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Seq: seq}}
+	}
+
+	assert.NoError(sched.AddWork(ctx, "did:plc:abc123", evt(1)))
+	<-started
+	assert.Equal(1, pool.InFlight())
+	assert.Equal(0, pool.QueueDepth())
+
+	// events for the same repo wait behind the in-flight one
+	assert.NoError(sched.AddWork(ctx, "did:plc:abc123", evt(2)))
+	assert.NoError(sched.AddWork(ctx, "did:plc:abc123", evt(3)))
+	assert.Equal(2, pool.QueueDepth())
+	assert.True(pool.QueueFull())
+
+	s.recordPoolSaturation()
+	assert.Equal(1.0, testutil.ToFloat64(hostWorkersInFlight.WithLabelValues("a.example.com")))
+	assert.Equal(2.0, testutil.ToFloat64(hostQueueDepth.WithLabelValues("a.example.com")))
+	// b is not connected, so only a counts towards the percentage
+	assert.Equal(100.0, testutil.ToFloat64(hostsQueueFullPercent))
+
+	// adding to a full queue does not block, but is counted
+	assert.NoError(sched.AddWork(ctx, "did:plc:abc123", evt(4)))
+	assert.Equal(3, pool.QueueDepth())
+	assert.Equal(fullBefore+1, testutil.ToFloat64(queueFull))
+
+	close(release)
+	pool.Shutdown()
+	assert.Equal(0, pool.InFlight())
+	assert.Equal(0, pool.QueueDepth())
+
+	a.setDisconnected(nil)
+	s.recordPoolSaturation()
+	assert.Equal(0.0, testutil.ToFloat64(hostWorkersInFlight.WithLabelValues("a.example.com")))
+	assert.Equal(0.0, testutil.ToFloat64(hostsQueueFullPercent))
+}
+
+func TestDisconnectReconnectHost(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "relay.sqlite")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(DomainBan{}, models.PDS{}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSlurper(db, nil, DefaultSlurperOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown()
+
+	// nothing listens on this port, so the reconnected subscription keeps redialing until cancelled
+	host := "127.0.0.1:1"
+	pds := models.PDS{Host: host, Cursor: 10}
+	assert.NoError(db.Create(&pds).Error)
+
+	// disconnecting an active host persists its cursor
+	sub := addTestSub(s, host)
+	sub.pds.ID = pds.ID
+	sub.updateCursor(42)
+	assert.NoError(s.DisconnectHost(ctx, host))
+	s.lk.Lock()
+	assert.Empty(s.active)
+	s.lk.Unlock()
+	assert.NoError(db.First(&pds, pds.ID).Error)
+	assert.Equal(int64(42), pds.Cursor)
+
+	// reconnecting resumes from the persisted cursor
+	assert.NoError(s.ReconnectHost(ctx, host))
+	s.lk.Lock()
+	resumed, ok := s.active[host]
+	s.lk.Unlock()
+	if assert.True(ok) {
+		assert.NotSame(sub, resumed)
+		assert.Equal(int64(42), resumed.pds.Cursor)
+	}
+
+	// reconnecting an already-connected host replaces the subscription
+	assert.NoError(s.ReconnectHost(ctx, host))
+	s.lk.Lock()
+	replaced := s.active[host]
+	s.lk.Unlock()
+	assert.NotSame(resumed, replaced)
+	select {
+	case <-resumed.done:
+	default:
+		t.Error("previous subscription still running after reconnect")
+	}
+
+	assert.NoError(s.DisconnectHost(ctx, host))
+	s.lk.Lock()
+	assert.Empty(s.active)
+	s.lk.Unlock()
+
+	// unknown hosts
+	assert.ErrorIs(s.DisconnectHost(ctx, "unknown.example.com"), ErrNoActiveConnection)
+	assert.ErrorIs(s.DisconnectHost(ctx, host), ErrNoActiveConnection)
+	assert.ErrorContains(s.ReconnectHost(ctx, "unknown.example.com"), "unknown host")
+	s.lk.Lock()
+	assert.Empty(s.active)
+	s.lk.Unlock()
+}
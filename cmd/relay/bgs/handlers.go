@@ -12,6 +12,7 @@ import (
 
 	atproto "github.com/bluesky-social/indigo/api/atproto"
 	comatprototypes "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/bluesky-social/indigo/cmd/relay/events"
 	"gorm.io/gorm"
 
@@ -69,6 +70,7 @@ func (s *BGS) handleComAtprotoSyncRequestCrawl(ctx context.Context, body *comatp
 		Host:   clientHost,
 		Client: http.DefaultClient, // not using the client that auto-retries
 	}
+	s.applyHostIdentity(c)
 
 	desc, err := atproto.ServerDescribeServer(ctx, c)
 	if err != nil {
@@ -103,7 +105,13 @@ func (s *BGS) handleComAtprotoSyncRequestCrawl(ctx context.Context, body *comatp
 		}
 	}
 
-	return s.slurper.SubscribeToPds(ctx, host, true, false, nil)
+	if err := s.slurper.SubscribeToPds(ctx, host, true, false, nil); err != nil {
+		if errors.Is(err, ErrMaintenanceMode) {
+			return echo.NewHTTPError(http.StatusServiceUnavailable, ErrMaintenanceMode.Error())
+		}
+		return err
+	}
+	return nil
 }
 
 func (s *BGS) handleComAtprotoSyncListRepos(ctx context.Context, cursor int64, limit int) (*comatprototypes.SyncListRepos_Output, error) {
@@ -155,6 +163,30 @@ func (s *BGS) handleComAtprotoSyncListRepos(ctx context.Context, cursor int64, l
 
 var ErrUserStatusUnavailable = errors.New("user status unavailable")
 
+func (s *BGS) handleComAtprotoSyncGetRepoStatus(ctx context.Context, did string) (*comatprototypes.SyncGetRepoStatus_Output, error) {
+	status, err := s.GetRepoStatus(ctx, syntax.DID(did))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "repo not found")
+		}
+		s.log.Error("failed to get repo status", "did", did, "err", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to lookup repo status")
+	}
+
+	out := &comatprototypes.SyncGetRepoStatus_Output{
+		Did:    did,
+		Active: status.Active,
+	}
+	// per the lexicon, rev is only included for active repos
+	if status.Active && status.Rev != "" {
+		out.Rev = &status.Rev
+	}
+	if !status.Active {
+		out.Status = &status.Status
+	}
+	return out, nil
+}
+
 func (s *BGS) handleComAtprotoSyncGetLatestCommit(ctx context.Context, did string) (*comatprototypes.SyncGetLatestCommit_Output, error) {
 	u, err := s.lookupUserByDid(ctx, did)
 	if err != nil {
@@ -0,0 +1,80 @@
+package bgs
+
+import (
+	"sync"
+)
+
+// weight of each new observation in the per-host moving average; roughly the last few hundred messages dominate
+const hostErrorRateAlpha = 0.01
+
+// minimum number of observations for a host before the error rate alert hook can fire
+const hostErrorRateMinSamples = 100
+
+// hostVerifyStats tracks an exponentially-weighted moving average of verification failures for each upstream host
+type hostVerifyStats struct {
+	lk    sync.Mutex
+	hosts map[string]*hostErrorRate
+
+	// threshold is the error fraction (0.0 to 1.0) above which onAlert is called. Zero disables alerting.
+	threshold float64
+	onAlert   func(hostname string, errorRate float64)
+}
+
+type hostErrorRate struct {
+	rate     float64
+	samples  int64
+	alerting bool
+}
+
+func newHostVerifyStats(threshold float64, onAlert func(hostname string, errorRate float64)) *hostVerifyStats {
+	return &hostVerifyStats{
+		hosts:     make(map[string]*hostErrorRate),
+		threshold: threshold,
+		onAlert:   onAlert,
+	}
+}
+
+// observe records one verification outcome for a host, updates the per-host gauge, and fires the alert hook when the host's error rate first crosses the threshold
+func (hs *hostVerifyStats) observe(hostname string, failed bool) {
+	sample := 0.0
+	if failed {
+		sample = 1.0
+	}
+
+	hs.lk.Lock()
+	her, ok := hs.hosts[hostname]
+	if !ok {
+		her = &hostErrorRate{rate: sample}
+		hs.hosts[hostname] = her
+	} else {
+		her.rate += hostErrorRateAlpha * (sample - her.rate)
+	}
+	her.samples++
+	rate := her.rate
+	fire := false
+	if hs.threshold > 0 && hs.onAlert != nil && her.samples >= hostErrorRateMinSamples {
+		if rate > hs.threshold && !her.alerting {
+			her.alerting = true
+			fire = true
+		} else if rate <= hs.threshold {
+			her.alerting = false
+		}
+	}
+	hs.lk.Unlock()
+
+	hostVerifyErrorRate.WithLabelValues(hostname).Set(rate)
+	if fire {
+		hs.onAlert(hostname, rate)
+	}
+}
+
+// errorRate returns the current moving-average verification error fraction for a host, or zero if the host hasn't been seen
+func (hs *hostVerifyStats) errorRate(hostname string) float64 {
+	hs.lk.Lock()
+	defer hs.lk.Unlock()
+	her, ok := hs.hosts[hostname]
+	if !ok {
+		return 0
+	}
+	return her.rate
+}
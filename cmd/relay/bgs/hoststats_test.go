@@ -0,0 +1,80 @@
+package bgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostVerifyStats(t *testing.T) {
+	assert := assert.New(t)
+
+	alerts := []float64{}
+	hs := newHostVerifyStats(0.5, func(hostname string, errorRate float64) {
+		assert.Equal("pds.example.com", hostname)
+		alerts = append(alerts, errorRate)
+	})
+	observeN := func(n int, failed bool) {
+		for i := 0; i < n; i++ {
+			hs.observe("pds.example.com", failed)
+		}
+	}
+
+	// unseen hosts have no errors; the first sample seeds the average
+	assert.Equal(0.0, hs.errorRate("pds.example.com"))
+	observeN(150, false)
+	assert.Equal(0.0, hs.errorRate("pds.example.com"))
+
+	// 1 - 0.99^68 is just under the threshold, and 1 - 0.99^69 just over
+	observeN(68, true)
+	assert.Less(hs.errorRate("pds.example.com"), 0.5)
+	assert.Empty(alerts)
+	observeN(1, true)
+	assert.Greater(hs.errorRate("pds.example.com"), 0.5)
+	assert.Equal(1, len(alerts))
+	assert.Greater(alerts[0], 0.5)
+
+	// no re-fire while the host stays above the threshold
+	observeN(200, true)
+	assert.Equal(1, len(alerts))
+
+	// dropping back to the threshold re-arms the hook, which fires again on the next crossing
+	for hs.errorRate("pds.example.com") > 0.5 {
+		observeN(1, false)
+	}
+	assert.Equal(1, len(alerts))
+	for hs.errorRate("pds.example.com") <= 0.5 {
+		observeN(1, true)
+	}
+	assert.Equal(2, len(alerts))
+
+	// hosts are tracked independently
+	assert.Equal(0.0, hs.errorRate("other.example.com"))
+}
+
+func TestHostVerifyStatsMinSamples(t *testing.T) {
+	assert := assert.New(t)
+
+	fired := 0
+	hs := newHostVerifyStats(0.5, func(hostname string, errorRate float64) {
+		fired++
+	})
+	// a new host which fails everything doesn't alert until there are enough samples
+	for i := 0; i < hostErrorRateMinSamples-1; i++ {
+		hs.observe("pds.example.com", true)
+	}
+	assert.Equal(1.0, hs.errorRate("pds.example.com"))
+	assert.Equal(0, fired)
+	hs.observe("pds.example.com", true)
+	assert.Equal(1, fired)
+
+	// zero threshold disables alerting, but rates are still tracked
+	disabled := newHostVerifyStats(0, func(hostname string, errorRate float64) {
+		fired++
+	})
+	for i := 0; i < 2*hostErrorRateMinSamples; i++ {
+		disabled.observe("pds.example.com", true)
+	}
+	assert.Equal(1.0, disabled.errorRate("pds.example.com"))
+	assert.Equal(1, fired)
+}
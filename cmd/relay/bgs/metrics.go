@@ -46,6 +46,11 @@ var eventsSentCounter = promauto.NewCounterVec(prometheus.CounterOpts{
 	Help: "The total number of events sent to consumers",
 }, []string{"remote_addr", "user_agent"})
 
+var eventsBytesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "events_sent_bytes",
+	Help: "Bytes of events sent to consumers. stage=payload counts serialized events before websocket framing and compression; stage=wire counts bytes written to the network connection",
+}, []string{"stage", "compressed"})
+
 var externalUserCreationAttempts = promauto.NewCounter(prometheus.CounterOpts{
 	Name: "bgs_external_user_creation_attempts",
 	Help: "The total number of external users created",
@@ -56,6 +61,11 @@ var connectedInbound = promauto.NewGauge(prometheus.GaugeOpts{
 	Help: "Number of inbound firehoses we are consuming",
 })
 
+var maintenanceModeGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "relay_maintenance_mode",
+	Help: "1 if the relay is in maintenance mode (rejecting new upstream subscriptions and firehose consumers), otherwise 0",
+})
+
 var newUsersDiscovered = promauto.NewCounter(prometheus.CounterOpts{
 	Name: "bgs_new_users_discovered",
 	Help: "The total number of new users discovered directly from the firehose (not from refs)",
@@ -98,6 +108,16 @@ var commitVerifyWarnings = promauto.NewCounterVec(prometheus.CounterOpts{
 	Name: "validator_commit_verify_warnings",
 }, []string{"host", "warn"})
 
+var accountCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "relay_account_cache_hits",
+	Help: "account lookups by DID served from the in-process cache",
+})
+
+var accountCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "relay_account_cache_misses",
+	Help: "account lookups by DID which fell through to the database",
+})
+
 // verify error and short code for why
 var commitVerifyErrors = promauto.NewCounterVec(prometheus.CounterOpts{
 	Name: "validator_commit_verify_errors",
@@ -113,11 +133,67 @@ var commitVerifyOkish = promauto.NewCounterVec(prometheus.CounterOpts{
 	Name: "validator_commit_verify_okish",
 }, []string{"host", "but"})
 
+// signing key cache (ValidatorConfig.KeyCacheSize) effectiveness; only counted when the cache is enabled
+var validatorKeyCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "validator_key_cache_hits",
+	Help: "commit signature verifications which used a cached signing key, skipping the identity directory",
+})
+
+var validatorKeyCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "validator_key_cache_misses",
+	Help: "commit signature verifications which needed an identity directory lookup (key cache enabled)",
+})
+
+// signature failures which triggered a forced identity re-fetch ("attempt"), and those which then verified ("fixed")
+var commitVerifyRefetch = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "validator_commit_verify_refetch",
+}, []string{"host", "result"})
+
+// successful commit signature verifications, by which DID document key matched: "atproto", or "other" (only with AllowAnySigningKey)
+var commitSigningKeyMatches = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "validator_commit_signing_key_matches",
+}, []string{"key"})
+
+// time spent in VerifyCommitMessage() and HandleSync(), by outcome: "ok", "okish", or "error"
+var commitVerifyDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "validator_commit_verify_duration",
+	Help:    "A histogram of commit and sync message verification latencies",
+	Buckets: prometheus.ExponentialBuckets(0.0001, 2, 17),
+}, []string{"host", "outcome"})
+
+// rev time minus local time for #commit and #sync messages, including those rejected for a rev too far in the future. Usually slightly negative (propagation delay); large negative values are backfill or a slow clock, and positive values a fast clock
+var commitClockSkew = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "validator_commit_clock_skew",
+	Help:    "A histogram of commit rev time minus relay time (seconds), by upstream host",
+	Buckets: []float64{-86400, -3600, -600, -60, -10, -1, 0, 1, 10, 60, 600, 3600, 86400},
+}, []string{"host"})
+
+// moving average of the fraction of messages failing verification, per host
+var hostVerifyErrorRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "validator_host_verify_error_rate",
+	Help: "exponentially-weighted moving average of the fraction of commit and sync messages failing verification",
+}, []string{"host"})
+
 // verify error and short code for why
 var syncVerifyErrors = promauto.NewCounterVec(prometheus.CounterOpts{
 	Name: "validator_sync_verify_errors",
 }, []string{"host", "err"})
 
+// verify error and short code for why
+var identityVerifyErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "validator_identity_verify_errors",
+}, []string{"host", "err"})
+
+var identityVerifyWarnings = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "validator_identity_verify_warnings",
+	Help: "#identity messages which were well-formed, but did not match the resolved identity",
+}, []string{"host", "warn"})
+
+// verify error and short code for why
+var accountVerifyErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "validator_account_verify_errors",
+}, []string{"host", "err"})
+
 var accountVerifyWarnings = promauto.NewCounterVec(prometheus.CounterOpts{
 	Name: "validator_account_verify_warnings",
 	Help: "things that have been a little bit wrong with account messages",
@@ -186,3 +262,36 @@ func computeApproximateRequestSize(r *http.Request) int {
 	}
 	return s
 }
+
+// last sequence number received from each upstream host, updated when cursors are flushed
+var hostLastReceivedSeq = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "relay_host_last_received_seq",
+	Help: "most recent sequence number received from an upstream host",
+}, []string{"host"})
+
+// events received from each upstream host but not yet processed, updated when cursors are flushed
+var hostSeqLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "relay_host_seq_lag",
+	Help: "difference between the last received and last processed sequence numbers for an upstream host",
+}, []string{"host"})
+
+// worker pool saturation for each upstream host, sampled when cursors are flushed; used to tune ConcurrencyPerPDS and MaxQueuePerPDS
+var hostWorkersInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "relay_host_workers_in_flight",
+	Help: "number of workers currently processing events from an upstream host (limit is concurrency-per-pds)",
+}, []string{"host"})
+
+var hostQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "relay_host_queue_depth",
+	Help: "number of events from an upstream host waiting for a worker",
+}, []string{"host"})
+
+var hostsQueueFullPercent = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "relay_hosts_queue_full_percent",
+	Help: "percentage of connected upstream hosts whose event queue depth is at max-queue-per-pds",
+})
+
+var hostQueueFullCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_host_queue_full_total",
+	Help: "events received from an upstream host while its queue depth was at max-queue-per-pds",
+}, []string{"host"})
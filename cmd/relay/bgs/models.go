@@ -5,4 +5,6 @@ import "gorm.io/gorm"
 type DomainBan struct {
 	gorm.Model
 	Domain string `gorm:"unique"`
+	// free-form note on why the domain was banned; optional
+	Reason string
 }
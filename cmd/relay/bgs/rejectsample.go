@@ -0,0 +1,90 @@
+package bgs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+
+	"gorm.io/gorm"
+)
+
+// RejectedCommit is a sampled #commit message which failed verification, kept so the failure can be reproduced offline. See BGSConfig.RejectSampleRate.
+type RejectedCommit struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	Host      string
+	DID       string `gorm:"column:did;index"`
+	Seq       int64
+	Rev       string
+	// Reason is the VerifyReason string (eg, "signature"), or "other" for failures which are not a *VerifyError
+	Reason string `gorm:"index"`
+	// Label is the specific VerifyError label, as used in the verification error metrics
+	Label string
+	Error string
+	// Blocks is the CAR slice from the message, as received
+	Blocks []byte
+}
+
+// RejectSink stores sampled rejected commits. Implementations are called synchronously from event processing, and must be safe for concurrent use.
+type RejectSink interface {
+	StoreReject(ctx context.Context, rej *RejectedCommit) error
+}
+
+// DBRejectSink stores rejected commits in a database table, keeping only the most recent samples for each reason
+type DBRejectSink struct {
+	db        *gorm.DB
+	perReason int
+}
+
+const defaultRejectSamplesPerReason = 100
+
+// NewDBRejectSink creates the rejected_commits table if needed. perReason is the number of samples to keep for each reason; zero or less means the default (100).
+func NewDBRejectSink(db *gorm.DB, perReason int) (*DBRejectSink, error) {
+	if err := db.AutoMigrate(RejectedCommit{}); err != nil {
+		return nil, err
+	}
+	if perReason <= 0 {
+		perReason = defaultRejectSamplesPerReason
+	}
+	return &DBRejectSink{db: db, perReason: perReason}, nil
+}
+
+func (s *DBRejectSink) StoreReject(ctx context.Context, rej *RejectedCommit) error {
+	db := s.db.WithContext(ctx)
+	if err := db.Create(rej).Error; err != nil {
+		return fmt.Errorf("storing rejected commit: %w", err)
+	}
+	newest := db.Model(&RejectedCommit{}).Select("id").Where("reason = ?", rej.Reason).Order("id desc").Limit(s.perReason)
+	if err := db.Where("reason = ? AND id NOT IN (?)", rej.Reason, newest).Delete(&RejectedCommit{}).Error; err != nil {
+		return fmt.Errorf("pruning rejected commits: %w", err)
+	}
+	return nil
+}
+
+// sampleRejectedCommit passes a fraction (BGSConfig.RejectSampleRate) of rejected commits to the reject sink, if one is configured
+func (bgs *BGS) sampleRejectedCommit(ctx context.Context, hostname string, evt *comatproto.SyncSubscribeRepos_Commit, verr error) {
+	if bgs.rejectSink == nil || rand.Float64() >= bgs.config.RejectSampleRate {
+		return
+	}
+	rej := &RejectedCommit{
+		Host:   hostname,
+		DID:    evt.Repo,
+		Seq:    evt.Seq,
+		Rev:    evt.Rev,
+		Reason: "other",
+		Error:  verr.Error(),
+		Blocks: evt.Blocks,
+	}
+	var ve *VerifyError
+	if errors.As(verr, &ve) {
+		rej.Reason = ve.Reason.String()
+		rej.Label = ve.Label
+	}
+	if err := bgs.rejectSink.StoreReject(ctx, rej); err != nil {
+		bgs.log.Error("failed to store rejected commit sample", "err", err, "pdsHost", hostname, "seq", evt.Seq)
+	}
+}
@@ -0,0 +1,62 @@
+package bgs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRejectSampling(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "relay.sqlite")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := NewDBRejectSink(db, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bgs := &BGS{
+		log:        slog.Default(),
+		rejectSink: sink,
+		config:     BGSConfig{RejectSampleRate: 1.0},
+	}
+
+	sigErr := &VerifyError{Reason: ReasonSignature, Label: "sig4", Err: errors.New("bad signature")}
+	for seq := int64(1); seq <= 3; seq++ {
+		evt := &comatproto.SyncSubscribeRepos_Commit{Repo: "did:plc:abc111", Rev: "3l3qo2vutsw2b", Seq: seq, Blocks: []byte{0x01, byte(seq)}}
+		bgs.sampleRejectedCommit(ctx, "pds.example.com", evt, sigErr)
+	}
+	bgs.sampleRejectedCommit(ctx, "pds.example.com", &comatproto.SyncSubscribeRepos_Commit{Repo: "did:plc:abc222", Seq: 4}, errors.New("db failure"))
+
+	// only the most recent samples are kept for each reason
+	var rejects []RejectedCommit
+	assert.NoError(db.Order("seq asc").Find(&rejects).Error)
+	assert.Equal(3, len(rejects))
+	assert.Equal(int64(2), rejects[0].Seq)
+	assert.Equal(ReasonSignature.String(), rejects[0].Reason)
+	assert.Equal("sig4", rejects[0].Label)
+	assert.Equal("pds.example.com", rejects[0].Host)
+	assert.Equal("did:plc:abc111", rejects[0].DID)
+	assert.Equal([]byte{0x01, 0x02}, rejects[0].Blocks)
+	assert.Equal(int64(3), rejects[1].Seq)
+	assert.Equal("other", rejects[2].Reason)
+	assert.Equal("db failure", rejects[2].Error)
+
+	// nothing is stored with sampling disabled
+	bgs.config.RejectSampleRate = 0
+	bgs.sampleRejectedCommit(ctx, "pds.example.com", &comatproto.SyncSubscribeRepos_Commit{Repo: "did:plc:abc333", Seq: 5}, sigErr)
+	var count int64
+	assert.NoError(db.Model(&RejectedCommit{}).Count(&count).Error)
+	assert.Equal(int64(3), count)
+}
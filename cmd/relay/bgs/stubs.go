@@ -23,6 +23,7 @@ func (s *BGS) RegisterHandlersAppBsky(e *echo.Echo) error {
 
 func (s *BGS) RegisterHandlersComAtproto(e *echo.Echo) error {
 	e.GET("/xrpc/com.atproto.sync.getLatestCommit", s.HandleComAtprotoSyncGetLatestCommit)
+	e.GET("/xrpc/com.atproto.sync.getRepoStatus", s.HandleComAtprotoSyncGetRepoStatus)
 	e.GET("/xrpc/com.atproto.sync.listRepos", s.HandleComAtprotoSyncListRepos)
 	e.POST("/xrpc/com.atproto.sync.requestCrawl", s.HandleComAtprotoSyncRequestCrawl)
 	return nil
@@ -48,6 +49,23 @@ func (s *BGS) HandleComAtprotoSyncGetLatestCommit(c echo.Context) error {
 	return c.JSON(200, out)
 }
 
+func (s *BGS) HandleComAtprotoSyncGetRepoStatus(c echo.Context) error {
+	ctx, span := otel.Tracer("server").Start(c.Request().Context(), "HandleComAtprotoSyncGetRepoStatus")
+	defer span.End()
+	did := c.QueryParam("did")
+
+	_, err := syntax.ParseDID(did)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, XRPCError{Message: fmt.Sprintf("invalid did: %s", did)})
+	}
+
+	out, handleErr := s.handleComAtprotoSyncGetRepoStatus(ctx, did)
+	if handleErr != nil {
+		return handleErr
+	}
+	return c.JSON(200, out)
+}
+
 func (s *BGS) HandleComAtprotoSyncListRepos(c echo.Context) error {
 	ctx, span := otel.Tracer("server").Start(c.Request().Context(), "HandleComAtprotoSyncListRepos")
 	defer span.End()
@@ -0,0 +1,78 @@
+package bgs
+
+import (
+	"context"
+	"errors"
+
+	atrepo "github.com/bluesky-social/indigo/atproto/repo"
+)
+
+// kinds of verification anomaly passed to TraceSink
+const (
+	AnomalyTooBig           = "tooBig"
+	AnomalyRebase           = "rebase"
+	AnomalyLegacyDelete     = "legacyDelete"
+	AnomalyLegacyUpdate     = "legacyUpdate"
+	AnomalyPrevDataMismatch = "prevDataMismatch"
+	AnomalyNormalizeOps     = "normalizeOps"
+	AnomalyInvertOp         = "invertOp"
+)
+
+// induction trace log messages, for each anomaly kind
+var anomalyLogMessages = map[string]string{
+	AnomalyTooBig:           "commit tooBig",
+	AnomalyRebase:           "commit rebase",
+	AnomalyLegacyDelete:     "commit delete op",
+	AnomalyLegacyUpdate:     "commit update op",
+	AnomalyPrevDataMismatch: "commit prevData mismatch",
+	AnomalyNormalizeOps:     "commit ops normalization failed",
+	AnomalyInvertOp:         "commit op inversion failed",
+}
+
+// TraceSink receives machine-readable records of verification anomalies: messages which are accepted, but not fully verifiable or not entirely consistent with relay state. Op normalization and inversion failures (which reject the message) are also recorded, with the offending op, to help debug malformed commits.
+//
+// Implementations are called synchronously from the verification path, and must be safe for concurrent use.
+type TraceSink interface {
+	RecordAnomaly(ctx context.Context, kind string, host string, did string, seq int64, detail map[string]any)
+}
+
+// recordAnomaly passes an anomaly to the configured TraceSink, or falls back to the induction trace log
+func (val *Validator) recordAnomaly(ctx context.Context, kind string, host string, did string, seq int64, detail map[string]any) {
+	if val.traceSink != nil {
+		val.traceSink.RecordAnomaly(ctx, kind, host, did, seq, detail)
+		return
+	}
+	if val.inductionTraceLog == nil {
+		return
+	}
+	msg, ok := anomalyLogMessages[kind]
+	if !ok {
+		msg = "commit " + kind
+	}
+	args := []any{"seq", seq, "pdsHost", host, "repo", did}
+	for k, v := range detail {
+		args = append(args, k, v)
+	}
+	val.inductionTraceLog.Warn(msg, args...)
+}
+
+// invertOpDetail describes a failed op for TraceSink, preferring the op identified by an atrepo.OperationError (from inversion or normalization)
+func invertOpDetail(op *atrepo.Operation, err error) map[string]any {
+	detail := map[string]any{"err": err.Error()}
+	var opErr *atrepo.OperationError
+	if errors.As(err, &opErr) {
+		op = &opErr.Op
+	}
+	if op == nil {
+		return detail
+	}
+	detail["path"] = op.Path
+	detail["action"] = op.Action()
+	if op.Value != nil {
+		detail["value"] = op.Value.String()
+	}
+	if op.Prev != nil {
+		detail["prev"] = op.Prev.String()
+	}
+	return detail
+}
@@ -3,59 +3,244 @@ package bgs
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	atproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/crypto"
+	"github.com/bluesky-social/indigo/atproto/data"
 	"github.com/bluesky-social/indigo/atproto/identity"
 	atrepo "github.com/bluesky-social/indigo/atproto/repo"
+	"github.com/bluesky-social/indigo/atproto/repo/mst"
 	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/bluesky-social/indigo/cmd/relay/events"
 	"github.com/bluesky-social/indigo/cmd/relay/models"
+	"github.com/hashicorp/golang-lru/v2/expirable"
 	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel"
 )
 
 const defaultMaxRevFuture = time.Hour
 
+const defaultKeyCacheTTL = 10 * time.Minute
+
+// well above the 200 ops per commit allowed by the firehose Lexicon, as a cheap guard against pathological commits
+const defaultMaxOpsPerCommit = 1_000
+
+// well above the 2,000,000 byte limit on #commit blocks in the firehose Lexicon. bounds the memory used when decoding an untrusted CAR slice; since every block has at least a length prefix and a CID, this also bounds the number of blocks, so there is no separate block count limit.
+const defaultMaxCommitBlocksBytes = 5_000_000
+
+type ValidatorConfig struct {
+	// MaxRevFuture is the limit of clock skew we'll accept for a `rev` in the future. Zero means defaultMaxRevFuture.
+	MaxRevFuture time.Duration
+
+	// MaxOpsPerCommit is the number of ops above which a #commit is rejected before any per-op verification. Zero means defaultMaxOpsPerCommit.
+	MaxOpsPerCommit int
+
+	// MaxCommitBlocksBytes is the size of the CAR slice (blocks field) above which a #commit or #sync message is rejected, before the CAR is parsed. Zero means defaultMaxCommitBlocksBytes.
+	MaxCommitBlocksBytes int
+
+	// BatchWorkers is the number of concurrent workers used by HandleCommitBatch(). Zero means runtime.NumCPU().
+	BatchWorkers int
+
+	// HostErrorRateThreshold is the moving-average verification error fraction (0.0 to 1.0) above which OnHostErrorRate is called. Zero disables the hook.
+	HostErrorRateThreshold float64
+
+	// OnHostErrorRate is called once each time a host's verification error rate rises above HostErrorRateThreshold
+	OnHostErrorRate func(hostname string, errorRate float64)
+
+	// TraceSink, if set, receives verification anomalies (eg, legacy ops or prevData mismatches) instead of them being written to the induction trace log
+	TraceSink TraceSink
+
+	// ResolveIdentityEvents enables resolving the DID of each #identity message in HandleIdentity(), to check the declared handle and PDS
+	ResolveIdentityEvents bool
+
+	// RequirePrevData rejects #commit messages without a prevData field (legacy protocol sources), instead of passing them as "okish"
+	RequirePrevData bool
+
+	// RejectLegacyOps rejects #commit messages with update or delete ops which lack a prev CID, instead of passing them as "okish"
+	RejectLegacyOps bool
+
+	// CheckBlobRefs decodes each created or updated record in #commit messages, and rejects the commit if any blob reference has a malformed CID (not a CIDv1 with "raw" codec and SHA-256 multihash), or if the record can't be decoded
+	CheckBlobRefs bool
+
+	// AllowAnySigningKey accepts #commit signatures made by any signing key listed in the account's DID document, not only the "atproto" key. The atproto key is always tried first. This is more lenient than the atproto specification, and is intended to reduce spurious rejections during key rotation windows, before DID documents fully propagate.
+	AllowAnySigningKey bool
+
+	// OnCommitBlobs, if set, is called with the blob CIDs referenced by records in each successfully verified #commit (if any). Decoding record data has a cost, so this is opt-in.
+	OnCommitBlobs func(hostname string, did string, rev string, blobs []cid.Cid)
+
+	// KeyCacheSize is the number of DIDs for which the parsed atproto signing key is cached by the Validator, skipping identity directory lookups for repeated commits. Zero disables the cache.
+	KeyCacheSize int
+
+	// KeyCacheTTL is how long a cached signing key is used before being looked up again. Zero means defaultKeyCacheTTL.
+	KeyCacheTTL time.Duration
+}
+
+func DefaultValidatorConfig() *ValidatorConfig {
+	return &ValidatorConfig{
+		MaxRevFuture:         defaultMaxRevFuture,
+		MaxOpsPerCommit:      defaultMaxOpsPerCommit,
+		MaxCommitBlocksBytes: defaultMaxCommitBlocksBytes,
+	}
+}
+
+// NewValidator creates a Validator with the default configuration (DefaultValidatorConfig)
 func NewValidator(directory identity.Directory, inductionTraceLog *slog.Logger) *Validator {
-	maxRevFuture := defaultMaxRevFuture // TODO: configurable
+	return NewValidatorWithConfig(directory, inductionTraceLog, nil)
+}
+
+// NewValidatorWithConfig creates a Validator with the given configuration. A nil config, or zero-valued fields, get the defaults.
+func NewValidatorWithConfig(directory identity.Directory, inductionTraceLog *slog.Logger, config *ValidatorConfig) *Validator {
+	if config == nil {
+		config = DefaultValidatorConfig()
+	}
+	maxRevFuture := config.MaxRevFuture
+	if maxRevFuture <= 0 {
+		maxRevFuture = defaultMaxRevFuture
+	}
 	ErrRevTooFarFuture := fmt.Errorf("new rev is > %s in the future", maxRevFuture)
+	maxOpsPerCommit := config.MaxOpsPerCommit
+	if maxOpsPerCommit <= 0 {
+		maxOpsPerCommit = defaultMaxOpsPerCommit
+	}
+	maxCommitBlocksBytes := config.MaxCommitBlocksBytes
+	if maxCommitBlocksBytes <= 0 {
+		maxCommitBlocksBytes = defaultMaxCommitBlocksBytes
+	}
+	batchWorkers := config.BatchWorkers
+	if batchWorkers <= 0 {
+		batchWorkers = runtime.NumCPU()
+	}
+	var keyCache *expirable.LRU[syntax.DID, crypto.PublicKey]
+	if config.KeyCacheSize > 0 {
+		keyCacheTTL := config.KeyCacheTTL
+		if keyCacheTTL <= 0 {
+			keyCacheTTL = defaultKeyCacheTTL
+		}
+		keyCache = expirable.NewLRU[syntax.DID, crypto.PublicKey](config.KeyCacheSize, nil, keyCacheTTL)
+	}
 
 	return &Validator{
 		userLocks:         make(map[models.Uid]*userLock),
 		log:               slog.Default().With("system", "validator"),
 		inductionTraceLog: inductionTraceLog,
 		directory:         directory,
+		OpInverter:        DefaultOpInverter{},
 
 		maxRevFuture:           maxRevFuture,
+		maxOpsPerCommit:        maxOpsPerCommit,
+		maxCommitBlocksBytes:   maxCommitBlocksBytes,
+		batchWorkers:           batchWorkers,
+		keyCache:               keyCache,
+		hostStats:              newHostVerifyStats(config.HostErrorRateThreshold, config.OnHostErrorRate),
+		onCommitBlobs:          config.OnCommitBlobs,
+		traceSink:              config.TraceSink,
+		resolveIdentityEvents:  config.ResolveIdentityEvents,
 		ErrRevTooFarFuture:     ErrRevTooFarFuture,
 		AllowSignatureNotFound: true, // TODO: configurable
+		RequirePrevData:        config.RequirePrevData,
+		RejectLegacyOps:        config.RejectLegacyOps,
+		CheckBlobRefs:          config.CheckBlobRefs,
+		AllowAnySigningKey:     config.AllowAnySigningKey,
 	}
 }
 
-// Validator contains the context and code necessary to validate #commit and #sync messages
+// Validator contains the context and code necessary to validate #commit, #sync, #identity, and #account messages
 type Validator struct {
 	lklk      sync.Mutex
 	userLocks map[models.Uid]*userLock
 
 	log               *slog.Logger
 	inductionTraceLog *slog.Logger
+	traceSink         TraceSink
 
 	directory identity.Directory
 
 	// maxRevFuture is added to time.Now() for a limit of clock skew we'll accept a `rev` in the future for
 	maxRevFuture time.Duration
 
+	// maxOpsPerCommit is the number of ops above which a #commit is rejected
+	maxOpsPerCommit int
+
+	// maxCommitBlocksBytes is the CAR slice size above which a #commit or #sync is rejected, before parsing
+	maxCommitBlocksBytes int
+
+	// batchWorkers is the number of concurrent workers used by HandleCommitBatch()
+	batchWorkers int
+
+	// keyCache holds recently used atproto signing keys by DID; nil if ValidatorConfig.KeyCacheSize is zero
+	keyCache *expirable.LRU[syntax.DID, crypto.PublicKey]
+
+	// hostStats tracks moving-average verification error rates per upstream host
+	hostStats *hostVerifyStats
+
+	// resolveIdentityEvents is ValidatorConfig.ResolveIdentityEvents
+	resolveIdentityEvents bool
+
+	// onCommitBlobs is the optional ValidatorConfig.OnCommitBlobs hook
+	onCommitBlobs func(hostname string, did string, rev string, blobs []cid.Cid)
+
 	// ErrRevTooFarFuture is the error we return
 	// held here because we fmt.Errorf() once with our configured maxRevFuture into the message
 	ErrRevTooFarFuture error
 
-	// AllowSignatureNotFound enables counting messages without findable public key to pass through with a warning counter
-	// TODO: refine this for what kind of 'not found' we accept.
+	// AllowSignatureNotFound enables counting messages without findable public key to pass through with a warning counter.
+	// Only applies when the identity definitively does not exist; network and resolution failures are still errors.
 	AllowSignatureNotFound bool
+
+	// RequirePrevData rejects #commit messages without prevData, instead of counting them as okish "old"
+	RequirePrevData bool
+
+	// RejectLegacyOps rejects #commit messages with update or delete ops missing a prev CID, instead of counting them as okish "del" or "up"
+	RejectLegacyOps bool
+
+	// CheckBlobRefs rejects #commit messages with records containing malformed blob reference CIDs
+	CheckBlobRefs bool
+
+	// AllowAnySigningKey verifies commit signatures against every signing key in the DID document (atproto key first), instead of only the atproto key
+	AllowAnySigningKey bool
+
+	// OpInverter normalizes and inverts commit ops when checking a commit's prevData against its MST. If nil, DefaultOpInverter is used.
+	OpInverter OpInverter
+}
+
+// OpInverter is the op normalization and inversion logic used to check #commit prevData
+type OpInverter interface {
+	Normalize(ops []atrepo.Operation) ([]atrepo.Operation, error)
+	Invert(tree *mst.Tree, op *atrepo.Operation) error
+}
+
+// DefaultOpInverter implements OpInverter using atproto/repo NormalizeOps() and InvertOp()
+type DefaultOpInverter struct{}
+
+func (DefaultOpInverter) Normalize(ops []atrepo.Operation) ([]atrepo.Operation, error) {
+	return atrepo.NormalizeOps(ops)
+}
+
+func (DefaultOpInverter) Invert(tree *mst.Tree, op *atrepo.Operation) error {
+	return atrepo.InvertOp(tree, op)
+}
+
+// opInverter returns the configured OpInverter, falling back to DefaultOpInverter for Validators constructed without one
+func (val *Validator) opInverter() OpInverter {
+	if val.OpInverter == nil {
+		return DefaultOpInverter{}
+	}
+	return val.OpInverter
+}
+
+// MaxRevFuture returns the configured limit of clock skew accepted for a `rev` in the future
+func (val *Validator) MaxRevFuture() time.Duration {
+	return val.maxRevFuture
 }
 
 type NextCommitHandler interface {
@@ -104,7 +289,7 @@ func (val *Validator) HandleCommit(ctx context.Context, host *models.PDS, accoun
 	uid := account.GetUid()
 	unlock := val.lockUser(ctx, uid)
 	defer unlock()
-	repoFragment, err := val.VerifyCommitMessage(ctx, host, commit, prevRoot)
+	repoFragment, err := val.verifyCommitMessage(ctx, host.Host, account, commit, prevRoot, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +300,137 @@ func (val *Validator) HandleCommit(ctx context.Context, host *models.PDS, accoun
 	return newRootCid, nil
 }
 
+// CommitJob is one #commit message to be verified by HandleCommitBatch()
+type CommitJob struct {
+	Account  *Account
+	Commit   *atproto.SyncSubscribeRepos_Commit
+	PrevRoot *AccountPreviousState
+}
+
+// CommitResult is the outcome of verifying a single CommitJob
+type CommitResult struct {
+	NewRoot *cid.Cid
+	Err     error
+}
+
+// HandleCommitBatch verifies many commits, working on distinct accounts concurrently.
+// Commits for the same account are verified in input order, and are still serialized against other callers via lockUser().
+// Results are in the same order as jobs, with per-item errors; the returned error is only set if ctx was cancelled, in which case unprocessed jobs carry the context error.
+func (val *Validator) HandleCommitBatch(ctx context.Context, host *models.PDS, jobs []CommitJob) ([]CommitResult, error) {
+	results := make([]CommitResult, len(jobs))
+
+	// group job indexes by account, preserving input order within each account
+	groups := [][]int{}
+	groupIdx := make(map[models.Uid]int)
+	for i, job := range jobs {
+		uid := job.Account.GetUid()
+		gi, ok := groupIdx[uid]
+		if !ok {
+			gi = len(groups)
+			groupIdx[uid] = gi
+			groups = append(groups, nil)
+		}
+		groups[gi] = append(groups[gi], i)
+	}
+
+	work := make(chan []int)
+	var wg sync.WaitGroup
+	workers := min(val.batchWorkers, len(groups))
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range work {
+				for _, i := range group {
+					if err := ctx.Err(); err != nil {
+						results[i].Err = err
+						continue
+					}
+					job := jobs[i]
+					results[i].NewRoot, results[i].Err = val.HandleCommit(ctx, host, job.Account, job.Commit, job.PrevRoot)
+				}
+			}
+		}()
+	}
+	for _, group := range groups {
+		work <- group
+	}
+	close(work)
+	wg.Wait()
+
+	return results, ctx.Err()
+}
+
+// minimum number of ops per worker when verifying a commit's records concurrently
+const parallelOpsThreshold = 32
+
+// verifyRecordOps checks that every create and update op in a commit matches the record CID in the MST, and that the record block is present.
+//
+// Returns the record bytes for each op (nil for ops without a record), in the same order as ops. On failure, returns a short metrics label and the error for the earliest failing op (by index), matching the result of checking the ops in order.
+//
+// Large commits are checked concurrently, by up to maxWorkers goroutines: repoFragment is only read (MST lookups and record store gets), which is safe from multiple goroutines.
+func verifyRecordOps(ctx context.Context, repoFragment *atrepo.Repo, ops []*atproto.SyncSubscribeRepos_RepoOp, maxWorkers int) ([][]byte, string, error) {
+	records := make([][]byte, len(ops))
+	labels := make([]string, len(ops))
+	errs := make([]error, len(ops))
+
+	workers := min(maxWorkers, len(ops)/parallelOpsThreshold)
+	if workers <= 1 {
+		for i, op := range ops {
+			records[i], labels[i], errs[i] = verifyRecordOp(ctx, repoFragment, op)
+			if errs[i] != nil {
+				return nil, labels[i], errs[i]
+			}
+		}
+		return records, "", nil
+	}
+
+	// split ops in to contiguous chunks, one per worker
+	var wg sync.WaitGroup
+	chunk := (len(ops) + workers - 1) / workers
+	for lo := 0; lo < len(ops); lo += chunk {
+		hi := min(lo+chunk, len(ops))
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			for i := lo; i < hi; i++ {
+				records[i], labels[i], errs[i] = verifyRecordOp(ctx, repoFragment, ops[i])
+			}
+		}(lo, hi)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, labels[i], err
+		}
+	}
+	return records, "", nil
+}
+
+func verifyRecordOp(ctx context.Context, repoFragment *atrepo.Repo, op *atproto.SyncSubscribeRepos_RepoOp) ([]byte, string, error) {
+	if !((op.Action == "create" || op.Action == "update") && op.Cid != nil) {
+		return nil, "", nil
+	}
+	c := (*cid.Cid)(op.Cid)
+	nsid, rkey, err := syntax.ParseRepoPath(op.Path)
+	if err != nil {
+		return nil, repoPathErrorLabel(err), fmt.Errorf("invalid repo path in ops list: %w", err)
+	}
+	treeCid, err := repoFragment.GetRecordCID(ctx, nsid, rkey)
+	if err != nil {
+		return nil, "rcid", err
+	}
+	if *c != *treeCid {
+		return nil, "opc", fmt.Errorf("record op doesn't match MST tree value")
+	}
+	recBytes, _, err := repoFragment.GetRecordBytes(ctx, nsid, rkey)
+	if err != nil {
+		return nil, "rec", err
+	}
+	return recBytes, "", nil
+}
+
 type revOutOfOrderError struct {
 	dt time.Duration
 }
@@ -125,98 +441,207 @@ func (roooe *revOutOfOrderError) Error() string {
 
 var ErrNewRevBeforePrevRev = &revOutOfOrderError{}
 
-func (val *Validator) VerifyCommitMessage(ctx context.Context, host *models.PDS, msg *atproto.SyncSubscribeRepos_Commit, prevRoot *AccountPreviousState) (*atrepo.Repo, error) {
-	hostname := host.Host
-	hasWarning := false
-	commitVerifyStarts.Inc()
-	logger := slog.Default().With("did", msg.Repo, "rev", msg.Rev, "seq", msg.Seq, "time", msg.Time)
+// VerifyReason enumerates the categories of #commit and #sync verification failure
+type VerifyReason int
 
-	did, err := syntax.ParseDID(msg.Repo)
-	if err != nil {
-		commitVerifyErrors.WithLabelValues(hostname, "did").Inc()
-		return nil, err
+const (
+	ReasonUnknown VerifyReason = iota
+	// malformed DID, rev TID, or timestamp
+	ReasonBadSyntax
+	// rev is older than the previous rev for the account
+	ReasonRevBeforePrev
+	// rev is further in the future than ValidatorConfig.MaxRevFuture
+	ReasonRevTooFuture
+	// too many ops, duplicate op paths, or ops which can't be parsed or normalized
+	ReasonBadOps
+	// CAR slice is too large, failed to parse, or is missing the commit object
+	ReasonBadCAR
+	// rev in the message doesn't match the signed commit
+	ReasonRevMismatch
+	// DID in the message doesn't match the signed commit (or the account)
+	ReasonDIDMismatch
+	// DID resolution failed (eg, network error)
+	ReasonIdentityLookup
+	// DID could not be found
+	ReasonIdentityNotFound
+	// no atproto signing key, or the commit signature is invalid
+	ReasonSignature
+	// a record op doesn't match the CAR blocks
+	ReasonBadRecord
+	// op is missing prev CID, and ValidatorConfig.RejectLegacyOps is set
+	ReasonLegacyOp
+	// commit is missing prevData, and ValidatorConfig.RequirePrevData is set
+	ReasonMissingPrevData
+	// inverting the ops did not result in the prevData tree root
+	ReasonPrevDataMismatch
+	// a record has a malformed blob reference, and ValidatorConfig.CheckBlobRefs is set
+	ReasonBadBlobRef
+	// the signed commit is not the supported repo version (v3)
+	ReasonRepoVersion
+)
+
+func (r VerifyReason) String() string {
+	switch r {
+	case ReasonBadSyntax:
+		return "bad-syntax"
+	case ReasonRevBeforePrev:
+		return "rev-before-prev"
+	case ReasonRevTooFuture:
+		return "rev-too-future"
+	case ReasonBadOps:
+		return "bad-ops"
+	case ReasonBadCAR:
+		return "bad-car"
+	case ReasonRevMismatch:
+		return "rev-mismatch"
+	case ReasonDIDMismatch:
+		return "did-mismatch"
+	case ReasonIdentityLookup:
+		return "identity-lookup"
+	case ReasonIdentityNotFound:
+		return "identity-not-found"
+	case ReasonSignature:
+		return "signature"
+	case ReasonBadRecord:
+		return "bad-record"
+	case ReasonLegacyOp:
+		return "legacy-op"
+	case ReasonMissingPrevData:
+		return "missing-prev-data"
+	case ReasonPrevDataMismatch:
+		return "prev-data-mismatch"
+	case ReasonBadBlobRef:
+		return "bad-blob-ref"
+	case ReasonRepoVersion:
+		return "repo-version"
+	default:
+		return "unknown"
 	}
-	rev, err := syntax.ParseTID(msg.Rev)
-	if err != nil {
-		commitVerifyErrors.WithLabelValues(hostname, "tid").Inc()
-		return nil, err
+}
+
+// VerifyError is returned by VerifyCommitMessage(), VerifyCommitSignature(), and HandleSync() for all verification failures.
+//
+// Label is the short code used for the failure in the commit/sync verification error metrics; it is more specific than Reason (eg, "sig3" vs "sig4" are both ReasonSignature). The underlying error is available with errors.Is() and errors.As(), so checks like errors.Is(err, ErrMissingPrevData) continue to work.
+type VerifyError struct {
+	Reason VerifyReason
+	Label  string
+	Err    error
+}
+
+func (ve *VerifyError) Error() string {
+	return ve.Err.Error()
+}
+
+func (ve *VerifyError) Unwrap() error {
+	return ve.Err
+}
+
+// verifyFailure increments the error metric and returns a VerifyError with the same label, so the two stay in sync
+func verifyFailure(counter *prometheus.CounterVec, hostname, label string, reason VerifyReason, err error) error {
+	counter.WithLabelValues(hostname, label).Inc()
+	return &VerifyError{Reason: reason, Label: label, Err: err}
+}
+
+// returned by VerifyCommitMessage() for legacy protocol messages, when RequirePrevData or RejectLegacyOps are enabled
+var ErrMissingPrevData = errors.New("commit missing prevData")
+var ErrLegacyOp = errors.New("commit op missing prev CID")
+
+func (val *Validator) VerifyCommitMessage(ctx context.Context, host *models.PDS, msg *atproto.SyncSubscribeRepos_Commit, prevRoot *AccountPreviousState) (*atrepo.Repo, error) {
+	return val.verifyCommitMessage(ctx, host.Host, nil, msg, prevRoot, nil)
+}
+
+// verifyCommitMessage implements VerifyCommitMessage() and VerifyCommitMessageTrace(). If account is not nil (as from HandleCommit), the message and prevRoot are also checked against the account. If trace is not nil, each step is recorded there, and nothing is counted in metrics.
+func (val *Validator) verifyCommitMessage(ctx context.Context, hostname string, account *Account, msg *atproto.SyncSubscribeRepos_Commit, prevRoot *AccountPreviousState, trace *verifyTrace) (_ *atrepo.Repo, err error) {
+	r := &verifyRun{val: val, ctx: ctx, hostname: hostname, msg: msg, trace: trace}
+	fullyVerified := false
+	var blobs []cid.Cid
+	if trace == nil {
+		commitVerifyStarts.Inc()
+		start := time.Now()
+		defer func() {
+			val.observeVerifyOutcome(hostname, start, fullyVerified, err)
+			if err == nil && len(blobs) > 0 {
+				val.onCommitBlobs(hostname, msg.Repo, msg.Rev, blobs)
+			}
+		}()
 	}
-	if prevRoot != nil {
-		prevRev := prevRoot.GetRev()
-		curTime := rev.Time()
-		prevTime := prevRev.Time()
-		if curTime.Before(prevTime) {
-			commitVerifyErrors.WithLabelValues(hostname, "revb").Inc()
-			dt := prevTime.Sub(curTime)
-			return nil, &revOutOfOrderError{dt}
+	logger := slog.Default().With("did", msg.Repo, "rev", msg.Rev, "seq", msg.Seq, "time", msg.Time)
+
+	if account != nil {
+		// prevData checks are only meaningful against previous state for this exact account
+		if account.GetDid() != msg.Repo {
+			return nil, r.fail("account", "acct", ReasonDIDMismatch, fmt.Errorf("commit repo did not match account: %s != %s", msg.Repo, account.GetDid()))
+		}
+		if prevRoot != nil && prevRoot.Uid != account.GetUid() {
+			return nil, r.fail("account", "puid", ReasonDIDMismatch, fmt.Errorf("previous repo state did not belong to account: %d != %d", prevRoot.Uid, account.GetUid()))
 		}
+		r.ok("account", "uid", account.GetUid())
 	}
-	if rev.Time().After(time.Now().Add(val.maxRevFuture)) {
-		commitVerifyErrors.WithLabelValues(hostname, "revf").Inc()
-		return nil, val.ErrRevTooFarFuture
+
+	// observed here, not in checkCommitFields(), so VerifyCommitSignatureOnly() audits don't count messages twice
+	if rev, err := syntax.ParseTID(msg.Rev); err == nil && trace == nil {
+		observeClockSkew(hostname, rev)
 	}
-	_, err = syntax.ParseDatetime(msg.Time)
+	did, rev, err := r.checkCommitFields(prevRoot)
 	if err != nil {
-		commitVerifyErrors.WithLabelValues(hostname, "time").Inc()
 		return nil, err
 	}
 
 	if msg.TooBig {
 		//logger.Warn("event with tooBig flag set")
-		commitVerifyWarnings.WithLabelValues(hostname, "big").Inc()
-		val.inductionTraceLog.Warn("commit tooBig", "seq", msg.Seq, "pdsHost", host.Host, "repo", msg.Repo)
-		hasWarning = true
+		r.warn("flags", "big", "tooBig flag set")
+		r.anomaly(AnomalyTooBig, nil)
 	}
 	if msg.Rebase {
 		//logger.Warn("event with rebase flag set")
-		commitVerifyWarnings.WithLabelValues(hostname, "reb").Inc()
-		val.inductionTraceLog.Warn("commit rebase", "seq", msg.Seq, "pdsHost", host.Host, "repo", msg.Repo)
-		hasWarning = true
+		r.warn("flags", "reb", "rebase flag set")
+		r.anomaly(AnomalyRebase, nil)
 	}
 
+	if err := r.checkBlocksSize(); err != nil {
+		return nil, err
+	}
 	commit, repoFragment, err := atrepo.LoadRepoFromCAR(ctx, bytes.NewReader([]byte(msg.Blocks)))
 	if err != nil {
-		commitVerifyErrors.WithLabelValues(hostname, "car").Inc()
-		return nil, err
+		return nil, r.carFailure(err)
 	}
+	r.ok("load-car", "blocksLen", len(msg.Blocks))
 
-	if commit.Rev != rev.String() {
-		commitVerifyErrors.WithLabelValues(hostname, "rev").Inc()
-		return nil, fmt.Errorf("rev did not match commit")
-	}
-	if commit.DID != did.String() {
-		commitVerifyErrors.WithLabelValues(hostname, "did2").Inc()
-		return nil, fmt.Errorf("rev did not match commit")
+	if err := r.checkCommitObject(commit, did, rev); err != nil {
+		return nil, err
 	}
 
-	err = val.VerifyCommitSignature(ctx, commit, hostname, &hasWarning)
-	if err != nil {
-		// signature errors are metrics counted inside VerifyCommitSignature()
+	if err := r.verifySignature(commit); err != nil {
 		return nil, err
 	}
 
 	// load out all the records
-	for _, op := range msg.Ops {
-		if (op.Action == "create" || op.Action == "update") && op.Cid != nil {
-			c := (*cid.Cid)(op.Cid)
-			nsid, rkey, err := syntax.ParseRepoPath(op.Path)
-			if err != nil {
-				commitVerifyErrors.WithLabelValues(hostname, "opp").Inc()
-				return nil, fmt.Errorf("invalid repo path in ops list: %w", err)
+	records, errLabel, err := verifyRecordOps(ctx, repoFragment, msg.Ops, runtime.NumCPU())
+	if err != nil {
+		return nil, r.fail("records", errLabel, ReasonBadRecord, err)
+	}
+	r.ok("records", "ops", len(msg.Ops))
+	if val.CheckBlobRefs {
+		for _, recBytes := range records {
+			if recBytes == nil {
+				continue
 			}
-			val, err := repoFragment.GetRecordCID(ctx, nsid, rkey)
+			recBlobs, errLabel, err := checkRecordBlobs(recBytes)
 			if err != nil {
-				commitVerifyErrors.WithLabelValues(hostname, "rcid").Inc()
-				return nil, err
+				return nil, r.fail("blobs", errLabel, ReasonBadBlobRef, err)
 			}
-			if *c != *val {
-				commitVerifyErrors.WithLabelValues(hostname, "opc").Inc()
-				return nil, fmt.Errorf("record op doesn't match MST tree value")
+			if val.onCommitBlobs != nil {
+				blobs = append(blobs, recBlobs...)
 			}
-			_, _, err = repoFragment.GetRecordBytes(ctx, nsid, rkey)
-			if err != nil {
-				commitVerifyErrors.WithLabelValues(hostname, "rec").Inc()
-				return nil, err
+		}
+		r.ok("blobs")
+	} else {
+		r.skip("blobs")
+		if val.onCommitBlobs != nil && trace == nil {
+			for _, recBytes := range records {
+				if recBytes != nil {
+					blobs = append(blobs, extractRecordBlobs(recBytes, logger)...)
+				}
 			}
 		}
 	}
@@ -227,15 +652,23 @@ func (val *Validator) VerifyCommitMessage(ctx context.Context, host *models.PDS,
 		case "delete":
 			if o.Prev == nil {
 				logger.Debug("can't invert legacy op", "action", o.Action)
-				val.inductionTraceLog.Warn("commit delete op", "seq", msg.Seq, "pdsHost", host.Host, "repo", msg.Repo)
-				commitVerifyOkish.WithLabelValues(hostname, "del").Inc()
+				r.anomaly(AnomalyLegacyDelete, map[string]any{"path": o.Path})
+				if val.RejectLegacyOps {
+					return nil, r.fail("legacy-ops", "ldel", ReasonLegacyOp, fmt.Errorf("%w: delete %s", ErrLegacyOp, o.Path))
+				}
+				r.okish("del")
+				r.note("legacy-ops", "can't invert legacy op", "action", o.Action, "path", o.Path)
 				return repoFragment, nil
 			}
 		case "update":
 			if o.Prev == nil {
 				logger.Debug("can't invert legacy op", "action", o.Action)
-				val.inductionTraceLog.Warn("commit update op", "seq", msg.Seq, "pdsHost", host.Host, "repo", msg.Repo)
-				commitVerifyOkish.WithLabelValues(hostname, "up").Inc()
+				r.anomaly(AnomalyLegacyUpdate, map[string]any{"path": o.Path})
+				if val.RejectLegacyOps {
+					return nil, r.fail("legacy-ops", "lup", ReasonLegacyOp, fmt.Errorf("%w: update %s", ErrLegacyOp, o.Path))
+				}
+				r.okish("up")
+				r.note("legacy-ops", "can't invert legacy op", "action", o.Action, "path", o.Path)
 				return repoFragment, nil
 			}
 		}
@@ -245,9 +678,11 @@ func (val *Validator) VerifyCommitMessage(ctx context.Context, host *models.PDS,
 		c := (*cid.Cid)(msg.PrevData)
 		if prevRoot != nil {
 			if *c != prevRoot.GetCid() {
-				commitVerifyWarnings.WithLabelValues(hostname, "pr").Inc()
-				val.inductionTraceLog.Warn("commit prevData mismatch", "seq", msg.Seq, "pdsHost", host.Host, "repo", msg.Repo)
-				hasWarning = true
+				// prevData is not the root we have stored for this DID (it may be another repo's tree, or we missed commits)
+				r.warn("prevData-stored", "pr", "commit prevData mismatch")
+				r.anomaly(AnomalyPrevDataMismatch, map[string]any{"prevData": c.String(), "storedRoot": prevRoot.GetCid().String()})
+			} else {
+				r.ok("prevData-stored", "prevData", c)
 			}
 		} else {
 			// see counter below for okish "new"
@@ -256,176 +691,581 @@ func (val *Validator) VerifyCommitMessage(ctx context.Context, host *models.PDS,
 		// check internal consistency that claimed previous root matches the rest of this message
 		ops, err := ParseCommitOps(msg.Ops)
 		if err != nil {
-			commitVerifyErrors.WithLabelValues(hostname, "pop").Inc()
-			return nil, err
+			return nil, r.fail("parse-ops", "pop", ReasonBadOps, err)
 		}
-		ops, err = atrepo.NormalizeOps(ops)
+		inverter := val.opInverter()
+		ops, err = inverter.Normalize(ops)
 		if err != nil {
-			commitVerifyErrors.WithLabelValues(hostname, "nop").Inc()
-			return nil, err
+			r.anomaly(AnomalyNormalizeOps, invertOpDetail(nil, err))
+			return nil, r.fail("normalize-ops", "nop", ReasonBadOps, err)
 		}
 
 		invTree := repoFragment.MST.Copy()
 		for _, op := range ops {
-			if err := atrepo.InvertOp(&invTree, &op); err != nil {
-				commitVerifyErrors.WithLabelValues(hostname, "inv").Inc()
-				return nil, err
+			if err := inverter.Invert(&invTree, &op); err != nil {
+				r.anomaly(AnomalyInvertOp, invertOpDetail(&op, err))
+				return nil, r.fail("invert-ops", "inv", ReasonPrevDataMismatch, err)
 			}
 		}
 		computed, err := invTree.RootCID()
 		if err != nil {
-			commitVerifyErrors.WithLabelValues(hostname, "it").Inc()
-			return nil, err
+			return nil, r.fail("invert-ops", "it", ReasonPrevDataMismatch, err)
 		}
 		if *computed != *c {
 			// this is self-inconsistent malformed data
-			commitVerifyErrors.WithLabelValues(hostname, "pd").Inc()
-			return nil, fmt.Errorf("inverted tree root didn't match prevData")
+			return nil, r.fail("prevData", "pd", ReasonPrevDataMismatch, fmt.Errorf("inverted tree root didn't match prevData"))
 		}
 		//logger.Debug("prevData matched", "prevData", c.String(), "computed", computed.String())
+		r.ok("prevData", "prevData", c, "computed", computed)
 
 		if prevRoot == nil {
-			commitVerifyOkish.WithLabelValues(hostname, "new").Inc()
-		} else if hasWarning {
-			commitVerifyOkish.WithLabelValues(hostname, "warn").Inc()
-		} else {
+			r.okish("new")
+		} else if r.hasWarning {
+			r.okish("warn")
+		} else if trace == nil {
 			// TODO: would it be better to make everything "okish"?
 			// commitVerifyOkish.WithLabelValues(hostname, "ok").Inc()
 			commitVerifyOk.WithLabelValues(hostname).Inc()
+			fullyVerified = true
 		}
 	} else {
 		// this source is still on old protocol without new prevData field
-		commitVerifyOkish.WithLabelValues(hostname, "old").Inc()
+		if val.RequirePrevData {
+			return nil, r.fail("prevData", "nopd", ReasonMissingPrevData, ErrMissingPrevData)
+		}
+		r.okish("old")
+		r.note("prevData", "source is on old protocol without prevData field")
 	}
 
 	return repoFragment, nil
 }
 
+// checkCommitFields checks the #commit message fields which don't require parsing the CAR slice: DID and rev syntax, rev ordering and clock skew, timestamp syntax, and the op list
+func (r *verifyRun) checkCommitFields(prevRoot *AccountPreviousState) (syntax.DID, syntax.TID, error) {
+	msg := r.msg
+	did, err := syntax.ParseDID(msg.Repo)
+	if err != nil {
+		return "", "", r.fail("parse-did", "did", ReasonBadSyntax, err)
+	}
+	r.ok("parse-did", "repo", msg.Repo)
+	rev, err := syntax.ParseTID(msg.Rev)
+	if err != nil {
+		return "", "", r.fail("parse-rev", "tid", ReasonBadSyntax, err)
+	}
+	r.ok("parse-rev", "rev", msg.Rev)
+	if prevRoot != nil {
+		prevRev := prevRoot.GetRev()
+		curTime := rev.Time()
+		prevTime := prevRev.Time()
+		if curTime.Before(prevTime) {
+			return "", "", r.fail("rev-order", "revb", ReasonRevBeforePrev, &revOutOfOrderError{prevTime.Sub(curTime)})
+		}
+		r.ok("rev-order", "prevRev", prevRev)
+	}
+	if rev.Time().After(time.Now().Add(r.val.maxRevFuture)) {
+		return "", "", r.fail("rev-future", "revf", ReasonRevTooFuture, r.val.ErrRevTooFarFuture)
+	}
+	r.ok("rev-future", "revTime", rev.Time())
+	_, err = syntax.ParseDatetime(msg.Time)
+	if err != nil {
+		return "", "", r.fail("parse-time", "time", ReasonBadSyntax, err)
+	}
+	r.ok("parse-time", "time", msg.Time)
+	if len(msg.Ops) > r.val.maxOpsPerCommit {
+		return "", "", r.fail("ops-count", "nops", ReasonBadOps, fmt.Errorf("commit has too many ops: %d > %d", len(msg.Ops), r.val.maxOpsPerCommit))
+	}
+	r.ok("ops-count", "ops", len(msg.Ops))
+	if err := checkDuplicateOpPaths(msg.Ops); err != nil {
+		return "", "", r.fail("dup-paths", "dup", ReasonBadOps, err)
+	}
+	r.ok("dup-paths")
+	return did, rev, nil
+}
+
+// checkBlocksSize checks the message CAR slice against MaxCommitBlocksBytes, before it is parsed
+func (r *verifyRun) checkBlocksSize() error {
+	if len(r.msg.Blocks) > r.val.maxCommitBlocksBytes {
+		return r.fail("size", "size", ReasonBadCAR, fmt.Errorf("commit blocks too large: %d > %d bytes", len(r.msg.Blocks), r.val.maxCommitBlocksBytes))
+	}
+	r.ok("size", "blocksLen", len(r.msg.Blocks))
+	return nil
+}
+
+// carFailure returns the failure for an error loading the CAR slice: "ver" if it parsed but the commit is an incompatible repo format, otherwise "car"
+func (r *verifyRun) carFailure(err error) error {
+	if errors.Is(err, atrepo.ErrUnsupportedRepoVersion) {
+		return r.fail("load-car", "ver", ReasonRepoVersion, err)
+	}
+	return r.fail("load-car", "car", ReasonBadCAR, err)
+}
+
+// checkCommitObject checks that the signed commit object matches the message DID and rev
+func (r *verifyRun) checkCommitObject(commit *atrepo.Commit, did syntax.DID, rev syntax.TID) error {
+	if commit.Rev != rev.String() {
+		return r.fail("commit-rev", "rev", ReasonRevMismatch, fmt.Errorf("rev did not match commit"))
+	}
+	r.ok("commit-rev", "commitRev", commit.Rev)
+	if commit.DID != did.String() {
+		return r.fail("commit-did", "did2", ReasonDIDMismatch, fmt.Errorf("DID did not match commit"))
+	}
+	r.ok("commit-did", "commitDID", commit.DID)
+	return nil
+}
+
+// observeClockSkew records the difference between a message's rev time and local time, to spot hosts with badly-set clocks before they exceed maxRevFuture
+func observeClockSkew(hostname string, rev syntax.TID) {
+	commitClockSkew.WithLabelValues(hostname).Observe(time.Until(rev.Time()).Seconds())
+}
+
+// VerifyCommitSignatureOnly is a cheaper, partial alternative to VerifyCommitMessage(), intended for sampling audits of a large fraction of traffic. It checks the message fields (DID, rev, and time syntax; rev ordering against prevRoot and clock skew; op count and duplicate paths), that the commit object in the CAR slice matches the message DID and rev, and the commit signature against the account's current signing key.
+//
+// Only the commit block is decoded from the CAR slice. The MST is not loaded, so this does NOT check that the ops match the MST, that record blocks are present and match their CIDs, that records are well-formed (CheckBlobRefs), or that prevData is consistent with the ops (RequirePrevData and RejectLegacyOps are not applied). A commit which passes may still be rejected by VerifyCommitMessage(). tooBig and rebase flags are not counted as warnings.
+//
+// Failures are returned as *VerifyError, and counted in the same metrics as VerifyCommitMessage(). Successes are not counted, and host error rates are not updated.
+func (val *Validator) VerifyCommitSignatureOnly(ctx context.Context, host *models.PDS, msg *atproto.SyncSubscribeRepos_Commit, prevRoot *AccountPreviousState) (*atrepo.Commit, error) {
+	r := &verifyRun{val: val, ctx: ctx, hostname: host.Host, msg: msg}
+
+	did, rev, err := r.checkCommitFields(prevRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.checkBlocksSize(); err != nil {
+		return nil, err
+	}
+	commit, _, err := atrepo.LoadCommitFromCAR(ctx, bytes.NewReader([]byte(msg.Blocks)))
+	if err != nil {
+		return nil, r.carFailure(err)
+	}
+
+	if err := r.checkCommitObject(commit, did, rev); err != nil {
+		return nil, err
+	}
+
+	if err := r.verifySignature(commit); err != nil {
+		return nil, err
+	}
+	return commit, nil
+}
+
 // HandleSync checks signed commit from a #sync message
 func (val *Validator) HandleSync(ctx context.Context, host *models.PDS, msg *atproto.SyncSubscribeRepos_Sync) (newRoot *cid.Cid, err error) {
 	hostname := host.Host
 	hasWarning := false
+	start := time.Now()
+	defer func() {
+		val.observeVerifyOutcome(hostname, start, !hasWarning, err)
+	}()
 
 	did, err := syntax.ParseDID(msg.Did)
 	if err != nil {
-		syncVerifyErrors.WithLabelValues(hostname, "did").Inc()
-		return nil, err
+		return nil, verifyFailure(syncVerifyErrors, hostname, "did", ReasonBadSyntax, err)
 	}
 	rev, err := syntax.ParseTID(msg.Rev)
 	if err != nil {
-		syncVerifyErrors.WithLabelValues(hostname, "tid").Inc()
-		return nil, err
+		return nil, verifyFailure(syncVerifyErrors, hostname, "tid", ReasonBadSyntax, err)
 	}
+	observeClockSkew(hostname, rev)
 	if rev.Time().After(time.Now().Add(val.maxRevFuture)) {
-		syncVerifyErrors.WithLabelValues(hostname, "revf").Inc()
-		return nil, val.ErrRevTooFarFuture
+		return nil, verifyFailure(syncVerifyErrors, hostname, "revf", ReasonRevTooFuture, val.ErrRevTooFarFuture)
 	}
 	_, err = syntax.ParseDatetime(msg.Time)
 	if err != nil {
-		syncVerifyErrors.WithLabelValues(hostname, "time").Inc()
-		return nil, err
+		return nil, verifyFailure(syncVerifyErrors, hostname, "time", ReasonBadSyntax, err)
 	}
 
+	if len(msg.Blocks) > val.maxCommitBlocksBytes {
+		return nil, verifyFailure(syncVerifyErrors, hostname, "size", ReasonBadCAR, fmt.Errorf("sync blocks too large: %d > %d bytes", len(msg.Blocks), val.maxCommitBlocksBytes))
+	}
+	// the remaining steps are shared with #commit verification, but failures are counted as sync errors
+	r := &verifyRun{val: val, ctx: ctx, hostname: hostname, errCounter: syncVerifyErrors}
 	commit, _, err := atrepo.LoadCommitFromCAR(ctx, bytes.NewReader([]byte(msg.Blocks)))
 	if err != nil {
-		commitVerifyErrors.WithLabelValues(hostname, "car").Inc()
-		return nil, err
+		return nil, r.carFailure(err)
 	}
 
-	if commit.Rev != rev.String() {
-		commitVerifyErrors.WithLabelValues(hostname, "rev").Inc()
-		return nil, fmt.Errorf("rev did not match commit")
-	}
-	if commit.DID != did.String() {
-		commitVerifyErrors.WithLabelValues(hostname, "did2").Inc()
-		return nil, fmt.Errorf("rev did not match commit")
+	if err := r.checkCommitObject(commit, did, rev); err != nil {
+		return nil, err
 	}
 
-	err = val.VerifyCommitSignature(ctx, commit, hostname, &hasWarning)
+	err = r.verifySignature(commit)
+	hasWarning = r.hasWarning
 	if err != nil {
-		// signature errors are metrics counted inside VerifyCommitSignature()
 		return nil, err
 	}
 
 	return &commit.Data, nil
 }
 
-// TODO: lift back to indigo/atproto/repo util code?
-func ParseCommitOps(ops []*atproto.SyncSubscribeRepos_RepoOp) ([]atrepo.Operation, error) {
-	out := []atrepo.Operation{}
-	for _, rop := range ops {
-		switch rop.Action {
-		case "create":
-			if rop.Cid == nil || rop.Prev != nil {
-				return nil, fmt.Errorf("invalid repoOp: create")
-			}
-			op := atrepo.Operation{
-				Path:  rop.Path,
-				Prev:  nil,
-				Value: (*cid.Cid)(rop.Cid),
-			}
-			out = append(out, op)
-		case "delete":
-			if rop.Cid != nil || rop.Prev == nil {
-				return nil, fmt.Errorf("invalid repoOp: delete")
-			}
-			op := atrepo.Operation{
-				Path:  rop.Path,
-				Prev:  (*cid.Cid)(rop.Prev),
-				Value: nil,
-			}
-			out = append(out, op)
-		case "update":
-			if rop.Cid == nil || rop.Prev == nil {
-				return nil, fmt.Errorf("invalid repoOp: update")
-			}
-			op := atrepo.Operation{
-				Path:  rop.Path,
-				Prev:  (*cid.Cid)(rop.Prev),
-				Value: (*cid.Cid)(rop.Cid),
-			}
-			out = append(out, op)
-		default:
-			return nil, fmt.Errorf("invalid repoOp action: %s", rop.Action)
+// ErrAccountMissingStatus is returned by HandleAccount() for an inactive #account message with no status
+var ErrAccountMissingStatus = errors.New("inactive #account message missing status")
+
+// HandleIdentity checks that an #identity message is well-formed
+//
+// If ValidatorConfig.ResolveIdentityEvents is set, the DID is also resolved and the declared handle and PDS endpoint are checked. Those are only counted as warnings, not errors, because DID documents and handles can change independently of the message.
+func (val *Validator) HandleIdentity(ctx context.Context, host *models.PDS, msg *atproto.SyncSubscribeRepos_Identity) error {
+	hostname := host.Host
+
+	did, err := syntax.ParseDID(msg.Did)
+	if err != nil {
+		identityVerifyErrors.WithLabelValues(hostname, "did").Inc()
+		return err
+	}
+	_, err = syntax.ParseDatetime(msg.Time)
+	if err != nil {
+		identityVerifyErrors.WithLabelValues(hostname, "time").Inc()
+		return err
+	}
+	// #identity messages are emitted on key rotation, among other changes
+	val.PurgeKeyCache(did)
+
+	var handle syntax.Handle
+	if msg.Handle != nil {
+		handle, err = syntax.ParseHandle(*msg.Handle)
+		if err != nil {
+			identityVerifyErrors.WithLabelValues(hostname, "hdl").Inc()
+			return fmt.Errorf("invalid handle in #identity message: %w", err)
 		}
 	}
-	return out, nil
+
+	if !val.resolveIdentityEvents {
+		return nil
+	}
+	ident, err := val.directory.LookupDID(ctx, did)
+	if err != nil {
+		identityVerifyWarnings.WithLabelValues(hostname, "res").Inc()
+		val.log.Debug("failed to resolve DID from #identity message", "did", did, "host", hostname, "err", err)
+		return nil
+	}
+	if ident.PDSEndpoint() == "" {
+		identityVerifyWarnings.WithLabelValues(hostname, "nopds").Inc()
+	}
+	if msg.Handle != nil && ident.Handle != handle.Normalize() {
+		identityVerifyWarnings.WithLabelValues(hostname, "hdl2").Inc()
+	}
+	return nil
+}
+
+// HandleAccount checks that an #account message is well-formed, including that the active flag and status are consistent
+//
+// Returns ErrAccountMissingStatus if the account is inactive with no status given.
+func (val *Validator) HandleAccount(ctx context.Context, host *models.PDS, msg *atproto.SyncSubscribeRepos_Account) error {
+	hostname := host.Host
+
+	_, err := syntax.ParseDID(msg.Did)
+	if err != nil {
+		accountVerifyErrors.WithLabelValues(hostname, "did").Inc()
+		return err
+	}
+	_, err = syntax.ParseDatetime(msg.Time)
+	if err != nil {
+		accountVerifyErrors.WithLabelValues(hostname, "time").Inc()
+		return err
+	}
+
+	if msg.Status == nil {
+		if !msg.Active {
+			accountVerifyWarnings.WithLabelValues(hostname, "nostat").Inc()
+			return ErrAccountMissingStatus
+		}
+		return nil
+	}
+	if msg.Active {
+		// status is only meaningful for inactive accounts
+		accountVerifyWarnings.WithLabelValues(hostname, "actstat").Inc()
+	} else if !events.AccountStatuses[*msg.Status] {
+		// the set of statuses is open-ended, so this isn't an error
+		accountVerifyWarnings.WithLabelValues(hostname, "unkstat").Inc()
+	}
+	return nil
+}
+
+// extractRecordBlobs returns the CIDs of any blobs referenced in CBOR record data
+// records which fail to decode are logged and skipped, not treated as verification errors
+func extractRecordBlobs(recBytes []byte, logger *slog.Logger) []cid.Cid {
+	obj, err := data.UnmarshalCBOR(recBytes)
+	if err != nil {
+		logger.Debug("failed to decode record for blob extraction", "err", err)
+		return nil
+	}
+	var out []cid.Cid
+	for _, blob := range data.ExtractBlobs(obj) {
+		out = append(out, cid.Cid(blob.Ref))
+	}
+	return out
+}
+
+// checkRecordBlobs decodes CBOR record data and checks that all blob references are well-formed, returning the blob CIDs
+// on failure, also returns a short metric code: "bdec" if the record could not be decoded, or "blob" for a malformed blob CID
+func checkRecordBlobs(recBytes []byte) ([]cid.Cid, string, error) {
+	obj, err := data.UnmarshalCBOR(recBytes)
+	if err != nil {
+		return nil, "bdec", fmt.Errorf("decoding record for blob refs: %w", err)
+	}
+	var out []cid.Cid
+	for _, blob := range data.ExtractBlobs(obj) {
+		c := cid.Cid(blob.Ref)
+		if err := checkBlobCID(c); err != nil {
+			return nil, "blob", err
+		}
+		out = append(out, c)
+	}
+	return out, "", nil
+}
+
+// checkBlobCID verifies that a blob reference is a CIDv1 with "raw" codec and a SHA-256 multihash, as required by atproto
+func checkBlobCID(c cid.Cid) error {
+	if !c.Defined() {
+		return fmt.Errorf("malformed blob ref: undefined CID")
+	}
+	p := c.Prefix()
+	if p.Version != 1 {
+		return fmt.Errorf("malformed blob ref %s: CID version %d", c, p.Version)
+	}
+	if p.Codec != cid.Raw {
+		return fmt.Errorf("malformed blob ref %s: codec 0x%x is not raw", c, p.Codec)
+	}
+	if p.MhType != multihash.SHA2_256 || p.MhLength != 32 {
+		return fmt.Errorf("malformed blob ref %s: multihash is not SHA-256", c)
+	}
+	return nil
+}
+
+// observeVerifyOutcome records time spent verifying a message, labeled by outcome, and updates the host's moving-average error rate
+func (val *Validator) observeVerifyOutcome(hostname string, start time.Time, fullyVerified bool, err error) {
+	outcome := "okish"
+	if err != nil {
+		outcome = "error"
+	} else if fullyVerified {
+		outcome = "ok"
+	}
+	commitVerifyDuration.WithLabelValues(hostname, outcome).Observe(time.Since(start).Seconds())
+	val.hostStats.observe(hostname, err != nil)
+}
+
+// HostErrorRate returns the moving-average fraction of messages from a host which failed verification
+func (val *Validator) HostErrorRate(hostname string) float64 {
+	return val.hostStats.errorRate(hostname)
+}
+
+// repoPathErrorLabel maps a syntax.ParseRepoPath() error to a short metric code: "opn" for a bad collection NSID, "opk" for a bad record key, otherwise "opp"
+func repoPathErrorLabel(err error) string {
+	switch {
+	case errors.Is(err, syntax.ErrRepoPathCollection):
+		return "opn"
+	case errors.Is(err, syntax.ErrRepoPathRecordKey):
+		return "opk"
+	default:
+		return "opp"
+	}
+}
+
+// checkDuplicateOpPaths returns an error if any repo path appears more than once in a commit's ops (eg, two creates, or a create and delete, of the same record), which is self-inconsistent
+func checkDuplicateOpPaths(ops []*atproto.SyncSubscribeRepos_RepoOp) error {
+	seen := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		if seen[op.Path] {
+			return fmt.Errorf("duplicate repo path in ops list: %s", op.Path)
+		}
+		seen[op.Path] = true
+	}
+	return nil
+}
+
+// ParseCommitOps is a wrapper around atrepo.ParseFirehoseOps(), kept for compatibility
+func ParseCommitOps(ops []*atproto.SyncSubscribeRepos_RepoOp) ([]atrepo.Operation, error) {
+	return atrepo.ParseFirehoseOps(ops)
 }
 
 // VerifyCommitSignature get's repo's registered public key from Identity Directory, verifies Commit
 // hostname is just for metrics in case of error
 func (val *Validator) VerifyCommitSignature(ctx context.Context, commit *atrepo.Commit, hostname string, hasWarning *bool) error {
+	r := &verifyRun{val: val, ctx: ctx, hostname: hostname}
+	err := r.verifySignature(commit)
+	if hasWarning != nil && r.hasWarning {
+		*hasWarning = true
+	}
+	return err
+}
+
+// verifySignature implements VerifyCommitSignature() as a verification step
+func (r *verifyRun) verifySignature(commit *atrepo.Commit) error {
+	val := r.val
+	ctx := r.ctx
+	hostname := r.hostname
 	if val.directory == nil {
+		r.skip("signature")
 		return nil
 	}
 	xdid, err := syntax.ParseDID(commit.DID)
 	if err != nil {
-		commitVerifyErrors.WithLabelValues(hostname, "sig1").Inc()
-		return fmt.Errorf("bad car DID, %w", err)
+		return r.fail("signature", "sig1", ReasonBadSyntax, fmt.Errorf("bad car DID, %w", err))
+	}
+	if val.keyCache != nil {
+		if pk, ok := val.keyCache.Get(xdid); ok {
+			validatorKeyCacheHits.Inc()
+			if err := commit.VerifySignature(pk); err != nil {
+				// cached key may be stale; fall through to the refetch path
+				val.keyCache.Remove(xdid)
+				if val.refetchVerifyCommitSignature(ctx, commit, xdid, []signingKey{{id: "atproto", pk: pk}}, hostname) {
+					r.ok("signature", "refetched", true)
+					return nil
+				}
+				return r.fail("signature", "sig4", ReasonSignature, fmt.Errorf("invalid signature, %w", err))
+			}
+			r.ok("signature", "cached", true)
+			return nil
+		}
+		validatorKeyCacheMisses.Inc()
 	}
 	ident, err := val.directory.LookupDID(ctx, xdid)
 	if err != nil {
+		if !isIdentityNotFound(err) {
+			// transient network or resolution failure; never treated as "not found"
+			return r.fail("signature", "sig2net", ReasonIdentityLookup, fmt.Errorf("DID lookup failed, %w", err))
+		}
 		if val.AllowSignatureNotFound {
 			// allow not-found conditions to pass without signature check
-			commitVerifyWarnings.WithLabelValues(hostname, "nok").Inc()
-			if hasWarning != nil {
-				*hasWarning = true
-			}
+			r.warn("signature", "nok", "identity not found; signature not checked")
 			return nil
 		}
-		commitVerifyErrors.WithLabelValues(hostname, "sig2").Inc()
-		return fmt.Errorf("DID lookup failed, %w", err)
+		return r.fail("signature", "sig2", ReasonIdentityNotFound, fmt.Errorf("DID lookup failed, %w", err))
 	}
-	pk, err := ident.GetPublicKey("atproto")
+	keys, err := val.signingKeys(ident)
 	if err != nil {
-		commitVerifyErrors.WithLabelValues(hostname, "sig3").Inc()
-		return fmt.Errorf("no atproto pubkey, %w", err)
+		return r.fail("signature", "sig3", ReasonSignature, fmt.Errorf("no atproto pubkey, %w", err))
 	}
-	err = commit.VerifySignature(pk)
+	pk, err := verifyCommitWithKeys(commit, keys)
 	if err != nil {
-		// TODO: if the DID document was stale, force re-fetch from source and re-try if pubkey has changed
-		commitVerifyErrors.WithLabelValues(hostname, "sig4").Inc()
-		return fmt.Errorf("invalid signature, %w", err)
+		// the DID document may have been stale (eg, signing key rotation); force re-fetch and re-try once if pubkey has changed
+		if val.refetchVerifyCommitSignature(ctx, commit, xdid, keys, hostname) {
+			r.ok("signature", "refetched", true)
+			return nil
+		}
+		return r.fail("signature", "sig4", ReasonSignature, fmt.Errorf("invalid signature, %w", err))
+	}
+	if val.keyCache != nil {
+		val.keyCache.Add(xdid, pk)
+	}
+	if r.trace != nil {
+		r.ok("signature", "key", pk.DIDKey())
 	}
 	return nil
 }
+
+// PurgeKeyCache removes any cached signing key for the DID, so the next commit triggers an identity lookup. No-op if the key cache is disabled.
+func (val *Validator) PurgeKeyCache(did syntax.DID) {
+	if val.keyCache != nil {
+		val.keyCache.Remove(did)
+	}
+}
+
+// signingKey is a candidate public key for commit signature verification, with the DID document key ID it came from
+type signingKey struct {
+	id string
+	pk crypto.PublicKey
+}
+
+// signingKeys returns the keys to verify commit signatures against: the "atproto" key, followed by any other parseable keys in the DID document (in key ID order) if AllowAnySigningKey is set.
+// Returns an error if there are no candidate keys.
+func (val *Validator) signingKeys(ident *identity.Identity) ([]signingKey, error) {
+	out := []signingKey{}
+	pk, atprotoErr := ident.GetPublicKey("atproto")
+	if atprotoErr == nil {
+		out = append(out, signingKey{id: "atproto", pk: pk})
+	}
+	if !val.AllowAnySigningKey {
+		return out, atprotoErr
+	}
+	ids := make([]string, 0, len(ident.Keys))
+	for id := range ident.Keys {
+		if id != "atproto" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		pk, err := ident.GetPublicKey(id)
+		if err != nil {
+			// keys of unsupported types can't have signed the commit
+			continue
+		}
+		out = append(out, signingKey{id: id, pk: pk})
+	}
+	if len(out) == 0 {
+		return nil, atprotoErr
+	}
+	return out, nil
+}
+
+// verifyCommitWithKeys checks the commit signature against each candidate key in order, returning the first key which matches. On failure, returns the error from the first key.
+func verifyCommitWithKeys(commit *atrepo.Commit, keys []signingKey) (crypto.PublicKey, error) {
+	var firstErr error
+	for _, k := range keys {
+		err := commit.VerifySignature(k.pk)
+		if err == nil {
+			if k.id == "atproto" {
+				commitSigningKeyMatches.WithLabelValues("atproto").Inc()
+			} else {
+				commitSigningKeyMatches.WithLabelValues("other").Inc()
+			}
+			return k.pk, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no signing keys")
+	}
+	return nil, firstErr
+}
+
+// refetchVerifyCommitSignature purges any cached identity for the DID, re-fetches the signing keys, and re-verifies the commit against any keys which changed.
+// Returns true if the commit signature is valid against a re-fetched key.
+func (val *Validator) refetchVerifyCommitSignature(ctx context.Context, commit *atrepo.Commit, did syntax.DID, staleKeys []signingKey, hostname string) bool {
+	commitVerifyRefetch.WithLabelValues(hostname, "attempt").Inc()
+	if err := val.directory.Purge(ctx, did.AtIdentifier()); err != nil {
+		val.log.Warn("failed to purge identity for re-fetch", "did", did, "err", err)
+		return false
+	}
+	ident, err := val.directory.LookupDID(ctx, did)
+	if err != nil {
+		val.log.Warn("failed to re-fetch identity", "did", did, "err", err)
+		return false
+	}
+	keys, err := val.signingKeys(ident)
+	if err != nil {
+		return false
+	}
+	fresh := []signingKey{}
+	for _, k := range keys {
+		stale := false
+		for _, sk := range staleKeys {
+			if k.pk.Equal(sk.pk) {
+				stale = true
+				break
+			}
+		}
+		if !stale {
+			fresh = append(fresh, k)
+		}
+	}
+	if len(fresh) == 0 {
+		return false
+	}
+	pk, err := verifyCommitWithKeys(commit, fresh)
+	if err != nil {
+		return false
+	}
+	if val.keyCache != nil {
+		val.keyCache.Add(did, pk)
+	}
+	commitVerifyRefetch.WithLabelValues(hostname, "fixed").Inc()
+	return true
+}
+
+// isIdentityNotFound is true if an identity lookup error means the identity definitively does not exist, as opposed to a network or resolution failure
+func isIdentityNotFound(err error) bool {
+	return errors.Is(err, identity.ErrDIDNotFound) || errors.Is(err, identity.ErrHandleNotFound)
+}
@@ -0,0 +1,1238 @@
+package bgs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	atproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/crypto"
+	"github.com/bluesky-social/indigo/atproto/data"
+	"github.com/bluesky-social/indigo/atproto/identity"
+	atrepo "github.com/bluesky-social/indigo/atproto/repo"
+	"github.com/bluesky-social/indigo/atproto/repo/mst"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/bluesky-social/indigo/cmd/relay/models"
+	lexutil "github.com/bluesky-social/indigo/lex/util"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+	"github.com/multiformats/go-multihash"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDirectory is an identity.MockDirectory which counts DID lookups and purges, with optional hooks: onLookup can fail a DID lookup by returning an error, and onPurge is called on each Purge()
+type testDirectory struct {
+	identity.MockDirectory
+	lookups  atomic.Int64
+	purges   atomic.Int64
+	onLookup func(did syntax.DID) error
+	onPurge  func()
+}
+
+func newTestDirectory(idents ...identity.Identity) *testDirectory {
+	d := &testDirectory{MockDirectory: identity.NewMockDirectory()}
+	for _, ident := range idents {
+		d.Insert(ident)
+	}
+	return d
+}
+
+func (d *testDirectory) LookupDID(ctx context.Context, did syntax.DID) (*identity.Identity, error) {
+	d.lookups.Add(1)
+	if d.onLookup != nil {
+		if err := d.onLookup(did); err != nil {
+			return nil, err
+		}
+	}
+	return d.MockDirectory.LookupDID(ctx, did)
+}
+
+func (d *testDirectory) Purge(ctx context.Context, atid syntax.AtIdentifier) error {
+	d.purges.Add(1)
+	if d.onPurge != nil {
+		d.onPurge()
+	}
+	return nil
+}
+
+func TestVerifyCommitSignatureLookupErrors(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	commit := &atrepo.Commit{DID: "did:plc:abc123", Version: 3}
+
+	testCases := []struct {
+		err      error
+		allow    bool
+		pass     bool
+		notFound bool
+	}{
+		{err: identity.ErrDIDNotFound, allow: true, pass: true, notFound: true},
+		{err: fmt.Errorf("%w: PLC directory 404", identity.ErrDIDNotFound), allow: true, pass: true, notFound: true},
+		{err: identity.ErrHandleNotFound, allow: true, pass: true, notFound: true},
+		{err: identity.ErrDIDNotFound, allow: false, pass: false, notFound: true},
+		{err: fmt.Errorf("%w: context deadline exceeded", identity.ErrDIDResolutionFailed), allow: true, pass: false},
+		{err: context.DeadlineExceeded, allow: true, pass: false},
+		{err: errors.New("connection refused"), allow: true, pass: false},
+	}
+
+	for _, tc := range testCases {
+		dir := newTestDirectory()
+		dir.onLookup = func(did syntax.DID) error { return tc.err }
+		val := NewValidator(dir, nil)
+		val.AllowSignatureNotFound = tc.allow
+		hasWarning := false
+		err := val.VerifyCommitSignature(ctx, commit, "test.example.com", &hasWarning)
+		assert.Equal(tc.notFound, isIdentityNotFound(tc.err), tc.err.Error())
+		if tc.pass {
+			assert.NoError(err, tc.err.Error())
+			assert.True(hasWarning, tc.err.Error())
+		} else {
+			assert.ErrorIs(err, tc.err)
+			assert.False(hasWarning, tc.err.Error())
+		}
+	}
+}
+
+// staleDirectory returns a testDirectory which serves the stale identity until purged, then the current identity
+func staleDirectory(stale, current identity.Identity) *testDirectory {
+	d := newTestDirectory(stale)
+	d.onPurge = func() {
+		d.Insert(current)
+	}
+	return d
+}
+
+func testIdentity(t *testing.T, did syntax.DID, priv crypto.PrivateKey) identity.Identity {
+	pub, err := priv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity.Identity{
+		DID: did,
+		Keys: map[string]identity.Key{
+			"atproto": {
+				Type:               "Multikey",
+				PublicKeyMultibase: pub.Multibase(),
+			},
+		},
+	}
+}
+
+func TestVerifyCommitSignatureRefetch(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	did := syntax.DID("did:plc:abc123")
+
+	oldPriv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	newPriv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+
+	commit := &atrepo.Commit{
+		DID:     did.String(),
+		Version: 3,
+		Data:    cid.MustParse("bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm"),
+		Rev:     "3l3qo2vutsw2b",
+	}
+	assert.NoError(commit.Sign(newPriv))
+
+	// rotated key: stale identity, re-fetch finds the new key
+	dir := staleDirectory(testIdentity(t, did, oldPriv), testIdentity(t, did, newPriv))
+	val := NewValidator(dir, nil)
+	assert.NoError(val.VerifyCommitSignature(ctx, commit, "test.example.com", nil))
+	assert.Equal(int64(1), dir.purges.Load())
+
+	// no rotation: re-fetch finds the same (wrong) key
+	dir = staleDirectory(testIdentity(t, did, oldPriv), testIdentity(t, did, oldPriv))
+	val = NewValidator(dir, nil)
+	assert.Error(val.VerifyCommitSignature(ctx, commit, "test.example.com", nil))
+	assert.Equal(int64(1), dir.purges.Load())
+}
+
+func TestVerifyCommitSignatureAnyKey(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	did := syntax.DID("did:plc:abc123")
+
+	oldPriv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	newPriv, err := crypto.GeneratePrivateKeyP256()
+	assert.NoError(err)
+	newPub, err := newPriv.PublicKey()
+	assert.NoError(err)
+
+	commit := &atrepo.Commit{
+		DID:     did.String(),
+		Version: 3,
+		Data:    cid.MustParse("bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm"),
+		Rev:     "3l3qo2vutsw2b",
+	}
+	assert.NoError(commit.Sign(newPriv))
+
+	// DID document lists the new key, but not yet as the atproto key
+	ident := testIdentity(t, did, oldPriv)
+	ident.Keys["next"] = identity.Key{Type: "Multikey", PublicKeyMultibase: newPub.Multibase()}
+	ident.Keys["broken"] = identity.Key{Type: "Multikey", PublicKeyMultibase: "zQ3"}
+	dir := newTestDirectory(ident)
+
+	val := NewValidator(dir, nil)
+	assert.Error(val.VerifyCommitSignature(ctx, commit, "test.example.com", nil))
+
+	config := DefaultValidatorConfig()
+	config.AllowAnySigningKey = true
+	val = NewValidatorWithConfig(dir, nil, config)
+	before := testutil.ToFloat64(commitSigningKeyMatches.WithLabelValues("other"))
+	assert.NoError(val.VerifyCommitSignature(ctx, commit, "test.example.com", nil))
+	assert.Equal(before+1, testutil.ToFloat64(commitSigningKeyMatches.WithLabelValues("other")))
+
+	// signature by a key not in the DID document still fails
+	otherPriv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	assert.NoError(commit.Sign(otherPriv))
+	assert.Error(val.VerifyCommitSignature(ctx, commit, "test.example.com", nil))
+}
+
+func TestVerifyCommitSignatureKeyCache(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	did := syntax.DID("did:plc:abc123")
+
+	oldPriv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	newPriv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+
+	signedCommit := func(priv crypto.PrivateKey) *atrepo.Commit {
+		commit := &atrepo.Commit{
+			DID:     did.String(),
+			Version: 3,
+			Data:    cid.MustParse("bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm"),
+			Rev:     "3l3qo2vutsw2b",
+		}
+		assert.NoError(commit.Sign(priv))
+		return commit
+	}
+	oldCommit := signedCommit(oldPriv)
+	newCommit := signedCommit(newPriv)
+
+	dir := staleDirectory(testIdentity(t, did, oldPriv), testIdentity(t, did, newPriv))
+	config := DefaultValidatorConfig()
+	config.KeyCacheSize = 10
+	val := NewValidatorWithConfig(dir, nil, config)
+
+	// repeated commits only look up the identity once
+	assert.NoError(val.VerifyCommitSignature(ctx, oldCommit, "test.example.com", nil))
+	assert.NoError(val.VerifyCommitSignature(ctx, oldCommit, "test.example.com", nil))
+	assert.Equal(int64(1), dir.lookups.Load())
+
+	// key rotation: cached key fails, and the forced re-fetch replaces the cached key
+	assert.NoError(val.VerifyCommitSignature(ctx, newCommit, "test.example.com", nil))
+	assert.Equal(int64(2), dir.lookups.Load())
+	assert.NoError(val.VerifyCommitSignature(ctx, newCommit, "test.example.com", nil))
+	assert.Equal(int64(2), dir.lookups.Load())
+
+	// explicit purge
+	val.PurgeKeyCache(did)
+	assert.NoError(val.VerifyCommitSignature(ctx, newCommit, "test.example.com", nil))
+	assert.Equal(int64(3), dir.lookups.Load())
+
+	// cache disabled by default
+	dir = newTestDirectory(testIdentity(t, did, oldPriv))
+	val = NewValidator(dir, nil)
+	assert.NoError(val.VerifyCommitSignature(ctx, oldCommit, "test.example.com", nil))
+	assert.NoError(val.VerifyCommitSignature(ctx, oldCommit, "test.example.com", nil))
+	assert.Equal(int64(2), dir.lookups.Load())
+}
+
+func TestVerifyCommitMessageTrace(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	did := syntax.DID("did:plc:abc123")
+	priv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	dir := identity.NewMockDirectory()
+	dir.Insert(testIdentity(t, did, priv))
+	config := DefaultValidatorConfig()
+	val := NewValidatorWithConfig(&dir, nil, config)
+
+	fragment, ops := testOpsFragment(t, 2)
+	msg := testCommitMessage(t, priv, did, fragment, ops)
+	account := &Account{ID: 1, Did: did.String()}
+
+	names := func(steps []VerifyStep) []string {
+		out := []string{}
+		for _, s := range steps {
+			out = append(out, s.Name)
+		}
+		return out
+	}
+
+	// same steps as HandleCommit(), with no host
+	steps := val.VerifyCommitMessageTrace(ctx, nil, account, msg, nil)
+	assert.Equal([]string{"account", "parse-did", "parse-rev", "rev-future", "parse-time", "ops-count", "dup-paths", "size", "load-car", "commit-rev", "commit-did", "signature", "records", "blobs", "prevData"}, names(steps))
+	for _, s := range steps[:len(steps)-2] {
+		assert.Equal(TraceStepOk, s.Outcome, s.Name)
+	}
+	assert.Equal(TraceStepSkip, steps[len(steps)-2].Outcome)
+	// no prevData in the message: accepted, but not fully verified
+	assert.Equal(TraceStepWarn, steps[len(steps)-1].Outcome)
+
+	// stops at the first failure, which is not counted in metrics
+	before := testutil.ToFloat64(commitVerifyErrors.WithLabelValues("", "acct"))
+	steps = val.VerifyCommitMessageTrace(ctx, nil, &Account{ID: 1, Did: "did:plc:other"}, msg, nil)
+	assert.Equal([]string{"account"}, names(steps))
+	assert.Equal(TraceStepFail, steps[0].Outcome)
+	assert.Equal("acct", steps[0].Values["label"])
+	assert.Equal(before, testutil.ToFloat64(commitVerifyErrors.WithLabelValues("", "acct")))
+
+	badDID := *msg
+	badDID.Repo = "not-a-did"
+	steps = val.VerifyCommitMessageTrace(ctx, nil, nil, &badDID, nil)
+	assert.Equal([]string{"parse-did"}, names(steps))
+	assert.Equal(TraceStepFail, steps[0].Outcome)
+
+	config.MaxCommitBlocksBytes = 100
+	val = NewValidatorWithConfig(&dir, nil, config)
+	steps = val.VerifyCommitMessageTrace(ctx, nil, nil, msg, nil)
+	last := steps[len(steps)-1]
+	assert.Equal("size", last.Name)
+	assert.Equal(TraceStepFail, last.Outcome)
+	assert.Equal("size", last.Values["label"])
+}
+
+// builds an in-memory repo fragment with n records, and matching create ops
+func testOpsFragment(t testing.TB, n int) (*atrepo.Repo, []*atproto.SyncSubscribeRepos_RepoOp) {
+	bs := atrepo.NewTinyBlockstore()
+	tree := mst.NewEmptyTree()
+	ops := make([]*atproto.SyncSubscribeRepos_RepoOp, n)
+	prefix := cid.NewPrefixV1(cid.DagCBOR, multihash.SHA2_256)
+	for i := 0; i < n; i++ {
+		path := fmt.Sprintf("app.bsky.feed.post/%013d", i)
+		c, err := prefix.Sum([]byte(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		blk, err := blocks.NewBlockWithCid([]byte(path), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bs.Put(context.Background(), blk); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tree.Insert([]byte(path), c); err != nil {
+			t.Fatal(err)
+		}
+		ll := lexutil.LexLink(c)
+		ops[i] = &atproto.SyncSubscribeRepos_RepoOp{Action: "create", Path: path, Cid: &ll}
+	}
+	return &atrepo.Repo{RecordStore: bs, MST: tree}, ops
+}
+
+func TestVerifyRecordOps(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	for _, n := range []int{5, 200} {
+		repoFragment, ops := testOpsFragment(t, n)
+		records, _, err := verifyRecordOps(ctx, repoFragment, ops, 4)
+		assert.NoError(err)
+		assert.Equal(n, len(records))
+		assert.Equal([]byte(ops[n-1].Path), records[n-1])
+
+		// earliest failing op is reported, regardless of concurrency
+		ops[n-1].Path = "app.bsky.feed.post/missing"
+		ops[3].Cid = ops[2].Cid
+		_, label, err := verifyRecordOps(ctx, repoFragment, ops, 4)
+		assert.Error(err)
+		assert.Equal("opc", label)
+	}
+}
+
+func benchmarkVerifyRecordOps(b *testing.B, serial bool) {
+	ctx := context.Background()
+	repoFragment, ops := testOpsFragment(b, 1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if serial {
+			for _, op := range ops {
+				if _, _, err := verifyRecordOp(ctx, repoFragment, op); err != nil {
+					b.Fatal(err)
+				}
+			}
+		} else {
+			if _, _, err := verifyRecordOps(ctx, repoFragment, ops, runtime.NumCPU()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkVerifyRecordOpsSerial(b *testing.B) {
+	benchmarkVerifyRecordOps(b, true)
+}
+
+func BenchmarkVerifyRecordOps(b *testing.B) {
+	benchmarkVerifyRecordOps(b, false)
+}
+
+// recordingTraceSink records the kind and detail of each anomaly, in order
+type recordingTraceSink struct {
+	kinds   []string
+	details []map[string]any
+}
+
+func (ts *recordingTraceSink) RecordAnomaly(ctx context.Context, kind string, host string, did string, seq int64, detail map[string]any) {
+	ts.kinds = append(ts.kinds, kind)
+	ts.details = append(ts.details, detail)
+}
+
+func TestTraceSink(t *testing.T) {
+	assert := assert.New(t)
+
+	sink := &recordingTraceSink{}
+	val := NewValidatorWithConfig(nil, nil, &ValidatorConfig{TraceSink: sink})
+	msg := &atproto.SyncSubscribeRepos_Commit{
+		Repo:   "did:example:one",
+		Rev:    syntax.NewTIDNow(0).String(),
+		Time:   syntax.DatetimeNow().String(),
+		TooBig: true,
+		Rebase: true,
+	}
+	_, err := val.VerifyCommitMessage(context.Background(), &models.PDS{Host: "pds.example.com"}, msg, nil)
+	// no CAR blocks
+	assert.Error(err)
+	assert.Equal([]string{AnomalyTooBig, AnomalyRebase}, sink.kinds)
+
+	// without a sink or trace log, anomalies are dropped
+	val = NewValidator(nil, nil)
+	val.recordAnomaly(context.Background(), AnomalyTooBig, "pds.example.com", "did:example:one", 1, nil)
+}
+
+func TestCheckDuplicateOpPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	c := lexutil.LexLink(cid.MustParse("bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm"))
+	a := "app.bsky.feed.post/3l3qo2vutsw2a"
+	b := "app.bsky.feed.post/3l3qo2vutsw2b"
+
+	assert.NoError(checkDuplicateOpPaths(nil))
+	assert.NoError(checkDuplicateOpPaths([]*atproto.SyncSubscribeRepos_RepoOp{
+		{Action: "create", Path: a, Cid: &c},
+		{Action: "create", Path: b, Cid: &c},
+	}))
+	assert.Error(checkDuplicateOpPaths([]*atproto.SyncSubscribeRepos_RepoOp{
+		{Action: "create", Path: a, Cid: &c},
+		{Action: "create", Path: a, Cid: &c},
+	}))
+	// create and delete of the same record in one commit
+	assert.Error(checkDuplicateOpPaths([]*atproto.SyncSubscribeRepos_RepoOp{
+		{Action: "create", Path: a, Cid: &c},
+		{Action: "create", Path: b, Cid: &c},
+		{Action: "delete", Path: a, Prev: &c},
+	}))
+}
+
+func TestHandleIdentity(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "pds.example.com"}
+	handle := "alice.example.com"
+	badHandle := "not a handle"
+	ts := syntax.DatetimeNow().String()
+
+	dir := identity.NewMockDirectory()
+	val := NewValidatorWithConfig(&dir, nil, &ValidatorConfig{ResolveIdentityEvents: true})
+
+	// resolution problems are only warnings
+	assert.NoError(val.HandleIdentity(ctx, host, &atproto.SyncSubscribeRepos_Identity{Did: "did:plc:abc111", Time: ts}))
+	assert.NoError(val.HandleIdentity(ctx, host, &atproto.SyncSubscribeRepos_Identity{Did: "did:plc:abc111", Time: ts, Handle: &handle}))
+
+	assert.Error(val.HandleIdentity(ctx, host, &atproto.SyncSubscribeRepos_Identity{Did: "not-a-did", Time: ts}))
+	assert.Error(val.HandleIdentity(ctx, host, &atproto.SyncSubscribeRepos_Identity{Did: "did:plc:abc111", Time: "yesterday"}))
+	assert.Error(val.HandleIdentity(ctx, host, &atproto.SyncSubscribeRepos_Identity{Did: "did:plc:abc111", Time: ts, Handle: &badHandle}))
+}
+
+func TestHandleAccount(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "pds.example.com"}
+	val := NewValidator(newTestDirectory(), nil)
+	ts := syntax.DatetimeNow().String()
+	deactivated := "deactivated"
+	novel := "some-new-status"
+
+	assert.NoError(val.HandleAccount(ctx, host, &atproto.SyncSubscribeRepos_Account{Did: "did:plc:abc111", Time: ts, Active: true}))
+	assert.NoError(val.HandleAccount(ctx, host, &atproto.SyncSubscribeRepos_Account{Did: "did:plc:abc111", Time: ts, Status: &deactivated}))
+	// unknown statuses are allowed, since the set is open-ended
+	assert.NoError(val.HandleAccount(ctx, host, &atproto.SyncSubscribeRepos_Account{Did: "did:plc:abc111", Time: ts, Status: &novel}))
+
+	assert.ErrorIs(val.HandleAccount(ctx, host, &atproto.SyncSubscribeRepos_Account{Did: "did:plc:abc111", Time: ts}), ErrAccountMissingStatus)
+	assert.Error(val.HandleAccount(ctx, host, &atproto.SyncSubscribeRepos_Account{Did: "not-a-did", Time: ts, Active: true}))
+	assert.Error(val.HandleAccount(ctx, host, &atproto.SyncSubscribeRepos_Account{Did: "did:plc:abc111", Time: "", Active: true}))
+}
+
+// builds a signed #commit message (without prevData) from a fresh fragment and create ops (from testOpsFragment()), plus any extra ops which don't touch the tree (eg, legacy deletes)
+func testCommitMessage(t *testing.T, priv crypto.PrivateKey, did syntax.DID, fragment *atrepo.Repo, ops []*atproto.SyncSubscribeRepos_RepoOp) *atproto.SyncSubscribeRepos_Commit {
+	ctx := context.Background()
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	root, err := fragment.MST.WriteDiffBlocks(ctx, bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range ops {
+		if op.Cid == nil {
+			continue
+		}
+		blk, err := fragment.RecordStore.Get(ctx, cid.Cid(*op.Cid))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bs.Put(ctx, blk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rev := syntax.NewTIDNow(0)
+	commit := atrepo.Commit{DID: did.String(), Version: 3, Data: *root, Rev: rev.String()}
+	if err := commit.Sign(priv); err != nil {
+		t.Fatal(err)
+	}
+	commitBuf := new(bytes.Buffer)
+	if err := commit.MarshalCBOR(commitBuf); err != nil {
+		t.Fatal(err)
+	}
+	commitCID, err := cid.NewPrefixV1(cid.DagCBOR, multihash.SHA2_256).Sum(commitBuf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitBlk, err := blocks.NewBlockWithCid(commitBuf.Bytes(), commitCID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.Put(ctx, commitBlk); err != nil {
+		t.Fatal(err)
+	}
+
+	carBuf := new(bytes.Buffer)
+	if err := car.WriteHeader(&car.CarHeader{Roots: []cid.Cid{commitCID}, Version: 1}, carBuf); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range keys {
+		blk, err := bs.Get(ctx, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the blockstore is keyed by multihash only; all blocks here are DAG-CBOR
+		k = cid.NewCidV1(cid.DagCBOR, k.Hash())
+		if err := carutil.LdWrite(carBuf, k.Bytes(), blk.RawData()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &atproto.SyncSubscribeRepos_Commit{
+		Repo:   did.String(),
+		Rev:    rev.String(),
+		Time:   syntax.DatetimeNow().String(),
+		Blocks: lexutil.LexBytes(carBuf.Bytes()),
+		Ops:    ops,
+	}
+}
+
+func TestVerifyCommitLegacyStrict(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "test.example.com"}
+	did := syntax.DID("did:plc:abc123")
+	priv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	dir := identity.NewMockDirectory()
+	dir.Insert(testIdentity(t, did, priv))
+
+	// note that fragments can't be re-used: only "dirty" tree nodes are written out
+	fragment, ops := testOpsFragment(t, 2)
+	noPrevData := testCommitMessage(t, priv, did, fragment, ops)
+	fragment, ops = testOpsFragment(t, 2)
+	legacyDelete := testCommitMessage(t, priv, did, fragment, append(ops, &atproto.SyncSubscribeRepos_RepoOp{Action: "delete", Path: "app.bsky.feed.post/3l3qo2vutsw2b"}))
+
+	// default: legacy messages pass
+	val := NewValidator(&dir, nil)
+	_, err = val.VerifyCommitMessage(ctx, host, noPrevData, nil)
+	assert.NoError(err)
+	_, err = val.VerifyCommitMessage(ctx, host, legacyDelete, nil)
+	assert.NoError(err)
+
+	config := DefaultValidatorConfig()
+	config.RequirePrevData = true
+	val = NewValidatorWithConfig(&dir, nil, config)
+	_, err = val.VerifyCommitMessage(ctx, host, noPrevData, nil)
+	assert.ErrorIs(err, ErrMissingPrevData)
+	// legacy ops are checked before prevData
+	_, err = val.VerifyCommitMessage(ctx, host, legacyDelete, nil)
+	assert.NoError(err)
+
+	config = DefaultValidatorConfig()
+	config.RejectLegacyOps = true
+	val = NewValidatorWithConfig(&dir, nil, config)
+	_, err = val.VerifyCommitMessage(ctx, host, noPrevData, nil)
+	assert.NoError(err)
+	_, err = val.VerifyCommitMessage(ctx, host, legacyDelete, nil)
+	assert.ErrorIs(err, ErrLegacyOp)
+}
+
+func TestVerifyErrorReason(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "test.example.com"}
+	did := syntax.DID("did:plc:abc123")
+	priv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	dir := identity.NewMockDirectory()
+	dir.Insert(testIdentity(t, did, priv))
+
+	config := DefaultValidatorConfig()
+	config.RejectLegacyOps = true
+	val := NewValidatorWithConfig(&dir, nil, config)
+
+	var verr *VerifyError
+	_, err = val.VerifyCommitMessage(ctx, host, &atproto.SyncSubscribeRepos_Commit{Repo: "not-a-did"}, nil)
+	assert.True(errors.As(err, &verr))
+	assert.Equal(ReasonBadSyntax, verr.Reason)
+	assert.Equal("did", verr.Label)
+
+	fragment, ops := testOpsFragment(t, 2)
+	msg := testCommitMessage(t, priv, did, fragment, ops)
+	prev := &AccountPreviousState{Rev: syntax.NewTID(time.Now().Add(time.Minute).UnixMicro(), 0).String()}
+	_, err = val.VerifyCommitMessage(ctx, host, msg, prev)
+	assert.True(errors.As(err, &verr))
+	assert.Equal(ReasonRevBeforePrev, verr.Reason)
+	var roooe *revOutOfOrderError
+	assert.True(errors.As(err, &roooe))
+
+	fragment, ops = testOpsFragment(t, 2)
+	legacyDelete := testCommitMessage(t, priv, did, fragment, append(ops, &atproto.SyncSubscribeRepos_RepoOp{Action: "delete", Path: "app.bsky.feed.post/3l3qo2vutsw2b"}))
+	_, err = val.VerifyCommitMessage(ctx, host, legacyDelete, nil)
+	assert.ErrorIs(err, ErrLegacyOp)
+	assert.True(errors.As(err, &verr))
+	assert.Equal(ReasonLegacyOp, verr.Reason)
+	assert.Equal("ldel", verr.Label)
+
+	// signature failures are reported the same way
+	other, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	fragment, ops = testOpsFragment(t, 2)
+	_, err = val.VerifyCommitMessage(ctx, host, testCommitMessage(t, other, did, fragment, ops), nil)
+	assert.True(errors.As(err, &verr))
+	assert.Equal(ReasonSignature, verr.Reason)
+	assert.Equal("sig4", verr.Label)
+}
+
+func TestCommitBlocksSizeLimit(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "test.example.com"}
+	did := syntax.DID("did:plc:abc123")
+	priv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	dir := identity.NewMockDirectory()
+	dir.Insert(testIdentity(t, did, priv))
+
+	fragment, ops := testOpsFragment(t, 2)
+	msg := testCommitMessage(t, priv, did, fragment, ops)
+
+	config := DefaultValidatorConfig()
+	config.MaxCommitBlocksBytes = len(msg.Blocks)
+	val := NewValidatorWithConfig(&dir, nil, config)
+	_, err = val.VerifyCommitMessage(ctx, host, msg, nil)
+	assert.NoError(err)
+
+	// oversized payload is rejected before parsing (the padding isn't even valid CAR data)
+	before := testutil.ToFloat64(commitVerifyErrors.WithLabelValues(host.Host, "size"))
+	msg.Blocks = append(msg.Blocks, make([]byte, 1)...)
+	_, err = val.VerifyCommitMessage(ctx, host, msg, nil)
+	var verr *VerifyError
+	assert.True(errors.As(err, &verr))
+	assert.Equal(ReasonBadCAR, verr.Reason)
+	assert.Equal("size", verr.Label)
+	assert.Equal(before+1, testutil.ToFloat64(commitVerifyErrors.WithLabelValues(host.Host, "size")))
+
+	// same for #sync
+	_, err = val.HandleSync(ctx, host, &atproto.SyncSubscribeRepos_Sync{
+		Did:    did.String(),
+		Rev:    msg.Rev,
+		Time:   msg.Time,
+		Blocks: make([]byte, len(msg.Blocks)),
+	})
+	assert.True(errors.As(err, &verr))
+	assert.Equal("size", verr.Label)
+
+	// #sync failures in the steps shared with #commit are still counted as sync errors
+	beforeSync := testutil.ToFloat64(syncVerifyErrors.WithLabelValues(host.Host, "car"))
+	beforeCommit := testutil.ToFloat64(commitVerifyErrors.WithLabelValues(host.Host, "car"))
+	_, err = val.HandleSync(ctx, host, &atproto.SyncSubscribeRepos_Sync{
+		Did:    did.String(),
+		Rev:    msg.Rev,
+		Time:   msg.Time,
+		Blocks: make([]byte, 1),
+	})
+	assert.True(errors.As(err, &verr))
+	assert.Equal("car", verr.Label)
+	assert.Equal(beforeSync+1, testutil.ToFloat64(syncVerifyErrors.WithLabelValues(host.Host, "car")))
+	assert.Equal(beforeCommit, testutil.ToFloat64(commitVerifyErrors.WithLabelValues(host.Host, "car")))
+}
+
+func TestMaxOpsPerCommit(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "test.example.com"}
+	did := syntax.DID("did:plc:abc123")
+	priv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	dir := identity.NewMockDirectory()
+	dir.Insert(testIdentity(t, did, priv))
+
+	fragment, ops := testOpsFragment(t, 5)
+	msg := testCommitMessage(t, priv, did, fragment, ops)
+
+	config := DefaultValidatorConfig()
+	config.MaxOpsPerCommit = 5
+	val := NewValidatorWithConfig(&dir, nil, config)
+	_, err = val.VerifyCommitMessage(ctx, host, msg, nil)
+	assert.NoError(err)
+
+	config.MaxOpsPerCommit = 4
+	val = NewValidatorWithConfig(&dir, nil, config)
+	before := testutil.ToFloat64(commitVerifyErrors.WithLabelValues(host.Host, "nops"))
+	_, err = val.VerifyCommitMessage(ctx, host, msg, nil)
+	var verr *VerifyError
+	if assert.True(errors.As(err, &verr)) {
+		assert.Equal(ReasonBadOps, verr.Reason)
+		assert.Equal("nops", verr.Label)
+	}
+	assert.Equal(before+1, testutil.ToFloat64(commitVerifyErrors.WithLabelValues(host.Host, "nops")))
+
+	// rejected before the CAR slice is parsed or any op is checked against the MST
+	steps := val.VerifyCommitMessageTrace(ctx, host, nil, msg, nil)
+	last := steps[len(steps)-1]
+	assert.Equal("ops-count", last.Name)
+	assert.Equal(TraceStepFail, last.Outcome)
+	garbage := *msg
+	garbage.Blocks = []byte("garbage")
+	_, err = val.VerifyCommitMessage(ctx, host, &garbage, nil)
+	if assert.True(errors.As(err, &verr)) {
+		assert.Equal("nops", verr.Label)
+	}
+}
+
+func TestCommitRepoVersion(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "test.example.com"}
+	did := syntax.DID("did:plc:abc123")
+	priv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	dir := identity.NewMockDirectory()
+	dir.Insert(testIdentity(t, did, priv))
+	val := NewValidator(&dir, nil)
+
+	// CAR slice containing only a (signed) commit object with an old repo version
+	rev := syntax.NewTIDNow(0)
+	commit := atrepo.Commit{
+		DID:     did.String(),
+		Version: 2,
+		Data:    cid.MustParse("bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm"),
+		Rev:     rev.String(),
+	}
+	assert.NoError(commit.Sign(priv))
+	commitBuf := new(bytes.Buffer)
+	assert.NoError(commit.MarshalCBOR(commitBuf))
+	commitCID, err := cid.NewPrefixV1(cid.DagCBOR, multihash.SHA2_256).Sum(commitBuf.Bytes())
+	assert.NoError(err)
+	carBuf := new(bytes.Buffer)
+	assert.NoError(car.WriteHeader(&car.CarHeader{Roots: []cid.Cid{commitCID}, Version: 1}, carBuf))
+	assert.NoError(carutil.LdWrite(carBuf, commitCID.Bytes(), commitBuf.Bytes()))
+
+	_, err = val.VerifyCommitMessage(ctx, host, &atproto.SyncSubscribeRepos_Commit{
+		Repo:   did.String(),
+		Rev:    rev.String(),
+		Time:   syntax.DatetimeNow().String(),
+		Blocks: lexutil.LexBytes(carBuf.Bytes()),
+	}, nil)
+	var verr *VerifyError
+	assert.True(errors.As(err, &verr))
+	assert.Equal(ReasonRepoVersion, verr.Reason)
+	assert.Equal("ver", verr.Label)
+	assert.ErrorIs(err, atrepo.ErrUnsupportedRepoVersion)
+
+	_, err = val.HandleSync(ctx, host, &atproto.SyncSubscribeRepos_Sync{
+		Did:    did.String(),
+		Rev:    rev.String(),
+		Time:   syntax.DatetimeNow().String(),
+		Blocks: carBuf.Bytes(),
+	})
+	assert.True(errors.As(err, &verr))
+	assert.Equal("ver", verr.Label)
+}
+
+func TestVerifyCommitSignatureOnly(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "test.example.com"}
+	did := syntax.DID("did:plc:abc123")
+	priv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	dir := identity.NewMockDirectory()
+	dir.Insert(testIdentity(t, did, priv))
+	val := NewValidator(&dir, nil)
+
+	fragment, ops := testOpsFragment(t, 2)
+	msg := testCommitMessage(t, priv, did, fragment, ops)
+	commit, err := val.VerifyCommitSignatureOnly(ctx, host, msg, nil)
+	assert.NoError(err)
+	assert.Equal(msg.Rev, commit.Rev)
+
+	// CAR slice containing only the signed commit object: the MST and records are missing, which is not checked
+	rev := syntax.NewTIDNow(0)
+	onlyCommit := atrepo.Commit{
+		DID:     did.String(),
+		Version: 3,
+		Data:    cid.MustParse("bafyreie5737gdxlw5i64vzichcalba3z2v5n6icifvx5xytvske7mr3hpm"),
+		Rev:     rev.String(),
+	}
+	assert.NoError(onlyCommit.Sign(priv))
+	commitBuf := new(bytes.Buffer)
+	assert.NoError(onlyCommit.MarshalCBOR(commitBuf))
+	commitCID, err := cid.NewPrefixV1(cid.DagCBOR, multihash.SHA2_256).Sum(commitBuf.Bytes())
+	assert.NoError(err)
+	carBuf := new(bytes.Buffer)
+	assert.NoError(car.WriteHeader(&car.CarHeader{Roots: []cid.Cid{commitCID}, Version: 1}, carBuf))
+	assert.NoError(carutil.LdWrite(carBuf, commitCID.Bytes(), commitBuf.Bytes()))
+	partial := &atproto.SyncSubscribeRepos_Commit{
+		Repo:   did.String(),
+		Rev:    rev.String(),
+		Time:   syntax.DatetimeNow().String(),
+		Blocks: lexutil.LexBytes(carBuf.Bytes()),
+		Ops:    ops,
+	}
+	_, err = val.VerifyCommitSignatureOnly(ctx, host, partial, nil)
+	assert.NoError(err)
+	_, err = val.VerifyCommitMessage(ctx, host, partial, nil)
+	assert.Error(err)
+
+	// field checks and signature failures are reported the same as for full verification
+	var verr *VerifyError
+	prev := &AccountPreviousState{Rev: syntax.NewTID(time.Now().Add(time.Minute).UnixMicro(), 0).String()}
+	_, err = val.VerifyCommitSignatureOnly(ctx, host, msg, prev)
+	assert.True(errors.As(err, &verr))
+	assert.Equal(ReasonRevBeforePrev, verr.Reason)
+
+	other, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	fragment, ops = testOpsFragment(t, 2)
+	_, err = val.VerifyCommitSignatureOnly(ctx, host, testCommitMessage(t, other, did, fragment, ops), nil)
+	assert.True(errors.As(err, &verr))
+	assert.Equal(ReasonSignature, verr.Reason)
+
+	fragment, ops = testOpsFragment(t, 2)
+	msg = testCommitMessage(t, priv, did, fragment, ops)
+	msg.Repo = "did:plc:other"
+	_, err = val.VerifyCommitSignatureOnly(ctx, host, msg, nil)
+	assert.True(errors.As(err, &verr))
+	assert.Equal(ReasonDIDMismatch, verr.Reason)
+}
+
+func TestCheckRecordBlobs(t *testing.T) {
+	assert := assert.New(t)
+
+	record := func(c cid.Cid) []byte {
+		b, err := data.MarshalCBOR(map[string]any{
+			"$type": "app.bsky.feed.post",
+			"embed": map[string]any{
+				"images": []any{map[string]any{"image": data.Blob{Ref: data.CIDLink(c), MimeType: "image/jpeg", Size: 123}}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	rawCid, err := cid.NewPrefixV1(cid.Raw, multihash.SHA2_256).Sum([]byte("blob"))
+	assert.NoError(err)
+	blobs, label, err := checkRecordBlobs(record(rawCid))
+	assert.NoError(err)
+	assert.Equal("", label)
+	assert.Equal([]cid.Cid{rawCid}, blobs)
+
+	// wrong codec
+	cborCid, err := cid.NewPrefixV1(cid.DagCBOR, multihash.SHA2_256).Sum([]byte("blob"))
+	assert.NoError(err)
+	_, label, err = checkRecordBlobs(record(cborCid))
+	assert.Error(err)
+	assert.Equal("blob", label)
+
+	// wrong multihash
+	blakeCid, err := cid.NewPrefixV1(cid.Raw, multihash.BLAKE2B_MIN+31).Sum([]byte("blob"))
+	assert.NoError(err)
+	_, label, err = checkRecordBlobs(record(blakeCid))
+	assert.Error(err)
+	assert.Equal("blob", label)
+
+	// not CBOR
+	_, label, err = checkRecordBlobs([]byte("app.bsky.feed.post/0000000000000"))
+	assert.Error(err)
+	assert.Equal("bdec", label)
+}
+
+// builds an in-memory repo fragment with the given CBOR records, and matching create ops, in order
+func testRecordsFragment(t *testing.T, records []map[string]any) (*atrepo.Repo, []*atproto.SyncSubscribeRepos_RepoOp) {
+	bs := atrepo.NewTinyBlockstore()
+	tree := mst.NewEmptyTree()
+	ops := make([]*atproto.SyncSubscribeRepos_RepoOp, len(records))
+	for i, rec := range records {
+		path := fmt.Sprintf("app.bsky.feed.post/%013d", i)
+		b, err := data.MarshalCBOR(rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := cid.NewPrefixV1(cid.DagCBOR, multihash.SHA2_256).Sum(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blk, err := blocks.NewBlockWithCid(b, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bs.Put(context.Background(), blk); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tree.Insert([]byte(path), c); err != nil {
+			t.Fatal(err)
+		}
+		ll := lexutil.LexLink(c)
+		ops[i] = &atproto.SyncSubscribeRepos_RepoOp{Action: "create", Path: path, Cid: &ll}
+	}
+	return &atrepo.Repo{RecordStore: bs, MST: tree}, ops
+}
+
+func TestOnCommitBlobs(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "test.example.com"}
+	did := syntax.DID("did:plc:abc123")
+	priv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	dir := identity.NewMockDirectory()
+	dir.Insert(testIdentity(t, did, priv))
+
+	blobCids := make([]cid.Cid, 4)
+	for i := range blobCids {
+		blobCids[i], err = cid.NewPrefixV1(cid.Raw, multihash.SHA2_256).Sum([]byte(fmt.Sprintf("blob%d", i)))
+		assert.NoError(err)
+	}
+	blob := func(i int) data.Blob {
+		return data.Blob{Ref: data.CIDLink(blobCids[i]), MimeType: "image/jpeg", Size: 123}
+	}
+	fragment, ops := testRecordsFragment(t, []map[string]any{
+		// blobs in an array, nested in an object
+		{"$type": "app.bsky.feed.post", "embed": map[string]any{
+			"images": []any{map[string]any{"image": blob(0)}, map[string]any{"image": blob(1)}},
+		}},
+		// no blobs
+		{"$type": "app.bsky.feed.post", "text": "hello"},
+		// top-level blob, and a blob nested in arrays of objects
+		{"$type": "app.bsky.actor.profile", "avatar": blob(2), "extra": []any{[]any{map[string]any{"deep": blob(3)}}}},
+	})
+	msg := testCommitMessage(t, priv, did, fragment, ops)
+
+	// extracted directly from record data
+	recBytes, _, err := fragment.GetRecordBytes(ctx, "app.bsky.feed.post", "0000000000002")
+	assert.NoError(err)
+	assert.ElementsMatch(blobCids[2:], extractRecordBlobs(recBytes, slog.Default()))
+
+	for _, checkBlobRefs := range []bool{false, true} {
+		calls := 0
+		var gotBlobs []cid.Cid
+		config := DefaultValidatorConfig()
+		config.CheckBlobRefs = checkBlobRefs
+		config.OnCommitBlobs = func(hostname string, hookDID string, rev string, blobs []cid.Cid) {
+			calls++
+			assert.Equal(host.Host, hostname)
+			assert.Equal(did.String(), hookDID)
+			assert.Equal(msg.Rev, rev)
+			gotBlobs = blobs
+		}
+		val := NewValidatorWithConfig(&dir, nil, config)
+		_, err := val.VerifyCommitMessage(ctx, host, msg, nil)
+		assert.NoError(err)
+		assert.Equal(1, calls)
+		assert.ElementsMatch(blobCids, gotBlobs)
+
+		// not called for failed or traced verification
+		config.RequirePrevData = true
+		val = NewValidatorWithConfig(&dir, nil, config)
+		_, err = val.VerifyCommitMessage(ctx, host, msg, nil)
+		assert.ErrorIs(err, ErrMissingPrevData)
+		val.VerifyCommitMessageTrace(ctx, host, nil, msg, nil)
+		assert.Equal(1, calls)
+	}
+
+	// blob extraction is skipped without the hook, and verification is unaffected
+	val := NewValidatorWithConfig(&dir, nil, DefaultValidatorConfig())
+	_, err = val.VerifyCommitMessage(ctx, host, msg, nil)
+	assert.NoError(err)
+}
+
+// inverts ops normally, except for the given path
+type failingOpInverter struct {
+	DefaultOpInverter
+	path string
+}
+
+func (fi failingOpInverter) Invert(tree *mst.Tree, op *atrepo.Operation) error {
+	if op.Path == fi.path {
+		return errors.New("inversion failed")
+	}
+	return fi.DefaultOpInverter.Invert(tree, op)
+}
+
+func TestInvertOpTrace(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "test.example.com"}
+	did := syntax.DID("did:plc:abc123")
+	priv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	dir := identity.NewMockDirectory()
+	dir.Insert(testIdentity(t, did, priv))
+
+	fragment, ops := testOpsFragment(t, 2)
+	msg := testCommitMessage(t, priv, did, fragment, ops)
+	prevData := *ops[0].Cid
+	msg.PrevData = &prevData
+
+	sink := &recordingTraceSink{}
+	config := DefaultValidatorConfig()
+	config.TraceSink = sink
+	val := NewValidatorWithConfig(&dir, nil, config)
+	val.OpInverter = failingOpInverter{path: ops[1].Path}
+
+	_, err = val.VerifyCommitMessage(ctx, host, msg, nil)
+	var verr *VerifyError
+	assert.True(errors.As(err, &verr))
+	assert.Equal("inv", verr.Label)
+	assert.Equal([]string{AnomalyInvertOp}, sink.kinds)
+	assert.Equal(ops[1].Path, sink.details[0]["path"])
+	assert.Equal("create", sink.details[0]["action"])
+	assert.Equal(ops[1].Cid.String(), sink.details[0]["value"])
+
+	// a nil OpInverter (eg, a Validator struct literal) falls back to the default inversion
+	val.OpInverter = nil
+	_, err = val.VerifyCommitMessage(ctx, host, msg, nil)
+	assert.True(errors.As(err, &verr))
+	assert.Equal("pd", verr.Label)
+
+	// errors from atproto/repo identify the op themselves
+	c := cid.Cid(*ops[0].Cid)
+	detail := invertOpDetail(nil, &atrepo.OperationError{Op: atrepo.Operation{Path: ops[0].Path, Prev: &c}, Cause: errors.New("oops")})
+	assert.Equal(ops[0].Path, detail["path"])
+	assert.Equal("delete", detail["action"])
+	assert.Equal(c.String(), detail["prev"])
+}
+
+func TestCommitClockSkew(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "skew.example.com"}
+	did := syntax.DID("did:plc:abc123")
+	priv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	dir := identity.NewMockDirectory()
+	dir.Insert(testIdentity(t, did, priv))
+	val := NewValidator(&dir, nil)
+
+	skew := func() *dto.Histogram {
+		var m dto.Metric
+		assert.NoError(commitClockSkew.WithLabelValues(host.Host).(prometheus.Histogram).Write(&m))
+		return m.GetHistogram()
+	}
+
+	fragment, ops := testOpsFragment(t, 2)
+	msg := testCommitMessage(t, priv, did, fragment, ops)
+	_, err = val.VerifyCommitMessage(ctx, host, msg, nil)
+	assert.NoError(err)
+	assert.Equal(uint64(1), skew().GetSampleCount())
+	assert.Less(skew().GetSampleSum(), 1.0)
+
+	// rejected messages are observed too, with positive skew for revs in the future
+	future := syntax.NewTID(time.Now().Add(2*time.Hour).UnixMicro(), 0).String()
+	_, err = val.HandleSync(ctx, host, &atproto.SyncSubscribeRepos_Sync{Did: did.String(), Rev: future, Time: msg.Time})
+	var verr *VerifyError
+	assert.True(errors.As(err, &verr))
+	assert.Equal("revf", verr.Label)
+	assert.Equal(uint64(2), skew().GetSampleCount())
+	assert.Greater(skew().GetSampleSum(), 3600.0)
+
+	// signature-only audits are not counted
+	_, err = val.VerifyCommitSignatureOnly(ctx, host, msg, nil)
+	assert.NoError(err)
+	assert.Equal(uint64(2), skew().GetSampleCount())
+}
+
+func TestHandleCommitAccountChecks(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "test.example.com"}
+	did := syntax.DID("did:plc:abc123")
+	priv, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+	dir := identity.NewMockDirectory()
+	dir.Insert(testIdentity(t, did, priv))
+	val := NewValidator(&dir, nil)
+
+	fragment, ops := testOpsFragment(t, 2)
+	msg := testCommitMessage(t, priv, did, fragment, ops)
+	root, err := fragment.MST.RootCID()
+	assert.NoError(err)
+	outcomes := func() uint64 {
+		var m dto.Metric
+		assert.NoError(commitVerifyDuration.WithLabelValues(host.Host, "error").(prometheus.Histogram).Write(&m))
+		return m.GetHistogram().GetSampleCount()
+	}
+
+	testCases := []struct {
+		name     string
+		account  *Account
+		prevRoot *AccountPreviousState
+		label    string
+	}{
+		{name: "ok", account: &Account{ID: 1, Did: did.String()}},
+		{name: "ok with prev", account: &Account{ID: 1, Did: did.String()}, prevRoot: &AccountPreviousState{Uid: 1}},
+		{name: "did mismatch", account: &Account{ID: 1, Did: "did:plc:other"}, label: "acct"},
+		{name: "uid mismatch", account: &Account{ID: 1, Did: did.String()}, prevRoot: &AccountPreviousState{Uid: 2}, label: "puid"},
+	}
+	for _, tc := range testCases {
+		if tc.label == "" {
+			newRoot, err := val.HandleCommit(ctx, host, tc.account, msg, tc.prevRoot)
+			assert.NoError(err, tc.name)
+			assert.Equal(root, newRoot, tc.name)
+			continue
+		}
+		before := testutil.ToFloat64(commitVerifyErrors.WithLabelValues(host.Host, tc.label))
+		errorsBefore := outcomes()
+		newRoot, err := val.HandleCommit(ctx, host, tc.account, msg, tc.prevRoot)
+		assert.Nil(newRoot, tc.name)
+		var verr *VerifyError
+		if assert.True(errors.As(err, &verr), tc.name) {
+			assert.Equal(ReasonDIDMismatch, verr.Reason, tc.name)
+			assert.Equal(tc.label, verr.Label, tc.name)
+		}
+		assert.Equal(before+1, testutil.ToFloat64(commitVerifyErrors.WithLabelValues(host.Host, tc.label)), tc.name)
+		// counted as a verification outcome, like other failures
+		assert.Equal(errorsBefore+1, outcomes(), tc.name)
+	}
+	assert.Greater(val.HostErrorRate(host.Host), 0.0)
+}
+
+func TestHandleCommitBatch(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	host := &models.PDS{Host: "test.example.com"}
+	dir := newTestDirectory()
+
+	var accounts []*Account
+	var privs []crypto.PrivateKey
+	for i := 1; i <= 4; i++ {
+		did := syntax.DID(fmt.Sprintf("did:plc:abc%d", i))
+		priv, err := crypto.GeneratePrivateKeyK256()
+		assert.NoError(err)
+		dir.Insert(testIdentity(t, did, priv))
+		accounts = append(accounts, &Account{ID: models.Uid(i), Did: did.String()})
+		privs = append(privs, priv)
+	}
+	other, err := crypto.GeneratePrivateKeyK256()
+	assert.NoError(err)
+
+	// jobs for several accounts, interleaved, with some failures; roots are nil for expected failures
+	var jobs []CommitJob
+	var roots []*cid.Cid
+	addJob := func(acct int, signer crypto.PrivateKey) {
+		fragment, ops := testOpsFragment(t, 2+len(jobs))
+		msg := testCommitMessage(t, signer, syntax.DID(accounts[acct].Did), fragment, ops)
+		jobs = append(jobs, CommitJob{Account: accounts[acct], Commit: msg})
+		root, err := fragment.MST.RootCID()
+		assert.NoError(err)
+		if signer != privs[acct] {
+			root = nil
+		}
+		roots = append(roots, root)
+	}
+	addJob(0, privs[0])
+	addJob(1, privs[1])
+	addJob(0, other)
+	addJob(2, privs[2])
+	addJob(1, privs[1])
+	addJob(3, other)
+	addJob(0, privs[0])
+
+	config := DefaultValidatorConfig()
+	config.BatchWorkers = 3
+	val := NewValidatorWithConfig(dir, nil, config)
+	results, err := val.HandleCommitBatch(ctx, host, jobs)
+	assert.NoError(err)
+	assert.Equal(len(jobs), len(results))
+	for i, res := range results {
+		if roots[i] == nil {
+			var verr *VerifyError
+			assert.True(errors.As(res.Err, &verr), "job %d", i)
+			assert.Nil(res.NewRoot, "job %d", i)
+			continue
+		}
+		assert.NoError(res.Err, "job %d", i)
+		assert.Equal(roots[i], res.NewRoot, "job %d", i)
+	}
+
+	// a single worker processes accounts in order of first appearance; cancel while verifying the second account
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	dir.onLookup = func(did syntax.DID) error {
+		if did.String() == accounts[1].Did {
+			cancel()
+		}
+		return nil
+	}
+	config.BatchWorkers = 1
+	val = NewValidatorWithConfig(dir, nil, config)
+	results, err = val.HandleCommitBatch(cctx, host, jobs)
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal(len(jobs), len(results))
+	// account 0 was verified before cancellation
+	assert.NoError(results[0].Err)
+	assert.Equal(roots[0], results[0].NewRoot)
+	assert.Error(results[2].Err)
+	assert.NoError(results[6].Err)
+	assert.Equal(roots[6], results[6].NewRoot)
+	// the later jobs for account 1, and accounts 2 and 3, were never started
+	for _, i := range []int{3, 4, 5} {
+		assert.ErrorIs(results[i].Err, context.Canceled, "job %d", i)
+		assert.Nil(results[i].NewRoot, "job %d", i)
+	}
+}
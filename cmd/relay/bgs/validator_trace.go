@@ -0,0 +1,134 @@
+package bgs
+
+import (
+	"context"
+	"fmt"
+
+	atproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/cmd/relay/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	TraceStepOk   = "ok"
+	TraceStepWarn = "warn"
+	TraceStepFail = "fail"
+	TraceStepSkip = "skip"
+)
+
+// VerifyStep is one step of a commit verification trace
+type VerifyStep struct {
+	Name string
+	// Outcome is one of TraceStepOk, TraceStepWarn, TraceStepFail, or TraceStepSkip (the check is disabled by configuration)
+	Outcome string
+	Err     error
+	// Values holds any computed values relevant to the step, eg "computed" root CID when inverting ops, or the metrics "label" of a failure
+	Values map[string]string
+}
+
+type verifyTrace struct {
+	steps []VerifyStep
+}
+
+func (vt *verifyTrace) add(name string, outcome string, err error, values map[string]string) {
+	vt.steps = append(vt.steps, VerifyStep{Name: name, Outcome: outcome, Err: err, Values: values})
+}
+
+// verifyRun carries the state of verifying a single message through the steps shared by VerifyCommitMessage(), VerifyCommitSignatureOnly(), and VerifyCommitMessageTrace().
+//
+// If trace is set, each step is recorded there instead of being counted: failures, warnings, and okish outcomes are not added to metrics, and anomalies are not recorded.
+type verifyRun struct {
+	val      *Validator
+	ctx      context.Context
+	hostname string
+	// msg is the message being verified, for anomaly records; nil when only checking a signature
+	msg *atproto.SyncSubscribeRepos_Commit
+	// errCounter is the metric failures are counted in; nil means commitVerifyErrors
+	errCounter *prometheus.CounterVec
+	trace      *verifyTrace
+	hasWarning bool
+}
+
+// traceValues converts alternating keys and values to a map, only when tracing, so the hot path doesn't pay for formatting
+func traceValues(kv []any) map[string]string {
+	if len(kv) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		values[fmt.Sprint(kv[i])] = fmt.Sprint(kv[i+1])
+	}
+	return values
+}
+
+// ok records a passed step in the trace, with optional alternating keys and values
+func (r *verifyRun) ok(step string, kv ...any) {
+	if r.trace != nil {
+		r.trace.add(step, TraceStepOk, nil, traceValues(kv))
+	}
+}
+
+// skip records a step which is disabled by configuration in the trace
+func (r *verifyRun) skip(step string) {
+	if r.trace != nil {
+		r.trace.add(step, TraceStepSkip, nil, nil)
+	}
+}
+
+// note records a step which passed with a caveat in the trace, without counting it as a warning
+func (r *verifyRun) note(step string, msg string, kv ...any) {
+	if r.trace != nil {
+		r.trace.add(step, TraceStepWarn, fmt.Errorf("%s", msg), traceValues(kv))
+	}
+}
+
+// fail returns a VerifyError for a failed step, counting it in the error metrics (or recording it in the trace)
+func (r *verifyRun) fail(step string, label string, reason VerifyReason, err error) error {
+	if r.trace == nil {
+		counter := r.errCounter
+		if counter == nil {
+			counter = commitVerifyErrors
+		}
+		return verifyFailure(counter, r.hostname, label, reason, err)
+	}
+	r.trace.add(step, TraceStepFail, err, map[string]string{"label": label})
+	return &VerifyError{Reason: reason, Label: label, Err: err}
+}
+
+// warn marks the message as having a warning, counting it in the commit warning metrics (or recording it in the trace)
+func (r *verifyRun) warn(step string, label string, msg string) {
+	r.hasWarning = true
+	if r.trace == nil {
+		commitVerifyWarnings.WithLabelValues(r.hostname, label).Inc()
+		return
+	}
+	r.trace.add(step, TraceStepWarn, fmt.Errorf("%s", msg), map[string]string{"label": label})
+}
+
+// okish counts a message which passed without being fully verified; no-op when tracing
+func (r *verifyRun) okish(label string) {
+	if r.trace == nil {
+		commitVerifyOkish.WithLabelValues(r.hostname, label).Inc()
+	}
+}
+
+// anomaly records a commit anomaly for the message; no-op when tracing
+func (r *verifyRun) anomaly(kind string, detail map[string]any) {
+	if r.trace == nil && r.msg != nil {
+		r.val.recordAnomaly(r.ctx, kind, r.hostname, r.msg.Repo, r.msg.Seq, detail)
+	}
+}
+
+// VerifyCommitMessageTrace is a debugging sibling of VerifyCommitMessage(), which returns an ordered record of each verification step's outcome and computed values.
+//
+// It runs exactly the same steps as HandleCommit() (or VerifyCommitMessage(), if account is nil), stopping at the first failure, so the last step shows why a message would be rejected. host may be nil. No metrics or anomalies are recorded, and hooks are not called; the signing key cache and identity directory are used as normal.
+func (val *Validator) VerifyCommitMessageTrace(ctx context.Context, host *models.PDS, account *Account, msg *atproto.SyncSubscribeRepos_Commit, prevRoot *AccountPreviousState) []VerifyStep {
+	hostname := ""
+	if host != nil {
+		hostname = host.Host
+	}
+	vt := &verifyTrace{}
+	val.verifyCommitMessage(ctx, hostname, account, msg, prevRoot, vt)
+	return vt.steps
+}
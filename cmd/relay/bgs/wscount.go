@@ -0,0 +1,65 @@
+package bgs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// offersWSCompression returns true if the websocket handshake request offers the permessage-deflate extension
+func offersWSCompression(r *http.Request) bool {
+	for _, ext := range r.Header.Values("Sec-WebSocket-Extensions") {
+		for _, part := range strings.Split(ext, ",") {
+			name, _, _ := strings.Cut(part, ";")
+			if strings.TrimSpace(name) == "permessage-deflate" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// countingResponseWriter wraps an HTTP response so that the connection returned by Hijack() (as used for a websocket upgrade) counts bytes written to the network
+type countingResponseWriter struct {
+	http.ResponseWriter
+	counter prometheus.Counter
+}
+
+func (w *countingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response does not implement http.Hijacker")
+	}
+	conn, brw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &countingConn{Conn: conn, counter: w.counter}, brw, nil
+}
+
+type countingConn struct {
+	net.Conn
+	counter prometheus.Counter
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.counter.Add(float64(n))
+	return n, err
+}
+
+type countingWriteCloser struct {
+	io.WriteCloser
+	counter prometheus.Counter
+}
+
+func (w *countingWriteCloser) Write(b []byte) (int, error) {
+	n, err := w.WriteCloser.Write(b)
+	w.counter.Add(float64(n))
+	return n, err
+}
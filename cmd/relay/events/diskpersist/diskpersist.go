@@ -720,6 +720,21 @@ func (dp *DiskPersistence) Playback(ctx context.Context, since int64, cb func(*e
 	return nil
 }
 
+var _ events.TimeIndexedPersistence = (*DiskPersistence)(nil)
+
+// SeqForTime implements events.TimeIndexedPersistence. Log file creation times are used to skip to the log file containing events around the given time, with one extra earlier log file scanned for events which were received late (eg, from a host catching up on a backlog).
+func (dp *DiskPersistence) SeqForTime(ctx context.Context, t time.Time) (int64, error) {
+	var logs []LogFileRef
+	if err := dp.meta.WithContext(ctx).Order("seq_start desc").Where("created_at <= ?", t).Limit(2).Find(&logs).Error; err != nil {
+		return 0, err
+	}
+	var since int64
+	if len(logs) > 0 {
+		since = max(logs[len(logs)-1].SeqStart-1, 0)
+	}
+	return events.ScanSeqForTime(ctx, dp.Playback, since, t)
+}
+
 func (dp *DiskPersistence) PlaybackLogfiles(ctx context.Context, since int64, cb func(*events.XRPCStreamEvent) error, logFiles []LogFileRef) (*int64, error) {
 	for i, lf := range logFiles {
 		lastSeq, err := dp.readEventsFrom(ctx, since, filepath.Join(dp.primaryDir, lf.Path), cb)
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	comatproto "github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/bluesky-social/indigo/cmd/relay/models"
 	lexutil "github.com/bluesky-social/indigo/lex/util"
 
@@ -63,6 +64,13 @@ type Operation struct {
 	evt *XRPCStreamEvent
 }
 
+// Sets the number of events buffered for each live subscriber. A subscriber which falls further behind than this is evicted. Must be called before any subscriptions are created; values less than 1 are ignored.
+func (em *EventManager) SetSubscriberBufferSize(size int) {
+	if size > 0 {
+		em.bufferSize = size
+	}
+}
+
 func (em *EventManager) Shutdown(ctx context.Context) error {
 	return em.persister.Shutdown(ctx)
 }
@@ -100,13 +108,14 @@ func (em *EventManager) broadcastEvent(evt *XRPCStreamEvent) {
 				s.filter = func(*XRPCStreamEvent) bool { return false }
 
 				em.log.Warn("dropping slow consumer due to event overflow", "bufferSize", len(s.outgoing), "ident", s.ident)
+				s.setEvicted(EvictReasonBufferFull)
 				go func(torem *Subscriber) {
 					torem.lk.Lock()
 					if !torem.cleanedUp {
 						select {
 						case torem.outgoing <- &XRPCStreamEvent{
 							Error: &ErrorFrame{
-								Error: "ConsumerTooSlow",
+								Error: ErrorConsumerTooSlow,
 							},
 						}:
 						case <-time.After(time.Second * 5):
@@ -131,9 +140,23 @@ func (em *EventManager) persistAndSendEvent(ctx context.Context, evt *XRPCStream
 	}
 }
 
+// Reasons a subscriber can be evicted (disconnected) for falling behind, used as metric labels
+const (
+	// the subscriber's send buffer filled up
+	EvictReasonBufferFull = "buffer_full"
+	// writing an event to the subscriber's connection took too long
+	EvictReasonWriteTimeout = "write_timeout"
+)
+
+// Name of the error frame sent to subscribers which are evicted for falling behind
+const ErrorConsumerTooSlow = "ConsumerTooSlow"
+
 type Subscriber struct {
 	outgoing chan *XRPCStreamEvent
 
+	// for subscriptions with a cursor, the channel events are actually consumed from. nil for live-only subscriptions.
+	crossover chan *XRPCStreamEvent
+
 	filter func(*XRPCStreamEvent) bool
 
 	done chan struct{}
@@ -143,11 +166,57 @@ type Subscriber struct {
 	lk        sync.Mutex
 	cleanedUp bool
 
+	evictLk     sync.Mutex
+	evictReason string
+
 	ident            string
 	enqueuedCounter  prometheus.Counter
 	broadcastCounter prometheus.Counter
 }
 
+// Returns the number of events queued for this subscriber, which have not yet been consumed.
+func (s *Subscriber) Backlog() int {
+	return len(s.outgoing) + len(s.crossover)
+}
+
+// Returns the reason this subscriber was evicted (one of the EvictReason* constants), or an empty string if it has not been evicted.
+func (s *Subscriber) EvictReason() string {
+	s.evictLk.Lock()
+	defer s.evictLk.Unlock()
+	return s.evictReason
+}
+
+// records the first eviction reason, and counts it in metrics. returns false if the subscriber was already evicted.
+func (s *Subscriber) setEvicted(reason string) bool {
+	s.evictLk.Lock()
+	defer s.evictLk.Unlock()
+	if s.evictReason != "" {
+		return false
+	}
+	s.evictReason = reason
+	consumersEvicted.WithLabelValues(reason).Inc()
+	return true
+}
+
+// Evicts the subscriber for falling behind, for reasons detected outside the EventManager (eg, [EvictReasonWriteTimeout]). The subscription is closed.
+func (s *Subscriber) Evict(reason string) {
+	s.setEvicted(reason)
+	s.cleanup()
+}
+
+// Channel of events for this subscription. It is closed when the subscription ends, including on eviction.
+func (s *Subscriber) Events() <-chan *XRPCStreamEvent {
+	if s.crossover != nil {
+		return s.crossover
+	}
+	return s.outgoing
+}
+
+// Ends the subscription. Safe to call multiple times.
+func (s *Subscriber) Close() {
+	s.cleanup()
+}
+
 const (
 	EvtKindErrorFrame = -1
 	EvtKindMessage    = 1
@@ -366,7 +435,15 @@ var (
 )
 
 func (em *EventManager) Subscribe(ctx context.Context, ident string, filter func(*XRPCStreamEvent) bool, since *int64) (<-chan *XRPCStreamEvent, func(), error) {
-	// TODO: the only known filters are 'true' and 'false', replace the function pointer with a bool
+	sub, err := em.SubscribeWithStatus(ctx, ident, filter, since)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sub.Events(), sub.cleanup, nil
+}
+
+// Same as Subscribe, but returns the [Subscriber] itself, which can be used to check the subscriber's backlog and eviction status.
+func (em *EventManager) SubscribeWithStatus(ctx context.Context, ident string, filter func(*XRPCStreamEvent) bool, since *int64) (*Subscriber, error) {
 	if filter == nil {
 		filter = func(*XRPCStreamEvent) bool { return true }
 	}
@@ -392,15 +469,26 @@ func (em *EventManager) Subscribe(ctx context.Context, ident string, filter func
 
 	if since == nil {
 		em.addSubscriber(sub)
-		return sub.outgoing, sub.cleanup, nil
+		return sub, nil
 	}
 
 	out := make(chan *XRPCStreamEvent, em.crossoverBufferSize)
+	sub.crossover = out
 
 	go func() {
+		// this goroutine is the only writer to 'out', so it is closed when this exits (including when the subscription is closed or evicted)
+		defer close(out)
+
 		lastSeq := *since
 		// run playback to get through *most* of the events, getting our current cursor close to realtime
 		if err := em.persister.Playback(ctx, *since, func(e *XRPCStreamEvent) error {
+			if !filter(e) {
+				// filtered events still advance the cursor, so they aren't played back again
+				if seq := SequenceForEvent(e); seq > 0 {
+					lastSeq = seq
+				}
+				return nil
+			}
 			select {
 			case <-done:
 				return ErrPlaybackShutdown
@@ -419,7 +507,6 @@ func (em *EventManager) Subscribe(ctx context.Context, ident string, filter func
 			}
 
 			// TODO: send an error frame or something?
-			close(out)
 			return
 		}
 
@@ -434,6 +521,9 @@ func (em *EventManager) Subscribe(ctx context.Context, ident string, filter func
 			if seq > SequenceForEvent(first) {
 				return ErrCaughtUp
 			}
+			if !filter(e) {
+				return nil
+			}
 
 			select {
 			case <-done:
@@ -446,7 +536,6 @@ func (em *EventManager) Subscribe(ctx context.Context, ident string, filter func
 				em.log.Error("events playback", "err", err)
 
 				// TODO: send an error frame or something?
-				close(out)
 				em.rmSubscriber(sub)
 				return
 			}
@@ -463,7 +552,71 @@ func (em *EventManager) Subscribe(ctx context.Context, ident string, filter func
 		}
 	}()
 
-	return out, sub.cleanup, nil
+	return sub, nil
+}
+
+// Plays back persisted events after the given cursor (sequence number), in order, passing each to the sink callback.
+//
+// Unlike Subscribe, this does not cross over to the live event stream: it returns nil once playback reaches the most recently persisted event (the "live tip"). Events persisted while playback is in progress may or may not be included. Backpressure is provided by the sink: the next event is not read until the callback returns.
+//
+// Returns early with ctx.Err() if the context is cancelled, or with the sink's error if it returns one.
+func (em *EventManager) Replay(ctx context.Context, since int64, sink func(*XRPCStreamEvent) error) error {
+	err := em.persister.Playback(ctx, since, func(e *XRPCStreamEvent) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// persisters should only return later events, but be defensive about duplicates at the cursor boundary
+		if seq, ok := e.GetSequence(); ok && seq <= since {
+			return nil
+		}
+		return sink(e)
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// Implemented by event persisters which can find the sequence number for a time more efficiently than a full playback scan (see [EventManager.SeqForTime])
+type TimeIndexedPersistence interface {
+	SeqForTime(ctx context.Context, t time.Time) (int64, error)
+}
+
+var errFoundTime = errors.New("found event at time")
+
+// Returns a cursor (sequence number) from which playback starts with the first persisted event at or after the given time, based on the 'time' field of the events (not when they were received by the relay).
+//
+// If t is before the earliest persisted event, the cursor is before that event, so playback starts from the beginning. If t is after the most recent event (the "live tip"), the cursor is that event, so a subscription starts with live events.
+//
+// Event times are not strictly ordered. This returns the cursor just before the first event (in sequence order) with a time at or after t, so a few earlier events may be included, and some events with time at or after t which were persisted before it may be missed.
+func (em *EventManager) SeqForTime(ctx context.Context, t time.Time) (int64, error) {
+	if tp, ok := em.persister.(TimeIndexedPersistence); ok {
+		return tp.SeqForTime(ctx, t)
+	}
+	return ScanSeqForTime(ctx, em.persister.Playback, 0, t)
+}
+
+// Implements the [EventManager.SeqForTime] search using playback from the given cursor, which should be before the events at time t. Persisters can use this to implement [TimeIndexedPersistence] after narrowing down the starting cursor.
+func ScanSeqForTime(ctx context.Context, playback func(ctx context.Context, since int64, cb func(*XRPCStreamEvent) error) error, since int64, t time.Time) (int64, error) {
+	cursor := since
+	err := playback(ctx, since, func(e *XRPCStreamEvent) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		seq, ok := e.GetSequence()
+		if !ok {
+			return nil
+		}
+		if et, ok := e.GetTime(); ok && !et.Before(t) {
+			return errFoundTime
+		}
+		cursor = seq
+		return nil
+	})
+	if err != nil && !errors.Is(err, errFoundTime) {
+		return 0, err
+	}
+	return cursor, nil
 }
 
 func SequenceForEvent(evt *XRPCStreamEvent) int64 {
@@ -524,6 +677,36 @@ func (evt *XRPCStreamEvent) GetSequence() (int64, bool) {
 	}
 }
 
+// Returns the 'time' field of a repo event, which is when the event was created by the upstream host (not when it was received)
+func (evt *XRPCStreamEvent) GetTime() (time.Time, bool) {
+	var raw string
+	switch {
+	case evt == nil:
+		return time.Time{}, false
+	case evt.RepoCommit != nil:
+		raw = evt.RepoCommit.Time
+	case evt.RepoSync != nil:
+		raw = evt.RepoSync.Time
+	case evt.RepoHandle != nil:
+		raw = evt.RepoHandle.Time
+	case evt.RepoMigrate != nil:
+		raw = evt.RepoMigrate.Time
+	case evt.RepoTombstone != nil:
+		raw = evt.RepoTombstone.Time
+	case evt.RepoIdentity != nil:
+		raw = evt.RepoIdentity.Time
+	case evt.RepoAccount != nil:
+		raw = evt.RepoAccount.Time
+	default:
+		return time.Time{}, false
+	}
+	dt, err := syntax.ParseDatetimeLenient(raw)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return dt.Time(), true
+}
+
 func (em *EventManager) rmSubscriber(sub *Subscriber) {
 	em.subsLk.Lock()
 	defer em.subsLk.Unlock()
@@ -0,0 +1,127 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bluesky-social/indigo/atproto/syntax"
+)
+
+// Maximum number of entries in each EventFilter list, to bound per-event matching cost
+const (
+	MaxFilterCollections = 100
+	MaxFilterDIDs        = 10_000
+)
+
+// EventFilter selects a subset of repo events for a single subscriber. The zero value (or nil) matches all events.
+//
+// Matching semantics:
+//
+//   - #commit events match if the repo DID is in WantedDIDs (when non-empty), and at least one op's collection matches WantedCollections (when non-empty). Matching commits are sent whole; ops for other collections are not removed.
+//   - #sync events only have the DID filter applied, as they have no ops.
+//   - all other events (#identity, #account, #info, and deprecated types) are always sent, so consumers can track account state.
+//
+// Entries in WantedCollections are either an exact NSID (eg, "app.bsky.feed.post"), or an NSID prefix ending in ".*" (eg, "app.bsky.feed.*"), which matches any collection under that prefix.
+type EventFilter struct {
+	dids        map[string]bool
+	collections map[string]bool
+	prefixes    []string
+}
+
+// Parses and validates filter lists (eg, from subscription query parameters). Returns a nil filter (matching all events) if both lists are empty.
+func NewEventFilter(wantedCollections, wantedDIDs []string) (*EventFilter, error) {
+	if len(wantedCollections) == 0 && len(wantedDIDs) == 0 {
+		return nil, nil
+	}
+	if len(wantedCollections) > MaxFilterCollections {
+		return nil, fmt.Errorf("too many wanted collections (max %d)", MaxFilterCollections)
+	}
+	if len(wantedDIDs) > MaxFilterDIDs {
+		return nil, fmt.Errorf("too many wanted DIDs (max %d)", MaxFilterDIDs)
+	}
+
+	f := &EventFilter{}
+	if len(wantedDIDs) > 0 {
+		f.dids = make(map[string]bool, len(wantedDIDs))
+		for _, raw := range wantedDIDs {
+			did, err := syntax.ParseDID(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid wanted DID: %w", err)
+			}
+			f.dids[did.String()] = true
+		}
+	}
+	if len(wantedCollections) > 0 {
+		f.collections = make(map[string]bool, len(wantedCollections))
+		for _, raw := range wantedCollections {
+			if prefix, ok := strings.CutSuffix(raw, ".*"); ok {
+				// the prefix must itself be a valid NSID-like dotted name
+				if _, err := syntax.ParseNSID(prefix + ".x"); err != nil {
+					return nil, fmt.Errorf("invalid wanted collection prefix: %s", raw)
+				}
+				f.prefixes = append(f.prefixes, prefix+".")
+				continue
+			}
+			nsid, err := syntax.ParseNSID(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid wanted collection: %w", err)
+			}
+			f.collections[nsid.String()] = true
+		}
+	}
+	return f, nil
+}
+
+func (f *EventFilter) wantsCollection(collection string) bool {
+	if f.collections[collection] {
+		return true
+	}
+	for _, p := range f.prefixes {
+		if strings.HasPrefix(collection, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the event should be sent to the subscriber. See [EventFilter] for the matching semantics.
+func (f *EventFilter) Match(evt *XRPCStreamEvent) bool {
+	if f == nil {
+		return true
+	}
+	switch {
+	case evt.RepoCommit != nil:
+		if f.dids != nil && !f.dids[evt.RepoCommit.Repo] {
+			return false
+		}
+		if f.collections == nil && f.prefixes == nil {
+			return true
+		}
+		for _, op := range evt.RepoCommit.Ops {
+			collection, _, _ := strings.Cut(op.Path, "/")
+			if f.wantsCollection(collection) {
+				return true
+			}
+		}
+		return false
+	case evt.RepoSync != nil:
+		return f.dids == nil || f.dids[evt.RepoSync.Did]
+	default:
+		return true
+	}
+}
+
+// Returns a subscriber filter function for use with [EventManager.Subscribe], which also counts filtered-out events. A nil EventFilter matches all events.
+func (f *EventFilter) Func() func(*XRPCStreamEvent) bool {
+	return func(evt *XRPCStreamEvent) bool {
+		if f.Match(evt) {
+			return true
+		}
+		if evt.RepoCommit != nil {
+			eventsFilteredOut.WithLabelValues("commit").Inc()
+		} else {
+			eventsFilteredOut.WithLabelValues("sync").Inc()
+		}
+		return false
+	}
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"testing"
+
+	comatproto "github.com/bluesky-social/indigo/api/atproto"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	commit := func(did string, paths ...string) *XRPCStreamEvent {
+		ops := []*comatproto.SyncSubscribeRepos_RepoOp{}
+		for _, p := range paths {
+			ops = append(ops, &comatproto.SyncSubscribeRepos_RepoOp{Action: "create", Path: p})
+		}
+		return &XRPCStreamEvent{RepoCommit: &comatproto.SyncSubscribeRepos_Commit{Repo: did, Ops: ops}}
+	}
+	post := commit("did:plc:aaa", "app.bsky.feed.post/3l3qo2vutsw2b")
+	like := commit("did:plc:bbb", "app.bsky.feed.like/3l3qo2vutsw2b")
+	profile := commit("did:plc:aaa", "app.bsky.actor.profile/self")
+	mixed := commit("did:plc:bbb", "app.bsky.actor.profile/self", "app.bsky.feed.post/3l3qo2vutsw2b")
+	sync := &XRPCStreamEvent{RepoSync: &comatproto.SyncSubscribeRepos_Sync{Did: "did:plc:bbb"}}
+	ident := &XRPCStreamEvent{RepoIdentity: &comatproto.SyncSubscribeRepos_Identity{Did: "did:plc:bbb"}}
+	account := &XRPCStreamEvent{RepoAccount: &comatproto.SyncSubscribeRepos_Account{Did: "did:plc:bbb"}}
+
+	// no filter
+	f, err := NewEventFilter(nil, nil)
+	assert.NoError(err)
+	assert.Nil(f)
+	assert.True(f.Match(post))
+	assert.True(f.Func()(sync))
+
+	// exact collections
+	f, err = NewEventFilter([]string{"app.bsky.feed.post"}, nil)
+	assert.NoError(err)
+	assert.True(f.Match(post))
+	assert.False(f.Match(like))
+	assert.False(f.Match(profile))
+	assert.True(f.Match(mixed))
+	assert.True(f.Match(sync))
+	assert.True(f.Match(ident))
+	assert.True(f.Match(account))
+
+	// collection prefix
+	f, err = NewEventFilter([]string{"app.bsky.feed.*"}, nil)
+	assert.NoError(err)
+	assert.True(f.Match(post))
+	assert.True(f.Match(like))
+	assert.False(f.Match(profile))
+
+	// DIDs, combined with collections
+	f, err = NewEventFilter([]string{"app.bsky.feed.post"}, []string{"did:plc:aaa"})
+	assert.NoError(err)
+	assert.True(f.Match(post))
+	assert.False(f.Match(like))
+	assert.False(f.Match(profile))
+	assert.False(f.Match(mixed))
+	assert.False(f.Match(sync))
+	assert.True(f.Match(ident))
+
+	before := testutil.ToFloat64(eventsFilteredOut.WithLabelValues("commit"))
+	assert.False(f.Func()(like))
+	assert.Equal(before+1, testutil.ToFloat64(eventsFilteredOut.WithLabelValues("commit")))
+
+	// invalid filters
+	for _, c := range [][]string{{"post"}, {"app.bsky.*.post"}, {".*"}, {"app..*"}} {
+		_, err = NewEventFilter(c, nil)
+		assert.Error(err, c)
+	}
+	_, err = NewEventFilter(nil, []string{"not-a-did"})
+	assert.Error(err)
+	_, err = NewEventFilter(make([]string, MaxFilterCollections+1), nil)
+	assert.Error(err)
+}
@@ -24,3 +24,13 @@ var eventsBroadcast = promauto.NewCounterVec(prometheus.CounterOpts{
 	Name: "indigo_events_broadcast_total",
 	Help: "Total number of events broadcast to subscribers",
 }, []string{"pool"})
+
+var consumersEvicted = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "indigo_events_consumers_evicted_total",
+	Help: "Total number of subscribers evicted for falling behind, by reason",
+}, []string{"reason"})
+
+var eventsFilteredOut = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "indigo_events_filtered_out_total",
+	Help: "Total number of events not sent to subscribers because of subscription filters, by event type",
+}, []string{"type"})
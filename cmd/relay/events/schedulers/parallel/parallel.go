@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 
 	"github.com/bluesky-social/indigo/cmd/relay/events"
 	"github.com/bluesky-social/indigo/events/schedulers"
@@ -24,6 +25,11 @@ type Scheduler struct {
 	lk     sync.Mutex
 	active map[string][]*consumerTask
 
+	// number of work items which have been added but not yet picked up by a worker
+	queued atomic.Int64
+	// number of workers currently running an event handler
+	inFlight atomic.Int64
+
 	ident string
 
 	// metrics
@@ -89,7 +95,9 @@ type consumerTask struct {
 	control string
 }
 
+// AddWork queues an event for processing. Events for the same repo are processed in order. The queue is not bounded by maxQ; see QueueFull.
 func (p *Scheduler) AddWork(ctx context.Context, repo string, val *events.XRPCStreamEvent) error {
+	p.queued.Add(1)
 	p.itemsAdded.Inc()
 	t := &consumerTask{
 		repo: repo,
@@ -111,6 +119,7 @@ func (p *Scheduler) AddWork(ctx context.Context, repo string, val *events.XRPCSt
 	case p.feeder <- t:
 		return nil
 	case <-ctx.Done():
+		p.queued.Add(-1)
 		return ctx.Err()
 	}
 }
@@ -123,10 +132,13 @@ func (p *Scheduler) worker() {
 				return
 			}
 
+			p.queued.Add(-1)
 			p.itemsActive.Inc()
+			p.inFlight.Add(1)
 			if err := p.do(context.TODO(), work.val); err != nil {
 				p.log.Error("event handler failed", "err", err)
 			}
+			p.inFlight.Add(-1)
 			p.itemsProcessed.Inc()
 
 			p.lk.Lock()
@@ -146,3 +158,18 @@ func (p *Scheduler) worker() {
 		}
 	}
 }
+
+// InFlight returns the number of workers currently processing an event
+func (p *Scheduler) InFlight() int {
+	return int(p.inFlight.Load())
+}
+
+// QueueDepth returns the number of events which have been added but not yet picked up by a worker
+func (p *Scheduler) QueueDepth() int {
+	return int(p.queued.Load())
+}
+
+// QueueFull reports whether the queue depth has reached maxQ. This is only a signal for metrics: AddWork does not block on a full queue. Always false if maxQ <= 0.
+func (p *Scheduler) QueueFull() bool {
+	return p.maxQueue > 0 && p.QueueDepth() >= p.maxQueue
+}
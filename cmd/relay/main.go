@@ -129,6 +129,61 @@ func run(args []string) error {
 			EnvVars: []string{"RELAY_DID_CACHE_SIZE"},
 			Value:   5_000_000,
 		},
+		&cli.DurationFlag{
+			Name:    "account-cache-ttl",
+			Usage:   "maximum age of in-process cached account state before re-reading from database (zero for no expiry)",
+			EnvVars: []string{"RELAY_ACCOUNT_CACHE_TTL"},
+		},
+		&cli.DurationFlag{
+			Name:    "max-reconnect-backoff",
+			Usage:   "maximum delay between reconnection attempts to a single upstream host",
+			EnvVars: []string{"RELAY_MAX_RECONNECT_BACKOFF"},
+			Value:   30 * time.Second,
+		},
+		&cli.BoolFlag{
+			Name:    "domain-ban-exact-match",
+			Usage:   "only apply domain bans to the exact hostname, instead of also covering all subdomains",
+			EnvVars: []string{"RELAY_DOMAIN_BAN_EXACT_MATCH"},
+		},
+		&cli.IntFlag{
+			Name:    "account-cache-size",
+			Usage:   "maximum number of accounts held in the in-process cache",
+			EnvVars: []string{"RELAY_ACCOUNT_CACHE_SIZE"},
+			Value:   1_000_000,
+		},
+		&cli.IntFlag{
+			Name:    "consumer-buffer-size",
+			Usage:   "number of events buffered for each firehose consumer; consumers which fall further behind are disconnected",
+			EnvVars: []string{"RELAY_CONSUMER_BUFFER_SIZE"},
+			Value:   16 << 10,
+		},
+		&cli.DurationFlag{
+			Name:    "consumer-write-timeout",
+			Usage:   "disconnect firehose consumers when writing a single event takes longer than this (0 for no limit)",
+			EnvVars: []string{"RELAY_CONSUMER_WRITE_TIMEOUT"},
+			Value:   0,
+		},
+		&cli.BoolFlag{
+			Name:    "ws-compression",
+			Usage:   "negotiate websocket compression (permessage-deflate) with firehose consumers which support it",
+			EnvVars: []string{"RELAY_WS_COMPRESSION"},
+		},
+		&cli.Float64Flag{
+			Name:    "reject-sample-rate",
+			Usage:   "fraction (0.0 to 1.0) of rejected #commit messages to store, with their blocks, in the rejected_commits table for debugging",
+			EnvVars: []string{"RELAY_REJECT_SAMPLE_RATE"},
+		},
+		&cli.IntFlag{
+			Name:    "reject-samples-per-reason",
+			Usage:   "number of recent rejected #commit samples to keep for each failure reason",
+			EnvVars: []string{"RELAY_REJECT_SAMPLES_PER_REASON"},
+			Value:   100,
+		},
+		&cli.StringFlag{
+			Name:    "user-agent",
+			Usage:   "User-Agent header for requests and firehose subscriptions to upstream hosts (default is library defaults)",
+			EnvVars: []string{"RELAY_USER_AGENT"},
+		},
 		&cli.DurationFlag{
 			Name:    "event-playback-ttl",
 			Usage:   "time to live for event playback buffering (only applies to disk persister)",
@@ -145,6 +200,66 @@ func run(args []string) error {
 			Usage:   "file path to log debug trace stuff about induction firehose",
 			EnvVars: []string{"RELAY_TRACE_INDUCTION"},
 		},
+		&cli.DurationFlag{
+			Name:    "max-rev-future",
+			Usage:   "maximum clock skew to accept for commit revs in the future",
+			EnvVars: []string{"RELAY_MAX_REV_FUTURE"},
+			Value:   time.Hour,
+		},
+		&cli.IntFlag{
+			Name:    "max-ops-per-commit",
+			Usage:   "reject commits with more than this many ops",
+			EnvVars: []string{"RELAY_MAX_OPS_PER_COMMIT"},
+			Value:   1_000,
+		},
+		&cli.IntFlag{
+			Name:    "max-commit-blocks-bytes",
+			Usage:   "reject commit and sync messages with a CAR slice (blocks) larger than this many bytes, before parsing",
+			EnvVars: []string{"RELAY_MAX_COMMIT_BLOCKS_BYTES"},
+			Value:   5_000_000,
+		},
+		&cli.BoolFlag{
+			Name:    "resolve-identity-events",
+			Usage:   "resolve the DID of each #identity event, counting handle or PDS mismatches as warnings",
+			EnvVars: []string{"RELAY_RESOLVE_IDENTITY_EVENTS"},
+		},
+		&cli.BoolFlag{
+			Name:    "require-prev-data",
+			Usage:   "reject #commit messages without prevData (legacy protocol), instead of passing them with a warning",
+			EnvVars: []string{"RELAY_REQUIRE_PREV_DATA"},
+		},
+		&cli.IntFlag{
+			Name:    "validator-key-cache-size",
+			Usage:   "number of DID signing keys cached by the commit validator (0 to disable)",
+			EnvVars: []string{"RELAY_VALIDATOR_KEY_CACHE_SIZE"},
+			Value:   0,
+		},
+		&cli.DurationFlag{
+			Name:    "validator-key-cache-ttl",
+			Usage:   "how long the commit validator uses a cached DID signing key",
+			EnvVars: []string{"RELAY_VALIDATOR_KEY_CACHE_TTL"},
+			Value:   10 * time.Minute,
+		},
+		&cli.BoolFlag{
+			Name:    "reject-legacy-ops",
+			Usage:   "reject #commit messages with update or delete ops missing a prev CID (legacy protocol), instead of passing them with a warning",
+			EnvVars: []string{"RELAY_REJECT_LEGACY_OPS"},
+		},
+		&cli.BoolFlag{
+			Name:    "check-blob-refs",
+			Usage:   "decode records in #commit messages, and reject commits with malformed blob reference CIDs",
+			EnvVars: []string{"RELAY_CHECK_BLOB_REFS"},
+		},
+		&cli.BoolFlag{
+			Name:    "allow-any-signing-key",
+			Usage:   "accept #commit signatures by any signing key in the account's DID document, not only the atproto key (lenient; for key rotation windows)",
+			EnvVars: []string{"RELAY_ALLOW_ANY_SIGNING_KEY"},
+		},
+		&cli.Float64Flag{
+			Name:    "host-error-alert-threshold",
+			Usage:   "log a warning when a host's moving-average commit verification error fraction exceeds this (0.0 to 1.0; zero disables)",
+			EnvVars: []string{"RELAY_HOST_ERROR_ALERT_THRESHOLD"},
+		},
 		&cli.BoolFlag{
 			Name:    "time-seq",
 			EnvVars: []string{"RELAY_TIME_SEQUENCE"},
@@ -212,7 +327,22 @@ func runRelay(cctx *cli.Context) error {
 	cacheDir := identity.NewCacheDirectory(&baseDir, cctx.Int("did-cache-size"), time.Hour*24, time.Minute*2, time.Minute*5)
 
 	// TODO: rename repoman
-	repoman := libbgs.NewValidator(&cacheDir, inductionTraceLog)
+	valConfig := libbgs.DefaultValidatorConfig()
+	valConfig.MaxRevFuture = cctx.Duration("max-rev-future")
+	valConfig.MaxOpsPerCommit = cctx.Int("max-ops-per-commit")
+	valConfig.MaxCommitBlocksBytes = cctx.Int("max-commit-blocks-bytes")
+	valConfig.ResolveIdentityEvents = cctx.Bool("resolve-identity-events")
+	valConfig.RequirePrevData = cctx.Bool("require-prev-data")
+	valConfig.RejectLegacyOps = cctx.Bool("reject-legacy-ops")
+	valConfig.CheckBlobRefs = cctx.Bool("check-blob-refs")
+	valConfig.AllowAnySigningKey = cctx.Bool("allow-any-signing-key")
+	valConfig.KeyCacheSize = cctx.Int("validator-key-cache-size")
+	valConfig.KeyCacheTTL = cctx.Duration("validator-key-cache-ttl")
+	valConfig.HostErrorRateThreshold = cctx.Float64("host-error-alert-threshold")
+	valConfig.OnHostErrorRate = func(hostname string, errorRate float64) {
+		logger.Warn("host commit verification error rate above threshold", "host", hostname, "errorRate", errorRate)
+	}
+	repoman := libbgs.NewValidatorWithConfig(&cacheDir, inductionTraceLog, valConfig)
 
 	var persister events.EventPersistence
 
@@ -253,6 +383,7 @@ func runRelay(cctx *cli.Context) error {
 	persister = dp
 
 	evtman := events.NewEventManager(persister)
+	evtman.SetSubscriberBufferSize(cctx.Int("consumer-buffer-size"))
 
 	ratelimitBypass := cctx.String("bsky-social-rate-limit-skip")
 
@@ -264,6 +395,15 @@ func runRelay(cctx *cli.Context) error {
 	bgsConfig.DefaultRepoLimit = cctx.Int64("default-repo-limit")
 	bgsConfig.ApplyPDSClientSettings = makePdsClientSetup(ratelimitBypass)
 	bgsConfig.InductionTraceLog = inductionTraceLog
+	bgsConfig.AccountCacheTTL = cctx.Duration("account-cache-ttl")
+	bgsConfig.AccountCacheSize = cctx.Int("account-cache-size")
+	bgsConfig.MaxReconnectBackoff = cctx.Duration("max-reconnect-backoff")
+	bgsConfig.DomainBanExactMatch = cctx.Bool("domain-ban-exact-match")
+	bgsConfig.ConsumerWriteTimeout = cctx.Duration("consumer-write-timeout")
+	bgsConfig.EnableWSCompression = cctx.Bool("ws-compression")
+	bgsConfig.UserAgent = cctx.String("user-agent")
+	bgsConfig.RejectSampleRate = cctx.Float64("reject-sample-rate")
+	bgsConfig.RejectSamplesPerReason = cctx.Int("reject-samples-per-reason")
 	nextCrawlers := cctx.StringSlice("next-crawler")
 	if len(nextCrawlers) != 0 {
 		nextCrawlerUrls := make([]*url.URL, len(nextCrawlers))
@@ -73,6 +73,9 @@ type PDS struct {
 	Registered bool
 	Blocked    bool
 
+	// LastReceivedSeq is the most recent sequence number received from the host, which may be ahead of Cursor (the last sequence processed)
+	LastReceivedSeq int64
+
 	RateLimit float64
 
 	RepoCount int64